@@ -0,0 +1,454 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core/database"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/achievement"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/auth"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/backup"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/cache"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/catalog"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/difficulty"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/leaderboard"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// cliActor is recorded as the actor of command line actions in audit_logs
+const cliActor = "cli"
+
+// Commands are the maintenance commands of the server binary. They read the
+// same Config as the server, so they work on the database it serves.
+// Every command runs with the context.Context and the io.Writer for its
+// output bound by the caller.
+type Commands struct {
+	Migrate     migrateCmd     `cmd:"" help:"Manage the database schema."`
+	Chart       chartCmd       `cmd:"" help:"Manage charts."`
+	User        userCmd        `cmd:"" help:"Manage players."`
+	Score       scoreCmd       `cmd:"" help:"Maintain values derived from scores."`
+	Ranking     rankingCmd     `cmd:"" help:"Maintain ranking snapshots."`
+	Achievement achievementCmd `cmd:"" help:"Maintain player achievements."`
+	Export      exportCmd      `cmd:"" help:"Write users, charts and scores as a versioned dump."`
+	Import      importCmd      `cmd:"" help:"Read a dump written by export."`
+}
+
+// withRepository connects to and migrates the database, like the server does on start
+func withRepository(config *Config, f func(*repository.Repository) error) (err error) {
+	db, err := config.SetupDatabase()
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, db.Close()) }()
+	return f(repository.New(db))
+}
+
+// withDatabase connects to the database without migrating it
+func withDatabase(config *Config, f func(*sqlx.DB) error) (err error) {
+	db, err := config.OpenDatabase()
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, db.Close()) }()
+	return f(db)
+}
+
+type migrateCmd struct {
+	Up     migrateUpCmd     `cmd:"" help:"Apply every pending migration."`
+	Down   migrateDownCmd   `cmd:"" help:"Roll back migrations."`
+	Status migrateStatusCmd `cmd:"" help:"List migrations and whether they are applied."`
+}
+
+type migrateUpCmd struct{}
+
+func (cmd *migrateUpCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	return withDatabase(config, func(db *sqlx.DB) error {
+		res, err := database.MigrateUp(ctx, db)
+		for _, r := range res {
+			fmt.Fprintln(out, r)
+		}
+		if err != nil {
+			return err
+		}
+		if len(res) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return nil
+	})
+}
+
+type migrateDownCmd struct {
+	To int64 `default:"-1" help:"Roll back every migration after this version instead of only the latest one."`
+}
+
+func (cmd *migrateDownCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	return withDatabase(config, func(db *sqlx.DB) error {
+		res, err := database.MigrateDown(ctx, db, cmd.To)
+		for _, r := range res {
+			fmt.Fprintln(out, r)
+		}
+		return err
+	})
+}
+
+type migrateStatusCmd struct{}
+
+func (cmd *migrateStatusCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	return withDatabase(config, func(db *sqlx.DB) error {
+		ss, err := database.MigrationStatus(ctx, db)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tMIGRATION")
+		for _, s := range ss {
+			applied := "-"
+			if !s.AppliedAt.IsZero() {
+				applied = s.AppliedAt.UTC().Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Source.Version, s.State, applied, filepath.Base(s.Source.Path))
+		}
+		return w.Flush()
+	})
+}
+
+type chartCmd struct {
+	Import chartImportCmd `cmd:"" help:"Register or update the charts of a catalog file."`
+	Export chartExportCmd `cmd:"" help:"Write every chart as a catalog file."`
+}
+
+type chartImportCmd struct {
+	File   string `arg:"" optional:"" type:"existingfile" help:"Catalog file. Read from stdin when omitted."`
+	Format string `enum:",csv,json,yaml" default:"" help:"csv, json or yaml. Told from the file extension when omitted, json for stdin."`
+	DryRun bool   `help:"Print the changes without writing them."`
+}
+
+func (cmd *chartImportCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	format, err := catalogFormat(cmd.Format, cmd.File)
+	if err != nil {
+		return err
+	}
+	in := io.Reader(os.Stdin)
+	if cmd.File != "" {
+		f, err := os.Open(cmd.File)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	es, err := catalog.Decode(in, format)
+	if err != nil {
+		return fmt.Errorf("read catalog: %w", err)
+	}
+	// reject the whole file before writing anything
+	if err := catalog.Validate(es); err != nil {
+		return err
+	}
+
+	return withRepository(config, func(repo *repository.Repository) error {
+		res, err := catalog.New(repo).Import(ctx, es, catalog.ImportOptions{DryRun: cmd.DryRun, Actor: cliActor})
+		if err != nil {
+			return err
+		}
+		for _, ch := range res.Changes {
+			fs := make([]string, len(ch.Fields))
+			for i, f := range ch.Fields {
+				if ch.Op == catalog.OpCreate {
+					fs[i] = fmt.Sprintf("%s=%s", f.Field, showValue(f.New))
+				} else {
+					fs[i] = fmt.Sprintf("%s: %s -> %s", f.Field, showValue(f.Old), showValue(f.New))
+				}
+			}
+			fmt.Fprintf(out, "%s %s: %s\n", ch.Op, ch.BeatmapID, strings.Join(fs, ", "))
+		}
+		fmt.Fprintf(out, "%d created, %d updated, %d unchanged", res.Created, res.Updated, res.Unchanged)
+		if res.DryRun {
+			fmt.Fprint(out, " (dry run, nothing written)")
+		}
+		fmt.Fprintln(out)
+		return nil
+	})
+}
+
+type chartExportCmd struct {
+	Output string `short:"o" type:"path" help:"File to write. Written to stdout when omitted."`
+	Format string `enum:",csv,json,yaml" default:"" help:"csv, json or yaml. Told from the extension of --output when omitted, json for stdout."`
+}
+
+func (cmd *chartExportCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	format, err := catalogFormat(cmd.Format, cmd.Output)
+	if err != nil {
+		return err
+	}
+	return withRepository(config, func(repo *repository.Repository) (err error) {
+		es, err := catalog.New(repo).Export(ctx)
+		if err != nil {
+			return err
+		}
+		if cmd.Output != "" {
+			f, cerr := os.Create(cmd.Output)
+			if cerr != nil {
+				return cerr
+			}
+			defer func() { err = errors.Join(err, f.Close()) }()
+			out = f
+		}
+		return catalog.Encode(out, format, es)
+	})
+}
+
+// catalogFormat is the format given by flag, or else told from the file name
+func catalogFormat(flag string, path string) (catalog.Format, error) {
+	switch {
+	case flag != "":
+		return catalog.ParseFormat(flag)
+	case path != "":
+		return catalog.FormatOf(path)
+	default:
+		return catalog.FormatJSON, nil
+	}
+}
+
+// showValue prints a catalog value in a diff
+func showValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type userCmd struct {
+	Ban    userBanCmd    `cmd:"" help:"Ban a player from submitting scores and invalidate their scores."`
+	Unban  userUnbanCmd  `cmd:"" help:"Let a banned player submit scores again."`
+	Token  userTokenCmd  `cmd:"" help:"Issue a new token for a player, replacing their current one."`
+	Delete userDeleteCmd `cmd:"" help:"Delete the account of a player and anonymise their scores."`
+}
+
+type userBanCmd struct {
+	UserID string `arg:"" help:"ID of the player."`
+	Reason string `help:"Recorded in the audit log and on the invalidated scores."`
+}
+
+func (cmd *userBanCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	return withRepository(config, func(repo *repository.Repository) error {
+		n, err := repo.BanUser(ctx, repository.BanUserParams{
+			UserID: cmd.UserID,
+			Reason: cmd.Reason,
+			Actor:  cliActor,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "banned %s, invalidated %d scores\n", cmd.UserID, n)
+		return nil
+	})
+}
+
+type userUnbanCmd struct {
+	UserID string `arg:"" help:"ID of the player."`
+}
+
+func (cmd *userUnbanCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	return withRepository(config, func(repo *repository.Repository) error {
+		if err := repo.UnbanUser(ctx, cmd.UserID, cliActor); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "unbanned %s\n", cmd.UserID)
+		return nil
+	})
+}
+
+type userTokenCmd struct {
+	UserID string `arg:"" help:"ID of the player."`
+}
+
+// Run prints the token, which is shown nowhere else
+func (cmd *userTokenCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return err
+	}
+	return withRepository(config, func(repo *repository.Repository) error {
+		if err := repo.SetUserToken(ctx, cmd.UserID, hash, cliActor); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, token)
+		return nil
+	})
+}
+
+type userDeleteCmd struct {
+	UserID string `arg:"" help:"ID of the player."`
+}
+
+func (cmd *userDeleteCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	return withRepository(config, func(repo *repository.Repository) error {
+		n, err := repo.DeleteUser(ctx, repository.DeleteUserParams{UserID: cmd.UserID, Actor: cliActor})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "deleted %s, anonymised %d scores\n", cmd.UserID, n)
+		return nil
+	})
+}
+
+type scoreCmd struct {
+	Recompute scoreRecomputeCmd `cmd:"" help:"Re-estimate chart difficulty constants from the scores."`
+}
+
+type scoreRecomputeCmd struct{}
+
+func (cmd *scoreRecomputeCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	return withRepository(config, func(repo *repository.Repository) error {
+		n, err := difficulty.NewEstimator(repo).Recompute(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "estimated %d charts\n", n)
+		return nil
+	})
+}
+
+type rankingCmd struct {
+	Rebuild rankingRebuildCmd `cmd:"" help:"Rebuild the ranking snapshots now."`
+}
+
+type rankingRebuildCmd struct{}
+
+// Run rebuilds the snapshots in the database. Running servers keep serving
+// results they have cached for up to CACHE_TTL.
+func (cmd *rankingRebuildCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	return withRepository(config, func(repo *repository.Repository) error {
+		lb := leaderboard.New(repo, cache.Nop{}, leaderboard.Options{Snapshots: true})
+		if err := lb.Rebuild(ctx); err != nil {
+			return err
+		}
+		at, err := lb.SnapshotTime(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "rebuilt ranking snapshots at %s\n", at.UTC().Format(time.RFC3339))
+		return nil
+	})
+}
+
+type achievementCmd struct {
+	Backfill achievementBackfillCmd `cmd:"" help:"Unlock the achievements every player has earned, such as after adding achievements."`
+}
+
+type achievementBackfillCmd struct{}
+
+func (cmd *achievementBackfillCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	defs, err := config.Achievements()
+	if err != nil {
+		return err
+	}
+	return withRepository(config, func(repo *repository.Repository) error {
+		players, unlocks, err := achievement.New(repo, defs).Backfill(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "evaluated %d players, unlocked %d achievements\n", players, unlocks)
+		return nil
+	})
+}
+
+type exportCmd struct {
+	Output string    `short:"o" type:"path" help:"File to write. Written to stdout when omitted."`
+	Format string    `enum:",jsonl,tar" default:"" help:"jsonl, or tar for a gzipped tar. Told from the extension of --output when omitted, jsonl for stdout."`
+	Since  time.Time `help:"Only export users updated and scores created, deleted or invalidated at or after this time (RFC3339)."`
+	Until  time.Time `help:"Only export rows changed before this time (RFC3339)."`
+}
+
+func (cmd *exportCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	format, err := dumpFormat(cmd.Format, cmd.Output)
+	if err != nil {
+		return err
+	}
+	return withBackup(config, func(b *backup.Service) (err error) {
+		d, err := b.Export(ctx, backup.Options{From: cmd.Since, To: cmd.Until})
+		if err != nil {
+			return err
+		}
+		if cmd.Output != "" {
+			f, cerr := os.Create(cmd.Output)
+			if cerr != nil {
+				return cerr
+			}
+			defer func() { err = errors.Join(err, f.Close()) }()
+			out = f
+		}
+		return backup.Encode(out, format, d)
+	})
+}
+
+type importCmd struct {
+	File   string `arg:"" type:"existingfile" help:"Dump written by export."`
+	Format string `enum:",jsonl,tar" default:"" help:"jsonl or tar. Told from the extension of the file when omitted."`
+}
+
+// Run reads the whole dump and checks its references before writing anything
+func (cmd *importCmd) Run(ctx context.Context, config *Config, out io.Writer) error {
+	format, err := dumpFormat(cmd.Format, cmd.File)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(cmd.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	d, err := backup.Decode(f, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd.File, err)
+	}
+
+	return withBackup(config, func(b *backup.Service) error {
+		if err := b.Validate(ctx, d); err != nil {
+			return err
+		}
+		res, err := b.Import(ctx, d, cliActor)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "imported %d users, %d charts, %d scores (%d already stored, %d moderated)\n", res.Users, res.Charts, res.Scores, res.SkippedScores, res.ModeratedScores)
+		return nil
+	})
+}
+
+// withBackup is withRepository for the export and import service
+func withBackup(config *Config, f func(*backup.Service) error) (err error) {
+	db, err := config.SetupDatabase()
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, db.Close()) }()
+	return f(newBackup(db))
+}
+
+// dumpFormat is the format given by flag, or else told from the file name
+func dumpFormat(flag string, path string) (backup.Format, error) {
+	switch {
+	case flag != "":
+		return backup.ParseFormat(flag)
+	case path != "":
+		return backup.FormatOf(path)
+	default:
+		return backup.FormatJSONL, nil
+	}
+}
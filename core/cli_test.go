@@ -0,0 +1,315 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/auth"
+)
+
+// runCommand runs the command line args against the SQLite database at path
+// and returns its output
+func runCommand(t *testing.T, path string, args ...string) (string, error) {
+	t.Helper()
+	var cli struct {
+		Config
+		Commands
+	}
+	parser, err := kong.New(&cli, kong.Exit(func(int) { t.Fatalf("exited parsing %q", args) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kctx, err := parser.Parse(append([]string{"--db-driver=sqlite", "--sq-lite-path=" + path}, args...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	kctx.BindTo(context.Background(), (*context.Context)(nil))
+	kctx.BindTo(&out, (*io.Writer)(nil))
+	err = kctx.Run(&cli.Config)
+	return out.String(), err
+}
+
+func TestMigrateCommands(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "app.db")
+
+	out, err := runCommand(t, path, "migrate", "up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "up 1_schema.sql") {
+		t.Errorf("migrate up: %s", out)
+	}
+
+	if _, err := runCommand(t, path, "migrate", "down", "--to", "1"); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runCommand(t, path, "migrate", "status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if !strings.Contains(lines[1], "applied") || !strings.Contains(lines[2], "pending") {
+		t.Errorf("migrate status after rolling back to 1:\n%s", out)
+	}
+
+	if _, err := runCommand(t, path, "migrate", "up"); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runCommand(t, path, "migrate", "up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "no pending migrations\n" {
+		t.Errorf("second migrate up: %s", out)
+	}
+}
+
+func TestUserBanCommand(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "app.db")
+
+	if _, err := runCommand(t, path, "chart", "import", filepath.Join("testdata", "charts.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{DBDriver: DBDriverSQLite, SQLitePath: path}
+	db, err := config.SetupDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	repo := repository.New(db)
+
+	uid, err := repo.CreateUser(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	score := repository.InsertScoreParams{UserID: uid.String(), BeatmapID: "cli_past", Score: 900000}
+	if _, err := repo.InsertScore(ctx, score); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCommand(t, path, "user", "ban", uid.String(), "--reason", "cheating")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "banned " + uid.String() + ", invalidated 1 scores\n"; out != want {
+		t.Errorf("user ban: %q, want %q", out, want)
+	}
+	if _, err := repo.InsertScore(ctx, score); !errors.Is(err, repository.ErrUserBanned) {
+		t.Errorf("score of a banned user: %v, want ErrUserBanned", err)
+	}
+	if bests, err := repo.GetUserBests(ctx, uid.String()); err != nil || len(bests) != 0 {
+		t.Errorf("bests of a banned user: %v, %v", bests, err)
+	}
+
+	if _, err := runCommand(t, path, "user", "unban", uid.String()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.InsertScore(ctx, score); err != nil {
+		t.Errorf("score after unban: %v", err)
+	}
+
+	if _, err := runCommand(t, path, "user", "ban", "00000000-0000-0000-0000-000000000000"); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("ban of an unknown user: %v", err)
+	}
+}
+
+func TestExportImportCommands(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src.db"), filepath.Join(dir, "dst.db")
+
+	if _, err := runCommand(t, src, "chart", "import", filepath.Join("testdata", "charts.json")); err != nil {
+		t.Fatal(err)
+	}
+	config := Config{DBDriver: DBDriverSQLite, SQLitePath: src}
+	db, err := config.SetupDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	repo := repository.New(db)
+	uid, err := repo.CreateUser(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []int{800000, 900000} {
+		if _, err := repo.InsertScore(ctx, repository.InsertScoreParams{UserID: uid.String(), BeatmapID: "cli_past", Score: s}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"dump.jsonl", "dump.tar.gz"} {
+		file := filepath.Join(dir, name)
+		if _, err := runCommand(t, src, "export", "-o", file); err != nil {
+			t.Fatal(err)
+		}
+		out, err := runCommand(t, dst, "import", file)
+		if err != nil {
+			t.Fatalf("import %s: %v", name, err)
+		}
+		// the second dump holds the same scores, which are already stored
+		want := "imported 1 users, 2 charts, 2 scores (0 already stored, 0 moderated)\n"
+		if name == "dump.tar.gz" {
+			want = "imported 1 users, 2 charts, 0 scores (2 already stored, 0 moderated)\n"
+		}
+		if out != want {
+			t.Errorf("import %s: %q, want %q", name, out, want)
+		}
+	}
+
+	// an incremental dump after the scores were played refers to no rows but the charts
+	file := filepath.Join(dir, "later.jsonl")
+	if _, err := runCommand(t, src, "export", "-o", file, "--since", "2999-01-01T00:00:00Z"); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := runCommand(t, dst, "import", file); err != nil || out != "imported 0 users, 2 charts, 0 scores (0 already stored, 0 moderated)\n" {
+		t.Errorf("import of an empty incremental dump: %q, %v", out, err)
+	}
+}
+
+func TestUserDeleteCommand(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "app.db")
+
+	if _, err := runCommand(t, path, "chart", "import", filepath.Join("testdata", "charts.json")); err != nil {
+		t.Fatal(err)
+	}
+	config := Config{DBDriver: DBDriverSQLite, SQLitePath: path}
+	db, err := config.SetupDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	repo := repository.New(db)
+
+	uid, err := repo.CreateUser(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []int{800000, 900000} {
+		if _, err := repo.InsertScore(ctx, repository.InsertScoreParams{UserID: uid.String(), BeatmapID: "cli_past", Score: s}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := runCommand(t, path, "user", "token", uid.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, err := repo.GetUserByTokenHash(ctx, auth.Hash(strings.TrimSpace(out))); err != nil || u.ID != uid.String() {
+		t.Errorf("user of the issued token: %v, %v", u, err)
+	}
+
+	out, err = runCommand(t, path, "user", "delete", uid.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "deleted " + uid.String() + ", anonymised 2 scores\n"; out != want {
+		t.Errorf("user delete: %q, want %q", out, want)
+	}
+	if _, err := repo.GetUser(ctx, uid.String()); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("deleted user: %v", err)
+	}
+	if ns, err := repo.GetUserNames(ctx, uid.String()); err != nil || len(ns) != 0 {
+		t.Errorf("names of a deleted user: %v, %v", ns, err)
+	}
+
+	// the scores still count, under a placeholder
+	stats, err := repo.GetChartStats(ctx, "cli_past")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.PlayCount != 2 || stats.PlayerCount != 1 {
+		t.Errorf("chart stats after deletion: %+v", stats)
+	}
+	ranking, err := repo.GetChartRanking(ctx, "cli_past", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	placeholder := ranking.Top[0].UserID
+	if ranking.Top[0].Name != repository.DeletedUserName || placeholder == uid.String() {
+		t.Errorf("ranking after deletion: %+v", ranking.Top[0])
+	}
+	if _, err := repo.InsertScore(ctx, repository.InsertScoreParams{UserID: placeholder, BeatmapID: "cli_past", Score: 1}); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("score of the placeholder: %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestAchievementBackfillCommand(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.db")
+
+	if _, err := runCommand(t, path, "chart", "import", filepath.Join("testdata", "charts.json")); err != nil {
+		t.Fatal(err)
+	}
+	config := Config{DBDriver: DBDriverSQLite, SQLitePath: path}
+	db, err := config.SetupDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	repo := repository.New(db)
+
+	for range 2 {
+		if _, err := repo.CreateUser(ctx, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uid, err := repo.CreateUser(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []int{800000, 900000} {
+		if _, err := repo.InsertScore(ctx, repository.InsertScoreParams{UserID: uid.String(), BeatmapID: "cli_past", Score: s}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// first_play and first_clear
+	out, err := runCommand(t, path, "achievement", "backfill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "evaluated 3 players, unlocked 2 achievements\n"; out != want {
+		t.Errorf("achievement backfill: %q, want %q", out, want)
+	}
+
+	file := filepath.Join(dir, "achievements.yaml")
+	if err := os.WriteFile(file, []byte("- {id: past_twice, name: Twice, condition: {count: 2, difficulty: past}}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"unlocked 1 achievements", "unlocked 0 achievements"} {
+		out, err := runCommand(t, path, "--achievements-file", file, "achievement", "backfill")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, want) {
+			t.Errorf("achievement backfill with %s: %q, want %q", file, out, want)
+		}
+	}
+
+	as, err := repo.GetUserAchievements(ctx, uid.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(as) != 3 {
+		t.Errorf("unlocked achievements: %+v", as)
+	}
+}
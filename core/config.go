@@ -1,25 +1,181 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core/database"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/achievement"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/logging"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/mission"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/tracing"
 
-	"github.com/alecthomas/kong"
 	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// serviceName identifies the server in traces
+const serviceName = "senirenol-server"
+
+// Database drivers selectable with DB_DRIVER
+const (
+	DBDriverMySQL    = "mysql"
+	DBDriverPostgres = "postgres"
+	DBDriverSQLite   = "sqlite"
 )
 
 type Config struct {
 	AppAddr string `env:"APP_ADDR" default:":8080"`
-	DBUser  string `env:"DB_USER" default:"root"`
-	DBPass  string `env:"DB_PASS" default:"pass"`
-	DBHost  string `env:"DB_HOST" default:"localhost"`
-	DBPort  int    `env:"DB_PORT" default:"3306"`
-	DBName  string `env:"DB_NAME" default:"app"`
+	// ShutdownTimeout is how long in-flight requests may take to finish after SIGTERM
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" default:"30s"`
+
+	LogLevel  string `env:"LOG_LEVEL" default:"info" enum:"debug,info,warn,error"`
+	LogFormat string `env:"LOG_FORMAT" default:"json" enum:"json,text"`
+
+	// TraceExporter sends traces over OTLP/HTTP or prints them to stdout. none disables tracing.
+	TraceExporter string `env:"TRACE_EXPORTER" default:"none" enum:"none,otlp,stdout"`
+	// TraceEndpoint is the OTLP/HTTP collector URL. Empty uses OTEL_EXPORTER_OTLP_* or http://localhost:4318.
+	TraceEndpoint    string  `env:"TRACE_ENDPOINT" default:""`
+	TraceSampleRatio float64 `env:"TRACE_SAMPLE_RATIO" default:"1"`
+
+	// DBDriver selects the storage backend. DB_* configure mysql and postgres.
+	DBDriver string `env:"DB_DRIVER" default:"mysql" enum:"mysql,postgres,sqlite"`
+	DBUser   string `env:"DB_USER" default:"root"`
+	DBPass   string `env:"DB_PASS" default:"pass"`
+	DBHost   string `env:"DB_HOST" default:"localhost"`
+	DBPort   int    `env:"DB_PORT" default:"3306"`
+	DBName   string `env:"DB_NAME" default:"app"`
+	// DBSSLMode is the PostgreSQL sslmode
+	DBSSLMode string `env:"DB_SSLMODE" default:"prefer"`
+
+	// SQLitePath is the database file used when DB_DRIVER=sqlite
+	SQLitePath string `env:"SQLITE_PATH" default:"app.db"`
+
+	// DifficultyEstimationInterval is how often chart difficulty constants are re-estimated. 0 disables the job.
+	DifficultyEstimationInterval time.Duration `env:"DIFFICULTY_ESTIMATION_INTERVAL" default:"1h"`
+	// RankingSnapshotInterval is how often ranking snapshots are rebuilt.
+	// Rankings are served from the snapshots; 0 disables them and serves live queries.
+	RankingSnapshotInterval time.Duration `env:"RANKING_SNAPSHOT_INTERVAL" default:"1m"`
+
+	// CacheTTL is how long ranking results are cached in memory. 0 disables the cache.
+	CacheTTL time.Duration `env:"CACHE_TTL" default:"30s"`
+	// CacheMaxBytes bounds the total size of cached results
+	CacheMaxBytes int64 `env:"CACHE_MAX_BYTES" default:"16777216"`
+
+	// AdminToken guards /api/v1/admin. Admin APIs are disabled when empty.
+	AdminToken string `env:"ADMIN_TOKEN" default:""`
+	// MetricsToken guards /metrics. The endpoint is disabled when empty.
+	MetricsToken string `env:"METRICS_TOKEN" default:""`
+
+	// AchievementsFile is a YAML file of achievement definitions replacing the built-in ones
+	AchievementsFile string `env:"ACHIEVEMENTS_FILE" default:""`
+	// MissionsFile is a YAML file of mission templates replacing the built-in ones
+	MissionsFile string `env:"MISSIONS_FILE" default:""`
+	// MissionRegions lists the regions as name=timezone separated by commas.
+	// Missions change at midnight in each region; the first one is the default.
+	MissionRegions string `env:"MISSION_REGIONS" default:"jp=Asia/Tokyo"`
+}
+
+// NewLogger builds the structured logger writing to stderr at LogLevel in LogFormat
+func (c Config) NewLogger() (*slog.Logger, error) {
+	return logging.New(os.Stderr, c.LogLevel, c.LogFormat)
+}
+
+// SetupTracing installs the tracer provider selected by TraceExporter.
+// The returned function flushes pending spans.
+func (c Config) SetupTracing(ctx context.Context) (func(context.Context) error, error) {
+	return tracing.Setup(ctx, tracing.Options{
+		Exporter:    c.TraceExporter,
+		Endpoint:    c.TraceEndpoint,
+		SampleRatio: c.TraceSampleRatio,
+		ServiceName: serviceName,
+	})
+}
+
+// Achievements loads the definitions of AchievementsFile, or the built-in ones when it is empty
+func (c Config) Achievements() (_ []achievement.Definition, err error) {
+	if c.AchievementsFile == "" {
+		return achievement.Default(), nil
+	}
+	f, err := os.Open(c.AchievementsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, f.Close()) }()
+	defs, err := achievement.Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("load achievements from %s: %w", c.AchievementsFile, err)
+	}
+	return defs, nil
+}
+
+// Missions loads the templates of MissionsFile, or the built-in ones when it is empty
+func (c Config) Missions() (_ *mission.Rotation, err error) {
+	if c.MissionsFile == "" {
+		return mission.Default(), nil
+	}
+	f, err := os.Open(c.MissionsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = errors.Join(err, f.Close()) }()
+	rot, err := mission.Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("load missions from %s: %w", c.MissionsFile, err)
+	}
+	return rot, nil
 }
 
-func (c *Config) Parse() {
-	kong.Parse(c)
+// SetupDatabase connects to and migrates the database selected by DBDriver
+func (c Config) SetupDatabase() (*sqlx.DB, error) {
+	switch c.DBDriver {
+	case DBDriverMySQL, "":
+		return database.Setup(c.MySQLConfig())
+	case DBDriverPostgres:
+		return database.SetupPostgres(c.PostgresDSN())
+	case DBDriverSQLite:
+		return database.SetupSQLite(c.SQLitePath)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", c.DBDriver)
+	}
+}
+
+// OpenDatabase connects to the database selected by DBDriver without migrating it
+func (c Config) OpenDatabase() (*sqlx.DB, error) {
+	switch c.DBDriver {
+	case DBDriverMySQL, "":
+		return database.Open(c.MySQLConfig())
+	case DBDriverPostgres:
+		return database.OpenPostgres(c.PostgresDSN())
+	case DBDriverSQLite:
+		return database.OpenSQLite(c.SQLitePath)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", c.DBDriver)
+	}
+}
+
+// PostgresDSN builds a connection URL from the DB_* settings.
+// The session time zone is UTC so that TIMESTAMP columns hold UTC like the other backends.
+func (c Config) PostgresDSN() string {
+	q := url.Values{}
+	q.Set("sslmode", c.DBSSLMode)
+	q.Set("timezone", "UTC")
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.DBUser, c.DBPass),
+		Host:     net.JoinHostPort(c.DBHost, strconv.Itoa(c.DBPort)),
+		Path:     "/" + c.DBName,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
 }
 
 func (c Config) MySQLConfig() *mysql.Config {
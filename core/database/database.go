@@ -1,12 +1,63 @@
 package database
 
 import (
+	"net/url"
+
 	"github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib" // PostgreSQL driver
 	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite" // SQLite driver (pure Go)
+)
+
+// Driver names as registered with database/sql
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "pgx"
+	DriverSQLite   = "sqlite"
 )
 
+func init() {
+	// sqlx only knows the cgo driver's name
+	sqlx.BindDriver(DriverSQLite, sqlx.QUESTION)
+}
+
 func Setup(mysqlConfig *mysql.Config) (*sqlx.DB, error) {
-	db, err := sqlx.Connect("mysql", mysqlConfig.FormatDSN())
+	return migrated(Open(mysqlConfig))
+}
+
+// SetupPostgres connects to PostgreSQL with a libpq style URL or keyword/value DSN
+func SetupPostgres(dsn string) (*sqlx.DB, error) {
+	return migrated(OpenPostgres(dsn))
+}
+
+// SetupSQLite opens (creating if needed) the SQLite database file at path
+func SetupSQLite(path string) (*sqlx.DB, error) {
+	return migrated(OpenSQLite(path))
+}
+
+// Open connects to MySQL without migrating, for the migrate commands
+func Open(mysqlConfig *mysql.Config) (*sqlx.DB, error) {
+	return connect(DriverMySQL, mysqlConfig.FormatDSN())
+}
+
+// OpenPostgres is SetupPostgres without migrating
+func OpenPostgres(dsn string) (*sqlx.DB, error) {
+	return connect(DriverPostgres, dsn)
+}
+
+// OpenSQLite is SetupSQLite without migrating
+func OpenSQLite(path string) (*sqlx.DB, error) {
+	q := url.Values{}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Set("_time_format", "sqlite")
+
+	return connect(DriverSQLite, "file:"+path+"?"+q.Encode())
+}
+
+func connect(driver string, dsn string) (*sqlx.DB, error) {
+	db, err := sqlx.Connect(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -15,7 +66,16 @@ func Setup(mysqlConfig *mysql.Config) (*sqlx.DB, error) {
 		return nil, err
 	}
 
-	if err := migrateTables(db.DB); err != nil {
+	return db, nil
+}
+
+func migrated(db *sqlx.DB, err error) (*sqlx.DB, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrateTables(db.DB, db.DriverName()); err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 
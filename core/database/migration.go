@@ -1,26 +1,134 @@
 package database
 
 import (
+	"cmp"
+	"context"
 	"database/sql"
 	"embed"
 	"fmt"
+	"io/fs"
+	"path"
+	"slices"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/pressly/goose/v3"
 )
 
-//go:embed migrations/*.sql
+//go:embed migrations/*/*.sql
 var embedMigrations embed.FS
 
-func migrateTables(db *sql.DB) error {
+// migrationSet is the goose dialect and migrations directory of a driver.
+// Every directory has the same versions.
+type migrationSet struct {
+	dialect goose.Dialect
+	dir     string
+}
+
+var migrationSets = map[string]migrationSet{
+	DriverMySQL:    {goose.DialectMySQL, "migrations/mysql"},
+	DriverPostgres: {goose.DialectPostgres, "migrations/postgres"},
+	DriverSQLite:   {goose.DialectSQLite3, "migrations/sqlite"},
+}
+
+func migrateTables(db *sql.DB, driver string) error {
+	set, ok := migrationSets[driver]
+	if !ok {
+		return fmt.Errorf("unsupported driver: %s", driver)
+	}
+
 	goose.SetBaseFS(embedMigrations)
 
-	if err := goose.SetDialect("mysql"); err != nil {
+	if err := goose.SetDialect(string(set.dialect)); err != nil {
 		return fmt.Errorf("set dialect: %w", err)
 	}
 
-	if err := goose.Up(db, "migrations"); err != nil {
+	if err := goose.Up(db, set.dir); err != nil {
 		return fmt.Errorf("up migration: %w", err)
 	}
 
 	return nil
 }
+
+// MigrationVersions returns the schema version of db and the latest version
+// embedded in the binary. They differ while a migration is pending or when the
+// database was migrated by a newer release.
+func MigrationVersions(ctx context.Context, db *sqlx.DB) (current int64, latest int64, err error) {
+	p, err := newProvider(db)
+	if err != nil {
+		return 0, 0, err
+	}
+	return p.GetVersions(ctx)
+}
+
+// MigrationStatus lists every embedded migration and whether db has it applied
+func MigrationStatus(ctx context.Context, db *sqlx.DB) ([]*goose.MigrationStatus, error) {
+	p, err := newProvider(db)
+	if err != nil {
+		return nil, err
+	}
+	return p.Status(ctx)
+}
+
+// MigrateUp applies every pending migration and returns what was applied
+func MigrateUp(ctx context.Context, db *sqlx.DB) ([]*goose.MigrationResult, error) {
+	p, err := newProvider(db)
+	if err != nil {
+		return nil, err
+	}
+	return p.Up(ctx)
+}
+
+// MigrateDown rolls back the migrations applied after version.
+// A negative version rolls back only the latest migration.
+func MigrateDown(ctx context.Context, db *sqlx.DB, version int64) ([]*goose.MigrationResult, error) {
+	p, err := newProvider(db)
+	if err != nil {
+		return nil, err
+	}
+	if version >= 0 {
+		return p.DownTo(ctx, version)
+	}
+	res, err := p.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []*goose.MigrationResult{res}, nil
+}
+
+// newProvider reads the migrations of the driver of db.
+// The provider must not be closed; that would close db.
+func newProvider(db *sqlx.DB) (*goose.Provider, error) {
+	set, ok := migrationSets[db.DriverName()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported driver: %s", db.DriverName())
+	}
+	dir, err := fs.Sub(embedMigrations, set.dir)
+	if err != nil {
+		return nil, err
+	}
+	p, err := goose.NewProvider(set.dialect, db.DB, versionOrder{dir})
+	if err != nil {
+		return nil, fmt.Errorf("migration provider: %w", err)
+	}
+	return p, nil
+}
+
+// versionOrder globs migrations in version order. goose.Provider keeps the
+// order of fs.Glob when there are no Go migrations, which would put
+// 10_*.sql before 2_*.sql.
+type versionOrder struct {
+	fs.FS
+}
+
+func (v versionOrder) Glob(pattern string) ([]string, error) {
+	names, err := fs.Glob(v.FS, pattern)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(names, func(a, b string) int {
+		va, _ := goose.NumericComponent(path.Base(a))
+		vb, _ := goose.NumericComponent(path.Base(b))
+		return cmp.Compare(va, vb)
+	})
+	return names, nil
+}
@@ -0,0 +1,20 @@
+-- +goose Up
+
+-- mission_progress: plays a player made towards a daily or weekly mission.
+-- A mission is a template drawn for a region; period_start is when its day
+-- or week began in the time zone of the region, in UTC.
+-- reward is recorded when the mission is claimed.
+CREATE TABLE IF NOT EXISTS mission_progress (
+	user_id VARCHAR(36) NOT NULL,
+	region VARCHAR(32) NOT NULL,
+	mission_id VARCHAR(64) NOT NULL,
+	period_start DATETIME NOT NULL,
+	progress INT NOT NULL DEFAULT 0,
+	reward INT NOT NULL DEFAULT 0,
+	claimed_at DATETIME NULL,
+	PRIMARY KEY (user_id, region, mission_id, period_start),
+	CONSTRAINT fk_mission_progress_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS mission_progress;
@@ -0,0 +1,8 @@
+-- +goose Up
+
+-- mission_progress keeps only claimed missions from now on: the progress of a
+-- mission is counted from the active scores of its period, so that deleted
+-- and invalidated scores stop counting. progress is the count at the claim.
+DELETE FROM mission_progress WHERE claimed_at IS NULL;
+
+-- +goose Down
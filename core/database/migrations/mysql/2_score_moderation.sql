@@ -0,0 +1,28 @@
+-- +goose Up
+
+-- scores: moderation state
+-- deleted_at: soft-deleted rows are hidden from every read
+-- invalidated_at: invalidated rows stay in history but never count for bests or rankings
+ALTER TABLE scores
+	ADD COLUMN deleted_at DATETIME NULL,
+	ADD COLUMN invalidated_at DATETIME NULL,
+	ADD COLUMN invalidation_reason VARCHAR(255) NULL;
+
+-- audit_logs: administrative actions
+CREATE TABLE IF NOT EXISTS audit_logs (
+	id BIGINT NOT NULL AUTO_INCREMENT,
+	actor VARCHAR(64) NOT NULL,
+	action VARCHAR(64) NOT NULL,
+	target VARCHAR(255) NOT NULL,
+	detail TEXT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (id),
+	KEY idx_audit_logs_created (created_at)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_logs;
+ALTER TABLE scores
+	DROP COLUMN invalidation_reason,
+	DROP COLUMN invalidated_at,
+	DROP COLUMN deleted_at;
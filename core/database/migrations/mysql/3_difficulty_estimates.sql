@@ -0,0 +1,16 @@
+-- +goose Up
+
+-- chart_difficulty_estimates: difficulty constants estimated from player performance
+-- rebuilt periodically by the difficulty estimation job
+CREATE TABLE IF NOT EXISTS chart_difficulty_estimates (
+	beatmap_id VARCHAR(128) NOT NULL,
+	estimated_constant DOUBLE NOT NULL,
+	irt_difficulty DOUBLE NOT NULL,
+	sample_players INT NOT NULL,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (beatmap_id),
+	CONSTRAINT fk_difficulty_estimates_chart FOREIGN KEY (beatmap_id) REFERENCES charts(beatmap_id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS chart_difficulty_estimates;
@@ -0,0 +1,53 @@
+-- +goose Up
+
+-- snapshots: when each materialised snapshot was last rebuilt
+CREATE TABLE IF NOT EXISTS snapshots (
+	name VARCHAR(64) NOT NULL,
+	taken_at DATETIME NOT NULL,
+	PRIMARY KEY (name)
+);
+
+-- chart_ranking_snapshots: every player's best active score per chart, ranked
+CREATE TABLE IF NOT EXISTS chart_ranking_snapshots (
+	beatmap_id VARCHAR(128) NOT NULL,
+	place INT NOT NULL,
+	user_id VARCHAR(36) NOT NULL,
+	player_name VARCHAR(255) NOT NULL,
+	best_score INT NOT NULL,
+	PRIMARY KEY (beatmap_id, place)
+);
+
+-- chart_stats_snapshots: player and play counts of every chart
+CREATE TABLE IF NOT EXISTS chart_stats_snapshots (
+	beatmap_id VARCHAR(128) NOT NULL,
+	player_count INT NOT NULL,
+	play_count INT NOT NULL,
+	PRIMARY KEY (beatmap_id)
+);
+
+-- song_playcount_snapshots: songs ranked by play count
+CREATE TABLE IF NOT EXISTS song_playcount_snapshots (
+	song_name VARCHAR(255) NOT NULL,
+	place INT NOT NULL,
+	play_count INT NOT NULL,
+	PRIMARY KEY (song_name),
+	KEY idx_song_playcount_snapshots_place (place)
+);
+
+-- rating_snapshots: players ranked by overall rating
+CREATE TABLE IF NOT EXISTS rating_snapshots (
+	user_id VARCHAR(36) NOT NULL,
+	place INT NOT NULL,
+	player_name VARCHAR(255) NOT NULL,
+	rating DOUBLE NOT NULL,
+	charts INT NOT NULL,
+	PRIMARY KEY (user_id),
+	KEY idx_rating_snapshots_place (place)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS rating_snapshots;
+DROP TABLE IF EXISTS song_playcount_snapshots;
+DROP TABLE IF EXISTS chart_stats_snapshots;
+DROP TABLE IF EXISTS chart_ranking_snapshots;
+DROP TABLE IF EXISTS snapshots;
@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- users: ban state
+-- banned_at: banned players cannot submit scores
+ALTER TABLE users
+	ADD COLUMN banned_at DATETIME NULL,
+	ADD COLUMN ban_reason VARCHAR(255) NULL;
+
+-- +goose Down
+ALTER TABLE users
+	DROP COLUMN ban_reason,
+	DROP COLUMN banned_at;
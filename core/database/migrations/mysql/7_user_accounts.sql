@@ -0,0 +1,27 @@
+-- +goose Up
+
+-- users: account state
+-- token_hash: SHA-256 of the bearer token that authenticates the player, hex encoded
+-- deleted_at: anonymous placeholder left by a deleted account; it holds the scores of the account
+ALTER TABLE users
+	ADD COLUMN token_hash CHAR(64) NULL,
+	ADD COLUMN deleted_at DATETIME NULL,
+	ADD UNIQUE KEY idx_users_token_hash (token_hash);
+
+-- user_names: every name a player has had, including the current one
+CREATE TABLE IF NOT EXISTS user_names (
+	id BIGINT NOT NULL AUTO_INCREMENT,
+	user_id VARCHAR(36) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (id),
+	KEY idx_user_names_user (user_id),
+	CONSTRAINT fk_user_names_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_names;
+ALTER TABLE users
+	DROP INDEX idx_users_token_hash,
+	DROP COLUMN deleted_at,
+	DROP COLUMN token_hash;
@@ -0,0 +1,47 @@
+-- +goose Up
+
+-- users: friend_code is derived from the id when the player first asks for it
+ALTER TABLE users
+	ADD COLUMN friend_code CHAR(10) NULL,
+	ADD UNIQUE KEY idx_users_friend_code (friend_code);
+
+-- friend_requests: pending requests from one player to another
+CREATE TABLE IF NOT EXISTS friend_requests (
+	from_user_id VARCHAR(36) NOT NULL,
+	to_user_id VARCHAR(36) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (from_user_id, to_user_id),
+	KEY idx_friend_requests_to (to_user_id),
+	CONSTRAINT fk_friend_requests_from FOREIGN KEY (from_user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_friend_requests_to FOREIGN KEY (to_user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- friends: accepted friendships, stored once in each direction
+CREATE TABLE IF NOT EXISTS friends (
+	user_id VARCHAR(36) NOT NULL,
+	friend_id VARCHAR(36) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, friend_id),
+	KEY idx_friends_friend (friend_id),
+	CONSTRAINT fk_friends_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_friends_friend FOREIGN KEY (friend_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- rivals: players someone follows, without their consent
+CREATE TABLE IF NOT EXISTS rivals (
+	user_id VARCHAR(36) NOT NULL,
+	rival_id VARCHAR(36) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, rival_id),
+	KEY idx_rivals_rival (rival_id),
+	CONSTRAINT fk_rivals_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_rivals_rival FOREIGN KEY (rival_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS rivals;
+DROP TABLE IF EXISTS friends;
+DROP TABLE IF EXISTS friend_requests;
+ALTER TABLE users
+	DROP INDEX idx_users_friend_code,
+	DROP COLUMN friend_code;
@@ -0,0 +1,14 @@
+-- +goose Up
+
+-- user_achievements: achievements a player has unlocked. achievement_id is the
+-- id of a definition in the achievement config; unlocks are never revoked.
+CREATE TABLE IF NOT EXISTS user_achievements (
+	user_id VARCHAR(36) NOT NULL,
+	achievement_id VARCHAR(64) NOT NULL,
+	unlocked_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id, achievement_id),
+	CONSTRAINT fk_user_achievements_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_achievements;
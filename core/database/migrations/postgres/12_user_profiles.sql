@@ -0,0 +1,31 @@
+-- +goose Up
+
+-- user_profiles: what a player chose to show on their profile. Players
+-- without a row have the default profile. title is the id of an unlocked
+-- achievement; visibility is public, friends or private.
+CREATE TABLE IF NOT EXISTS user_profiles (
+	user_id VARCHAR(36) NOT NULL,
+	title VARCHAR(64) NULL,
+	favorite_beatmap_id VARCHAR(128) NULL,
+	visibility VARCHAR(16) NOT NULL DEFAULT 'public',
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id),
+	CONSTRAINT fk_user_profiles_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_user_profiles_favorite FOREIGN KEY (favorite_beatmap_id) REFERENCES charts(beatmap_id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_favorite ON user_profiles (favorite_beatmap_id);
+
+-- user_showcase: up to 3 charts whose personal best the player shows, in order
+CREATE TABLE IF NOT EXISTS user_showcase (
+	user_id VARCHAR(36) NOT NULL,
+	slot INT NOT NULL,
+	beatmap_id VARCHAR(128) NOT NULL,
+	PRIMARY KEY (user_id, slot),
+	CONSTRAINT fk_user_showcase_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_user_showcase_chart FOREIGN KEY (beatmap_id) REFERENCES charts(beatmap_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_user_showcase_chart ON user_showcase (beatmap_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_showcase;
+DROP TABLE IF EXISTS user_profiles;
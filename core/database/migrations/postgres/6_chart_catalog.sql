@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- charts: catalog details maintained by the chart team
+-- chart_constant: the declared difficulty constant, as opposed to the estimated one
+ALTER TABLE charts
+	ADD COLUMN note_count INT NULL,
+	ADD COLUMN chart_constant DOUBLE PRECISION NULL;
+
+-- +goose Down
+ALTER TABLE charts
+	DROP COLUMN chart_constant,
+	DROP COLUMN note_count;
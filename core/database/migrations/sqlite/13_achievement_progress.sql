@@ -0,0 +1,16 @@
+-- +goose Up
+
+-- achievement_progress: plays or charts a player has counted towards an
+-- achievement not yet unlocked, kept so that a submission does not rescan the
+-- player's history. Rows are dropped when moderation or an import changes the
+-- player's scores and rebuilt from the history by the next evaluation.
+CREATE TABLE IF NOT EXISTS achievement_progress (
+	user_id VARCHAR(36) NOT NULL,
+	achievement_id VARCHAR(64) NOT NULL,
+	progress INT NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, achievement_id),
+	CONSTRAINT fk_achievement_progress_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS achievement_progress;
@@ -0,0 +1,10 @@
+-- +goose Up
+
+-- users: ban state
+-- banned_at: banned players cannot submit scores
+ALTER TABLE users ADD COLUMN banned_at DATETIME NULL;
+ALTER TABLE users ADD COLUMN ban_reason VARCHAR(255) NULL;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN ban_reason;
+ALTER TABLE users DROP COLUMN banned_at;
@@ -0,0 +1,24 @@
+-- +goose Up
+
+-- users: account state
+-- token_hash: SHA-256 of the bearer token that authenticates the player, hex encoded
+-- deleted_at: anonymous placeholder left by a deleted account; it holds the scores of the account
+ALTER TABLE users ADD COLUMN token_hash CHAR(64) NULL;
+ALTER TABLE users ADD COLUMN deleted_at DATETIME NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_token_hash ON users (token_hash);
+
+-- user_names: every name a player has had, including the current one
+CREATE TABLE IF NOT EXISTS user_names (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id VARCHAR(36) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT fk_user_names_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_user_names_user ON user_names (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_names;
+DROP INDEX IF EXISTS idx_users_token_hash;
+ALTER TABLE users DROP COLUMN deleted_at;
+ALTER TABLE users DROP COLUMN token_hash;
@@ -0,0 +1,45 @@
+-- +goose Up
+
+-- users: friend_code is derived from the id when the player first asks for it
+ALTER TABLE users ADD COLUMN friend_code CHAR(10) NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_friend_code ON users (friend_code);
+
+-- friend_requests: pending requests from one player to another
+CREATE TABLE IF NOT EXISTS friend_requests (
+	from_user_id VARCHAR(36) NOT NULL,
+	to_user_id VARCHAR(36) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (from_user_id, to_user_id),
+	CONSTRAINT fk_friend_requests_from FOREIGN KEY (from_user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_friend_requests_to FOREIGN KEY (to_user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_friend_requests_to ON friend_requests (to_user_id);
+
+-- friends: accepted friendships, stored once in each direction
+CREATE TABLE IF NOT EXISTS friends (
+	user_id VARCHAR(36) NOT NULL,
+	friend_id VARCHAR(36) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, friend_id),
+	CONSTRAINT fk_friends_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_friends_friend FOREIGN KEY (friend_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_friends_friend ON friends (friend_id);
+
+-- rivals: players someone follows, without their consent
+CREATE TABLE IF NOT EXISTS rivals (
+	user_id VARCHAR(36) NOT NULL,
+	rival_id VARCHAR(36) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, rival_id),
+	CONSTRAINT fk_rivals_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT fk_rivals_rival FOREIGN KEY (rival_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_rivals_rival ON rivals (rival_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS rivals;
+DROP TABLE IF EXISTS friends;
+DROP TABLE IF EXISTS friend_requests;
+DROP INDEX IF EXISTS idx_users_friend_code;
+ALTER TABLE users DROP COLUMN friend_code;
@@ -0,0 +1,29 @@
+-- +goose Up
+
+-- teams: groups of players such as a school or a lab
+-- invite_code lets players join; owners and admins can replace it
+CREATE TABLE IF NOT EXISTS teams (
+	id VARCHAR(36) NOT NULL,
+	name VARCHAR(64) NOT NULL,
+	invite_code CHAR(8) NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_teams_name ON teams (name);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_teams_invite_code ON teams (invite_code);
+
+-- team_members: role is owner, admin or member; every team has one owner
+CREATE TABLE IF NOT EXISTS team_members (
+	team_id VARCHAR(36) NOT NULL,
+	user_id VARCHAR(36) NOT NULL,
+	role VARCHAR(16) NOT NULL,
+	joined_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (team_id, user_id),
+	CONSTRAINT fk_team_members_team FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE,
+	CONSTRAINT fk_team_members_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_team_members_user ON team_members (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS team_members;
+DROP TABLE IF EXISTS teams;
@@ -1,21 +1,126 @@
 package core
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/pikachu0310/senirenol-server/core/database"
 	"github.com/pikachu0310/senirenol-server/core/internal/handler"
 	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/achievement"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/backup"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/cache"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/catalog"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/difficulty"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/health"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/leaderboard"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/metrics"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/mission"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/realtime"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/worker"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// eventBufferSize is the number of undelivered events kept per stream subscriber
+const eventBufferSize = 64
+
+// difficultyEstimationJob is the worker job name of the difficulty estimator
+const difficultyEstimationJob = "difficulty-estimation"
+
 type Deps struct {
 	Handler *handler.Handler
+	// Scheduler runs the background jobs. The caller starts and stops it.
+	Scheduler *worker.Scheduler
+	// Health reports readiness. The caller drains it when shutting down.
+	Health *health.Checker
 }
 
-func InjectDeps(db *sqlx.DB) *Deps {
+func InjectDeps(db *sqlx.DB, config Config) (*Deps, error) {
 	repo := repository.New(db)
-	h := handler.New(repo)
+	broker := realtime.NewHub(eventBufferSize)
+	estimator := difficulty.NewEstimator(repo)
 
-	return &Deps{
-		Handler: h,
+	var c cache.Cache = cache.Nop{}
+	if config.CacheTTL > 0 {
+		c = cache.NewMemory(config.CacheMaxBytes)
+	}
+	lb := leaderboard.New(repo, c, leaderboard.Options{
+		CacheTTL:  config.CacheTTL,
+		Snapshots: config.RankingSnapshotInterval > 0,
+	})
+
+	scheduler := worker.NewScheduler()
+	if config.DifficultyEstimationInterval > 0 {
+		if err := scheduler.Register(worker.Job{
+			Name:     difficultyEstimationJob,
+			Interval: config.DifficultyEstimationInterval,
+			Run: func(ctx context.Context) error {
+				_, err := estimator.Recompute(ctx)
+				return err
+			},
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if config.RankingSnapshotInterval > 0 {
+		if err := scheduler.Register(worker.Job{
+			Name:     leaderboard.SnapshotJob,
+			Interval: config.RankingSnapshotInterval,
+			Run:      lb.Rebuild,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	defs, err := config.Achievements()
+	if err != nil {
+		return nil, err
 	}
+	rot, err := config.Missions()
+	if err != nil {
+		return nil, err
+	}
+	regions, err := mission.ParseRegions(config.MissionRegions)
+	if err != nil {
+		return nil, fmt.Errorf("mission regions: %w", err)
+	}
+
+	m := metrics.New(db.DB, db.DriverName())
+	m.RegisterCache("leaderboard", lb.CacheStats)
+
+	checker := health.New(db, func(ctx context.Context) (int64, int64, error) {
+		return database.MigrationVersions(ctx, db)
+	}, scheduler)
+
+	h := handler.New(handler.Services{
+		Repo:        repo,
+		Broker:      broker,
+		Estimator:   estimator,
+		Leaderboard: lb,
+		Jobs:        scheduler,
+		Metrics:     m,
+		Health:      checker,
+		Catalog:     catalog.New(repo),
+		Backup:      newBackup(db),
+		Achievement: achievement.New(repo, defs),
+		Missions:    mission.New(repo, rot, regions),
+	}, handler.Options{
+		AdminToken:   config.AdminToken,
+		MetricsToken: config.MetricsToken,
+	})
+
+	return &Deps{
+		Handler:   h,
+		Scheduler: scheduler,
+		Health:    checker,
+	}, nil
+}
+
+// newBackup returns the export and import service of db
+func newBackup(db *sqlx.DB) *backup.Service {
+	return backup.New(repository.New(db), func(ctx context.Context) (int64, error) {
+		current, _, err := database.MigrationVersions(ctx, db)
+		return current, err
+	})
 }
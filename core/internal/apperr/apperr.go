@@ -0,0 +1,154 @@
+// Package apperr defines the errors reported to API clients: an HTTP status,
+// a stable machine-readable code, a message and optional per-field details.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+)
+
+// Code identifies the kind of an error. Codes are part of the API and never change meaning.
+type Code string
+
+const (
+	CodeInvalidRequest        Code = "INVALID_REQUEST"
+	CodeValidationFailed      Code = "VALIDATION_FAILED"
+	CodeUnauthorized          Code = "UNAUTHORIZED"
+	CodeForbidden             Code = "FORBIDDEN"
+	CodeNotFound              Code = "NOT_FOUND"
+	CodeUserNotFound          Code = "USER_NOT_FOUND"
+	CodeUserBanned            Code = "USER_BANNED"
+	CodeChartNotFound         Code = "CHART_NOT_FOUND"
+	CodeScoreNotFound         Code = "SCORE_NOT_FOUND"
+	CodeFriendRequestNotFound Code = "FRIEND_REQUEST_NOT_FOUND"
+	CodeFriendNotFound        Code = "FRIEND_NOT_FOUND"
+	CodeRivalNotFound         Code = "RIVAL_NOT_FOUND"
+	CodeRivalLimit            Code = "RIVAL_LIMIT"
+	CodeTeamNotFound          Code = "TEAM_NOT_FOUND"
+	CodeTeamMemberNotFound    Code = "TEAM_MEMBER_NOT_FOUND"
+	CodeMissionNotFound       Code = "MISSION_NOT_FOUND"
+	CodeMissionIncomplete     Code = "MISSION_INCOMPLETE"
+	CodeProfilePrivate        Code = "PROFILE_PRIVATE"
+	CodeConflict              Code = "CONFLICT"
+	CodeInternal              Code = "INTERNAL"
+)
+
+// FieldError tells why one field of a request was rejected
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Error is an error reported to the client. Err is its cause, which is
+// logged but never sent to the client.
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	Details []FieldError
+	Err     error
+}
+
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of e caused by err
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// InvalidBody rejects a request body that cannot be decoded
+func InvalidBody(err error) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, "invalid request body").Wrap(err)
+}
+
+// InvalidParam rejects a path or query parameter that cannot be parsed
+func InvalidParam(name string, err error) *Error {
+	e := New(http.StatusBadRequest, CodeInvalidRequest, "invalid "+name).Wrap(err)
+	e.Details = []FieldError{{Field: name, Message: "is invalid"}}
+	return e
+}
+
+// InvalidField rejects a well-formed request because of one field
+func InvalidField(field string, message string) *Error {
+	e := New(http.StatusUnprocessableEntity, CodeValidationFailed, "validation failed")
+	e.Details = []FieldError{{Field: field, Message: message}}
+	return e
+}
+
+// Validation converts an ozzo-validation error into a VALIDATION_FAILED error
+// with one detail per field, nested fields joined with dots.
+func Validation(err error) *Error {
+	e := New(http.StatusUnprocessableEntity, CodeValidationFailed, "validation failed").Wrap(err)
+	var es vd.Errors
+	if errors.As(err, &es) {
+		e.Details = fieldErrors("", es)
+	} else {
+		e.Message = err.Error()
+	}
+	return e
+}
+
+func fieldErrors(prefix string, es vd.Errors) []FieldError {
+	keys := make([]string, 0, len(es))
+	for k := range es {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var out []FieldError
+	for _, k := range keys {
+		var nested vd.Errors
+		if errors.As(es[k], &nested) {
+			out = append(out, fieldErrors(prefix+k+".", nested)...)
+			continue
+		}
+		out = append(out, FieldError{Field: prefix + k, Message: es[k].Error()})
+	}
+	return out
+}
+
+// FromStatus is the error for a bare HTTP status, such as an echo.HTTPError or a routing failure
+func FromStatus(status int, message string) *Error {
+	return New(status, codeOf(status), message)
+}
+
+func codeOf(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeValidationFailed
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	// e.g. 405 METHOD_NOT_ALLOWED
+	return Code(strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_")))
+}
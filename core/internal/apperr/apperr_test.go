@@ -0,0 +1,61 @@
+package apperr
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+)
+
+func TestValidation(t *testing.T) {
+	t.Parallel()
+
+	err := Validation(vd.Errors{
+		"user_id": errors.New("cannot be blank"),
+		"judge":   vd.Errors{"miss": errors.New("must be no less than 0")},
+		"beatmap": nil,
+	}.Filter())
+	if err.Status != http.StatusUnprocessableEntity || err.Code != CodeValidationFailed {
+		t.Fatalf("got %d %s", err.Status, err.Code)
+	}
+	want := []FieldError{
+		{Field: "judge.miss", Message: "must be no less than 0"},
+		{Field: "user_id", Message: "cannot be blank"},
+	}
+	if !reflect.DeepEqual(err.Details, want) {
+		t.Errorf("details = %v, want %v", err.Details, want)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	t.Parallel()
+
+	base := New(http.StatusNotFound, CodeUserNotFound, "user not found")
+	cause := errors.New("no rows")
+	err := base.Wrap(cause)
+	if base.Err != nil {
+		t.Error("Wrap must not modify the original")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("wrapped error should unwrap to its cause")
+	}
+}
+
+func TestFromStatus(t *testing.T) {
+	t.Parallel()
+
+	for status, want := range map[int]Code{
+		http.StatusBadRequest:          CodeInvalidRequest,
+		http.StatusNotFound:            CodeNotFound,
+		http.StatusConflict:            CodeConflict,
+		http.StatusMethodNotAllowed:    "METHOD_NOT_ALLOWED",
+		http.StatusServiceUnavailable:  CodeInternal,
+		http.StatusInternalServerError: CodeInternal,
+	} {
+		if got := FromStatus(status, "").Code; got != want {
+			t.Errorf("FromStatus(%d) = %s, want %s", status, got, want)
+		}
+	}
+}
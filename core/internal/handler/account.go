@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/auth"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/friendcode"
+)
+
+// currentUserKey is the echo context key of the player authenticated by RequireUser
+const currentUserKey = "current_user"
+
+// RequireUser rejects requests without "Authorization: Bearer <token>",
+// where the token is the one returned when the player registered
+func (h *Handler) RequireUser(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := h.authenticate(c); err != nil {
+			return err
+		}
+		return next(c)
+	}
+}
+
+// authenticate sets the current user from the user token of the request,
+// for handlers that only sometimes require one
+func (h *Handler) authenticate(c echo.Context) error {
+	token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !ok || token == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "missing user token")
+	}
+	u, err := h.repo.GetUserByTokenHash(c.Request().Context(), auth.Hash(token))
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid user token")
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	setUser(c, u.ID)
+	c.Set(currentUserKey, u)
+	return nil
+}
+
+// currentUser is the player authenticated by RequireUser
+func currentUser(c echo.Context) *repository.User {
+	return c.Get(currentUserKey).(*repository.User)
+}
+
+// ExportMyData godoc
+// @Summary 個人データの書き出し
+// @Description 認証したプレイヤーのプロフィール・名前の履歴・すべてのスコア (削除・無効化されたものを含む)・実績・フレンド・フレンド申請・ライバル・所属チーム・報酬を受け取ったミッションと獲得ポイントをJSONで返します
+// @Tags users
+// @Produce json
+// @Security UserToken
+// @Success 200 {object} PersonalDataResponse "個人データ"
+// @Failure 401 {object} ErrorResponse
+// @Router /users/me/export [get]
+func (h *Handler) ExportMyData(c echo.Context) error {
+	ctx := c.Request().Context()
+	u := currentUser(c)
+
+	names, err := h.repo.GetUserNames(ctx, u.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	scores, err := h.repo.GetUserScoreHistory(ctx, u.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	achievements, err := h.repo.GetUserAchievements(ctx, u.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	profile, err := h.repo.GetProfile(ctx, u.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	friends, err := h.repo.GetFriends(ctx, u.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	incoming, outgoing, err := h.repo.GetFriendRequests(ctx, u.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	rivals, err := h.repo.GetRivals(ctx, u.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	teams, err := h.repo.GetUserTeams(ctx, u.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	missions, err := h.repo.GetUserMissionClaims(ctx, u.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	points, err := h.repo.GetMissionPoints(ctx, u.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	res := PersonalDataResponse{
+		ExportedAt: time.Now().UTC(),
+		Profile: PersonalProfileResponse{
+			ID:        u.ID,
+			Name:      u.Name,
+			CreatedAt: u.CreatedAt,
+			UpdatedAt: u.UpdatedAt,
+			BannedAt:  u.BannedAt,
+			BanReason: u.BanReason,
+
+			Title:             profile.Title,
+			FavoriteBeatmapID: profile.FavoriteBeatmapID,
+			Showcase:          profile.Showcase,
+			Visibility:        string(profile.Visibility),
+		},
+		NameHistory:  make([]UserNameResponse, len(names)),
+		Scores:       make([]PersonalScoreResponse, len(scores)),
+		Achievements: make([]PersonalAchievementResponse, len(achievements)),
+		Friends:      toContactResponses(friends),
+		FriendRequests: FriendRequestsResponse{
+			Incoming: toContactResponses(incoming),
+			Outgoing: toContactResponses(outgoing),
+		},
+		Rivals: toContactResponses(rivals),
+		Teams:  toMyTeamResponses(teams),
+
+		Missions:      make([]PersonalMissionResponse, len(missions)),
+		MissionPoints: points,
+	}
+	if u.FriendCode != nil {
+		code := friendcode.Format(*u.FriendCode)
+		res.Profile.FriendCode = &code
+	}
+	for i, n := range names {
+		res.NameHistory[i] = UserNameResponse{Name: n.Name, CreatedAt: n.CreatedAt}
+	}
+	for i, s := range scores {
+		res.Scores[i] = PersonalScoreResponse{
+			ID:                  s.ID,
+			BeatmapID:           s.BeatmapID,
+			Score:               s.Score,
+			MaxCombo:            s.MaxCombo,
+			PerfectCriticalFast: s.PerfectCriticalFast,
+			PerfectCriticalLate: s.PerfectCriticalLate,
+			PerfectFast:         s.PerfectFast,
+			PerfectLate:         s.PerfectLate,
+			GoodFast:            s.GoodFast,
+			GoodLate:            s.GoodLate,
+			Miss:                s.Miss,
+			Input:               uint8(s.Input),
+			CreatedAt:           s.CreatedAt,
+			DeletedAt:           s.DeletedAt,
+			InvalidatedAt:       s.InvalidatedAt,
+			InvalidationReason:  s.InvalidationReason,
+		}
+	}
+	for i, a := range achievements {
+		res.Achievements[i] = PersonalAchievementResponse{AchievementID: a.AchievementID, UnlockedAt: a.UnlockedAt}
+	}
+	for i, m := range missions {
+		res.Missions[i] = PersonalMissionResponse{
+			Region:      m.Region,
+			MissionID:   m.MissionID,
+			PeriodStart: m.PeriodStart,
+			Progress:    m.Progress,
+			Reward:      m.Reward,
+			ClaimedAt:   m.ClaimedAt,
+		}
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": "senirenol-" + u.ID + ".json"}))
+	return c.JSON(http.StatusOK, res)
+}
+
+// DeleteMe godoc
+// @Summary アカウント削除
+// @Description 認証したプレイヤーのアカウント・トークン・名前の履歴を削除します。
+// @Description スコアは誰のものか分からない匿名ユーザー ("Deleted player") に移すので、プレイ回数やランキングの集計は変わりません。削除は監査ログに記録します。
+// @Tags users
+// @Produce json
+// @Security UserToken
+// @Success 200 {object} DeleteUserResponse "削除結果"
+// @Failure 401 {object} ErrorResponse
+// @Router /users/me [delete]
+func (h *Handler) DeleteMe(c echo.Context) error {
+	ctx := c.Request().Context()
+	u := currentUser(c)
+	n, err := h.repo.DeleteUser(ctx, repository.DeleteUserParams{UserID: u.ID, Actor: "user:" + u.ID})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	// ランキングにはプレイヤー名が含まれる
+	h.leaderboard.InvalidateAll(ctx)
+	return c.JSON(http.StatusOK, DeleteUserResponse{Status: "deleted", AnonymisedScores: n})
+}
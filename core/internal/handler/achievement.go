@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/achievement"
+)
+
+// GetAchievements godoc
+// @Summary 実績一覧
+// @Description 定義されているすべての実績を返します
+// @Tags achievements
+// @Produce json
+// @Success 200 {array} AchievementResponse "実績一覧"
+// @Router /achievements [get]
+func (h *Handler) GetAchievements(c echo.Context) error {
+	defs := h.achievement.Definitions()
+	res := make([]AchievementResponse, len(defs))
+	for i, d := range defs {
+		res[i] = toAchievementResponse(d)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetUserAchievements godoc
+// @Summary ユーザーの実績
+// @Description すべての実績について、指定したユーザーの進捗と解除日時を返します。未解除の実績のunlocked_atはnullです
+// @Tags users
+// @Produce json
+// @Param userID path string true "User ID" format(uuid)
+// @Success 200 {array} UserAchievementResponse "実績の進捗"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "ユーザーが存在しない (USER_NOT_FOUND)"
+// @Router /users/{userID}/achievements [get]
+func (h *Handler) GetUserAchievements(c echo.Context) error {
+	userID := c.Param("userID")
+	if _, err := uuid.Parse(userID); err != nil {
+		return apperr.InvalidParam("userID", err)
+	}
+	ctx := c.Request().Context()
+	if _, err := h.repo.GetUser(ctx, userID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	ss, err := h.achievement.Statuses(ctx, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, toUserAchievementResponses(ss))
+}
+
+func toAchievementResponse(d achievement.Definition) AchievementResponse {
+	return AchievementResponse{
+		ID:          d.ID,
+		Name:        d.Name,
+		Description: d.Description,
+		Goal:        d.Condition.Count,
+	}
+}
+
+func toUserAchievementResponses(ss []achievement.Status) []UserAchievementResponse {
+	out := make([]UserAchievementResponse, len(ss))
+	for i, s := range ss {
+		out[i] = UserAchievementResponse{
+			AchievementResponse: toAchievementResponse(s.Definition),
+			Progress:            s.Progress,
+			UnlockedAt:          s.UnlockedAt,
+		}
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
+)
+
+// adminActor is recorded as the actor of admin API actions in audit_logs
+const adminActor = "admin"
+
+// RequireAdmin rejects requests without "Authorization: Bearer <ADMIN_TOKEN>"
+func (h *Handler) RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if h.opts.AdminToken == "" {
+			return echo.NewHTTPError(http.StatusForbidden, "admin API is disabled")
+		}
+		if !hasBearerToken(c, h.opts.AdminToken) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid admin token")
+		}
+		setUser(c, adminActor)
+		return next(c)
+	}
+}
+
+// hasBearerToken reports whether the request carries "Authorization: Bearer <token>"
+func hasBearerToken(c echo.Context, token string) bool {
+	got, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// GetAuditLogs godoc
+// @Summary 監査ログ取得
+// @Description 管理操作の履歴を新しい順に返します
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param limit query int false "取得件数 (既定50, 最大100)"
+// @Success 200 {array} AuditLogResponse "監査ログ"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/audit-logs [get]
+func (h *Handler) GetAuditLogs(c echo.Context) error {
+	limit := 50
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return apperr.InvalidParam("limit", err)
+		}
+		limit = min(n, maxPageSize)
+	}
+	ls, err := h.repo.GetAuditLogs(c.Request().Context(), limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	out := make([]AuditLogResponse, len(ls))
+	for i, l := range ls {
+		out[i] = AuditLogResponse{
+			ID:        l.ID,
+			Actor:     l.Actor,
+			Action:    l.Action,
+			Target:    l.Target,
+			Detail:    l.Detail,
+			CreatedAt: l.CreatedAt,
+		}
+	}
+	return c.JSON(http.StatusOK, out)
+}
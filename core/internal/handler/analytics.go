@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/analytics"
+)
+
+const (
+	defaultTrendSize = 20
+	maxTrendSize     = 100
+)
+
+// GetUserChartAnalytics godoc
+// @Summary ユーザーの譜面別判定分析
+// @Description 直近N回のプレイから判定の平均分布、FAST/LATE比、MISS率、推移と推奨オフセットを返します
+// @Description プロフィールの公開範囲がfriendsのプレイヤーはフレンドと本人、privateのプレイヤーは本人だけが取得できます。本人やフレンドとして見るにはトークンを付けてください
+// @Tags users
+// @Produce json
+// @Security UserToken
+// @Param userID path string true "User ID" format(uuid)
+// @Param beatmapID path string true "譜面ID"
+// @Param last query int false "対象とする直近のプレイ数 (既定20, 最大100)"
+// @Success 200 {object} UserChartAnalyticsResponse "判定分析"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "トークンが無効"
+// @Failure 403 {object} ErrorResponse "公開範囲により見られない (PROFILE_PRIVATE)"
+// @Router /users/{userID}/charts/{beatmapID}/analytics [get]
+func (h *Handler) GetUserChartAnalytics(c echo.Context) error {
+	uid := c.Param("userID")
+	if _, err := uuid.Parse(uid); err != nil {
+		return apperr.InvalidParam("userID", err)
+	}
+	if err := h.requireVisiblePlays(c, uid); err != nil {
+		return err
+	}
+	beatmapID := c.Param("beatmapID")
+	last := defaultTrendSize
+	if v := c.QueryParam("last"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return apperr.InvalidParam("last", err)
+		}
+		last = min(n, maxTrendSize)
+	}
+
+	ctx := c.Request().Context()
+	if _, err := h.repo.GetChart(ctx, beatmapID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	rs, total, err := h.repo.GetUserScores(ctx, repository.GetUserScoresParams{
+		UserID:    uid,
+		BeatmapID: beatmapID,
+		Sort:      repository.SortByDate,
+		Limit:     last,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	plays := toAnalyticsPlays(rs)
+	slices.Reverse(plays)
+
+	a := analytics.AnalysePlayerChart(plays)
+	trend := make([]TrendPointResponse, len(a.Trend))
+	for i, p := range a.Trend {
+		trend[i] = TrendPointResponse{
+			ScoreID:   p.ScoreID,
+			PlayedAt:  p.PlayedAt,
+			Score:     p.Score,
+			Fast:      p.Fast,
+			Late:      p.Late,
+			Miss:      p.Miss,
+			MissRate:  p.MissRate,
+			FastRatio: p.FastRatio,
+		}
+	}
+	return c.JSON(http.StatusOK, UserChartAnalyticsResponse{
+		UserID:       uid,
+		BeatmapID:    beatmapID,
+		TotalPlays:   total,
+		SampleSize:   a.Plays,
+		AverageScore: a.AverageScore,
+		AverageJudgements: JudgementAveragesResponse{
+			PerfectCriticalFast: a.Average.PerfectCriticalFast,
+			PerfectCriticalLate: a.Average.PerfectCriticalLate,
+			PerfectFast:         a.Average.PerfectFast,
+			PerfectLate:         a.Average.PerfectLate,
+			GoodFast:            a.Average.GoodFast,
+			GoodLate:            a.Average.GoodLate,
+			Miss:                a.Average.Miss,
+		},
+		FastCount:           a.Fast,
+		LateCount:           a.Late,
+		FastRatio:           a.FastRatio,
+		MissRate:            a.MissRate,
+		MeanTimingErrorMs:   a.MeanTimingErrorMs,
+		RecommendedOffsetMs: a.RecommendedOffsetMs,
+		ScoreTrend:          a.ScoreSlope,
+		MissRateTrend:       a.MissRateSlope,
+		Trend:               trend,
+	})
+}
+
+func toAnalyticsPlays(in []*repository.PlayRow) []analytics.Play {
+	out := make([]analytics.Play, len(in))
+	for i, p := range in {
+		out[i] = toAnalyticsPlay(p.ScoreRow)
+	}
+	return out
+}
+
+func toAnalyticsPlay(s repository.ScoreRow) analytics.Play {
+	return analytics.Play{
+		ID:         s.ID,
+		UserID:     s.UserID,
+		Score:      s.Score,
+		Judgements: s.Judgements(),
+		Input:      uint8(s.Input),
+		PlayedAt:   s.CreatedAt,
+	}
+}
+
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 365
+)
+
+// GetChartAnalytics godoc
+// @Summary 譜面の分析
+// @Description 各プレイヤーの自己ベストによるスコア分布・ランプ/グレード分布・パーセンタイルと、全プレイのクリア率・入力デバイス比・日別プレイ数を返します
+// @Tags charts
+// @Produce json
+// @Param beatmapID path string true "譜面ID"
+// @Param days query int false "日別プレイ数の日数 (既定30, 最大365)"
+// @Success 200 {object} ChartAnalyticsResponse "譜面の分析"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /charts/{beatmapID}/analytics [get]
+func (h *Handler) GetChartAnalytics(c echo.Context) error {
+	beatmapID := c.Param("beatmapID")
+	days := defaultAnalyticsDays
+	if v := c.QueryParam("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return apperr.InvalidParam("days", err)
+		}
+		days = min(n, maxAnalyticsDays)
+	}
+
+	ctx := c.Request().Context()
+	chart, err := h.repo.GetChart(ctx, beatmapID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	stats, err := h.repo.GetChartStats(ctx, beatmapID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	estimate, err := h.repo.GetDifficultyEstimate(ctx, beatmapID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	rows, err := h.repo.GetChartPlays(ctx, beatmapID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	plays := make([]analytics.Play, len(rows))
+	for i, r := range rows {
+		plays[i] = toAnalyticsPlay(*r)
+	}
+
+	a := analytics.AnalyseChart(plays, time.Now(), days)
+	res := ChartAnalyticsResponse{
+		BeatmapID:         chart.BeatmapID,
+		SongName:          chart.SongName,
+		Difficulty:        chart.Difficulty,
+		ParallelString:    chart.ParallelString,
+		PlayCount:         stats.PlayCount,
+		PlayerCount:       stats.PlayerCount,
+		AverageScore:      stats.AvgScore,
+		BestScore:         stats.BestScore,
+		ScoreHistogram:    make([]HistogramBucketResponse, len(a.ScoreHistogram)),
+		GradeDistribution: map[string]int{},
+		LampDistribution:  map[string]int{},
+		ClearRate:         a.ClearRate,
+		PlayerClearRate:   a.PlayerClearRate,
+		MedianScore:       a.Median,
+		Percentiles:       map[string]float64{},
+		InputSplit:        make([]InputSplitResponse, len(a.Inputs)),
+		PlaysOverTime:     make([]DailyPlaysResponse, len(a.PlaysOverTime)),
+	}
+	if estimate != nil {
+		res.EstimatedConstant = &estimate.EstimatedConstant
+	}
+	for i, b := range a.ScoreHistogram {
+		res.ScoreHistogram[i] = HistogramBucketResponse{Min: b.Min, Max: b.Max, Count: b.Count}
+	}
+	for g, n := range a.Grades {
+		res.GradeDistribution[string(g)] = n
+	}
+	for l, n := range a.Lamps {
+		res.LampDistribution[string(l)] = n
+	}
+	for p, v := range a.Percentiles {
+		res.Percentiles["p"+strconv.Itoa(p)] = v
+	}
+	for i, in := range a.Inputs {
+		res.InputSplit[i] = InputSplitResponse{Input: in.Input, Plays: in.Plays, AverageScore: in.AverageScore}
+	}
+	for i, d := range a.PlaysOverTime {
+		res.PlaysOverTime[i] = DailyPlaysResponse{Date: d.Date.Format(time.DateOnly), Plays: d.Plays}
+	}
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/backup"
+)
+
+// ExportData godoc
+// @Summary データの書き出し
+// @Description ユーザー・譜面・スコアをバージョン付きのダンプとして返します。1行目 (tarではmanifest.json) がバージョンと件数を持つマニフェストです。
+// @Description from/toを指定すると、その期間に更新されたユーザーと、作成・削除・無効化されたスコアだけを書き出します (差分エクスポート)。譜面は常にすべて書き出します。
+// @Tags admin
+// @Produce application/x-ndjson,application/gzip
+// @Security AdminToken
+// @Param format query string false "形式 (jsonl, tar。既定jsonl)。tarはgzip圧縮したtarです"
+// @Param from query string false "この時刻以降 (RFC3339)"
+// @Param to query string false "この時刻より前 (RFC3339)"
+// @Success 200 {file} binary "ダンプ"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/export [get]
+func (h *Handler) ExportData(c echo.Context) error {
+	format := backup.FormatJSONL
+	if v := c.QueryParam("format"); v != "" {
+		f, err := backup.ParseFormat(v)
+		if err != nil {
+			return apperr.InvalidParam("format", err)
+		}
+		format = f
+	}
+	var (
+		opts backup.Options
+		err  error
+	)
+	if opts.From, err = parseTimeParam(c, "from"); err != nil {
+		return apperr.InvalidParam("from", err)
+	}
+	if opts.To, err = parseTimeParam(c, "to"); err != nil {
+		return apperr.InvalidParam("to", err)
+	}
+
+	d, err := h.backup.Export(c.Request().Context(), opts)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, format.ContentType())
+	res.Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": format.FileName()}))
+	res.WriteHeader(http.StatusOK)
+	return backup.Encode(res, format, d)
+}
+
+// ImportData godoc
+// @Summary データの読み込み
+// @Description ExportDataのダンプを1つのトランザクションで読み込みます。形式はformatかContent-Typeで指定します。
+// @Description ユーザーと譜面はIDごとに上書きします。スコアには新しいIDを振り、同じプレイヤー・譜面・スコア・時刻 (秒) のスコアが既にあれば読み飛ばします。
+// @Description ダンプにもデータベースにもないユーザーや譜面を参照するスコアがあれば、何も書き込まずに422を返します
+// @Tags admin
+// @Accept application/x-ndjson,application/gzip
+// @Produce json
+// @Security AdminToken
+// @Param format query string false "形式 (jsonl, tar)。未指定時はContent-Typeから判定"
+// @Param dump body string true "ダンプ"
+// @Success 200 {object} DataImportResponse "書き込んだ件数"
+// @Failure 400 {object} ErrorResponse "読み込めないダンプ、または未対応のバージョン"
+// @Failure 401 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse "未対応の形式"
+// @Failure 422 {object} ErrorResponse "存在しないユーザー・譜面への参照 (detailsに行ごとの理由)"
+// @Router /admin/import [post]
+func (h *Handler) ImportData(c echo.Context) error {
+	format, err := dumpFormat(c)
+	if err != nil {
+		return err
+	}
+	d, err := backup.Decode(c.Request().Body, format)
+	if err != nil {
+		return apperr.InvalidBody(err)
+	}
+
+	ctx := c.Request().Context()
+	if err := h.backup.Validate(ctx, d); err != nil {
+		var es vd.Errors
+		if errors.As(err, &es) {
+			return apperr.Validation(err)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	res, err := h.backup.Import(ctx, d, adminActor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	h.leaderboard.InvalidateAll(ctx)
+
+	return c.JSON(http.StatusOK, DataImportResponse{
+		Users:           res.Users,
+		Charts:          res.Charts,
+		Scores:          res.Scores,
+		SkippedScores:   res.SkippedScores,
+		ModeratedScores: res.ModeratedScores,
+	})
+}
+
+// dumpFormat is the format of the uploaded dump: the format query
+// parameter, or else the Content-Type. JSON Lines is assumed without either.
+func dumpFormat(c echo.Context) (backup.Format, error) {
+	if v := c.QueryParam("format"); v != "" {
+		f, err := backup.ParseFormat(v)
+		if err != nil {
+			return "", apperr.InvalidParam("format", err)
+		}
+		return f, nil
+	}
+	ct := c.Request().Header.Get(echo.HeaderContentType)
+	if ct == "" {
+		return backup.FormatJSONL, nil
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return "", echo.ErrUnsupportedMediaType.WithInternal(err)
+	}
+	switch mt {
+	case "application/x-ndjson", "application/jsonl", "application/json-lines":
+		return backup.FormatJSONL, nil
+	case "application/gzip", "application/x-gzip":
+		return backup.FormatTar, nil
+	}
+	return "", echo.ErrUnsupportedMediaType.WithInternal(errors.New("unsupported dump type " + mt))
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetCacheStats godoc
+// @Summary キャッシュ統計
+// @Description ランキング等のキャッシュのヒット/ミス数を返します
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} CacheStatsResponse "キャッシュ統計"
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/cache/stats [get]
+func (h *Handler) GetCacheStats(c echo.Context) error {
+	s := h.leaderboard.CacheStats()
+	res := CacheStatsResponse{
+		Hits:      s.Hits,
+		Misses:    s.Misses,
+		Evictions: s.Evictions,
+		Entries:   s.Entries,
+		Bytes:     s.Bytes,
+	}
+	if total := s.Hits + s.Misses; total > 0 {
+		res.HitRatio = float64(s.Hits) / float64(total)
+	}
+	return c.JSON(http.StatusOK, res)
+}
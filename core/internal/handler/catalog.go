@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/catalog"
+)
+
+// ImportChartCatalog godoc
+// @Summary 譜面カタログの一括登録
+// @Description CSV/JSON/YAMLの譜面カタログをまとめて登録・更新します。形式はformatかContent-Typeで指定します。
+// @Description すべての譜面を1つのトランザクションで反映し、dry_run=trueのときは反映せずに差分だけを返します。カタログにない譜面は削除しません。
+// @Description CSVは1行目に列名 (beatmap_id, song_name, difficulty, parallel_string, note_count, constant) を書きます
+// @Tags admin
+// @Accept json,text/csv,application/yaml
+// @Produce json
+// @Security AdminToken
+// @Param format query string false "形式 (csv, json, yaml)。未指定時はContent-Typeから判定"
+// @Param dry_run query bool false "trueなら反映せずに差分を返す"
+// @Param catalog body []ChartCatalogEntry true "譜面カタログ"
+// @Success 200 {object} ChartImportResponse "作成・更新した譜面"
+// @Failure 400 {object} ErrorResponse "読み込めないファイル"
+// @Failure 401 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse "未対応の形式"
+// @Failure 422 {object} ErrorResponse "入力値の検証エラー (detailsに行ごとの理由)"
+// @Router /admin/charts/catalog [post]
+func (h *Handler) ImportChartCatalog(c echo.Context) error {
+	format, err := catalogFormat(c)
+	if err != nil {
+		return err
+	}
+	dryRun := false
+	if v := c.QueryParam("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			return apperr.InvalidParam("dry_run", err)
+		}
+	}
+
+	es, err := catalog.Decode(c.Request().Body, format)
+	if err != nil {
+		return apperr.InvalidBody(err)
+	}
+	if err := catalog.Validate(es); err != nil {
+		return apperr.Validation(err)
+	}
+
+	ctx := c.Request().Context()
+	res, err := h.catalog.Import(ctx, es, catalog.ImportOptions{DryRun: dryRun, Actor: adminActor})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	if len(res.Changes) > 0 && !res.DryRun {
+		h.leaderboard.InvalidateAll(ctx)
+	}
+
+	out := ChartImportResponse{
+		DryRun:    res.DryRun,
+		Created:   res.Created,
+		Updated:   res.Updated,
+		Unchanged: res.Unchanged,
+		Changes:   make([]ChartChangeResponse, len(res.Changes)),
+	}
+	for i, ch := range res.Changes {
+		fs := make([]ChartFieldChangeResponse, len(ch.Fields))
+		for j, f := range ch.Fields {
+			fs[j] = ChartFieldChangeResponse{Field: f.Field, Old: f.Old, New: f.New}
+		}
+		out.Changes[i] = ChartChangeResponse{Op: string(ch.Op), BeatmapID: ch.BeatmapID, Fields: fs}
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// ExportChartCatalog godoc
+// @Summary 譜面カタログの出力
+// @Description すべての譜面をインポートと同じ形式で返します
+// @Tags admin
+// @Produce json,text/csv,application/yaml
+// @Security AdminToken
+// @Param format query string false "形式 (csv, json, yaml。既定json)"
+// @Success 200 {array} ChartCatalogEntry "譜面カタログ"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/charts/catalog [get]
+func (h *Handler) ExportChartCatalog(c echo.Context) error {
+	format := catalog.FormatJSON
+	if v := c.QueryParam("format"); v != "" {
+		f, err := catalog.ParseFormat(v)
+		if err != nil {
+			return apperr.InvalidParam("format", err)
+		}
+		format = f
+	}
+
+	es, err := h.catalog.Export(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, format.ContentType())
+	res.Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": "charts." + string(format)}))
+	res.WriteHeader(http.StatusOK)
+	return catalog.Encode(res, format, es)
+}
+
+// catalogFormat is the format of the uploaded catalog: the format query
+// parameter, or else the Content-Type. JSON is assumed without either.
+func catalogFormat(c echo.Context) (catalog.Format, error) {
+	if v := c.QueryParam("format"); v != "" {
+		f, err := catalog.ParseFormat(v)
+		if err != nil {
+			return "", apperr.InvalidParam("format", err)
+		}
+		return f, nil
+	}
+	ct := c.Request().Header.Get(echo.HeaderContentType)
+	if ct == "" {
+		return catalog.FormatJSON, nil
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return "", echo.ErrUnsupportedMediaType.WithInternal(err)
+	}
+	switch mt {
+	case "text/csv":
+		return catalog.FormatCSV, nil
+	case echo.MIMEApplicationJSON:
+		return catalog.FormatJSON, nil
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return catalog.FormatYAML, nil
+	}
+	return "", echo.ErrUnsupportedMediaType.WithInternal(errors.New("unsupported catalog type " + mt))
+}
@@ -6,6 +6,7 @@ import (
 
 	vd "github.com/go-ozzo/ozzo-validation"
 	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
 	"github.com/pikachu0310/senirenol-server/core/internal/repository"
 )
 
@@ -24,19 +25,20 @@ type UpsertChartRequest struct {
 // @Param chart body UpsertChartRequest true "譜面情報"
 // @Success 200 {object} UpsertChartResponse "登録結果"
 // @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "入力値の検証エラー (detailsに項目ごとの理由)"
 // @Router /charts [post]
 func (h *Handler) UpsertChart(c echo.Context) error {
 	req := new(UpsertChartRequest)
 	if err := c.Bind(req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body").SetInternal(err)
+		return apperr.InvalidBody(err)
 	}
 	if err := vd.ValidateStruct(
 		req,
 		vd.Field(&req.BeatmapID, vd.Required),
 		vd.Field(&req.SongName, vd.Required),
-		vd.Field(&req.Difficulty, vd.Required),
+		vd.Field(&req.Difficulty, vd.Max(uint8(repository.DiffParallel))),
 	); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err)
+		return apperr.Validation(err)
 	}
 	if err := h.repo.UpsertChart(c.Request().Context(), repository.UpsertChartParams{
 		BeatmapID:      req.BeatmapID,
@@ -46,17 +48,24 @@ func (h *Handler) UpsertChart(c echo.Context) error {
 	}); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
 	}
+	h.leaderboard.InvalidateChart(c.Request().Context(), req.BeatmapID)
 	return c.JSON(http.StatusOK, UpsertChartResponse{Status: "ok"})
 }
 
 // GetChartRanking godoc
 // @Summary 譜面ランキング
-// @Description beatmap_idを指定したランキング、未指定時は全譜面のランキング
+// @Description beatmap_idを指定したランキング、未指定時は全譜面のランキング。
+// @Description スナップショットから返す場合はsnapshot_atに集計時刻が入ります
+// @Description scope=friendsでは認証したプレイヤーとそのフレンドだけのランキングを返します (キャッシュ・スナップショットは使いません)
 // @Tags charts
 // @Produce json
 // @Param beatmap_id query string false "譜面ID"
 // @Param limit query int false "ランキング上限"
+// @Param scope query string false "範囲 (all, friends。既定all)。friendsはユーザートークンが必要です"
+// @Security UserToken
 // @Success 200 {array} ChartRankingResponse "ランキング配列"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "scope=friendsでユーザートークンがない"
 // @Router /charts/ranking [get]
 func (h *Handler) GetChartRanking(c echo.Context) error {
 	beatmapID := c.QueryParam("beatmap_id")
@@ -67,30 +76,43 @@ func (h *Handler) GetChartRanking(c echo.Context) error {
 			limit = v
 		}
 	}
+	switch c.QueryParam("scope") {
+	case "", "all":
+	case "friends":
+		if err := h.authenticate(c); err != nil {
+			return err
+		}
+		return h.getFriendsChartRanking(c, beatmapID, limit)
+	default:
+		return apperr.InvalidParam("scope", nil)
+	}
 	if beatmapID != "" {
-		r, err := h.repo.GetChartRanking(c.Request().Context(), beatmapID, limit)
+		res, err := h.leaderboard.ChartRanking(c.Request().Context(), beatmapID, limit)
 		if err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
 		}
+		r := res.Items
 		// 単体でも配列で返す
 		return c.JSON(http.StatusOK, []ChartRankingResponse{{
 			BeatmapID:   r.BeatmapID,
 			PlayerCount: r.PlayerCount,
 			PlayCount:   r.PlayCount,
 			Top:         toRankingEntryResponse(r.Top),
+			SnapshotAt:  res.SnapshotAt,
 		}})
 	}
-	rs, err := h.repo.GetAllChartsRankings(c.Request().Context(), limit)
+	res, err := h.leaderboard.AllChartRankings(c.Request().Context(), limit)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
 	}
-	out := make([]ChartRankingResponse, 0, len(rs))
-	for _, r := range rs {
+	out := make([]ChartRankingResponse, 0, len(res.Items))
+	for _, r := range res.Items {
 		out = append(out, ChartRankingResponse{
 			BeatmapID:   r.BeatmapID,
 			PlayerCount: r.PlayerCount,
 			PlayCount:   r.PlayCount,
 			Top:         toRankingEntryResponse(r.Top),
+			SnapshotAt:  res.SnapshotAt,
 		})
 	}
 	return c.JSON(http.StatusOK, out)
@@ -103,13 +125,43 @@ func (h *Handler) GetChartRanking(c echo.Context) error {
 // @Success 200 {array} map[string]any
 // @Router /songs/playcount [get]
 func (h *Handler) GetSongPlaycountRanking(c echo.Context) error {
-	rs, err := h.repo.GetSongPlaycountRanking(c.Request().Context())
+	res, err := h.leaderboard.SongPlaycounts(c.Request().Context())
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
 	}
-	out := make([]SongPlaycountResponse, len(rs))
+	out := make([]SongPlaycountResponse, len(res.Items))
+	for i, r := range res.Items {
+		out[i] = SongPlaycountResponse{SongName: r.SongName, PlayCount: r.PlayCount, SnapshotAt: res.SnapshotAt}
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// getFriendsChartRanking answers GetChartRanking with scope=friends
+func (h *Handler) getFriendsChartRanking(c echo.Context, beatmapID string, limit int) error {
+	ctx := c.Request().Context()
+	me := currentUser(c)
+	var (
+		rs  []*repository.ChartRanking
+		err error
+	)
+	if beatmapID != "" {
+		var r *repository.ChartRanking
+		r, err = h.repo.GetFriendsChartRanking(ctx, me.ID, beatmapID, limit)
+		rs = []*repository.ChartRanking{r}
+	} else {
+		rs, err = h.repo.GetAllFriendsChartRankings(ctx, me.ID, limit)
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	out := make([]ChartRankingResponse, len(rs))
 	for i, r := range rs {
-		out[i] = SongPlaycountResponse{SongName: r.SongName, PlayCount: r.PlayCount}
+		out[i] = ChartRankingResponse{
+			BeatmapID:   r.BeatmapID,
+			PlayerCount: r.PlayerCount,
+			PlayCount:   r.PlayCount,
+			Top:         toRankingEntryResponse(r.Top),
+		}
 	}
 	return c.JSON(http.StatusOK, out)
 }
@@ -128,3 +180,47 @@ func toRankingEntryResponse(in []repository.RankingEntry) []RankingEntryResponse
 	}
 	return out
 }
+
+// GetChartDifficulties godoc
+// @Summary 譜面難易度一覧
+// @Description 申告された難易度と、プレイヤーの成績から推定した譜面定数を並べて返します
+// @Tags charts
+// @Produce json
+// @Success 200 {array} ChartDifficultyResponse "譜面難易度"
+// @Router /charts/difficulty [get]
+func (h *Handler) GetChartDifficulties(c echo.Context) error {
+	cs, err := h.repo.GetChartDifficulties(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	out := make([]ChartDifficultyResponse, len(cs))
+	for i, d := range cs {
+		out[i] = ChartDifficultyResponse{
+			BeatmapID:          d.BeatmapID,
+			SongName:           d.SongName,
+			DeclaredDifficulty: d.Difficulty,
+			ParallelString:     d.ParallelString,
+			EstimatedConstant:  d.EstimatedConstant,
+			SamplePlayers:      d.SamplePlayers,
+			EstimatedAt:        d.EstimatedAt,
+		}
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// RecomputeChartDifficulties godoc
+// @Summary 譜面定数の再推定
+// @Description 定期ジョブを待たずに全譜面の難易度を再推定します
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} RecomputeDifficultyResponse "推定した譜面数"
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/charts/difficulty/recompute [post]
+func (h *Handler) RecomputeChartDifficulties(c echo.Context) error {
+	n, err := h.estimator.Recompute(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, RecomputeDifficultyResponse{Estimated: n})
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+)
+
+// repositoryErrors maps repository sentinel errors to client errors
+var repositoryErrors = []struct {
+	err error
+	api *apperr.Error
+}{
+	{repository.ErrUserNotFound, apperr.New(http.StatusNotFound, apperr.CodeUserNotFound, "user not found")},
+	{repository.ErrChartNotFound, apperr.New(http.StatusNotFound, apperr.CodeChartNotFound, "chart not found")},
+	{repository.ErrScoreNotFound, apperr.New(http.StatusNotFound, apperr.CodeScoreNotFound, "score not found")},
+	{repository.ErrFriendRequestNotFound, apperr.New(http.StatusNotFound, apperr.CodeFriendRequestNotFound, "friend request not found")},
+	{repository.ErrFriendNotFound, apperr.New(http.StatusNotFound, apperr.CodeFriendNotFound, "friend not found")},
+	{repository.ErrRivalNotFound, apperr.New(http.StatusNotFound, apperr.CodeRivalNotFound, "rival not found")},
+	{repository.ErrRivalLimit, apperr.New(http.StatusConflict, apperr.CodeRivalLimit, "too many rivals")},
+	{repository.ErrTeamNotFound, apperr.New(http.StatusNotFound, apperr.CodeTeamNotFound, "team not found")},
+	{repository.ErrTeamMemberNotFound, apperr.New(http.StatusNotFound, apperr.CodeTeamMemberNotFound, "team member not found")},
+	{repository.ErrTeamOwner, apperr.New(http.StatusConflict, apperr.CodeConflict, "the owner must hand the team over first")},
+	{repository.ErrMissionNotFound, apperr.New(http.StatusNotFound, apperr.CodeMissionNotFound, "mission not found")},
+	{repository.ErrMissionIncomplete, apperr.New(http.StatusConflict, apperr.CodeMissionIncomplete, "mission not complete")},
+	{repository.ErrMissionClaimed, apperr.New(http.StatusConflict, apperr.CodeConflict, "mission already claimed")},
+	{repository.ErrUserBanned, apperr.New(http.StatusForbidden, apperr.CodeUserBanned, "user is banned")},
+	{repository.ErrDuplicate, apperr.New(http.StatusConflict, apperr.CodeConflict, "already exists")},
+	{repository.ErrMissingReference, apperr.New(http.StatusUnprocessableEntity, apperr.CodeValidationFailed, "refers to a missing user or chart")},
+}
+
+// apiError resolves what err is reported to the client as. Repository
+// sentinels take precedence over the status of an echo.HTTPError wrapping
+// them, so a handler may wrap any repository error as a 500.
+func apiError(err error) *apperr.Error {
+	var ae *apperr.Error
+	if errors.As(err, &ae) {
+		return ae
+	}
+	for _, m := range repositoryErrors {
+		if errors.Is(err, m.err) {
+			return m.api.Wrap(err)
+		}
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		msg, ok := he.Message.(string)
+		if !ok {
+			msg = http.StatusText(he.Code)
+		}
+		return apperr.FromStatus(he.Code, msg).Wrap(he.Internal)
+	}
+	return apperr.FromStatus(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)).Wrap(err)
+}
+
+// responseStatus is the status of the response to a request, once
+// HandleError has answered err
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	return apiError(err).Status
+}
+
+// HandleError is the echo.HTTPErrorHandler. Every error is answered with an
+// ErrorResponse. Server errors are logged with their cause, route, user and
+// request ID, and the cause is never sent to the client.
+func (h *Handler) HandleError(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	ae := apiError(err)
+
+	ctx := c.Request().Context()
+	if ae.Status >= http.StatusInternalServerError {
+		cause := ae.Err
+		if cause == nil {
+			cause = err
+		}
+		slog.ErrorContext(ctx, "request failed",
+			"method", c.Request().Method,
+			"route", c.Path(),
+			"status", ae.Status,
+			"code", ae.Code,
+			"user_id", userOf(c),
+			"error", cause,
+		)
+	} else if ae.Err != nil {
+		slog.DebugContext(ctx, "request rejected",
+			"route", c.Path(),
+			"status", ae.Status,
+			"code", ae.Code,
+			"user_id", userOf(c),
+			"error", ae.Err,
+		)
+	}
+
+	res := ErrorResponse{Code: string(ae.Code), Message: ae.Message}
+	for _, d := range ae.Details {
+		res.Details = append(res.Details, FieldErrorResponse{Field: d.Field, Message: d.Message})
+	}
+
+	var werr error
+	if c.Request().Method == http.MethodHead {
+		werr = c.NoContent(ae.Status)
+	} else {
+		werr = c.JSON(ae.Status, res)
+	}
+	if werr != nil {
+		slog.ErrorContext(ctx, "write error response", "error", werr)
+	}
+}
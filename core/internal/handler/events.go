@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/realtime"
+)
+
+const (
+	// EventPersonalBest is sent when a submission beats the player's previous best
+	EventPersonalBest = "personal_best"
+	// EventRankingUpdated is sent when a submission changes a chart's top-N
+	EventRankingUpdated = "ranking_updated"
+
+	// rankingEventTopN is the ranking size watched for EventRankingUpdated
+	rankingEventTopN = 10
+	// streamHeartbeat keeps idle connections open through proxies
+	streamHeartbeat = 15 * time.Second
+)
+
+type (
+	PersonalBestEvent struct {
+		ScoreID      int64  `json:"score_id"`
+		BeatmapID    string `json:"beatmap_id"`
+		UserID       string `json:"user_id"`
+		PlayerName   string `json:"player_name"`
+		Score        int    `json:"score"`
+		PreviousBest *int   `json:"previous_best"`
+	}
+
+	RankingUpdatedEvent struct {
+		BeatmapID string                 `json:"beatmap_id"`
+		Top       []RankingEntryResponse `json:"top"`
+	}
+)
+
+// StreamRankingEvents godoc
+// @Summary ランキング更新のストリーム
+// @Description Server-Sent Eventsで自己ベスト更新(personal_best)とトップN変動(ranking_updated)を配信します。beatmap_id未指定時は全譜面のイベントを配信します
+// @Tags charts
+// @Produce text/event-stream
+// @Param beatmap_id query string false "譜面ID"
+// @Success 200 {string} string "event stream"
+// @Router /charts/ranking/events [get]
+func (h *Handler) StreamRankingEvents(c echo.Context) error {
+	topic := realtime.GlobalTopic
+	if beatmapID := c.QueryParam("beatmap_id"); beatmapID != "" {
+		topic = realtime.ChartTopic(beatmapID)
+	}
+	events, cancel := h.broker.Subscribe(topic)
+	defer cancel()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx := c.Request().Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-h.health.Draining():
+			// clients reconnect to another instance
+			return nil
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", ev.Type, ev.Data); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+// publishScoreEvents notifies subscribers when a submission is a new personal
+// best, and when that best also changes the chart's top-N.
+func (h *Handler) publishScoreEvents(ctx context.Context, scoreID int64, req SubmitScoreRequest, prevBest *int) error {
+	if prevBest != nil && req.Score <= *prevBest {
+		return nil
+	}
+
+	u, err := h.repo.GetUser(ctx, req.UserID)
+	if err != nil {
+		return err
+	}
+	if err := h.publish(ctx, req.BeatmapID, EventPersonalBest, PersonalBestEvent{
+		ScoreID:      scoreID,
+		BeatmapID:    req.BeatmapID,
+		UserID:       req.UserID,
+		PlayerName:   u.Name,
+		Score:        req.Score,
+		PreviousBest: prevBest,
+	}); err != nil {
+		return err
+	}
+
+	// A new best changes the top-N exactly when it shows up in it
+	r, err := h.repo.GetChartRanking(ctx, req.BeatmapID, rankingEventTopN)
+	if err != nil {
+		return err
+	}
+	for _, e := range r.Top {
+		if e.UserID == req.UserID && e.Score == req.Score {
+			return h.publish(ctx, req.BeatmapID, EventRankingUpdated, RankingUpdatedEvent{
+				BeatmapID: req.BeatmapID,
+				Top:       toRankingEntryResponse(r.Top),
+			})
+		}
+	}
+	return nil
+}
+
+// publish sends the event to both the chart topic and the global feed
+func (h *Handler) publish(ctx context.Context, beatmapID string, typ string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", typ, err)
+	}
+	ev := realtime.Event{Type: typ, Data: b, At: time.Now()}
+	for _, topic := range []string{realtime.ChartTopic(beatmapID), realtime.GlobalTopic} {
+		if err := h.broker.Publish(ctx, topic, ev); err != nil {
+			return fmt.Errorf("publish %s event: %w", typ, err)
+		}
+	}
+	return nil
+}
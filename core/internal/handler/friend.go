@@ -0,0 +1,407 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/friendcode"
+)
+
+type SendFriendRequestRequest struct {
+	FriendCode string `json:"friend_code" example:"7K3QF-M9XA2"`
+}
+
+// GetMyFriendCode godoc
+// @Summary フレンドコード
+// @Description 認証したプレイヤーのフレンドコードを返します。フレンドコードはユーザーIDから決まり、変わりません。
+// @Tags friends
+// @Produce json
+// @Security UserToken
+// @Success 200 {object} FriendCodeResponse "フレンドコード"
+// @Failure 401 {object} ErrorResponse
+// @Router /users/me/friend-code [get]
+func (h *Handler) GetMyFriendCode(c echo.Context) error {
+	u := currentUser(c)
+	code := friendcode.Of(u.ID)
+	if u.FriendCode == nil {
+		// 検索できるように初回に保存する
+		if err := h.repo.SetFriendCode(c.Request().Context(), u.ID, code); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+	}
+	return c.JSON(http.StatusOK, FriendCodeResponse{FriendCode: friendcode.Format(code)})
+}
+
+// GetMyFriends godoc
+// @Summary フレンド一覧
+// @Tags friends
+// @Produce json
+// @Security UserToken
+// @Success 200 {array} ContactResponse "フレンド (名前順)"
+// @Failure 401 {object} ErrorResponse
+// @Router /users/me/friends [get]
+func (h *Handler) GetMyFriends(c echo.Context) error {
+	cs, err := h.repo.GetFriends(c.Request().Context(), currentUser(c).ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, toContactResponses(cs))
+}
+
+// GetMyFriendRequests godoc
+// @Summary フレンド申請一覧
+// @Description 受け取った申請と送った申請を新しい順に返します
+// @Tags friends
+// @Produce json
+// @Security UserToken
+// @Success 200 {object} FriendRequestsResponse "フレンド申請"
+// @Failure 401 {object} ErrorResponse
+// @Router /users/me/friends/requests [get]
+func (h *Handler) GetMyFriendRequests(c echo.Context) error {
+	in, out, err := h.repo.GetFriendRequests(c.Request().Context(), currentUser(c).ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, FriendRequestsResponse{
+		Incoming: toContactResponses(in),
+		Outgoing: toContactResponses(out),
+	})
+}
+
+// SendFriendRequest godoc
+// @Summary フレンド申請
+// @Description フレンドコードのプレイヤーにフレンド申請を送ります。相手から申請が届いていれば、その場でフレンドになります。
+// @Description フレンドコードの大文字・小文字とハイフンは区別しません
+// @Tags friends
+// @Accept json
+// @Produce json
+// @Security UserToken
+// @Param request body SendFriendRequestRequest true "相手のフレンドコード"
+// @Success 200 {object} SendFriendRequestResponse "requestedまたはaccepted"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "フレンドコードのプレイヤーが存在しない (USER_NOT_FOUND)"
+// @Failure 409 {object} ErrorResponse "既にフレンド、または申請済み"
+// @Failure 422 {object} ErrorResponse "フレンドコードが不正、または自分のフレンドコード"
+// @Router /users/me/friends/requests [post]
+func (h *Handler) SendFriendRequest(c echo.Context) error {
+	req := new(SendFriendRequestRequest)
+	if err := c.Bind(req); err != nil {
+		return apperr.InvalidBody(err)
+	}
+	if err := vd.ValidateStruct(req, vd.Field(&req.FriendCode, vd.Required)); err != nil {
+		return apperr.Validation(err)
+	}
+	code, ok := friendcode.Parse(req.FriendCode)
+	if !ok {
+		return apperr.InvalidField("friend_code", "must be a friend code")
+	}
+
+	ctx := c.Request().Context()
+	me := currentUser(c)
+	other, err := h.repo.GetUserByFriendCode(ctx, code)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	if other.ID == me.ID {
+		return apperr.InvalidField("friend_code", "must not be your own friend code")
+	}
+	accepted, err := h.repo.RequestFriend(ctx, me.ID, other.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	if accepted {
+		return c.JSON(http.StatusOK, SendFriendRequestResponse{Status: "accepted"})
+	}
+	return c.JSON(http.StatusOK, SendFriendRequestResponse{Status: "requested"})
+}
+
+// AcceptFriendRequest godoc
+// @Summary フレンド申請の承認
+// @Tags friends
+// @Produce json
+// @Security UserToken
+// @Param userID path string true "申請したプレイヤーのID" format(uuid)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "申請が存在しない (FRIEND_REQUEST_NOT_FOUND)"
+// @Router /users/me/friends/requests/{userID}/accept [post]
+func (h *Handler) AcceptFriendRequest(c echo.Context) error {
+	other, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	if err := h.repo.AcceptFriend(c.Request().Context(), currentUser(c).ID, other); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DeleteFriendRequest godoc
+// @Summary フレンド申請の拒否・取り消し
+// @Description 受け取った申請を断るか、送った申請を取り消します
+// @Tags friends
+// @Produce json
+// @Security UserToken
+// @Param userID path string true "相手のプレイヤーのID" format(uuid)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "申請が存在しない (FRIEND_REQUEST_NOT_FOUND)"
+// @Router /users/me/friends/requests/{userID} [delete]
+func (h *Handler) DeleteFriendRequest(c echo.Context) error {
+	other, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	if err := h.repo.DeleteFriendRequest(c.Request().Context(), currentUser(c).ID, other); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RemoveFriend godoc
+// @Summary フレンド解除
+// @Description 双方のフレンド一覧から削除します
+// @Tags friends
+// @Produce json
+// @Security UserToken
+// @Param userID path string true "フレンドのID" format(uuid)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "フレンドではない (FRIEND_NOT_FOUND)"
+// @Router /users/me/friends/{userID} [delete]
+func (h *Handler) RemoveFriend(c echo.Context) error {
+	other, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	if err := h.repo.RemoveFriend(c.Request().Context(), currentUser(c).ID, other); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetMyRivals godoc
+// @Summary ライバル一覧
+// @Tags friends
+// @Produce json
+// @Security UserToken
+// @Success 200 {array} ContactResponse "ライバル (名前順)"
+// @Failure 401 {object} ErrorResponse
+// @Router /users/me/rivals [get]
+func (h *Handler) GetMyRivals(c echo.Context) error {
+	cs, err := h.repo.GetRivals(c.Request().Context(), currentUser(c).ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, toContactResponses(cs))
+}
+
+// AddRival godoc
+// @Summary ライバル登録
+// @Description 相手の承認なしに登録できます。登録できるのは20人までです。
+// @Tags friends
+// @Produce json
+// @Security UserToken
+// @Param userID path string true "ライバルにするプレイヤーのID" format(uuid)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "プレイヤーが存在しない (USER_NOT_FOUND)"
+// @Failure 409 {object} ErrorResponse "登録済み、または上限 (RIVAL_LIMIT)"
+// @Failure 422 {object} ErrorResponse "自分自身"
+// @Router /users/me/rivals/{userID} [put]
+func (h *Handler) AddRival(c echo.Context) error {
+	other, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	me := currentUser(c)
+	if other == me.ID {
+		return apperr.InvalidField("userID", "must not be yourself")
+	}
+	if err := h.repo.AddRival(c.Request().Context(), me.ID, other); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RemoveRival godoc
+// @Summary ライバル解除
+// @Tags friends
+// @Produce json
+// @Security UserToken
+// @Param userID path string true "ライバルのID" format(uuid)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "ライバルではない (RIVAL_NOT_FOUND)"
+// @Router /users/me/rivals/{userID} [delete]
+func (h *Handler) RemoveRival(c echo.Context) error {
+	other, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	if err := h.repo.RemoveRival(c.Request().Context(), currentUser(c).ID, other); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetVersus godoc
+// @Summary 自己ベストの比較
+// @Description 2人のプレイヤーの自己ベストが異なる譜面を並べ、勝ち・負け・引き分けの数を返します。
+// @Description 片方しかプレイしていない譜面も含み、プレイしていない側のスコアはnullです。差の大きい順に並べます。
+// @Description 片方だけがスコア0でプレイした譜面は差が0のため、勝ち・負けのどちらにも数えません。
+// @Description プロフィールの公開範囲がfriendsのプレイヤーはフレンドと本人、privateのプレイヤーは本人だけが取得できます。本人やフレンドとして見るにはトークンを付けてください
+// @Tags users
+// @Produce json
+// @Security UserToken
+// @Param userID path string true "User ID" format(uuid)
+// @Param otherID path string true "比較相手のUser ID" format(uuid)
+// @Success 200 {object} VersusResponse "比較結果"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "ユーザーが存在しない (USER_NOT_FOUND)"
+// @Failure 401 {object} ErrorResponse "トークンが無効"
+// @Failure 403 {object} ErrorResponse "公開範囲により見られない (PROFILE_PRIVATE)"
+// @Router /users/{userID}/versus/{otherID} [get]
+func (h *Handler) GetVersus(c echo.Context) error {
+	uid, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	otherID := c.Param("otherID")
+	if _, err := uuid.Parse(otherID); err != nil {
+		return apperr.InvalidParam("otherID", err)
+	}
+	if err := h.requireVisiblePlays(c, uid, otherID); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	var (
+		users [2]*repository.User
+		bests [2][]*repository.PlayRow
+	)
+	for i, id := range []string{uid, otherID} {
+		if users[i], err = h.repo.GetUser(ctx, id); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+		if bests[i], err = h.repo.GetUserBests(ctx, id); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+	}
+
+	res := VersusResponse{
+		UserID:     users[0].ID,
+		PlayerName: users[0].Name,
+		OtherID:    users[1].ID,
+		OtherName:  users[1].Name,
+		Charts:     versusCharts(bests[0], bests[1]),
+	}
+	res.Draws = countDraws(bests[0], bests[1])
+	// a chart only one side played with a score of 0 counts as neither
+	for _, ch := range res.Charts {
+		switch {
+		case ch.Diff > 0:
+			res.Wins++
+		case ch.Diff < 0:
+			res.Losses++
+		}
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// versusCharts lists the charts where the bests differ, biggest difference first
+func versusCharts(mine, theirs []*repository.PlayRow) []VersusChartResponse {
+	byChart := make(map[string]*VersusChartResponse, len(mine)+len(theirs))
+	order := make([]string, 0, len(mine)+len(theirs))
+	entry := func(p *repository.PlayRow) *VersusChartResponse {
+		ch, ok := byChart[p.BeatmapID]
+		if !ok {
+			ch = &VersusChartResponse{
+				BeatmapID:      p.BeatmapID,
+				SongName:       p.SongName,
+				Difficulty:     p.Difficulty,
+				ParallelString: p.ParallelString,
+			}
+			byChart[p.BeatmapID] = ch
+			order = append(order, p.BeatmapID)
+		}
+		return ch
+	}
+	for _, p := range mine {
+		entry(p).Score = &p.Score
+	}
+	for _, p := range theirs {
+		entry(p).OtherScore = &p.Score
+	}
+
+	out := make([]VersusChartResponse, 0, len(order))
+	for _, id := range order {
+		ch := byChart[id]
+		ch.Diff = valueOr(ch.Score, 0) - valueOr(ch.OtherScore, 0)
+		if ch.Score != nil && ch.OtherScore != nil && ch.Diff == 0 {
+			continue
+		}
+		out = append(out, *ch)
+	}
+	slices.SortStableFunc(out, func(a, b VersusChartResponse) int {
+		return abs(b.Diff) - abs(a.Diff)
+	})
+	return out
+}
+
+// countDraws counts the charts both players played with the same best
+func countDraws(mine, theirs []*repository.PlayRow) int {
+	best := make(map[string]int, len(mine))
+	for _, p := range mine {
+		best[p.BeatmapID] = p.Score
+	}
+	n := 0
+	for _, p := range theirs {
+		if s, ok := best[p.BeatmapID]; ok && s == p.Score {
+			n++
+		}
+	}
+	return n
+}
+
+func valueOr(p *int, v int) int {
+	if p == nil {
+		return v
+	}
+	return *p
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// userIDParam is the userID path parameter, validated as a UUID
+func userIDParam(c echo.Context) (string, error) {
+	id := c.Param("userID")
+	if _, err := uuid.Parse(id); err != nil {
+		return "", apperr.InvalidParam("userID", err)
+	}
+	return id, nil
+}
+
+func toContactResponses(in []*repository.Contact) []ContactResponse {
+	out := make([]ContactResponse, len(in))
+	for i, c := range in {
+		out[i] = ContactResponse{UserID: c.UserID, PlayerName: c.Name, Since: c.Since}
+	}
+	return out
+}
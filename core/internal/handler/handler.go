@@ -1,28 +1,297 @@
 package handler
 
 import (
+	"time"
+
 	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/achievement"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/backup"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/catalog"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/difficulty"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/health"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/leaderboard"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/metrics"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/mission"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/realtime"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/worker"
 )
 
 type Handler struct {
-	repo *repository.Repository
+	repo        *repository.Repository
+	broker      realtime.Broker
+	estimator   *difficulty.Estimator
+	leaderboard *leaderboard.Service
+	jobs        *worker.Scheduler
+	metrics     *metrics.Metrics
+	health      *health.Checker
+	catalog     *catalog.Service
+	backup      *backup.Service
+	achievement *achievement.Service
+	missions    *mission.Service
+	opts        Options
+}
+
+// Services are the dependencies of the handlers
+type Services struct {
+	Repo        *repository.Repository
+	Broker      realtime.Broker
+	Estimator   *difficulty.Estimator
+	Leaderboard *leaderboard.Service
+	Jobs        *worker.Scheduler
+	Metrics     *metrics.Metrics
+	Health      *health.Checker
+	Catalog     *catalog.Service
+	Backup      *backup.Service
+	Achievement *achievement.Service
+	Missions    *mission.Service
+}
+
+// Options configures optional handler behaviour
+type Options struct {
+	// AdminToken is the bearer token for /admin APIs. Empty disables them.
+	AdminToken string
+	// MetricsToken is the bearer token for /metrics. Empty disables it.
+	MetricsToken string
 }
 
-func New(repo *repository.Repository) *Handler {
+func New(s Services, opts Options) *Handler {
 	return &Handler{
-		repo: repo,
+		repo:        s.Repo,
+		broker:      s.Broker,
+		estimator:   s.Estimator,
+		leaderboard: s.Leaderboard,
+		jobs:        s.Jobs,
+		metrics:     s.Metrics,
+		health:      s.Health,
+		catalog:     s.Catalog,
+		backup:      s.Backup,
+		achievement: s.Achievement,
+		missions:    s.Missions,
+		opts:        opts,
 	}
 }
 
 // Common response
-type ErrorResponse struct {
-	Message string `json:"message"`
-}
+type (
+	// ErrorResponse is the body of every error. Code is stable and meant for
+	// programs; Message is for people and may change.
+	ErrorResponse struct {
+		Code    string               `json:"code" example:"USER_NOT_FOUND"`
+		Message string               `json:"message" example:"user not found"`
+		Details []FieldErrorResponse `json:"details,omitempty"`
+	}
+
+	FieldErrorResponse struct {
+		Field   string `json:"field" example:"user_id"`
+		Message string `json:"message" example:"cannot be blank"`
+	}
+)
 
 // Response DTOs
 type (
 	RegisterUserResponse struct {
 		ID string `json:"id"`
+		// Token authenticates the player as "Authorization: Bearer <token>". It is only returned here.
+		Token string `json:"token"`
+	}
+
+	// PersonalDataResponse is everything stored about a player
+	PersonalDataResponse struct {
+		ExportedAt  time.Time               `json:"exported_at"`
+		Profile     PersonalProfileResponse `json:"profile"`
+		NameHistory []UserNameResponse      `json:"name_history"`
+		Scores      []PersonalScoreResponse `json:"scores"`
+		// Achievements are every stored unlock, including those of achievements no longer defined
+		Achievements   []PersonalAchievementResponse `json:"achievements"`
+		Friends        []ContactResponse             `json:"friends"`
+		FriendRequests FriendRequestsResponse        `json:"friend_requests"`
+		Rivals         []ContactResponse             `json:"rivals"`
+		Teams          []MyTeamResponse              `json:"teams"`
+		// Missions are the missions the player claimed. The progress of the
+		// others is counted from the scores.
+		Missions      []PersonalMissionResponse `json:"missions"`
+		MissionPoints int                       `json:"mission_points"`
+	}
+
+	PersonalProfileResponse struct {
+		ID        string     `json:"id"`
+		Name      string     `json:"name"`
+		CreatedAt time.Time  `json:"created_at"`
+		UpdatedAt time.Time  `json:"updated_at"`
+		BannedAt  *time.Time `json:"banned_at,omitempty"`
+		BanReason *string    `json:"ban_reason,omitempty"`
+		// FriendCode is null until the player first asks for it
+		FriendCode *string `json:"friend_code" example:"7K3QF-M9XA2"`
+		// Title, FavoriteBeatmapID, Showcase and Visibility are the profile settings
+		Title             *string  `json:"title"`
+		FavoriteBeatmapID *string  `json:"favorite_beatmap_id"`
+		Showcase          []string `json:"showcase"`
+		Visibility        string   `json:"visibility"`
+	}
+
+	// PersonalMissionResponse is a mission of the period beginning at
+	// period_start that the player claimed with progress plays
+	PersonalMissionResponse struct {
+		Region      string    `json:"region" example:"jp"`
+		MissionID   string    `json:"mission_id"`
+		PeriodStart time.Time `json:"period_start"`
+		Progress    int       `json:"progress"`
+		Reward      int       `json:"reward"`
+		ClaimedAt   time.Time `json:"claimed_at"`
+	}
+
+	// UserNameResponse is a name the player took at created_at
+	UserNameResponse struct {
+		Name      string    `json:"name"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	PersonalScoreResponse struct {
+		ID                  int64      `json:"id"`
+		BeatmapID           string     `json:"beatmap_id"`
+		Score               int        `json:"score"`
+		MaxCombo            int        `json:"max_combo"`
+		PerfectCriticalFast int        `json:"perfect_critical_fast"`
+		PerfectCriticalLate int        `json:"perfect_critical_late"`
+		PerfectFast         int        `json:"perfect_fast"`
+		PerfectLate         int        `json:"perfect_late"`
+		GoodFast            int        `json:"good_fast"`
+		GoodLate            int        `json:"good_late"`
+		Miss                int        `json:"miss"`
+		Input               uint8      `json:"input"`
+		CreatedAt           time.Time  `json:"created_at"`
+		DeletedAt           *time.Time `json:"deleted_at,omitempty"`
+		InvalidatedAt       *time.Time `json:"invalidated_at,omitempty"`
+		InvalidationReason  *string    `json:"invalidation_reason,omitempty"`
+	}
+
+	PersonalAchievementResponse struct {
+		AchievementID string    `json:"achievement_id"`
+		UnlockedAt    time.Time `json:"unlocked_at"`
+	}
+
+	DeleteUserResponse struct {
+		Status string `json:"status" example:"deleted"`
+		// scores moved to the anonymous placeholder
+		AnonymisedScores int64 `json:"anonymised_scores"`
+	}
+
+	FriendCodeResponse struct {
+		FriendCode string `json:"friend_code" example:"7K3QF-M9XA2"`
+	}
+
+	// ContactResponse is a friend, rival or friend request made at since
+	ContactResponse struct {
+		UserID     string    `json:"user_id"`
+		PlayerName string    `json:"player_name"`
+		Since      time.Time `json:"since"`
+	}
+
+	FriendRequestsResponse struct {
+		Incoming []ContactResponse `json:"incoming"`
+		Outgoing []ContactResponse `json:"outgoing"`
+	}
+
+	SendFriendRequestResponse struct {
+		// Status is "accepted" when the other player had already asked, else "requested"
+		Status string `json:"status" example:"requested"`
+	}
+
+	// VersusResponse compares the personal bests of two players
+	VersusResponse struct {
+		UserID     string `json:"user_id"`
+		PlayerName string `json:"player_name"`
+		OtherID    string `json:"other_id"`
+		OtherName  string `json:"other_name"`
+		Wins       int    `json:"wins"`
+		Losses     int    `json:"losses"`
+		Draws      int    `json:"draws"`
+		// Charts are the charts where the bests differ
+		Charts []VersusChartResponse `json:"charts"`
+	}
+
+	// VersusChartResponse has nil scores for a player who has not played the chart
+	VersusChartResponse struct {
+		BeatmapID      string  `json:"beatmap_id"`
+		SongName       string  `json:"song_name"`
+		Difficulty     int     `json:"difficulty"`
+		ParallelString *string `json:"parallel_string"`
+		Score          *int    `json:"score"`
+		OtherScore     *int    `json:"other_score"`
+		// Diff is score minus other_score, counting a missing score as 0
+		Diff int `json:"diff"`
+	}
+
+	// TeamResponse is a team; Members is only set for a single team
+	TeamResponse struct {
+		ID          string               `json:"id"`
+		Name        string               `json:"name"`
+		MemberCount int                  `json:"member_count"`
+		CreatedAt   time.Time            `json:"created_at"`
+		Members     []TeamMemberResponse `json:"members,omitempty"`
+	}
+
+	TeamMemberResponse struct {
+		UserID     string    `json:"user_id"`
+		PlayerName string    `json:"player_name"`
+		Role       string    `json:"role" example:"member"`
+		JoinedAt   time.Time `json:"joined_at"`
+	}
+
+	// MyTeamResponse is a team with the role of the authenticated player in it
+	MyTeamResponse struct {
+		ID          string    `json:"id"`
+		Name        string    `json:"name"`
+		MemberCount int       `json:"member_count"`
+		CreatedAt   time.Time `json:"created_at"`
+		Role        string    `json:"role" example:"owner"`
+		JoinedAt    time.Time `json:"joined_at"`
+	}
+
+	CreateTeamResponse struct {
+		ID         string `json:"id"`
+		Name       string `json:"name"`
+		InviteCode string `json:"invite_code" example:"K7QF2M9X"`
+	}
+
+	InviteCodeResponse struct {
+		InviteCode string `json:"invite_code" example:"K7QF2M9X"`
+	}
+
+	// TeamTotalResponse sums the personal bests of the members of a team
+	TeamTotalResponse struct {
+		TeamID   string `json:"team_id"`
+		TeamName string `json:"team_name"`
+		// Players is the number of members with a score
+		Players    int `json:"players"`
+		Charts     int `json:"charts"`
+		TotalScore int `json:"total_score"`
+		PlayCount  int `json:"play_count"`
+	}
+
+	TeamRankingEntryResponse struct {
+		Rank int `json:"rank"`
+		TeamTotalResponse
+	}
+
+	// TeamRankingResponse has Week, From and To for weekly rankings
+	TeamRankingResponse struct {
+		BeatmapID string                     `json:"beatmap_id,omitempty"`
+		Week      string                     `json:"week,omitempty" example:"2026-W42"`
+		From      *time.Time                 `json:"from,omitempty"`
+		To        *time.Time                 `json:"to,omitempty"`
+		Items     []TeamRankingEntryResponse `json:"items"`
+	}
+
+	// TeamVersusResponse compares the weekly totals of two teams
+	TeamVersusResponse struct {
+		Week  string            `json:"week" example:"2026-W42"`
+		From  time.Time         `json:"from"`
+		To    time.Time         `json:"to"`
+		Team  TeamTotalResponse `json:"team"`
+		Other TeamTotalResponse `json:"other"`
+		// WinnerID is the team with the higher total, or null for a draw
+		WinnerID *string `json:"winner_id"`
 	}
 
 	UpdateUserNameResponse struct {
@@ -32,6 +301,95 @@ type (
 	GetUserResponse struct {
 		ID   string `json:"id"`
 		Name string `json:"name"`
+		// Title is the achievement the player chose to show, if any
+		Title *AchievementResponse `json:"title"`
+		// Achievements are the unlocked achievements, oldest first
+		Achievements []UserAchievementResponse `json:"achievements"`
+	}
+
+	AchievementResponse struct {
+		ID          string `json:"id" example:"future_full_combo_10"`
+		Name        string `json:"name" example:"Future Proof"`
+		Description string `json:"description" example:"Full Combo 10 Future charts"`
+		// Goal is the number of plays, or of charts, that unlocks the achievement
+		Goal int `json:"goal" example:"10"`
+	}
+
+	// UserAchievementResponse is how far a player is towards an achievement
+	UserAchievementResponse struct {
+		AchievementResponse
+		Progress int `json:"progress" example:"4"`
+		// UnlockedAt is null while the achievement is locked
+		UnlockedAt *time.Time `json:"unlocked_at"`
+	}
+
+	// UserProfileResponse is the profile of a player. Details is null when
+	// the privacy setting of the player hides it from the viewer.
+	UserProfileResponse struct {
+		ID         string                  `json:"id"`
+		Name       string                  `json:"name"`
+		Title      *AchievementResponse    `json:"title"`
+		Visibility string                  `json:"visibility" enums:"public,friends,private"`
+		Details    *ProfileDetailsResponse `json:"details"`
+	}
+
+	ProfileDetailsResponse struct {
+		FavoriteChart *ProfileChartResponse `json:"favorite_chart"`
+		// Showcase is the personal best on each showcased chart, or the best
+		// rated plays when the player has not chosen any
+		Showcase []PlayResponse `json:"showcase"`
+		Rating   float64        `json:"rating" example:"12.35"`
+		// TotalPlays, FirstPlayedAt and LastPlayedAt count active scores only
+		TotalPlays    int        `json:"total_plays" example:"128"`
+		FirstPlayedAt *time.Time `json:"first_played_at"`
+		LastPlayedAt  *time.Time `json:"last_played_at"`
+		// PreferredInput is the input of most plays (0=keyboard, 1=button), null before the first play
+		PreferredInput *uint8 `json:"preferred_input" example:"1"`
+	}
+
+	ProfileChartResponse struct {
+		BeatmapID      string  `json:"beatmap_id"`
+		SongName       string  `json:"song_name"`
+		Difficulty     int     `json:"difficulty"`
+		ParallelString *string `json:"parallel_string"`
+	}
+
+	MissionResponse struct {
+		ID          string `json:"id" example:"daily_present_3"`
+		Period      string `json:"period" enums:"daily,weekly" example:"daily"`
+		Name        string `json:"name" example:"Present Practice"`
+		Description string `json:"description" example:"Play 3 Present charts today"`
+		// Goal is the number of plays that completes the mission
+		Goal   int `json:"goal" example:"3"`
+		Reward int `json:"reward" example:"10"`
+		// StartsAt and EndsAt bound the day or week of the mission
+		StartsAt time.Time `json:"starts_at"`
+		EndsAt   time.Time `json:"ends_at"`
+	}
+
+	MissionsResponse struct {
+		Region   string            `json:"region" example:"jp"`
+		Missions []MissionResponse `json:"missions"`
+	}
+
+	// UserMissionResponse is how far a player is on a mission
+	UserMissionResponse struct {
+		MissionResponse
+		Progress int `json:"progress" example:"2"`
+		// ClaimedAt is null until the reward is claimed
+		ClaimedAt *time.Time `json:"claimed_at"`
+	}
+
+	MyMissionsResponse struct {
+		Region string `json:"region" example:"jp"`
+		// Points is the sum of every reward claimed so far
+		Points   int                   `json:"points" example:"120"`
+		Missions []UserMissionResponse `json:"missions"`
+	}
+
+	ClaimMissionResponse struct {
+		Reward int `json:"reward" example:"10"`
+		Points int `json:"points" example:"130"`
 	}
 
 	UserStatsResponse struct {
@@ -56,10 +414,256 @@ type (
 		PlayerCount int                    `json:"player_count"`
 		PlayCount   int                    `json:"play_count"`
 		Top         []RankingEntryResponse `json:"top"`
+		// SnapshotAt is set when served from a ranking snapshot
+		SnapshotAt *time.Time `json:"snapshot_at,omitempty"`
 	}
 
 	SongPlaycountResponse struct {
-		SongName  string `json:"song_name"`
-		PlayCount int    `json:"play_count"`
+		SongName   string     `json:"song_name"`
+		PlayCount  int        `json:"play_count"`
+		SnapshotAt *time.Time `json:"snapshot_at,omitempty"`
+	}
+
+	PlayResponse struct {
+		ID                  int64     `json:"id"`
+		UserID              string    `json:"user_id"`
+		PlayerName          string    `json:"player_name"`
+		BeatmapID           string    `json:"beatmap_id"`
+		SongName            string    `json:"song_name"`
+		Difficulty          int       `json:"difficulty"`
+		ParallelString      *string   `json:"parallel_string"`
+		Score               int       `json:"score"`
+		Grade               string    `json:"grade"`
+		Lamp                string    `json:"lamp"`
+		MaxCombo            int       `json:"max_combo"`
+		PerfectCriticalFast int       `json:"perfect_critical_fast"`
+		PerfectCriticalLate int       `json:"perfect_critical_late"`
+		PerfectFast         int       `json:"perfect_fast"`
+		PerfectLate         int       `json:"perfect_late"`
+		GoodFast            int       `json:"good_fast"`
+		GoodLate            int       `json:"good_late"`
+		Miss                int       `json:"miss"`
+		Input               uint8     `json:"input"`
+		PlayedAt            time.Time `json:"played_at"`
+	}
+
+	RecentPlaysResponse struct {
+		Items []PlayResponse `json:"items"`
+		// NextBeforeID is the before_id for the next page, null on the last page
+		NextBeforeID *int64 `json:"next_before_id"`
+	}
+
+	UserScoresResponse struct {
+		Items  []PlayResponse `json:"items"`
+		Total  int            `json:"total"`
+		Limit  int            `json:"limit"`
+		Offset int            `json:"offset"`
+	}
+
+	JudgementAveragesResponse struct {
+		PerfectCriticalFast float64 `json:"perfect_critical_fast"`
+		PerfectCriticalLate float64 `json:"perfect_critical_late"`
+		PerfectFast         float64 `json:"perfect_fast"`
+		PerfectLate         float64 `json:"perfect_late"`
+		GoodFast            float64 `json:"good_fast"`
+		GoodLate            float64 `json:"good_late"`
+		Miss                float64 `json:"miss"`
+	}
+
+	TrendPointResponse struct {
+		ScoreID   int64     `json:"score_id"`
+		PlayedAt  time.Time `json:"played_at"`
+		Score     int       `json:"score"`
+		Fast      int       `json:"fast"`
+		Late      int       `json:"late"`
+		Miss      int       `json:"miss"`
+		MissRate  float64   `json:"miss_rate"`
+		FastRatio *float64  `json:"fast_ratio"`
+	}
+
+	UserChartAnalyticsResponse struct {
+		UserID            string                    `json:"user_id"`
+		BeatmapID         string                    `json:"beatmap_id"`
+		TotalPlays        int                       `json:"total_plays"`
+		SampleSize        int                       `json:"sample_size"`
+		AverageScore      float64                   `json:"average_score"`
+		AverageJudgements JudgementAveragesResponse `json:"average_judgements"`
+		FastCount         int                       `json:"fast_count"`
+		LateCount         int                       `json:"late_count"`
+		// FastRatio is fast / (fast + late), null without hits
+		FastRatio *float64 `json:"fast_ratio"`
+		MissRate  float64  `json:"miss_rate"`
+		// MeanTimingErrorMs is negative when hitting early
+		MeanTimingErrorMs *float64 `json:"mean_timing_error_ms"`
+		// RecommendedOffsetMs is the judgement offset adjustment, null until enough hits are recorded
+		RecommendedOffsetMs *int                 `json:"recommended_offset_ms"`
+		ScoreTrend          float64              `json:"score_trend_per_play"`
+		MissRateTrend       float64              `json:"miss_rate_trend_per_play"`
+		Trend               []TrendPointResponse `json:"trend"`
+	}
+
+	HistogramBucketResponse struct {
+		Min   int `json:"min"`
+		Max   int `json:"max"`
+		Count int `json:"count"`
+	}
+
+	InputSplitResponse struct {
+		Input        uint8   `json:"input"`
+		Plays        int     `json:"plays"`
+		AverageScore float64 `json:"average_score"`
+	}
+
+	DailyPlaysResponse struct {
+		Date  string `json:"date"`
+		Plays int    `json:"plays"`
+	}
+
+	ChartAnalyticsResponse struct {
+		BeatmapID      string   `json:"beatmap_id"`
+		SongName       string   `json:"song_name"`
+		Difficulty     int      `json:"difficulty"`
+		ParallelString *string  `json:"parallel_string"`
+		PlayCount      int      `json:"play_count"`
+		PlayerCount    int      `json:"player_count"`
+		AverageScore   *float64 `json:"average_score"`
+		BestScore      *int     `json:"best_score"`
+		// EstimatedConstant is null until the chart has enough players to be estimated
+		EstimatedConstant *float64 `json:"estimated_constant"`
+		// ScoreHistogram, GradeDistribution, LampDistribution, MedianScore and
+		// Percentiles are computed from each player's best
+		ScoreHistogram    []HistogramBucketResponse `json:"score_histogram"`
+		GradeDistribution map[string]int            `json:"grade_distribution"`
+		LampDistribution  map[string]int            `json:"lamp_distribution"`
+		MedianScore       *float64                  `json:"median_score"`
+		Percentiles       map[string]float64        `json:"percentiles"`
+		// ClearRate is over plays, PlayerClearRate over players
+		ClearRate       float64              `json:"clear_rate"`
+		PlayerClearRate float64              `json:"player_clear_rate"`
+		InputSplit      []InputSplitResponse `json:"input_split"`
+		PlaysOverTime   []DailyPlaysResponse `json:"plays_over_time"`
+	}
+
+	ChartDifficultyResponse struct {
+		BeatmapID          string  `json:"beatmap_id"`
+		SongName           string  `json:"song_name"`
+		DeclaredDifficulty int     `json:"declared_difficulty"`
+		ParallelString     *string `json:"parallel_string"`
+		// EstimatedConstant, SamplePlayers and EstimatedAt are null until the chart has been estimated
+		EstimatedConstant *float64   `json:"estimated_constant"`
+		SamplePlayers     *int       `json:"sample_players"`
+		EstimatedAt       *time.Time `json:"estimated_at"`
+	}
+
+	RecomputeDifficultyResponse struct {
+		Estimated int `json:"estimated"`
+	}
+
+	ModerateScoresResponse struct {
+		Affected int64 `json:"affected"`
+	}
+
+	AuditLogResponse struct {
+		ID        int64     `json:"id"`
+		Actor     string    `json:"actor"`
+		Action    string    `json:"action"`
+		Target    string    `json:"target"`
+		Detail    *string   `json:"detail"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	RatingEntryResponse struct {
+		Rank       int     `json:"rank"`
+		UserID     string  `json:"user_id"`
+		PlayerName string  `json:"player_name"`
+		Rating     float64 `json:"rating"`
+		Charts     int     `json:"charts"`
+	}
+
+	RatingRankingResponse struct {
+		Items      []RatingEntryResponse `json:"items"`
+		SnapshotAt *time.Time            `json:"snapshot_at,omitempty"`
+	}
+
+	RebuildRankingsResponse struct {
+		SnapshotAt *time.Time `json:"snapshot_at"`
+	}
+
+	JobStatusResponse struct {
+		Name            string     `json:"name"`
+		IntervalSeconds float64    `json:"interval_seconds"`
+		Running         bool       `json:"running"`
+		Runs            int        `json:"runs"`
+		Failures        int        `json:"failures"`
+		LastStartedAt   *time.Time `json:"last_started_at"`
+		LastFinishedAt  *time.Time `json:"last_finished_at"`
+		LastSuccessAt   *time.Time `json:"last_success_at"`
+		LastError       string     `json:"last_error,omitempty"`
+	}
+
+	HealthResponse struct {
+		// ok, degraded or unavailable
+		Status string                `json:"status" example:"ok"`
+		Checks []HealthCheckResponse `json:"checks,omitempty"`
+	}
+
+	HealthCheckResponse struct {
+		Name   string `json:"name" example:"database"`
+		Status string `json:"status" example:"ok"`
+		Detail string `json:"detail,omitempty"`
+	}
+
+	CacheStatsResponse struct {
+		Hits      uint64  `json:"hits"`
+		Misses    uint64  `json:"misses"`
+		HitRatio  float64 `json:"hit_ratio"`
+		Evictions uint64  `json:"evictions"`
+		Entries   int     `json:"entries"`
+		Bytes     int64   `json:"bytes"`
+	}
+
+	// ChartCatalogEntry is a row of the chart catalog in every format
+	ChartCatalogEntry struct {
+		BeatmapID string `json:"beatmap_id" example:"songA_future"`
+		SongName  string `json:"song_name" example:"Song A"`
+		// 0-4, or past/present/future/lycoris/parallel on import
+		Difficulty     int      `json:"difficulty" example:"2"`
+		ParallelString *string  `json:"parallel_string,omitempty"`
+		NoteCount      *int     `json:"note_count,omitempty" example:"812"`
+		Constant       *float64 `json:"constant,omitempty" example:"9.8"`
+	}
+
+	ChartImportResponse struct {
+		DryRun    bool `json:"dry_run"`
+		Created   int  `json:"created"`
+		Updated   int  `json:"updated"`
+		Unchanged int  `json:"unchanged"`
+		// created and updated charts
+		Changes []ChartChangeResponse `json:"changes"`
+	}
+
+	ChartChangeResponse struct {
+		// create or update
+		Op        string                     `json:"op" example:"update"`
+		BeatmapID string                     `json:"beatmap_id" example:"songA_future"`
+		Fields    []ChartFieldChangeResponse `json:"fields"`
+	}
+
+	// ChartFieldChangeResponse is a column the import sets. old is null for a new chart.
+	ChartFieldChangeResponse struct {
+		Field string `json:"field" example:"constant"`
+		Old   any    `json:"old"`
+		New   any    `json:"new"`
+	}
+
+	// DataImportResponse counts the rows an import wrote
+	DataImportResponse struct {
+		Users  int `json:"users"`
+		Charts int `json:"charts"`
+		Scores int `json:"scores"`
+		// scores already stored, which were not imported again
+		SkippedScores int `json:"skipped_scores"`
+		// scores already stored whose deletion or invalidation was updated from the dump
+		ModeratedScores int `json:"moderated_scores"`
 	}
 )
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/health"
+)
+
+// GetLiveness answers the liveness probe. It only tells that the process
+// serves requests and checks no dependencies, so a database outage does not
+// get the server restarted.
+func (h *Handler) GetLiveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, HealthResponse{Status: string(health.StatusOK)})
+}
+
+// GetReadiness answers the readiness probe: 200 while ok or degraded, 503
+// while unavailable, with the result of every check.
+func (h *Handler) GetReadiness(c echo.Context) error {
+	r := h.health.Ready(c.Request().Context())
+	res := HealthResponse{
+		Status: string(r.Status),
+		Checks: make([]HealthCheckResponse, len(r.Checks)),
+	}
+	for i, ch := range r.Checks {
+		res.Checks[i] = HealthCheckResponse{Name: ch.Name, Status: string(ch.Status), Detail: ch.Detail}
+	}
+	status := http.StatusOK
+	if r.Status == health.StatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	return c.JSON(status, res)
+}
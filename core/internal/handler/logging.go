@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"log/slog"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/logging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// userIDKey is the echo context key under which handlers record who a request
+// acts for, when it is not already in the path
+const userIDKey = "user_id"
+
+// setUser records the user a request acts for in error logs
+func setUser(c echo.Context, id string) {
+	c.Set(userIDKey, id)
+}
+
+func userOf(c echo.Context) string {
+	if id, ok := c.Get(userIDKey).(string); ok && id != "" {
+		return id
+	}
+	return c.Param("userID")
+}
+
+// RequestID gives every request an ID, reusing X-Request-ID when the client
+// sends one. The ID is returned in X-Request-ID and carried in the request
+// context so that logs down to the repository include it.
+func RequestID() echo.MiddlewareFunc {
+	return middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, id string) {
+			req := c.Request()
+			trace.SpanFromContext(req.Context()).SetAttributes(attribute.String("request_id", id))
+			c.SetRequest(req.WithContext(logging.WithRequestID(req.Context(), id)))
+		},
+	})
+}
+
+// LogRequests writes an access log line for every request but the probes. It must run inside RequestID.
+func LogRequests() echo.MiddlewareFunc {
+	return middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == "/livez" || c.Path() == "/readyz"
+		},
+		LogMethod:    true,
+		LogURI:       true,
+		LogRoutePath: true,
+		LogStatus:    true,
+		LogLatency:   true,
+		LogRemoteIP:  true,
+		// answer the error here so that the logged status is the one sent
+		HandleError: true,
+		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
+			slog.LogAttrs(c.Request().Context(), slog.LevelInfo, "request",
+				slog.String("method", v.Method),
+				slog.String("uri", v.URI),
+				slog.String("route", v.RoutePath),
+				slog.Int("status", v.Status),
+				slog.Duration("latency", v.Latency),
+				slog.String("remote_ip", v.RemoteIP),
+			)
+			return nil
+		},
+	})
+}
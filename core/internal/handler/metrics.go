@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ObserveRequests records HTTP metrics for every request
+func (h *Handler) ObserveRequests(next echo.HandlerFunc) echo.HandlerFunc {
+	return h.metrics.Middleware(responseStatus)(next)
+}
+
+// ServeMetrics serves the metrics in the Prometheus exposition format to
+// requests with "Authorization: Bearer <METRICS_TOKEN>".
+func (h *Handler) ServeMetrics(c echo.Context) error {
+	if h.opts.MetricsToken == "" {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	if !hasBearerToken(c, h.opts.MetricsToken) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid metrics token")
+	}
+	h.metrics.Handler().ServeHTTP(c.Response(), c.Request())
+	return nil
+}
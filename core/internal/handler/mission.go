@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/mission"
+)
+
+// GetMissions godoc
+// @Summary 今日と今週のミッション
+// @Description 地域のタイムゾーンで今日と今週に出ているミッションを、デイリー、ウィークリーの順に返します
+// @Tags missions
+// @Produce json
+// @Param region query string false "地域 (省略時は既定の地域)"
+// @Success 200 {object} MissionsResponse "ミッション一覧"
+// @Failure 400 {object} ErrorResponse
+// @Router /missions [get]
+func (h *Handler) GetMissions(c echo.Context) error {
+	r, err := h.missionRegion(c)
+	if err != nil {
+		return err
+	}
+	ms := h.missions.Missions(r)
+	res := MissionsResponse{Region: r.Name, Missions: make([]MissionResponse, len(ms))}
+	for i, m := range ms {
+		res.Missions[i] = toMissionResponse(m)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetMyMissions godoc
+// @Summary 自分のミッション
+// @Description 今日と今週のミッションの進捗と受け取り状況、これまでに獲得したミッションポイントを返します
+// @Tags missions
+// @Produce json
+// @Security UserToken
+// @Param region query string false "地域 (省略時は既定の地域)"
+// @Success 200 {object} MyMissionsResponse "ミッションの進捗"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /users/me/missions [get]
+func (h *Handler) GetMyMissions(c echo.Context) error {
+	r, err := h.missionRegion(c)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	userID := currentUser(c).ID
+	ss, err := h.missions.Statuses(ctx, userID, r)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	points, err := h.missions.Points(ctx, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	res := MyMissionsResponse{Region: r.Name, Points: points, Missions: make([]UserMissionResponse, len(ss))}
+	for i, s := range ss {
+		res.Missions[i] = UserMissionResponse{
+			MissionResponse: toMissionResponse(s.Mission),
+			Progress:        s.Progress,
+			ClaimedAt:       s.ClaimedAt,
+		}
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// ClaimMission godoc
+// @Summary ミッション報酬の受け取り
+// @Description 達成したミッションの報酬を受け取ります。受け取れるのは今日と今週のミッションだけです
+// @Tags missions
+// @Produce json
+// @Security UserToken
+// @Param missionID path string true "ミッションID"
+// @Param region query string false "地域 (省略時は既定の地域)"
+// @Success 200 {object} ClaimMissionResponse "受け取った報酬と合計ミッションポイント"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "今日と今週のミッションにない (MISSION_NOT_FOUND)"
+// @Failure 409 {object} ErrorResponse "未達成 (MISSION_INCOMPLETE) または受け取り済み (CONFLICT)"
+// @Router /users/me/missions/{missionID}/claim [post]
+func (h *Handler) ClaimMission(c echo.Context) error {
+	r, err := h.missionRegion(c)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	userID := currentUser(c).ID
+	m, err := h.missions.Claim(ctx, userID, r, c.Param("missionID"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	points, err := h.missions.Points(ctx, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, ClaimMissionResponse{Reward: m.Reward, Points: points})
+}
+
+// missionRegion returns the region of the region query parameter
+func (h *Handler) missionRegion(c echo.Context) (mission.Region, error) {
+	name := c.QueryParam("region")
+	r, ok := h.missions.Region(name)
+	if !ok {
+		return r, apperr.InvalidParam("region", errors.New("unknown region"))
+	}
+	return r, nil
+}
+
+func toMissionResponse(m mission.Mission) MissionResponse {
+	return MissionResponse{
+		ID:          m.ID,
+		Period:      string(m.Period),
+		Name:        m.Name,
+		Description: m.Description,
+		Goal:        m.Condition.Count,
+		Reward:      m.Reward,
+		StartsAt:    m.Start,
+		EndsAt:      m.End,
+	}
+}
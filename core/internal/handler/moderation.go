@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+)
+
+type InvalidateScoreRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ModerateScoresRequest struct {
+	Action    string    `json:"action"`
+	UserID    string    `json:"user_id"`
+	BeatmapID string    `json:"beatmap_id"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Reason    string    `json:"reason"`
+}
+
+// DeleteScore godoc
+// @Summary スコア削除
+// @Description 指定したスコアを論理削除します
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param scoreID path int true "Score ID"
+// @Success 200 {object} ModerateScoresResponse "影響件数"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/scores/{scoreID} [delete]
+func (h *Handler) DeleteScore(c echo.Context) error {
+	return h.moderateScore(c, repository.ModerationDelete, "")
+}
+
+// RestoreScore godoc
+// @Summary スコア復元
+// @Description 論理削除・無効化されたスコアを元に戻します
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param scoreID path int true "Score ID"
+// @Success 200 {object} ModerateScoresResponse "影響件数"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/scores/{scoreID}/restore [post]
+func (h *Handler) RestoreScore(c echo.Context) error {
+	return h.moderateScore(c, repository.ModerationRestore, "")
+}
+
+// InvalidateScore godoc
+// @Summary スコア無効化
+// @Description 履歴には残したまま、自己ベスト・ランキング・統計の対象から外します
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param scoreID path int true "Score ID"
+// @Param body body InvalidateScoreRequest false "無効化理由"
+// @Success 200 {object} ModerateScoresResponse "影響件数"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "入力値の検証エラー (detailsに項目ごとの理由)"
+// @Router /admin/scores/{scoreID}/invalidate [post]
+func (h *Handler) InvalidateScore(c echo.Context) error {
+	var req InvalidateScoreRequest
+	if err := c.Bind(&req); err != nil {
+		return apperr.InvalidBody(err)
+	}
+	if err := vd.ValidateStruct(&req,
+		vd.Field(&req.Reason, vd.RuneLength(0, 255)),
+	); err != nil {
+		return apperr.Validation(err)
+	}
+	return h.moderateScore(c, repository.ModerationInvalidate, req.Reason)
+}
+
+func (h *Handler) moderateScore(c echo.Context, action repository.ModerationAction, reason string) error {
+	scoreID, err := strconv.ParseInt(c.Param("scoreID"), 10, 64)
+	if err != nil || scoreID <= 0 {
+		return apperr.InvalidParam("scoreID", err)
+	}
+	n, err := h.repo.ModerateScores(c.Request().Context(), repository.ModerateScoresParams{
+		Action:  action,
+		ScoreID: scoreID,
+		Reason:  reason,
+		Actor:   adminActor,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	h.leaderboard.InvalidateAll(c.Request().Context())
+	return c.JSON(http.StatusOK, ModerateScoresResponse{Affected: n})
+}
+
+// ModerateScores godoc
+// @Summary スコア一括モデレーション
+// @Description ユーザーまたは譜面の、期間[from, to)内の全スコアを削除・復元・無効化します
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param body body ModerateScoresRequest true "対象と操作 (action: delete/restore/invalidate)"
+// @Success 200 {object} ModerateScoresResponse "影響件数"
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "入力値の検証エラー (detailsに項目ごとの理由)"
+// @Router /admin/scores/moderate [post]
+func (h *Handler) ModerateScores(c echo.Context) error {
+	var req ModerateScoresRequest
+	if err := c.Bind(&req); err != nil {
+		return apperr.InvalidBody(err)
+	}
+	if err := vd.ValidateStruct(&req,
+		vd.Field(&req.Action, vd.Required, vd.In(
+			string(repository.ModerationDelete),
+			string(repository.ModerationRestore),
+			string(repository.ModerationInvalidate),
+		)),
+		vd.Field(&req.From, vd.Required),
+		vd.Field(&req.To, vd.Required, vd.Min(req.From).Exclusive()),
+		vd.Field(&req.Reason, vd.RuneLength(0, 255)),
+	); err != nil {
+		return apperr.Validation(err)
+	}
+	if req.UserID == "" && req.BeatmapID == "" {
+		return apperr.InvalidField("user_id", "user_id or beatmap_id is required")
+	}
+	n, err := h.repo.ModerateScores(c.Request().Context(), repository.ModerateScoresParams{
+		Action:    repository.ModerationAction(req.Action),
+		UserID:    req.UserID,
+		BeatmapID: req.BeatmapID,
+		From:      req.From,
+		To:        req.To,
+		Reason:    req.Reason,
+		Actor:     adminActor,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	h.leaderboard.InvalidateAll(c.Request().Context())
+	return c.JSON(http.StatusOK, ModerateScoresResponse{Affected: n})
+}
@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/achievement"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/leaderboard"
+)
+
+type UpdateProfileRequest struct {
+	// Title is the id of an unlocked achievement. An empty string removes the title.
+	Title *string `json:"title" example:"lycoris_clear"`
+	// FavoriteBeatmapID is a registered chart. An empty string removes it.
+	FavoriteBeatmapID *string `json:"favorite_beatmap_id" example:"songA_future"`
+	// Showcase lists up to 3 charts the player has played. An empty list
+	// shows their best rated plays instead.
+	Showcase   []string `json:"showcase" example:"songA_future,songB_lycoris"`
+	Visibility *string  `json:"visibility" enums:"public,friends,private" example:"friends"`
+}
+
+// GetMyProfile godoc
+// @Summary 自分のプロフィール
+// @Description 公開範囲にかかわらず、認証したプレイヤーのプロフィールをすべて返します
+// @Tags users
+// @Produce json
+// @Security UserToken
+// @Success 200 {object} UserProfileResponse "プロフィール"
+// @Failure 401 {object} ErrorResponse
+// @Router /users/me/profile [get]
+func (h *Handler) GetMyProfile(c echo.Context) error {
+	ctx := c.Request().Context()
+	u := currentUser(c)
+	p, err := h.repo.GetProfile(ctx, u.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	res, err := h.userProfile(ctx, u, p, true)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// UpdateMyProfile godoc
+// @Summary プロフィール更新
+// @Description 指定した項目だけを更新し、更新後のプロフィールを返します。
+// @Description 称号は解除済みの実績、ショーケースはプレイしたことのある譜面(3つまで、重複不可)だけを指定できます。titleとfavorite_beatmap_idは空文字列で解除します
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security UserToken
+// @Param body body UpdateProfileRequest true "更新する項目"
+// @Success 200 {object} UserProfileResponse "更新後のプロフィール"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse "入力値の検証エラー (detailsに項目ごとの理由)"
+// @Router /users/me/profile [patch]
+func (h *Handler) UpdateMyProfile(c echo.Context) error {
+	var req UpdateProfileRequest
+	if err := c.Bind(&req); err != nil {
+		return apperr.InvalidBody(err)
+	}
+	if err := vd.ValidateStruct(&req,
+		vd.Field(&req.Title, vd.RuneLength(0, 64)),
+		vd.Field(&req.FavoriteBeatmapID, vd.RuneLength(0, 128)),
+		vd.Field(&req.Showcase, vd.Length(0, repository.ShowcaseSize), vd.Each(vd.Required, vd.RuneLength(1, 128))),
+		vd.Field(&req.Visibility, vd.NilOrNotEmpty, vd.In(string(repository.VisibilityPublic), string(repository.VisibilityFriends), string(repository.VisibilityPrivate))),
+	); err != nil {
+		return apperr.Validation(err)
+	}
+
+	ctx := c.Request().Context()
+	u := currentUser(c)
+	p, err := h.repo.GetProfile(ctx, u.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	errs := vd.Errors{}
+	if req.Title != nil {
+		p.Title = nil
+		if *req.Title != "" {
+			unlocked, err := h.achievement.Unlocked(ctx, u.ID)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+			}
+			if !slices.ContainsFunc(unlocked, func(s achievement.Status) bool { return s.ID == *req.Title }) {
+				errs["title"] = errors.New("must be an unlocked achievement")
+			}
+			p.Title = req.Title
+		}
+	}
+	if req.FavoriteBeatmapID != nil {
+		p.FavoriteBeatmapID = nil
+		if *req.FavoriteBeatmapID != "" {
+			_, err := h.repo.GetChart(ctx, *req.FavoriteBeatmapID)
+			if errors.Is(err, repository.ErrChartNotFound) {
+				errs["favorite_beatmap_id"] = errors.New("must be a registered chart")
+			} else if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+			}
+			p.FavoriteBeatmapID = req.FavoriteBeatmapID
+		}
+	}
+	if req.Showcase != nil {
+		bests, err := h.repo.GetUserBests(ctx, u.ID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+		for i, id := range req.Showcase {
+			if slices.Contains(req.Showcase[:i], id) {
+				errs["showcase"] = errors.New("must not list a chart twice")
+			} else if !slices.ContainsFunc(bests, func(b *repository.PlayRow) bool { return b.BeatmapID == id }) {
+				errs["showcase"] = errors.New("must only list charts the player has played")
+			}
+		}
+		p.Showcase = req.Showcase
+	}
+	if req.Visibility != nil {
+		p.Visibility = repository.Visibility(*req.Visibility)
+	}
+	if len(errs) > 0 {
+		return apperr.Validation(errs)
+	}
+
+	if err := h.repo.SaveProfile(ctx, u.ID, p); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	res, err := h.userProfile(ctx, u, p, true)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetUserProfile godoc
+// @Summary プロフィール
+// @Description 指定したユーザーのプロフィールを返します。名前と称号は常に公開されます。
+// @Description それ以外(details)は公開範囲がfriendsならフレンドと本人、privateなら本人にだけ返し、それ以外にはnullを返します。本人やフレンドとして見るにはトークンを付けてください
+// @Tags users
+// @Produce json
+// @Security UserToken
+// @Param userID path string true "User ID" format(uuid)
+// @Success 200 {object} UserProfileResponse "プロフィール"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "トークンが無効"
+// @Failure 404 {object} ErrorResponse "ユーザーが存在しない (USER_NOT_FOUND)"
+// @Router /users/{userID}/profile [get]
+func (h *Handler) GetUserProfile(c echo.Context) error {
+	userID := c.Param("userID")
+	if _, err := uuid.Parse(userID); err != nil {
+		return apperr.InvalidParam("userID", err)
+	}
+	viewer, err := h.optionalUser(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	u, err := h.repo.GetUser(ctx, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	p, err := h.repo.GetProfile(ctx, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	visible, err := h.canSeeProfile(ctx, viewer, u.ID, p.Visibility)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	res, err := h.userProfile(ctx, u, p, visible)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// optionalUser authenticates the request when it has a user token and
+// returns nil when it is anonymous
+func (h *Handler) optionalUser(c echo.Context) (*repository.User, error) {
+	// トークンは任意
+	if c.Request().Header.Get(echo.HeaderAuthorization) == "" {
+		return nil, nil
+	}
+	if err := h.authenticate(c); err != nil {
+		return nil, err
+	}
+	return currentUser(c), nil
+}
+
+// requireVisiblePlays rejects the request with PROFILE_PRIVATE unless the
+// requester may see the profile details, and so the plays, of every one of
+// userIDs
+func (h *Handler) requireVisiblePlays(c echo.Context, userIDs ...string) error {
+	viewer, err := h.optionalUser(c)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	for _, id := range userIDs {
+		p, err := h.repo.GetProfile(ctx, id)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+		visible, err := h.canSeeProfile(ctx, viewer, id, p.Visibility)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+		if !visible {
+			return apperr.New(http.StatusForbidden, apperr.CodeProfilePrivate, "the plays of the player are not public")
+		}
+	}
+	return nil
+}
+
+// canSeeProfile reports whether viewer, nil when anonymous, may see the
+// details of the profile of userID
+func (h *Handler) canSeeProfile(ctx context.Context, viewer *repository.User, userID string, v repository.Visibility) (bool, error) {
+	switch {
+	case v == repository.VisibilityPublic:
+		return true, nil
+	case viewer == nil:
+		return false, nil
+	case viewer.ID == userID:
+		return true, nil
+	case v == repository.VisibilityFriends:
+		return h.repo.IsFriend(ctx, viewer.ID, userID)
+	}
+	return false, nil
+}
+
+// userProfile builds the profile of u, with the details only when withDetails
+func (h *Handler) userProfile(ctx context.Context, u *repository.User, p *repository.Profile, withDetails bool) (UserProfileResponse, error) {
+	unlocked, err := h.achievement.Unlocked(ctx, u.ID)
+	if err != nil {
+		return UserProfileResponse{}, err
+	}
+	res := UserProfileResponse{
+		ID:         u.ID,
+		Name:       u.Name,
+		Title:      titleOf(unlocked, p.Title),
+		Visibility: string(p.Visibility),
+	}
+	if !withDetails {
+		return res, nil
+	}
+
+	plays, rating, err := h.leaderboard.PlayerRating(ctx, u.ID)
+	if err != nil {
+		return UserProfileResponse{}, err
+	}
+	summary, err := h.repo.GetPlaySummary(ctx, u.ID)
+	if err != nil {
+		return UserProfileResponse{}, err
+	}
+	details := &ProfileDetailsResponse{
+		Rating:        rating,
+		TotalPlays:    summary.TotalPlays,
+		FirstPlayedAt: summary.FirstPlayedAt,
+		LastPlayedAt:  summary.LastPlayedAt,
+	}
+	if summary.PreferredInput != nil {
+		input := uint8(*summary.PreferredInput)
+		details.PreferredInput = &input
+	}
+	if p.FavoriteBeatmapID != nil {
+		c, err := h.repo.GetChart(ctx, *p.FavoriteBeatmapID)
+		if err != nil {
+			return UserProfileResponse{}, err
+		}
+		details.FavoriteChart = &ProfileChartResponse{
+			BeatmapID:      c.BeatmapID,
+			SongName:       c.SongName,
+			Difficulty:     c.Difficulty,
+			ParallelString: c.ParallelString,
+		}
+	}
+	details.Showcase = toPlayResponses(showcaseOf(plays, p.Showcase))
+	res.Details = details
+	return res, nil
+}
+
+// showcaseOf returns the best plays on the showcased charts, skipping charts
+// without an active play, or the best rated plays when none are showcased
+func showcaseOf(plays []leaderboard.RatedPlay, showcase []string) []*repository.PlayRow {
+	var out []*repository.PlayRow
+	if len(showcase) == 0 {
+		for _, p := range plays[:min(repository.ShowcaseSize, len(plays))] {
+			out = append(out, p.PlayRow)
+		}
+		return out
+	}
+	for _, id := range showcase {
+		if i := slices.IndexFunc(plays, func(p leaderboard.RatedPlay) bool { return p.BeatmapID == id }); i >= 0 {
+			out = append(out, plays[i].PlayRow)
+		}
+	}
+	return out
+}
+
+// titleOf returns the unlocked achievement chosen as the title, if it is still defined
+func titleOf(unlocked []achievement.Status, title *string) *AchievementResponse {
+	if title == nil {
+		return nil
+	}
+	i := slices.IndexFunc(unlocked, func(s achievement.Status) bool { return s.ID == *title })
+	if i < 0 {
+		return nil
+	}
+	res := toAchievementResponse(unlocked[i].Definition)
+	return &res
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/leaderboard"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/worker"
+)
+
+// GetRatingRanking godoc
+// @Summary レーティングランキング
+// @Description 各譜面のベストスコアと譜面定数から算出した総合レーティングの順位を返します。
+// @Description スナップショットから返す場合はsnapshot_atに集計時刻が入ります
+// @Tags ratings
+// @Produce json
+// @Param limit query int false "取得件数 (既定20, 最大100)"
+// @Success 200 {object} RatingRankingResponse "レーティングランキング"
+// @Router /ratings/ranking [get]
+func (h *Handler) GetRatingRanking(c echo.Context) error {
+	res, err := h.leaderboard.Ratings(c.Request().Context(), pageSize(c))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	out := RatingRankingResponse{
+		Items:      make([]RatingEntryResponse, len(res.Items)),
+		SnapshotAt: res.SnapshotAt,
+	}
+	for i, e := range res.Items {
+		out.Items[i] = RatingEntryResponse{
+			Rank:       e.Place,
+			UserID:     e.UserID,
+			PlayerName: e.PlayerName,
+			Rating:     math.Round(e.Rating*1000) / 1000,
+			Charts:     e.Charts,
+		}
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// RebuildRankings godoc
+// @Summary ランキングスナップショットの再構築
+// @Description 定期ジョブを待たずに譜面・楽曲・レーティングのランキングスナップショットを再構築します
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} RebuildRankingsResponse "集計時刻"
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "スナップショットが無効"
+// @Router /admin/rankings/rebuild [post]
+func (h *Handler) RebuildRankings(c echo.Context) error {
+	ctx := c.Request().Context()
+	if err := h.jobs.RunNow(ctx, leaderboard.SnapshotJob); err != nil {
+		if errors.Is(err, worker.ErrUnknownJob) {
+			return echo.NewHTTPError(http.StatusConflict, "ranking snapshots are disabled")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	at, err := h.leaderboard.SnapshotTime(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, RebuildRankingsResponse{SnapshotAt: at})
+}
+
+// GetJobs godoc
+// @Summary バックグラウンドジョブの状態
+// @Description 登録されている定期ジョブの実行状況を返します
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {array} JobStatusResponse "ジョブ一覧"
+// @Failure 401 {object} ErrorResponse
+// @Router /admin/jobs [get]
+func (h *Handler) GetJobs(c echo.Context) error {
+	ss := h.jobs.Statuses()
+	out := make([]JobStatusResponse, len(ss))
+	for i, s := range ss {
+		out[i] = JobStatusResponse{
+			Name:            s.Name,
+			IntervalSeconds: s.Interval.Seconds(),
+			Running:         s.Running,
+			Runs:            s.Runs,
+			Failures:        s.Failures,
+			LastStartedAt:   timeOrNil(s.LastStarted),
+			LastFinishedAt:  timeOrNil(s.LastFinished),
+			LastSuccessAt:   timeOrNil(s.LastSuccess),
+			LastError:       s.LastError,
+		}
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
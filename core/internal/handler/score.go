@@ -1,11 +1,17 @@
 package handler
 
 import (
+	"log/slog"
 	"net/http"
+	"strconv"
 
 	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
 	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/achievement"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/scoring"
 )
 
 type SubmitScoreRequest struct {
@@ -25,6 +31,8 @@ type SubmitScoreRequest struct {
 
 type SubmitScoreResponse struct {
 	ID int64 `json:"id"`
+	// UnlockedAchievements are the achievements this play unlocked
+	UnlockedAchievements []UserAchievementResponse `json:"unlocked_achievements"`
 }
 
 // SubmitScore godoc
@@ -34,22 +42,32 @@ type SubmitScoreResponse struct {
 // @Accept json
 // @Produce json
 // @Param score body SubmitScoreRequest true "スコア情報"
-// @Success 200 {object} SubmitScoreResponse "登録されたスコアのID"
+// @Success 200 {object} SubmitScoreResponse "登録されたスコアのIDと、このプレイで解除された実績"
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "BANされたユーザー (USER_BANNED)"
+// @Failure 404 {object} ErrorResponse "ユーザーまたは譜面が存在しない (USER_NOT_FOUND / CHART_NOT_FOUND)"
+// @Failure 422 {object} ErrorResponse "入力値の検証エラー (detailsに項目ごとの理由)"
 // @Router /scores [post]
 func (h *Handler) SubmitScore(c echo.Context) error {
 	var req SubmitScoreRequest
 	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body").SetInternal(err)
+		return apperr.InvalidBody(err)
 	}
+	setUser(c, req.UserID)
 	if err := vd.ValidateStruct(&req,
 		vd.Field(&req.UserID, vd.Required),
 		vd.Field(&req.BeatmapID, vd.Required),
 	); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err)
+		return apperr.Validation(err)
 	}
 
-	id, err := h.repo.InsertScore(c.Request().Context(), repository.InsertScoreParams{
+	ctx := c.Request().Context()
+	prevBest, err := h.repo.GetPersonalBest(ctx, req.UserID, req.BeatmapID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+
+	id, err := h.repo.InsertScore(ctx, repository.InsertScoreParams{
 		UserID:              req.UserID,
 		BeatmapID:           req.BeatmapID,
 		Score:               req.Score,
@@ -66,6 +84,126 @@ func (h *Handler) SubmitScore(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
 	}
+	h.leaderboard.InvalidateChart(ctx, req.BeatmapID)
+	h.countSubmission(c, req)
+
+	// 配信の失敗はスコア登録の失敗にしない
+	if err := h.publishScoreEvents(ctx, id, req, prevBest); err != nil {
+		slog.WarnContext(ctx, "publish score events", "score_id", id, "error", err)
+	}
+	// 実績の評価の失敗もスコア登録の失敗にしない
+	var unlocked []achievement.Status
+	if play, err := h.repo.GetPlay(ctx, id); err != nil {
+		slog.WarnContext(ctx, "evaluate achievements", "score_id", id, "error", err)
+	} else if unlocked, err = h.achievement.Record(ctx, play); err != nil {
+		slog.WarnContext(ctx, "evaluate achievements", "score_id", id, "error", err)
+	}
+
+	return c.JSON(http.StatusOK, SubmitScoreResponse{ID: id, UnlockedAchievements: toUserAchievementResponses(unlocked)})
+}
+
+// countSubmission records the submission in metrics, labelled by the chart's difficulty
+func (h *Handler) countSubmission(c echo.Context, req SubmitScoreRequest) {
+	difficulty := "unknown"
+	ctx := c.Request().Context()
+	if chart, err := h.repo.GetChart(ctx, req.BeatmapID); err != nil {
+		slog.WarnContext(ctx, "count submission", "beatmap_id", req.BeatmapID, "error", err)
+	} else {
+		difficulty = repository.Difficulty(chart.Difficulty).String()
+	}
+	h.metrics.ScoreSubmitted(req.BeatmapID, difficulty, repository.InputType(req.Input).String())
+}
 
-	return c.JSON(http.StatusOK, SubmitScoreResponse{ID: id})
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// GetRecentPlays godoc
+// @Summary 最近のプレイ
+// @Description 最新のプレイを新しい順に返します。next_before_idをbefore_idに指定すると続きを取得できます
+// @Description user_idを指定した場合、プロフィールの公開範囲がfriendsのプレイヤーはフレンドと本人、privateのプレイヤーは本人だけが取得できます。本人やフレンドとして見るにはトークンを付けてください
+// @Tags scores
+// @Produce json
+// @Security UserToken
+// @Param beatmap_id query string false "譜面ID"
+// @Param song_name query string false "楽曲名"
+// @Param user_id query string false "User ID" format(uuid)
+// @Param before_id query int false "このスコアIDより古いプレイを返す"
+// @Param limit query int false "取得件数 (既定20, 最大100)"
+// @Success 200 {object} RecentPlaysResponse "プレイ一覧"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "トークンが無効"
+// @Failure 403 {object} ErrorResponse "公開範囲により見られない (PROFILE_PRIVATE)"
+// @Router /scores/recent [get]
+func (h *Handler) GetRecentPlays(c echo.Context) error {
+	p := repository.GetRecentPlaysParams{
+		BeatmapID: c.QueryParam("beatmap_id"),
+		SongName:  c.QueryParam("song_name"),
+		UserID:    c.QueryParam("user_id"),
+		Limit:     pageSize(c),
+	}
+	if p.UserID != "" {
+		if _, err := uuid.Parse(p.UserID); err != nil {
+			return apperr.InvalidParam("user_id", err)
+		}
+		if err := h.requireVisiblePlays(c, p.UserID); err != nil {
+			return err
+		}
+	}
+	if v := c.QueryParam("before_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return apperr.InvalidParam("before_id", err)
+		}
+		p.BeforeID = id
+	}
+
+	rs, err := h.repo.GetRecentPlays(c.Request().Context(), p)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	res := RecentPlaysResponse{Items: toPlayResponses(rs)}
+	if len(rs) == p.Limit {
+		res.NextBeforeID = &rs[len(rs)-1].ID
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// pageSize reads the limit query parameter, clamped to maxPageSize
+func pageSize(c echo.Context) int {
+	limit := defaultPageSize
+	if v, err := strconv.Atoi(c.QueryParam("limit")); err == nil && v > 0 {
+		limit = min(v, maxPageSize)
+	}
+	return limit
+}
+
+func toPlayResponses(in []*repository.PlayRow) []PlayResponse {
+	out := make([]PlayResponse, len(in))
+	for i, p := range in {
+		out[i] = PlayResponse{
+			ID:                  p.ID,
+			UserID:              p.UserID,
+			PlayerName:          p.PlayerName,
+			BeatmapID:           p.BeatmapID,
+			SongName:            p.SongName,
+			Difficulty:          p.Difficulty,
+			ParallelString:      p.ParallelString,
+			Score:               p.Score,
+			Grade:               string(scoring.GradeOf(p.Score)),
+			Lamp:                string(scoring.LampOf(p.Score, p.Judgements())),
+			MaxCombo:            p.MaxCombo,
+			PerfectCriticalFast: p.PerfectCriticalFast,
+			PerfectCriticalLate: p.PerfectCriticalLate,
+			PerfectFast:         p.PerfectFast,
+			PerfectLate:         p.PerfectLate,
+			GoodFast:            p.GoodFast,
+			GoodLate:            p.GoodLate,
+			Miss:                p.Miss,
+			Input:               uint8(p.Input),
+			PlayedAt:            p.CreatedAt,
+		}
+	}
+	return out
 }
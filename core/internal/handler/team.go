@@ -0,0 +1,517 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+)
+
+type CreateTeamRequest struct {
+	Name string `json:"name" example:"Senirenol Lab"`
+}
+
+type JoinTeamRequest struct {
+	InviteCode string `json:"invite_code" example:"K7QF2M9X"`
+}
+
+type SetTeamRoleRequest struct {
+	// Role is owner, admin or member. Making someone the owner hands the team over.
+	Role string `json:"role" example:"admin"`
+}
+
+// errNotTeamMember is returned for players acting on a team they are not in
+var errNotTeamMember = apperr.New(http.StatusForbidden, apperr.CodeForbidden, "not a member of the team")
+
+// CreateTeam godoc
+// @Summary チーム作成
+// @Description 認証したプレイヤーをオーナーとしてチームを作成し、招待コードを返します
+// @Tags teams
+// @Accept json
+// @Produce json
+// @Security UserToken
+// @Param team body CreateTeamRequest true "チーム名"
+// @Success 200 {object} CreateTeamResponse "作成したチームと招待コード"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "同じ名前のチームがある"
+// @Failure 422 {object} ErrorResponse "入力値の検証エラー (detailsに項目ごとの理由)"
+// @Router /teams [post]
+func (h *Handler) CreateTeam(c echo.Context) error {
+	req := new(CreateTeamRequest)
+	if err := c.Bind(req); err != nil {
+		return apperr.InvalidBody(err)
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if err := vd.ValidateStruct(req, vd.Field(&req.Name, vd.Required, vd.RuneLength(1, 64))); err != nil {
+		return apperr.Validation(err)
+	}
+	t, err := h.repo.CreateTeam(c.Request().Context(), req.Name, currentUser(c).ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, CreateTeamResponse{ID: t.ID, Name: t.Name, InviteCode: t.InviteCode})
+}
+
+// GetTeams godoc
+// @Summary チーム一覧
+// @Tags teams
+// @Produce json
+// @Success 200 {array} TeamResponse "チーム (名前順)"
+// @Router /teams [get]
+func (h *Handler) GetTeams(c echo.Context) error {
+	ts, err := h.repo.GetTeams(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	out := make([]TeamResponse, len(ts))
+	for i, t := range ts {
+		out[i] = toTeamResponse(t)
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
+// GetTeam godoc
+// @Summary チーム情報
+// @Description チームとメンバー (オーナー・管理者・メンバーの順) を返します
+// @Tags teams
+// @Produce json
+// @Param teamID path string true "Team ID" format(uuid)
+// @Success 200 {object} TeamResponse "チーム"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "チームが存在しない (TEAM_NOT_FOUND)"
+// @Router /teams/{teamID} [get]
+func (h *Handler) GetTeam(c echo.Context) error {
+	teamID, err := teamIDParam(c, "teamID")
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	t, err := h.repo.GetTeam(ctx, teamID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	ms, err := h.repo.GetTeamMembers(ctx, teamID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	res := toTeamResponse(t)
+	res.Members = make([]TeamMemberResponse, len(ms))
+	for i, m := range ms {
+		res.Members[i] = TeamMemberResponse{UserID: m.UserID, PlayerName: m.Name, Role: string(m.Role), JoinedAt: m.JoinedAt}
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// DeleteTeam godoc
+// @Summary チーム削除
+// @Description オーナーだけが削除できます。スコアは削除されません。
+// @Tags teams
+// @Produce json
+// @Security UserToken
+// @Param teamID path string true "Team ID" format(uuid)
+// @Success 204
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "オーナーではない"
+// @Failure 404 {object} ErrorResponse "チームが存在しない (TEAM_NOT_FOUND)"
+// @Router /teams/{teamID} [delete]
+func (h *Handler) DeleteTeam(c echo.Context) error {
+	teamID, role, err := h.myTeamRole(c)
+	if err != nil {
+		return err
+	}
+	if role != repository.TeamOwner {
+		return apperr.New(http.StatusForbidden, apperr.CodeForbidden, "only the owner may delete the team")
+	}
+	if err := h.repo.DeleteTeam(c.Request().Context(), teamID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetMyTeams godoc
+// @Summary 所属チーム一覧
+// @Tags teams
+// @Produce json
+// @Security UserToken
+// @Success 200 {array} MyTeamResponse "所属チームと役割"
+// @Failure 401 {object} ErrorResponse
+// @Router /users/me/teams [get]
+func (h *Handler) GetMyTeams(c echo.Context) error {
+	ts, err := h.repo.GetUserTeams(c.Request().Context(), currentUser(c).ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, toMyTeamResponses(ts))
+}
+
+func toMyTeamResponses(in []*repository.UserTeam) []MyTeamResponse {
+	out := make([]MyTeamResponse, len(in))
+	for i, t := range in {
+		out[i] = MyTeamResponse{
+			ID:          t.ID,
+			Name:        t.Name,
+			MemberCount: t.MemberCount,
+			CreatedAt:   t.CreatedAt,
+			Role:        string(t.Role),
+			JoinedAt:    t.JoinedAt,
+		}
+	}
+	return out
+}
+
+// JoinTeam godoc
+// @Summary チームへの参加
+// @Description 招待コードのチームにメンバーとして参加します。招待コードの大文字・小文字とハイフンは区別しません。
+// @Tags teams
+// @Accept json
+// @Produce json
+// @Security UserToken
+// @Param request body JoinTeamRequest true "招待コード"
+// @Success 200 {object} TeamResponse "参加したチーム"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "招待コードのチームが存在しない (TEAM_NOT_FOUND)"
+// @Failure 409 {object} ErrorResponse "参加済み"
+// @Failure 422 {object} ErrorResponse "入力値の検証エラー (detailsに項目ごとの理由)"
+// @Router /teams/join [post]
+func (h *Handler) JoinTeam(c echo.Context) error {
+	req := new(JoinTeamRequest)
+	if err := c.Bind(req); err != nil {
+		return apperr.InvalidBody(err)
+	}
+	code := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(req.InviteCode))
+	if err := vd.Validate(code, vd.Required); err != nil {
+		return apperr.InvalidField("invite_code", err.Error())
+	}
+	t, err := h.repo.JoinTeam(c.Request().Context(), code, currentUser(c).ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, toTeamResponse(t))
+}
+
+// GetTeamInviteCode godoc
+// @Summary 招待コード
+// @Description オーナーと管理者だけが取得できます
+// @Tags teams
+// @Produce json
+// @Security UserToken
+// @Param teamID path string true "Team ID" format(uuid)
+// @Success 200 {object} InviteCodeResponse "招待コード"
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "オーナー・管理者ではない"
+// @Failure 404 {object} ErrorResponse "チームが存在しない (TEAM_NOT_FOUND)"
+// @Router /teams/{teamID}/invite-code [get]
+func (h *Handler) GetTeamInviteCode(c echo.Context) error {
+	teamID, role, err := h.myTeamRole(c)
+	if err != nil {
+		return err
+	}
+	if !role.Outranks(repository.TeamMember) {
+		return apperr.New(http.StatusForbidden, apperr.CodeForbidden, "only owners and admins may see the invite code")
+	}
+	t, err := h.repo.GetTeam(c.Request().Context(), teamID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, InviteCodeResponse{InviteCode: t.InviteCode})
+}
+
+// ResetTeamInviteCode godoc
+// @Summary 招待コードの再発行
+// @Description 新しい招待コードを発行し、古いコードを使えなくします。オーナーと管理者だけが実行できます。
+// @Tags teams
+// @Produce json
+// @Security UserToken
+// @Param teamID path string true "Team ID" format(uuid)
+// @Success 200 {object} InviteCodeResponse "新しい招待コード"
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "オーナー・管理者ではない"
+// @Failure 404 {object} ErrorResponse "チームが存在しない (TEAM_NOT_FOUND)"
+// @Router /teams/{teamID}/invite-code [post]
+func (h *Handler) ResetTeamInviteCode(c echo.Context) error {
+	teamID, role, err := h.myTeamRole(c)
+	if err != nil {
+		return err
+	}
+	if !role.Outranks(repository.TeamMember) {
+		return apperr.New(http.StatusForbidden, apperr.CodeForbidden, "only owners and admins may reset the invite code")
+	}
+	code, err := h.repo.ResetInviteCode(c.Request().Context(), teamID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, InviteCodeResponse{InviteCode: code})
+}
+
+// SetTeamMemberRole godoc
+// @Summary メンバーの役割変更
+// @Description オーナーだけが変更できます。他のメンバーをオーナーにすると、自分は管理者になります。
+// @Tags teams
+// @Accept json
+// @Produce json
+// @Security UserToken
+// @Param teamID path string true "Team ID" format(uuid)
+// @Param userID path string true "メンバーのID" format(uuid)
+// @Param request body SetTeamRoleRequest true "役割"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "オーナーではない"
+// @Failure 404 {object} ErrorResponse "チームまたはメンバーが存在しない (TEAM_NOT_FOUND, TEAM_MEMBER_NOT_FOUND)"
+// @Failure 409 {object} ErrorResponse "オーナーが引き継がずに降りようとした"
+// @Failure 422 {object} ErrorResponse "入力値の検証エラー (detailsに項目ごとの理由)"
+// @Router /teams/{teamID}/members/{userID}/role [put]
+func (h *Handler) SetTeamMemberRole(c echo.Context) error {
+	teamID, role, err := h.myTeamRole(c)
+	if err != nil {
+		return err
+	}
+	memberID, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	req := new(SetTeamRoleRequest)
+	if err := c.Bind(req); err != nil {
+		return apperr.InvalidBody(err)
+	}
+	if err := vd.ValidateStruct(req, vd.Field(&req.Role, vd.Required, vd.In(
+		string(repository.TeamOwner), string(repository.TeamAdmin), string(repository.TeamMember),
+	))); err != nil {
+		return apperr.Validation(err)
+	}
+	if role != repository.TeamOwner {
+		return apperr.New(http.StatusForbidden, apperr.CodeForbidden, "only the owner may change roles")
+	}
+	if err := h.repo.SetTeamRole(c.Request().Context(), teamID, memberID, repository.TeamRole(req.Role)); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RemoveTeamMember godoc
+// @Summary チームからの脱退・除名
+// @Description 自分を指定すると脱退します。オーナーは管理者とメンバーを、管理者はメンバーを除名できます。
+// @Description オーナーが脱退すると最も古い管理者 (いなければメンバー) がオーナーになり、最後のメンバーが脱退するとチームは削除されます。
+// @Tags teams
+// @Produce json
+// @Security UserToken
+// @Param teamID path string true "Team ID" format(uuid)
+// @Param userID path string true "メンバーのID" format(uuid)
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse "除名する権限がない"
+// @Failure 404 {object} ErrorResponse "チームまたはメンバーが存在しない (TEAM_NOT_FOUND, TEAM_MEMBER_NOT_FOUND)"
+// @Router /teams/{teamID}/members/{userID} [delete]
+func (h *Handler) RemoveTeamMember(c echo.Context) error {
+	teamID, role, err := h.myTeamRole(c)
+	if err != nil {
+		return err
+	}
+	memberID, err := userIDParam(c)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	if memberID != currentUser(c).ID {
+		target, err := h.repo.GetTeamRole(ctx, teamID, memberID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+		if !role.Outranks(target) {
+			return apperr.New(http.StatusForbidden, apperr.CodeForbidden, "not allowed to remove the member")
+		}
+	}
+	if err := h.repo.RemoveTeamMember(ctx, teamID, memberID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetTeamRanking godoc
+// @Summary チームランキング
+// @Description 現在のメンバーの自己ベストを合計した順位を返します。beatmap_idを指定するとその譜面、未指定時は全譜面の合計です。
+// @Description スコアのないチームは含みません
+// @Tags teams
+// @Produce json
+// @Param beatmap_id query string false "譜面ID"
+// @Param limit query int false "取得件数 (既定20, 最大100)"
+// @Success 200 {object} TeamRankingResponse "チームランキング"
+// @Router /teams/ranking [get]
+func (h *Handler) GetTeamRanking(c echo.Context) error {
+	p := repository.TeamTotalsParams{BeatmapID: c.QueryParam("beatmap_id"), Limit: pageSize(c)}
+	ts, err := h.repo.GetTeamTotals(c.Request().Context(), p)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, TeamRankingResponse{BeatmapID: p.BeatmapID, Items: toTeamRankingEntries(ts)})
+}
+
+// GetWeeklyTeamRanking godoc
+// @Summary 週間チームランキング
+// @Description 週 (月曜0時UTCから1週間) の間のプレイだけで、現在のメンバーの譜面ごとのベストを合計した順位を返します
+// @Tags teams
+// @Produce json
+// @Param week query string false "ISO週 (例: 2026-W42)。既定は今週"
+// @Param limit query int false "取得件数 (既定20, 最大100)"
+// @Success 200 {object} TeamRankingResponse "週間チームランキング"
+// @Failure 400 {object} ErrorResponse
+// @Router /teams/ranking/weekly [get]
+func (h *Handler) GetWeeklyTeamRanking(c echo.Context) error {
+	week, tr, err := parseWeekParam(c, time.Now())
+	if err != nil {
+		return err
+	}
+	ts, err := h.repo.GetTeamTotals(c.Request().Context(), repository.TeamTotalsParams{Range: tr, Limit: pageSize(c)})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, TeamRankingResponse{
+		Week:  week,
+		From:  &tr.From,
+		To:    &tr.To,
+		Items: toTeamRankingEntries(ts),
+	})
+}
+
+// GetTeamVersus godoc
+// @Summary チーム対抗戦
+// @Description 2つのチームの週間合計 (週間チームランキングと同じ集計) を比べます
+// @Tags teams
+// @Produce json
+// @Param teamID path string true "Team ID" format(uuid)
+// @Param otherID path string true "対戦相手のTeam ID" format(uuid)
+// @Param week query string false "ISO週 (例: 2026-W42)。既定は今週"
+// @Success 200 {object} TeamVersusResponse "対抗戦の結果"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "チームが存在しない (TEAM_NOT_FOUND)"
+// @Router /teams/{teamID}/versus/{otherID} [get]
+func (h *Handler) GetTeamVersus(c echo.Context) error {
+	teamID, err := teamIDParam(c, "teamID")
+	if err != nil {
+		return err
+	}
+	otherID, err := teamIDParam(c, "otherID")
+	if err != nil {
+		return err
+	}
+	week, tr, err := parseWeekParam(c, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	res := TeamVersusResponse{Week: week, From: tr.From, To: tr.To}
+	for _, side := range []struct {
+		id  string
+		out *TeamTotalResponse
+	}{{teamID, &res.Team}, {otherID, &res.Other}} {
+		t, err := h.repo.GetTeam(ctx, side.id)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+		}
+		*side.out = TeamTotalResponse{TeamID: t.ID, TeamName: t.Name}
+	}
+	ts, err := h.repo.GetTeamTotals(ctx, repository.TeamTotalsParams{Range: tr, TeamIDs: []string{teamID, otherID}})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	for _, t := range ts {
+		if t.TeamID == teamID {
+			res.Team = toTeamTotalResponse(t)
+		} else {
+			res.Other = toTeamTotalResponse(t)
+		}
+	}
+	switch {
+	case res.Team.TotalScore > res.Other.TotalScore:
+		res.WinnerID = &res.Team.TeamID
+	case res.Team.TotalScore < res.Other.TotalScore:
+		res.WinnerID = &res.Other.TeamID
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// myTeamRole is the teamID path parameter and the role the authenticated
+// player has in that team. Non-members are forbidden.
+func (h *Handler) myTeamRole(c echo.Context) (string, repository.TeamRole, error) {
+	teamID, err := teamIDParam(c, "teamID")
+	if err != nil {
+		return "", "", err
+	}
+	ctx := c.Request().Context()
+	if _, err := h.repo.GetTeam(ctx, teamID); err != nil {
+		return "", "", echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	role, err := h.repo.GetTeamRole(ctx, teamID, currentUser(c).ID)
+	if errors.Is(err, repository.ErrTeamMemberNotFound) {
+		return "", "", errNotTeamMember
+	} else if err != nil {
+		return "", "", echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return teamID, role, nil
+}
+
+func teamIDParam(c echo.Context, name string) (string, error) {
+	id := c.Param(name)
+	if _, err := uuid.Parse(id); err != nil {
+		return "", apperr.InvalidParam(name, err)
+	}
+	return id, nil
+}
+
+// parseWeekParam parses the optional ISO week query parameter, such as
+// "2026-W42", defaulting to the week of now. Weeks start on Monday in UTC.
+func parseWeekParam(c echo.Context, now time.Time) (string, repository.TimeRange, error) {
+	year, week := now.UTC().ISOWeek()
+	if v := c.QueryParam("week"); v != "" {
+		if _, err := fmt.Sscanf(v, "%d-W%d", &year, &week); err != nil {
+			return "", repository.TimeRange{}, apperr.InvalidParam("week", err)
+		}
+	}
+	from := isoWeekStart(year, week)
+	if y, w := from.ISOWeek(); y != year || w != week {
+		return "", repository.TimeRange{}, apperr.InvalidParam("week", fmt.Errorf("%d has no week %d", year, week))
+	}
+	return fmt.Sprintf("%04d-W%02d", year, week), repository.TimeRange{From: from, To: from.AddDate(0, 0, 7)}, nil
+}
+
+// isoWeekStart is the Monday starting the ISO week, which is the week
+// containing January 4th for week 1
+func isoWeekStart(year, week int) time.Time {
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	return monday.AddDate(0, 0, (week-1)*7)
+}
+
+func toTeamResponse(t *repository.Team) TeamResponse {
+	return TeamResponse{ID: t.ID, Name: t.Name, MemberCount: t.MemberCount, CreatedAt: t.CreatedAt}
+}
+
+func toTeamTotalResponse(t *repository.TeamTotal) TeamTotalResponse {
+	return TeamTotalResponse{
+		TeamID:     t.TeamID,
+		TeamName:   t.Name,
+		Players:    t.Players,
+		Charts:     t.Charts,
+		TotalScore: t.TotalScore,
+		PlayCount:  t.PlayCount,
+	}
+}
+
+func toTeamRankingEntries(ts []*repository.TeamTotal) []TeamRankingEntryResponse {
+	out := make([]TeamRankingEntryResponse, len(ts))
+	for i, t := range ts {
+		out[i] = TeamRankingEntryResponse{Rank: i + 1, TeamTotalResponse: toTeamTotalResponse(t)}
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/pikachu0310/senirenol-server/core/internal/handler")
+
+// Trace starts a server span for every request, continuing the trace of the
+// caller when the request carries a traceparent header. Queries issued with
+// the request context become its children.
+func Trace(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+		route := c.Path()
+		name := req.Method
+		if route != "" {
+			name += " " + route
+		}
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(req.URL.Path),
+				semconv.ClientAddress(c.RealIP()),
+			),
+		)
+		defer span.End()
+		c.SetRequest(req.WithContext(ctx))
+
+		err := next(c)
+
+		status := responseStatus(c, err)
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+			if err != nil {
+				span.RecordError(err)
+			}
+		}
+		return err
+	}
+}
@@ -2,26 +2,42 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	vd "github.com/go-ozzo/ozzo-validation"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/pikachu0310/senirenol-server/core/internal/apperr"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/auth"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/friendcode"
 )
 
 // RegisterUser godoc
 // @Summary ユーザー登録
-// @Description 初回起動時に匿名ユーザーを生成し、user_idを返します
+// @Description 初回起動時に匿名ユーザーを生成し、user_idとトークンを返します。
+// @Description トークンは/users/meのAPIの認証に使います。再発行できないので、クライアントで保存してください。
 // @Tags users
 // @Accept json
 // @Produce json
-// @Success 200 {object} RegisterUserResponse "登録されたユーザーのID"
+// @Success 200 {object} RegisterUserResponse "登録されたユーザーのIDとトークン"
 // @Router /users [post]
 func (h *Handler) RegisterUser(c echo.Context) error {
-	id, err := h.repo.CreateUser(c.Request().Context())
+	token, hash, err := auth.NewToken()
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
 	}
-	return c.JSON(http.StatusOK, RegisterUserResponse{ID: id.String()})
+	ctx := c.Request().Context()
+	id, err := h.repo.CreateUser(ctx, hash)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	if err := h.repo.SetFriendCode(ctx, id.String(), friendcode.Of(id.String())); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	h.metrics.UserRegistered()
+	return c.JSON(http.StatusOK, RegisterUserResponse{ID: id.String(), Token: token})
 }
 
 type updateUserNameRequest struct {
@@ -38,62 +54,89 @@ type updateUserNameRequest struct {
 // @Param body body updateUserNameRequest true "更新内容"
 // @Success 200 {object} UpdateUserNameResponse "更新結果"
 // @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "ユーザーが存在しない (USER_NOT_FOUND)"
+// @Failure 422 {object} ErrorResponse "入力値の検証エラー (detailsに項目ごとの理由)"
 // @Router /users/update [post]
 func (h *Handler) UpdateUserName(c echo.Context) error {
 	var req updateUserNameRequest
 	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body").SetInternal(err)
+		return apperr.InvalidBody(err)
 	}
+	setUser(c, req.UserID)
 	if err := vd.ValidateStruct(&req,
 		vd.Field(&req.UserID, vd.Required),
 		vd.Field(&req.UserName, vd.Required, vd.RuneLength(1, 255)),
 	); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err)
+		return apperr.Validation(err)
 	}
 	if _, err := uuid.Parse(req.UserID); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid user_id").SetInternal(err)
+		return apperr.InvalidField("user_id", "must be a valid UUID")
 	}
 	if err := h.repo.UpdateUserName(c.Request().Context(), req.UserID, req.UserName); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
 	}
+	// ランキングにはプレイヤー名が含まれる
+	h.leaderboard.InvalidateAll(c.Request().Context())
 	return c.JSON(http.StatusOK, UpdateUserNameResponse{Status: "ok"})
 }
 
 // GetUser godoc
 // @Summary ユーザー情報取得
-// @Description 指定したIDのユーザー情報を取得します（名前のみ）
+// @Description 指定したIDのユーザーの名前・称号と解除した実績を返します。プロフィールの詳細は/users/{userID}/profileを使ってください
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param userID path string true "User ID" format(uuid)
 // @Success 200 {object} GetUserResponse "ユーザー情報"
 // @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "ユーザーが存在しない (USER_NOT_FOUND)"
 // @Router /users/{userID} [get]
 func (h *Handler) GetUser(c echo.Context) error {
 	userID := c.Param("userID")
 	if _, err := uuid.Parse(userID); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid userID").SetInternal(err)
+		return apperr.InvalidParam("userID", err)
+	}
+	ctx := c.Request().Context()
+	u, err := h.repo.GetUser(ctx, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
 	}
-	u, err := h.repo.GetUser(c.Request().Context(), userID)
+	unlocked, err := h.achievement.Unlocked(ctx, userID)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error()).SetInternal(err)
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
 	}
-	return c.JSON(http.StatusOK, GetUserResponse{ID: u.ID, Name: u.Name})
+	p, err := h.repo.GetProfile(ctx, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, GetUserResponse{
+		ID:           u.ID,
+		Name:         u.Name,
+		Title:        titleOf(unlocked, p.Title),
+		Achievements: toUserAchievementResponses(unlocked),
+	})
 }
 
 // GetUserStats godoc
 // @Summary ユーザー統計
 // @Description プレイ回数など統計情報を返します
+// @Description プロフィールの公開範囲がfriendsのプレイヤーはフレンドと本人、privateのプレイヤーは本人だけが取得できます。本人やフレンドとして見るにはトークンを付けてください
 // @Tags users
 // @Produce json
+// @Security UserToken
 // @Param userID path string true "User ID" format(uuid)
 // @Success 200 {object} UserStatsResponse "統計情報"
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "トークンが無効"
+// @Failure 403 {object} ErrorResponse "公開範囲により見られない (PROFILE_PRIVATE)"
 // @Router /users/{userID}/stats [get]
 func (h *Handler) GetUserStats(c echo.Context) error {
 	uid := c.Param("userID")
 	if _, err := uuid.Parse(uid); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid userID").SetInternal(err)
+		return apperr.InvalidParam("userID", err)
+	}
+	if err := h.requireVisiblePlays(c, uid); err != nil {
+		return err
 	}
 	s, err := h.repo.GetUserStats(c.Request().Context(), uid)
 	if err != nil {
@@ -106,3 +149,133 @@ func (h *Handler) GetUserStats(c echo.Context) error {
 		AverageScore:   s.AverageScore,
 	})
 }
+
+// GetUserScores godoc
+// @Summary ユーザーのプレイ履歴
+// @Description 指定したユーザーのプレイ履歴を譜面・難易度・入力デバイス・期間で絞り込んで返します
+// @Description プロフィールの公開範囲がfriendsのプレイヤーはフレンドと本人、privateのプレイヤーは本人だけが取得できます。本人やフレンドとして見るにはトークンを付けてください
+// @Tags users
+// @Produce json
+// @Security UserToken
+// @Param userID path string true "User ID" format(uuid)
+// @Param beatmap_id query string false "譜面ID"
+// @Param difficulty query int false "難易度 (0=Past,1=Present,2=Future,3=Lycoris,4=Parallel)"
+// @Param input query int false "入力デバイス (0=keyboard,1=button)"
+// @Param from query string false "この日時以降 (RFC3339)"
+// @Param to query string false "この日時より前 (RFC3339)"
+// @Param sort query string false "並び順の基準 (date/score, 既定date)"
+// @Param order query string false "昇順/降順 (asc/desc, 既定desc)"
+// @Param limit query int false "取得件数 (既定20, 最大100)"
+// @Param offset query int false "オフセット"
+// @Success 200 {object} UserScoresResponse "プレイ履歴"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "トークンが無効"
+// @Failure 403 {object} ErrorResponse "公開範囲により見られない (PROFILE_PRIVATE)"
+// @Router /users/{userID}/scores [get]
+func (h *Handler) GetUserScores(c echo.Context) error {
+	uid := c.Param("userID")
+	if _, err := uuid.Parse(uid); err != nil {
+		return apperr.InvalidParam("userID", err)
+	}
+	if err := h.requireVisiblePlays(c, uid); err != nil {
+		return err
+	}
+	p := repository.GetUserScoresParams{
+		UserID:    uid,
+		BeatmapID: c.QueryParam("beatmap_id"),
+		Sort:      repository.SortByDate,
+		Limit:     pageSize(c),
+	}
+	if v := c.QueryParam("difficulty"); v != "" {
+		d, err := strconv.ParseUint(v, 10, 8)
+		if err != nil || !repository.Difficulty(d).Valid() {
+			return apperr.InvalidParam("difficulty", err)
+		}
+		diff := repository.Difficulty(d)
+		p.Difficulty = &diff
+	}
+	if v := c.QueryParam("input"); v != "" {
+		i, err := strconv.ParseUint(v, 10, 8)
+		if err != nil || !repository.InputType(i).Valid() {
+			return apperr.InvalidParam("input", err)
+		}
+		input := repository.InputType(i)
+		p.Input = &input
+	}
+	var err error
+	if p.From, err = parseTimeParam(c, "from"); err != nil {
+		return apperr.InvalidParam("from", err)
+	}
+	if p.To, err = parseTimeParam(c, "to"); err != nil {
+		return apperr.InvalidParam("to", err)
+	}
+	switch v := c.QueryParam("sort"); v {
+	case "", string(repository.SortByDate):
+	case string(repository.SortByScore):
+		p.Sort = repository.SortByScore
+	default:
+		return apperr.InvalidParam("sort", nil)
+	}
+	switch c.QueryParam("order") {
+	case "", "desc":
+	case "asc":
+		p.Asc = true
+	default:
+		return apperr.InvalidParam("order", nil)
+	}
+	if v := c.QueryParam("offset"); v != "" {
+		o, err := strconv.Atoi(v)
+		if err != nil || o < 0 {
+			return apperr.InvalidParam("offset", err)
+		}
+		p.Offset = o
+	}
+
+	rs, total, err := h.repo.GetUserScores(c.Request().Context(), p)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, UserScoresResponse{
+		Items:  toPlayResponses(rs),
+		Total:  total,
+		Limit:  p.Limit,
+		Offset: p.Offset,
+	})
+}
+
+// GetUserBests godoc
+// @Summary ユーザーの自己ベスト一覧
+// @Description プレイした各譜面の自己ベストを1件ずつ返します
+// @Description プロフィールの公開範囲がfriendsのプレイヤーはフレンドと本人、privateのプレイヤーは本人だけが取得できます。本人やフレンドとして見るにはトークンを付けてください
+// @Tags users
+// @Produce json
+// @Security UserToken
+// @Param userID path string true "User ID" format(uuid)
+// @Success 200 {array} PlayResponse "譜面ごとの自己ベスト"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse "トークンが無効"
+// @Failure 403 {object} ErrorResponse "公開範囲により見られない (PROFILE_PRIVATE)"
+// @Router /users/{userID}/bests [get]
+func (h *Handler) GetUserBests(c echo.Context) error {
+	uid := c.Param("userID")
+	if _, err := uuid.Parse(uid); err != nil {
+		return apperr.InvalidParam("userID", err)
+	}
+	if err := h.requireVisiblePlays(c, uid); err != nil {
+		return err
+	}
+	rs, err := h.repo.GetUserBests(c.Request().Context(), uid)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError).SetInternal(err)
+	}
+	return c.JSON(http.StatusOK, toPlayResponses(rs))
+}
+
+// parseTimeParam parses an optional RFC3339 query parameter
+func parseTimeParam(c echo.Context, name string) (time.Time, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
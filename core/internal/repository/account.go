@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// GetUserByTokenHash returns the player authenticated by the token with the hash
+func (r *Repository) GetUserByTokenHash(ctx context.Context, tokenHash string) (*User, error) {
+	user := &User{}
+	if err := r.db.GetContext(ctx, user, "SELECT "+userColumns+" FROM users WHERE token_hash = ? AND deleted_at IS NULL", tokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("select user by token: %w", err)
+	}
+	return user, nil
+}
+
+// SetUserToken replaces the token of the player, for players registered
+// before tokens were issued or who lost theirs. It is recorded in audit_logs.
+func (r *Repository) SetUserToken(ctx context.Context, userID string, tokenHash string, actor string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin set token: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, "UPDATE users SET token_hash = ? WHERE id = ? AND deleted_at IS NULL", tokenHash, userID)
+	if err != nil {
+		return fmt.Errorf("set token: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	if err := insertAuditLog(ctx, tx, actor, "users.token", "user:"+userID, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit set token: %w", err)
+	}
+	return nil
+}
+
+// GetUserNames returns every name the player has had, oldest first
+func (r *Repository) GetUserNames(ctx context.Context, userID string) ([]*UserName, error) {
+	var ns []*UserName
+	if err := r.db.SelectContext(ctx, &ns, `
+        SELECT name, created_at FROM user_names WHERE user_id = ? ORDER BY created_at, id
+    `, userID); err != nil {
+		return nil, fmt.Errorf("get user names: %w", err)
+	}
+	return ns, nil
+}
+
+// GetUserScoreHistory returns every score of the player, including deleted
+// and invalidated ones, in insertion order
+func (r *Repository) GetUserScoreHistory(ctx context.Context, userID string) ([]*ScoreRow, error) {
+	var rs []*ScoreRow
+	if err := r.db.SelectContext(ctx, &rs, `
+        SELECT `+scoreColumns+`
+        FROM scores s
+        WHERE s.user_id = ?
+        ORDER BY s.id
+    `, userID); err != nil {
+		return nil, fmt.Errorf("get score history: %w", err)
+	}
+	return rs, nil
+}
+
+type DeleteUserParams struct {
+	UserID string
+	Actor  string
+}
+
+// DeleteUser deletes the account of a player along with their token and name
+// history, and hands over the teams they owned. Their scores are moved to a
+// new anonymous placeholder user named DeletedUserName, so that play counts,
+// player counts and rankings stay consistent while no longer identifying the
+// player. The deletion is
+// recorded in audit_logs, which never mention the placeholder.
+// It returns the number of scores moved.
+func (r *Repository) DeleteUser(ctx context.Context, p DeleteUserParams) (_ int64, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin delete user: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var exists int
+	if err := tx.GetContext(ctx, &exists, "SELECT 1 FROM users WHERE id = ? AND deleted_at IS NULL", p.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUserNotFound
+		}
+		return 0, fmt.Errorf("select user: %w", err)
+	}
+
+	placeholder := uuid.NewString()
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO users (id, name, deleted_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+    `, placeholder, DeletedUserName); err != nil {
+		return 0, fmt.Errorf("insert placeholder: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE scores SET user_id = ? WHERE user_id = ?", placeholder, p.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("move scores: %w", err)
+	}
+	n, _ := res.RowsAffected()
+
+	// snapshots are only rebuilt periodically
+	for _, table := range []string{"chart_ranking_snapshots", "rating_snapshots"} {
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE `+table+` SET user_id = ?, player_name = ? WHERE user_id = ?
+        `, placeholder, DeletedUserName, p.UserID); err != nil {
+			return 0, fmt.Errorf("anonymise %s: %w", table, err)
+		}
+	}
+
+	var teams []string
+	if err := tx.SelectContext(ctx, &teams, "SELECT team_id FROM team_members WHERE user_id = ?", p.UserID); err != nil {
+		return 0, fmt.Errorf("select teams: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_names WHERE user_id = ?", p.UserID); err != nil {
+		return 0, fmt.Errorf("delete user names: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = ?", p.UserID); err != nil {
+		return 0, fmt.Errorf("delete user: %w", err)
+	}
+	for _, id := range teams {
+		if err := handOverTeam(ctx, tx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := insertAuditLog(ctx, tx, p.Actor, "users.delete", "user:"+p.UserID, map[string]any{"anonymised_scores": n}); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit delete user: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// user_achievements table
+type UserAchievement struct {
+	AchievementID string    `db:"achievement_id"`
+	UnlockedAt    time.Time `db:"unlocked_at"`
+}
+
+type GetUserPlaysParams struct {
+	UserID string
+	// BeatmapID limits the plays to one chart when set
+	BeatmapID string
+	// Played bounds created_at
+	Played TimeRange
+}
+
+// GetUserPlays returns the active plays of the user, oldest first
+func (r *Repository) GetUserPlays(ctx context.Context, p GetUserPlaysParams) ([]*PlayRow, error) {
+	conds := []string{"s.user_id = ?", activeScoreCond}
+	args := []any{p.UserID}
+	if p.BeatmapID != "" {
+		conds = append(conds, "s.beatmap_id = ?")
+		args = append(args, p.BeatmapID)
+	}
+	played, playedArgs := p.Played.cond("s.created_at")
+	conds = append(conds, played)
+	args = append(args, playedArgs...)
+	var rs []*PlayRow
+	if err := r.db.SelectContext(ctx, &rs, `
+        SELECT `+scoreColumns+`,
+               u.name AS player_name, c.song_name, c.difficulty, c.parallel_string
+        FROM scores s
+        JOIN users u ON u.id = s.user_id
+        JOIN charts c ON c.beatmap_id = s.beatmap_id
+        WHERE `+strings.Join(conds, " AND ")+`
+        ORDER BY s.created_at, s.id
+    `, args...); err != nil {
+		return nil, fmt.Errorf("user plays: %w", err)
+	}
+	return rs, nil
+}
+
+// GetPlay returns the score with its player and chart
+func (r *Repository) GetPlay(ctx context.Context, scoreID int64) (*PlayRow, error) {
+	var p PlayRow
+	if err := r.db.GetContext(ctx, &p, `
+        SELECT `+scoreColumns+`,
+               u.name AS player_name, c.song_name, c.difficulty, c.parallel_string
+        FROM scores s
+        JOIN users u ON u.id = s.user_id
+        JOIN charts c ON c.beatmap_id = s.beatmap_id
+        WHERE s.id = ?
+    `, scoreID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrScoreNotFound
+		}
+		return nil, fmt.Errorf("get play: %w", err)
+	}
+	return &p, nil
+}
+
+// GetUserAchievements returns the achievements the user has unlocked, oldest first
+func (r *Repository) GetUserAchievements(ctx context.Context, userID string) ([]*UserAchievement, error) {
+	var as []*UserAchievement
+	if err := r.db.SelectContext(ctx, &as, `
+        SELECT achievement_id, unlocked_at
+        FROM user_achievements
+        WHERE user_id = ?
+        ORDER BY unlocked_at, achievement_id
+    `, userID); err != nil {
+		return nil, fmt.Errorf("get user achievements: %w", err)
+	}
+	return as, nil
+}
+
+// UnlockAchievements stores the unlocks of the user. Achievements the user
+// has already unlocked keep their first timestamp. It returns the number of
+// unlocks stored.
+func (r *Repository) UnlockAchievements(ctx context.Context, userID string, as []UserAchievement) (int, error) {
+	n := 0
+	for _, a := range as {
+		if _, err := r.db.ExecContext(ctx, `
+            INSERT INTO user_achievements (user_id, achievement_id, unlocked_at) VALUES (?, ?, ?)
+        `, userID, a.AchievementID, a.UnlockedAt.UTC()); err != nil {
+			// unlocked by a concurrent submission
+			if violatedConstraint(err) == constraintUnique {
+				continue
+			}
+			return n, fmt.Errorf("unlock achievement %s: %w", a.AchievementID, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// GetAchievementProgress returns the counters of the user by achievement ID.
+// Achievements without a counter have not been counted since the counters
+// were last reset.
+func (r *Repository) GetAchievementProgress(ctx context.Context, userID string) (map[string]int, error) {
+	var rs []struct {
+		AchievementID string `db:"achievement_id"`
+		Progress      int    `db:"progress"`
+	}
+	if err := r.db.SelectContext(ctx, &rs, `
+        SELECT achievement_id, progress FROM achievement_progress WHERE user_id = ?
+    `, userID); err != nil {
+		return nil, fmt.Errorf("get achievement progress: %w", err)
+	}
+	m := make(map[string]int, len(rs))
+	for _, r := range rs {
+		m[r.AchievementID] = r.Progress
+	}
+	return m, nil
+}
+
+// SetAchievementProgress stores the counters of the user, such as after
+// counting them from the whole history
+func (r *Repository) SetAchievementProgress(ctx context.Context, userID string, progress map[string]int) error {
+	q := r.dialect.upsertSQL("achievement_progress",
+		[]string{"user_id", "achievement_id", "progress"},
+		[]string{"user_id", "achievement_id"},
+		[]string{"progress"})
+	for id, n := range progress {
+		if _, err := r.db.ExecContext(ctx, q, userID, id, n); err != nil {
+			return fmt.Errorf("set achievement progress %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// AddAchievementProgress counts a play towards each of the achievements and
+// returns their new counters. Achievements without a counter are left out.
+func (r *Repository) AddAchievementProgress(ctx context.Context, userID string, achievementIDs []string) (map[string]int, error) {
+	m := make(map[string]int, len(achievementIDs))
+	for _, id := range achievementIDs {
+		res, err := r.db.ExecContext(ctx, `
+            UPDATE achievement_progress SET progress = progress + 1 WHERE user_id = ? AND achievement_id = ?
+        `, userID, id)
+		if err != nil {
+			return nil, fmt.Errorf("add achievement progress %s: %w", id, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		var progress int
+		if err := r.db.GetContext(ctx, &progress, `
+            SELECT progress FROM achievement_progress WHERE user_id = ? AND achievement_id = ?
+        `, userID, id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// reset since the update
+				continue
+			}
+			return nil, fmt.Errorf("get achievement progress %s: %w", id, err)
+		}
+		m[id] = progress
+	}
+	return m, nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// audit_logs table
+type AuditLog struct {
+	ID        int64     `db:"id"`
+	Actor     string    `db:"actor"`
+	Action    string    `db:"action"`
+	Target    string    `db:"target"`
+	Detail    *string   `db:"detail"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// insertAuditLog records an administrative action. detail is stored as JSON.
+func insertAuditLog(ctx context.Context, ex sqlx.ExecerContext, actor, action, target string, detail any) error {
+	var d any
+	if detail != nil {
+		b, err := json.Marshal(detail)
+		if err != nil {
+			return fmt.Errorf("marshal audit detail: %w", err)
+		}
+		d = string(b)
+	}
+	if _, err := ex.ExecContext(ctx, `
+        INSERT INTO audit_logs (actor, action, target, detail) VALUES (?, ?, ?, ?)
+    `, actor, action, target, d); err != nil {
+		return fmt.Errorf("insert audit log: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetAuditLogs(ctx context.Context, limit int) ([]*AuditLog, error) {
+	var ls []*AuditLog
+	if err := r.db.SelectContext(ctx, &ls, `
+        SELECT id, actor, action, target, detail, created_at
+        FROM audit_logs
+        ORDER BY id DESC
+        LIMIT ?
+    `, limit); err != nil {
+		return nil, fmt.Errorf("get audit logs: %w", err)
+	}
+	return ls, nil
+}
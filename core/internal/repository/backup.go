@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeRange selects rows in [From, To). A zero bound leaves that side open.
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// cond selects the rows with any of cols within the range
+func (tr TimeRange) cond(cols ...string) (string, []any) {
+	if tr.From.IsZero() && tr.To.IsZero() {
+		return "1 = 1", nil
+	}
+	var (
+		ors  []string
+		args []any
+	)
+	for _, c := range cols {
+		var ands []string
+		if !tr.From.IsZero() {
+			ands = append(ands, c+" >= ?")
+			args = append(args, tr.From.UTC())
+		}
+		if !tr.To.IsZero() {
+			ands = append(ands, c+" < ?")
+			args = append(args, tr.To.UTC())
+		}
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// keyChunk bounds the number of placeholders of an IN list
+const keyChunk = 500
+
+// MissingUserIDs returns the ids among ids that no user has
+func (r *Repository) MissingUserIDs(ctx context.Context, ids []string) ([]string, error) {
+	return r.missingKeys(ctx, "users", "id", ids)
+}
+
+// MissingBeatmapIDs returns the ids among ids that no chart has
+func (r *Repository) MissingBeatmapIDs(ctx context.Context, ids []string) ([]string, error) {
+	return r.missingKeys(ctx, "charts", "beatmap_id", ids)
+}
+
+func (r *Repository) missingKeys(ctx context.Context, table, col string, keys []string) ([]string, error) {
+	found := make(map[string]bool, len(keys))
+	for start := 0; start < len(keys); start += keyChunk {
+		chunk := keys[start:min(start+keyChunk, len(keys))]
+		args := make([]any, len(chunk))
+		for i, k := range chunk {
+			args[i] = k
+		}
+		var got []string
+		if err := r.db.SelectContext(ctx, &got, `
+            SELECT `+col+` FROM `+table+` WHERE `+col+` IN (`+placeholders(len(chunk))+`)
+        `, args...); err != nil {
+			return nil, fmt.Errorf("find %s: %w", table, err)
+		}
+		for _, k := range got {
+			found[k] = true
+		}
+	}
+	var missing []string
+	for _, k := range keys {
+		if !found[k] {
+			missing = append(missing, k)
+		}
+	}
+	return missing, nil
+}
+
+type ImportDataParams struct {
+	Users  []*User
+	Charts []*Chart
+	// Scores get new IDs; their own IDs are ignored
+	Scores []*ScoreRow
+	Actor  string
+}
+
+type ImportDataResult struct {
+	Users  int
+	Charts int
+	Scores int
+	// SkippedScores were already stored: a score by the same player on the
+	// same chart with the same value at the same second
+	SkippedScores int
+	// ModeratedScores were already stored but deleted, invalidated or
+	// restored differently, and took the moderation of the dump
+	ModeratedScores int
+}
+
+// ImportData restores exported rows in one transaction and records the import
+// in audit_logs. Users and charts are inserted or overwritten by their key.
+// Scores that refer to missing users or charts fail the whole import.
+func (r *Repository) ImportData(ctx context.Context, p ImportDataParams) (_ *ImportDataResult, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin import: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res := &ImportDataResult{}
+
+	userSQL := r.dialect.upsertSQL("users",
+		[]string{"id", "name", "created_at", "updated_at", "banned_at", "ban_reason", "token_hash", "deleted_at", "friend_code"},
+		[]string{"id"},
+		[]string{"name", "updated_at", "banned_at", "ban_reason", "token_hash", "deleted_at", "friend_code"},
+	)
+	for _, u := range p.Users {
+		if _, err := tx.ExecContext(ctx, userSQL, u.ID, u.Name, u.CreatedAt.UTC(), u.UpdatedAt.UTC(), utcOrNil(u.BannedAt), u.BanReason, u.TokenHash, utcOrNil(u.DeletedAt), u.FriendCode); err != nil {
+			return nil, fmt.Errorf("import user %s: %w", u.ID, err)
+		}
+		res.Users++
+	}
+
+	chartSQL := r.dialect.upsertSQL("charts",
+		[]string{"beatmap_id", "song_name", "difficulty", "parallel_string", "note_count", "chart_constant", "created_at"},
+		[]string{"beatmap_id"},
+		[]string{"song_name", "difficulty", "parallel_string", "note_count", "chart_constant"},
+	)
+	for _, c := range p.Charts {
+		if _, err := tx.ExecContext(ctx, chartSQL, c.BeatmapID, c.SongName, c.Difficulty, c.ParallelString, c.NoteCount, c.Constant, c.CreatedAt.UTC()); err != nil {
+			return nil, fmt.Errorf("import chart %s: %w", c.BeatmapID, err)
+		}
+		res.Charts++
+	}
+
+	stored, err := storedScores(ctx, tx, p.Scores)
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]bool)
+	for _, s := range p.Scores {
+		if old := takeStored(stored, s); old != nil {
+			if sameModeration(old, s) {
+				res.SkippedScores++
+				continue
+			}
+			changed[s.UserID] = true
+			// moderation in the source carries over
+			if _, err := tx.ExecContext(ctx, `
+                UPDATE scores SET deleted_at = ?, invalidated_at = ?, invalidation_reason = ? WHERE id = ?
+            `, utcOrNil(s.DeletedAt), utcOrNil(s.InvalidatedAt), s.InvalidationReason, old.ID); err != nil {
+				return nil, fmt.Errorf("update score %d: %w", old.ID, err)
+			}
+			res.ModeratedScores++
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO scores (
+                user_id, beatmap_id, score, max_combo,
+                perfect_critical_fast, perfect_critical_late,
+                perfect_fast, perfect_late,
+                good_fast, good_late,
+                miss, input, created_at,
+                deleted_at, invalidated_at, invalidation_reason
+            ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        `, s.UserID, s.BeatmapID, s.Score, s.MaxCombo,
+			s.PerfectCriticalFast, s.PerfectCriticalLate,
+			s.PerfectFast, s.PerfectLate,
+			s.GoodFast, s.GoodLate,
+			s.Miss, s.Input, s.CreatedAt.UTC(),
+			utcOrNil(s.DeletedAt), utcOrNil(s.InvalidatedAt), s.InvalidationReason,
+		); err != nil {
+			if violatedConstraint(err) == constraintForeignKey {
+				return nil, fmt.Errorf("import score %d: %w", s.ID, ErrMissingReference)
+			}
+			return nil, fmt.Errorf("import score %d: %w", s.ID, err)
+		}
+		changed[s.UserID] = true
+		res.Scores++
+	}
+	if err := resetAchievementProgress(ctx, tx, slices.Collect(maps.Keys(changed))); err != nil {
+		return nil, err
+	}
+
+	if err := insertAuditLog(ctx, tx, p.Actor, "data.import", "export", map[string]any{
+		"users":            res.Users,
+		"charts":           res.Charts,
+		"scores":           res.Scores,
+		"skipped_scores":   res.SkippedScores,
+		"moderated_scores": res.ModeratedScores,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit import: %w", err)
+	}
+	return res, nil
+}
+
+// storedScores returns the stored scores that may match scores, grouped by
+// scoreKey in the order of their IDs. It looks them up by the players and
+// charts of each chunk of scores.
+func storedScores(ctx context.Context, tx *boundTx, scores []*ScoreRow) (map[string][]*ScoreRow, error) {
+	stored := make(map[string][]*ScoreRow)
+	seen := make(map[int64]bool)
+	for start := 0; start < len(scores); start += keyChunk {
+		chunk := scores[start:min(start+keyChunk, len(scores))]
+		users, charts := map[string]bool{}, map[string]bool{}
+		var userArgs, chartArgs []any
+		for _, s := range chunk {
+			if !users[s.UserID] {
+				users[s.UserID] = true
+				userArgs = append(userArgs, s.UserID)
+			}
+			if !charts[s.BeatmapID] {
+				charts[s.BeatmapID] = true
+				chartArgs = append(chartArgs, s.BeatmapID)
+			}
+		}
+		var rs []*ScoreRow
+		if err := tx.SelectContext(ctx, &rs, `
+            SELECT id, user_id, beatmap_id, score, created_at, deleted_at, invalidated_at, invalidation_reason
+            FROM scores
+            WHERE user_id IN (`+placeholders(len(userArgs))+`) AND beatmap_id IN (`+placeholders(len(chartArgs))+`)
+            ORDER BY id
+        `, append(userArgs, chartArgs...)...); err != nil {
+			return nil, fmt.Errorf("stored scores: %w", err)
+		}
+		for _, r := range rs {
+			// chunks sharing a player and a chart find the same rows
+			if seen[r.ID] {
+				continue
+			}
+			seen[r.ID] = true
+			stored[scoreKey(r)] = append(stored[scoreKey(r)], r)
+		}
+	}
+	return stored, nil
+}
+
+// resetAchievementProgress drops the achievement counters of the users so
+// that they are rebuilt from their history
+func resetAchievementProgress(ctx context.Context, tx *boundTx, userIDs []string) error {
+	for start := 0; start < len(userIDs); start += keyChunk {
+		chunk := userIDs[start:min(start+keyChunk, len(userIDs))]
+		args := make([]any, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+		if _, err := tx.ExecContext(ctx, `
+            DELETE FROM achievement_progress WHERE user_id IN (`+placeholders(len(args))+`)
+        `, args...); err != nil {
+			return fmt.Errorf("reset achievement progress: %w", err)
+		}
+	}
+	return nil
+}
+
+// takeStored removes and returns the stored score that s is a copy of,
+// preferring one moderated alike, or nil when none is left. Each stored score
+// matches one score of the dump at most, so identical plays stay apart and
+// scores inserted by the import are never matched.
+func takeStored(stored map[string][]*ScoreRow, s *ScoreRow) *ScoreRow {
+	k := scoreKey(s)
+	rs := stored[k]
+	if len(rs) == 0 {
+		return nil
+	}
+	i := slices.IndexFunc(rs, func(r *ScoreRow) bool { return sameModeration(r, s) })
+	if i < 0 {
+		i = 0
+	}
+	old := rs[i]
+	stored[k] = slices.Delete(rs, i, i+1)
+	return old
+}
+
+// sameModeration reports whether the two copies of a score are deleted and
+// invalidated alike, comparing times by the second
+func sameModeration(a, b *ScoreRow) bool {
+	sameTime := func(x, y *time.Time) bool {
+		return (x == nil) == (y == nil) && (x == nil || x.Unix() == y.Unix())
+	}
+	sameReason := (a.InvalidationReason == nil) == (b.InvalidationReason == nil) &&
+		(a.InvalidationReason == nil || *a.InvalidationReason == *b.InvalidationReason)
+	return sameTime(a.DeletedAt, b.DeletedAt) && sameTime(a.InvalidatedAt, b.InvalidatedAt) && sameReason
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// scoreKey identifies a play across databases, where IDs differ.
+// Times are compared by the second, the precision of MySQL DATETIME.
+func scoreKey(s *ScoreRow) string {
+	return s.UserID + "\x00" + s.BeatmapID + "\x00" + strconv.Itoa(s.Score) + "\x00" + strconv.FormatInt(s.CreatedAt.Unix(), 10)
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -13,6 +14,9 @@ type Chart struct {
 	Difficulty     int       `db:"difficulty"`
 	ParallelString *string   `db:"parallel_string"`
 	CreatedAt      time.Time `db:"created_at"`
+	// NoteCount and Constant are only known for charts registered from the catalog
+	NoteCount *int     `db:"note_count"`
+	Constant  *float64 `db:"chart_constant"`
 }
 
 type UpsertChartParams struct {
@@ -23,27 +27,74 @@ type UpsertChartParams struct {
 }
 
 func (r *Repository) UpsertChart(ctx context.Context, p UpsertChartParams) error {
-	// MySQL upsert
 	var par interface{}
 	if p.ParallelString != nil {
 		par = *p.ParallelString
 	} else {
 		par = nil
 	}
-	_, err := r.db.ExecContext(ctx, `
-        INSERT INTO charts (beatmap_id, song_name, difficulty, parallel_string)
-        VALUES (?, ?, ?, ?)
-        ON DUPLICATE KEY UPDATE song_name=VALUES(song_name), difficulty=VALUES(difficulty), parallel_string=VALUES(parallel_string)
-    `, p.BeatmapID, p.SongName, p.Difficulty, par)
+	_, err := r.db.ExecContext(ctx, r.dialect.upsertSQL("charts",
+		[]string{"beatmap_id", "song_name", "difficulty", "parallel_string"},
+		[]string{"beatmap_id"},
+		[]string{"song_name", "difficulty", "parallel_string"},
+	), p.BeatmapID, p.SongName, p.Difficulty, par)
 	if err != nil {
 		return fmt.Errorf("upsert chart: %w", err)
 	}
 	return nil
 }
 
+type ImportChartsParams struct {
+	Charts []*Chart
+	Actor  string
+	// Created and Updated count the charts that are new and changed, for the audit log
+	Created int
+	Updated int
+}
+
+// ImportCharts writes every column of the charts in one transaction and
+// records the import in audit_logs. Charts not in p.Charts are left as they are.
+func (r *Repository) ImportCharts(ctx context.Context, p ImportChartsParams) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin chart import: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	query := r.dialect.upsertSQL("charts",
+		[]string{"beatmap_id", "song_name", "difficulty", "parallel_string", "note_count", "chart_constant"},
+		[]string{"beatmap_id"},
+		[]string{"song_name", "difficulty", "parallel_string", "note_count", "chart_constant"},
+	)
+	for _, c := range p.Charts {
+		if _, err := tx.ExecContext(ctx, query, c.BeatmapID, c.SongName, c.Difficulty, c.ParallelString, c.NoteCount, c.Constant); err != nil {
+			return fmt.Errorf("import chart %s: %w", c.BeatmapID, err)
+		}
+	}
+
+	if err := insertAuditLog(ctx, tx, p.Actor, "charts.import", "charts", map[string]any{
+		"created": p.Created,
+		"updated": p.Updated,
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit chart import: %w", err)
+	}
+	return nil
+}
+
 func (r *Repository) GetChart(ctx context.Context, beatmapID string) (*Chart, error) {
 	var c Chart
 	if err := r.db.GetContext(ctx, &c, `SELECT * FROM charts WHERE beatmap_id=?`, beatmapID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrChartNotFound
+		}
 		return nil, fmt.Errorf("get chart: %w", err)
 	}
 	return &c, nil
@@ -66,16 +117,14 @@ type ChartStats struct {
 }
 
 func (r *Repository) GetChartStats(ctx context.Context, beatmapID string) (*ChartStats, error) {
-	var s ChartStats
+	s := ChartStats{BeatmapID: beatmapID}
 	// Aggregates with NULL-safe handling
 	if err := r.db.GetContext(ctx, &s, `
-        SELECT ? AS beatmap_id,
-               COUNT(*) AS play_count,
-               COUNT(DISTINCT user_id) AS player_count,
-               AVG(score) AS avg_score,
-               MAX(score) AS best_score
-        FROM scores WHERE beatmap_id = ?
-    `, beatmapID, beatmapID); err != nil {
+        SELECT COUNT(*) AS play_count,
+               COUNT(DISTINCT s.user_id) AS player_count,
+               AVG(s.score) AS avg_score,
+               MAX(s.score) AS best_score
+        FROM scores s WHERE s.beatmap_id = ? AND `+activeScoreCond, beatmapID); err != nil {
 		if err == sql.ErrNoRows {
 			return &ChartStats{BeatmapID: beatmapID, PlayCount: 0, PlayerCount: 0, AvgScore: nil, BestScore: nil}, nil
 		}
@@ -95,6 +144,7 @@ func (r *Repository) GetSongPlaycountRanking(ctx context.Context) ([]*SongPlayCo
         SELECT c.song_name, COUNT(*) AS play_count
         FROM scores s
         JOIN charts c ON c.beatmap_id = s.beatmap_id
+        WHERE `+activeScoreCond+`
         GROUP BY c.song_name
         ORDER BY play_count DESC, c.song_name ASC
     `); err != nil {
@@ -102,3 +152,17 @@ func (r *Repository) GetSongPlaycountRanking(ctx context.Context) ([]*SongPlayCo
 	}
 	return rs, nil
 }
+
+// GetChartPlays returns every active play of the chart, oldest first
+func (r *Repository) GetChartPlays(ctx context.Context, beatmapID string) ([]*ScoreRow, error) {
+	var rs []*ScoreRow
+	if err := r.db.SelectContext(ctx, &rs, `
+        SELECT `+scoreColumns+`
+        FROM scores s
+        WHERE s.beatmap_id = ? AND `+activeScoreCond+`
+        ORDER BY s.id
+    `, beatmapID); err != nil {
+		return nil, fmt.Errorf("chart plays: %w", err)
+	}
+	return rs, nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// chart_difficulty_estimates table
+type DifficultyEstimate struct {
+	BeatmapID         string    `db:"beatmap_id"`
+	EstimatedConstant float64   `db:"estimated_constant"`
+	IRTDifficulty     float64   `db:"irt_difficulty"`
+	SamplePlayers     int       `db:"sample_players"`
+	UpdatedAt         time.Time `db:"updated_at"`
+}
+
+// PlayerChartBest is a player's best active score on a chart
+type PlayerChartBest struct {
+	UserID     string `db:"user_id"`
+	PlayerName string `db:"player_name"`
+	BeatmapID  string `db:"beatmap_id"`
+	Score      int    `db:"best_score"`
+}
+
+func (r *Repository) GetPlayerChartBests(ctx context.Context) ([]*PlayerChartBest, error) {
+	var bs []*PlayerChartBest
+	if err := r.db.SelectContext(ctx, &bs, `
+        SELECT s.user_id, u.name AS player_name, s.beatmap_id, MAX(s.score) AS best_score
+        FROM scores s
+        JOIN users u ON u.id = s.user_id
+        WHERE `+activeScoreCond+`
+        GROUP BY s.user_id, u.name, s.beatmap_id
+    `); err != nil {
+		return nil, fmt.Errorf("player chart bests: %w", err)
+	}
+	return bs, nil
+}
+
+// ReplaceDifficultyEstimates swaps every estimate for es in one transaction
+func (r *Repository) ReplaceDifficultyEstimates(ctx context.Context, es []DifficultyEstimate) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin replace estimates: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chart_difficulty_estimates`); err != nil {
+		return fmt.Errorf("clear estimates: %w", err)
+	}
+	for _, e := range es {
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO chart_difficulty_estimates (beatmap_id, estimated_constant, irt_difficulty, sample_players, updated_at)
+            VALUES (?, ?, ?, ?, ?)
+        `, e.BeatmapID, e.EstimatedConstant, e.IRTDifficulty, e.SamplePlayers, e.UpdatedAt.UTC()); err != nil {
+			return fmt.Errorf("insert estimate: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit replace estimates: %w", err)
+	}
+	return nil
+}
+
+// ChartDifficulty is a chart with its declared and estimated difficulty
+type ChartDifficulty struct {
+	Chart
+	EstimatedConstant *float64   `db:"estimated_constant"`
+	SamplePlayers     *int       `db:"sample_players"`
+	EstimatedAt       *time.Time `db:"estimated_at"`
+}
+
+func (r *Repository) GetChartDifficulties(ctx context.Context) ([]*ChartDifficulty, error) {
+	var cs []*ChartDifficulty
+	if err := r.db.SelectContext(ctx, &cs, `
+        SELECT c.*, e.estimated_constant, e.sample_players, e.updated_at AS estimated_at
+        FROM charts c
+        LEFT JOIN chart_difficulty_estimates e ON e.beatmap_id = c.beatmap_id
+        ORDER BY c.song_name, c.difficulty, c.beatmap_id
+    `); err != nil {
+		return nil, fmt.Errorf("chart difficulties: %w", err)
+	}
+	return cs, nil
+}
+
+// GetDifficultyEstimate returns nil when the chart has no estimate yet
+func (r *Repository) GetDifficultyEstimate(ctx context.Context, beatmapID string) (*DifficultyEstimate, error) {
+	var es []*DifficultyEstimate
+	if err := r.db.SelectContext(ctx, &es, `
+        SELECT * FROM chart_difficulty_estimates WHERE beatmap_id = ?
+    `, beatmapID); err != nil {
+		return nil, fmt.Errorf("difficulty estimate: %w", err)
+	}
+	if len(es) == 0 {
+		return nil, nil
+	}
+	return es[0], nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Errors returned by the repository for the handlers to map to responses.
+// They are wrapped with context, so test for them with errors.Is.
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrChartNotFound = errors.New("chart not found")
+	ErrScoreNotFound = errors.New("score not found")
+	// ErrFriendRequestNotFound is returned for a friend request that was never sent or already answered
+	ErrFriendRequestNotFound = errors.New("friend request not found")
+	ErrFriendNotFound        = errors.New("friend not found")
+	ErrRivalNotFound         = errors.New("rival not found")
+	// ErrRivalLimit is returned when a player already has MaxRivals rivals
+	ErrRivalLimit = errors.New("too many rivals")
+	// ErrTeamNotFound is returned for an unknown team or invite code
+	ErrTeamNotFound = errors.New("team not found")
+	// ErrTeamMemberNotFound is returned for a player who is not in the team
+	ErrTeamMemberNotFound = errors.New("team member not found")
+	// ErrTeamOwner is returned when the owner would step down without handing the team over
+	ErrTeamOwner = errors.New("team owner must hand the team over")
+	// ErrMissionNotFound is returned for a mission that is not active now
+	ErrMissionNotFound = errors.New("mission not found")
+	// ErrMissionIncomplete is returned when a mission is claimed before it is complete
+	ErrMissionIncomplete = errors.New("mission not complete")
+	// ErrMissionClaimed is returned when the reward of a mission was already claimed
+	ErrMissionClaimed = errors.New("mission already claimed")
+	// ErrUserBanned is returned when a banned player submits a score
+	ErrUserBanned = errors.New("user is banned")
+	// ErrMissingReference is returned when imported rows refer to a user or chart that does not exist
+	ErrMissingReference = errors.New("missing referenced row")
+	// ErrDuplicate is returned when a row with the same unique key already exists
+	ErrDuplicate = errors.New("duplicate entry")
+)
+
+// constraint is the kind of integrity constraint a statement violated
+type constraint int
+
+const (
+	constraintNone constraint = iota
+	constraintUnique
+	constraintForeignKey
+)
+
+// violatedConstraint classifies integrity errors of every supported driver
+func violatedConstraint(err error) constraint {
+	var myErr *mysql.MySQLError
+	var pgErr *pgconn.PgError
+	var liteErr *sqlite.Error
+	switch {
+	case errors.As(err, &myErr):
+		switch myErr.Number {
+		case 1062:
+			return constraintUnique
+		case 1452:
+			return constraintForeignKey
+		}
+	case errors.As(err, &pgErr):
+		switch pgErr.Code {
+		case "23505":
+			return constraintUnique
+		case "23503":
+			return constraintForeignKey
+		}
+	case errors.As(err, &liteErr):
+		switch liteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return constraintUnique
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return constraintForeignKey
+		}
+	}
+	return constraintNone
+}
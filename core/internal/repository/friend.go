@@ -0,0 +1,336 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MaxRivals is the number of rivals a player may follow
+const MaxRivals = 20
+
+// Contact is a friend, rival or friend request, with the time it was made
+type Contact struct {
+	UserID string    `db:"user_id"`
+	Name   string    `db:"name"`
+	Since  time.Time `db:"created_at"`
+}
+
+// SetFriendCode stores the friend code of the player, unless they already have one
+func (r *Repository) SetFriendCode(ctx context.Context, userID string, code string) error {
+	if _, err := r.db.ExecContext(ctx, `
+        UPDATE users SET friend_code = ? WHERE id = ? AND friend_code IS NULL
+    `, code, userID); err != nil {
+		return fmt.Errorf("set friend code: %w", err)
+	}
+	return nil
+}
+
+// GetUserByFriendCode returns the player who has the code
+func (r *Repository) GetUserByFriendCode(ctx context.Context, code string) (*User, error) {
+	user := &User{}
+	if err := r.db.GetContext(ctx, user, "SELECT "+userColumns+" FROM users WHERE friend_code = ? AND deleted_at IS NULL", code); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("select user by friend code: %w", err)
+	}
+	return user, nil
+}
+
+// RequestFriend sends a friend request from one player to another. If the
+// other player had already asked, they become friends at once, and accepted
+// is true. It returns ErrDuplicate when they are friends or the request was
+// already sent.
+func (r *Repository) RequestFriend(ctx context.Context, fromID string, toID string) (accepted bool, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin friend request: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var friends int
+	if err := tx.GetContext(ctx, &friends, "SELECT COUNT(*) FROM friends WHERE user_id = ? AND friend_id = ?", fromID, toID); err != nil {
+		return false, fmt.Errorf("check friends: %w", err)
+	}
+	if friends > 0 {
+		return false, ErrDuplicate
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM friend_requests WHERE from_user_id = ? AND to_user_id = ?", toID, fromID)
+	if err != nil {
+		return false, fmt.Errorf("delete friend request: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		accepted = true
+		err = insertFriendship(ctx, tx, fromID, toID)
+	} else {
+		_, err = tx.ExecContext(ctx, "INSERT INTO friend_requests (from_user_id, to_user_id) VALUES (?, ?)", fromID, toID)
+	}
+	if err != nil {
+		switch violatedConstraint(err) {
+		case constraintUnique:
+			return false, ErrDuplicate
+		case constraintForeignKey:
+			return false, ErrUserNotFound
+		}
+		return false, fmt.Errorf("request friend: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit friend request: %w", err)
+	}
+	return accepted, nil
+}
+
+// AcceptFriend accepts the request the player received from requesterID
+func (r *Repository) AcceptFriend(ctx context.Context, userID string, requesterID string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin accept friend: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM friend_requests WHERE from_user_id = ? AND to_user_id = ?", requesterID, userID)
+	if err != nil {
+		return fmt.Errorf("delete friend request: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrFriendRequestNotFound
+	}
+	if err := insertFriendship(ctx, tx, userID, requesterID); err != nil {
+		return fmt.Errorf("accept friend: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit accept friend: %w", err)
+	}
+	return nil
+}
+
+// insertFriendship stores a friendship in both directions
+func insertFriendship(ctx context.Context, tx sqlx.ExecerContext, a, b string) error {
+	for _, pair := range [][2]string{{a, b}, {b, a}} {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO friends (user_id, friend_id) VALUES (?, ?)", pair[0], pair[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteFriendRequest declines a request the player received from otherID,
+// or cancels one they sent to otherID
+func (r *Repository) DeleteFriendRequest(ctx context.Context, userID string, otherID string) error {
+	res, err := r.db.ExecContext(ctx, `
+        DELETE FROM friend_requests
+        WHERE (from_user_id = ? AND to_user_id = ?) OR (from_user_id = ? AND to_user_id = ?)
+    `, userID, otherID, otherID, userID)
+	if err != nil {
+		return fmt.Errorf("delete friend request: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrFriendRequestNotFound
+	}
+	return nil
+}
+
+// RemoveFriend ends a friendship for both players
+func (r *Repository) RemoveFriend(ctx context.Context, userID string, friendID string) error {
+	res, err := r.db.ExecContext(ctx, `
+        DELETE FROM friends
+        WHERE (user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)
+    `, userID, friendID, friendID, userID)
+	if err != nil {
+		return fmt.Errorf("remove friend: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrFriendNotFound
+	}
+	return nil
+}
+
+// GetFriends returns the friends of the player by name
+func (r *Repository) GetFriends(ctx context.Context, userID string) ([]*Contact, error) {
+	var cs []*Contact
+	if err := r.db.SelectContext(ctx, &cs, `
+        SELECT f.friend_id AS user_id, u.name, f.created_at
+        FROM friends f
+        JOIN users u ON u.id = f.friend_id
+        WHERE f.user_id = ?
+        ORDER BY u.name, f.friend_id
+    `, userID); err != nil {
+		return nil, fmt.Errorf("get friends: %w", err)
+	}
+	return cs, nil
+}
+
+// IsFriend reports whether the two players are friends
+func (r *Repository) IsFriend(ctx context.Context, userID string, otherID string) (bool, error) {
+	var n int
+	if err := r.db.GetContext(ctx, &n, `
+        SELECT COUNT(*) FROM friends WHERE user_id = ? AND friend_id = ?
+    `, userID, otherID); err != nil {
+		return false, fmt.Errorf("is friend: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetFriendRequests returns the pending requests the player received and
+// sent, newest first
+func (r *Repository) GetFriendRequests(ctx context.Context, userID string) (incoming []*Contact, outgoing []*Contact, err error) {
+	if err := r.db.SelectContext(ctx, &incoming, `
+        SELECT fr.from_user_id AS user_id, u.name, fr.created_at
+        FROM friend_requests fr
+        JOIN users u ON u.id = fr.from_user_id
+        WHERE fr.to_user_id = ?
+        ORDER BY fr.created_at DESC, fr.from_user_id
+    `, userID); err != nil {
+		return nil, nil, fmt.Errorf("get incoming friend requests: %w", err)
+	}
+	if err := r.db.SelectContext(ctx, &outgoing, `
+        SELECT fr.to_user_id AS user_id, u.name, fr.created_at
+        FROM friend_requests fr
+        JOIN users u ON u.id = fr.to_user_id
+        WHERE fr.from_user_id = ?
+        ORDER BY fr.created_at DESC, fr.to_user_id
+    `, userID); err != nil {
+		return nil, nil, fmt.Errorf("get outgoing friend requests: %w", err)
+	}
+	return incoming, outgoing, nil
+}
+
+// AddRival follows rivalID. It returns ErrRivalLimit beyond MaxRivals,
+// ErrDuplicate when the player already follows them and ErrUserNotFound when
+// rivalID is not an active player.
+func (r *Repository) AddRival(ctx context.Context, userID string, rivalID string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin add rival: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var n int
+	if err := tx.GetContext(ctx, &n, "SELECT COUNT(*) FROM rivals WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("count rivals: %w", err)
+	}
+	if n >= MaxRivals {
+		return ErrRivalLimit
+	}
+	// deleted players stay in users as placeholders, but cannot be followed
+	var exists int
+	if err := tx.GetContext(ctx, &exists, "SELECT 1 FROM users WHERE id = ? AND deleted_at IS NULL", rivalID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("select rival: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO rivals (user_id, rival_id) VALUES (?, ?)", userID, rivalID); err != nil {
+		switch violatedConstraint(err) {
+		case constraintUnique:
+			return ErrDuplicate
+		case constraintForeignKey:
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("add rival: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit add rival: %w", err)
+	}
+	return nil
+}
+
+// RemoveRival stops following rivalID
+func (r *Repository) RemoveRival(ctx context.Context, userID string, rivalID string) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM rivals WHERE user_id = ? AND rival_id = ?", userID, rivalID)
+	if err != nil {
+		return fmt.Errorf("remove rival: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrRivalNotFound
+	}
+	return nil
+}
+
+// GetRivals returns the rivals of the player by name
+func (r *Repository) GetRivals(ctx context.Context, userID string) ([]*Contact, error) {
+	var cs []*Contact
+	if err := r.db.SelectContext(ctx, &cs, `
+        SELECT rv.rival_id AS user_id, u.name, rv.created_at
+        FROM rivals rv
+        JOIN users u ON u.id = rv.rival_id
+        WHERE rv.user_id = ?
+        ORDER BY u.name, rv.rival_id
+    `, userID); err != nil {
+		return nil, fmt.Errorf("get rivals: %w", err)
+	}
+	return cs, nil
+}
+
+// GetFriendsChartRanking is GetChartRanking among the player and their friends
+func (r *Repository) GetFriendsChartRanking(ctx context.Context, userID string, beatmapID string, limit int) (*ChartRanking, error) {
+	const circle = `(s.user_id = ? OR s.user_id IN (SELECT friend_id FROM friends WHERE user_id = ?))`
+
+	var counts struct {
+		PlayerCount int `db:"player_count"`
+		PlayCount   int `db:"play_count"`
+	}
+	if err := r.db.GetContext(ctx, &counts, `
+        SELECT COUNT(DISTINCT s.user_id) AS player_count, COUNT(*) AS play_count
+        FROM scores s WHERE s.beatmap_id = ? AND `+circle+` AND `+activeScoreCond, beatmapID, userID, userID); err != nil {
+		return nil, fmt.Errorf("count friends ranking: %w", err)
+	}
+
+	var top []RankingEntry
+	if err := r.db.SelectContext(ctx, &top, `
+        SELECT s.user_id, u.name, MAX(s.score) AS best_score
+        FROM scores s
+        JOIN users u ON u.id = s.user_id
+        WHERE s.beatmap_id = ? AND `+circle+` AND `+activeScoreCond+`
+        GROUP BY s.user_id, u.name
+        ORDER BY best_score DESC, u.name ASC
+        LIMIT `+strconv.Itoa(limit), beatmapID, userID, userID); err != nil {
+		return nil, fmt.Errorf("top friends ranking: %w", err)
+	}
+
+	return &ChartRanking{
+		BeatmapID:   beatmapID,
+		PlayerCount: counts.PlayerCount,
+		PlayCount:   counts.PlayCount,
+		Top:         top,
+	}, nil
+}
+
+// GetAllFriendsChartRankings is GetFriendsChartRanking for every chart
+func (r *Repository) GetAllFriendsChartRankings(ctx context.Context, userID string, limit int) ([]*ChartRanking, error) {
+	charts, err := r.GetCharts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*ChartRanking, 0, len(charts))
+	for _, c := range charts {
+		cr, err := r.GetFriendsChartRanking(ctx, userID, c.BeatmapID, limit)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, cr)
+	}
+	return res, nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MissionKey identifies a mission of one period for a player
+type MissionKey struct {
+	UserID    string
+	Region    string
+	MissionID string
+	// PeriodStart is when the day or week of the mission began
+	PeriodStart time.Time
+}
+
+// mission_progress table. A row is a claimed mission; the progress of
+// unclaimed missions is counted from the scores of the period.
+type MissionClaim struct {
+	Region      string    `db:"region"`
+	MissionID   string    `db:"mission_id"`
+	PeriodStart time.Time `db:"period_start"`
+	// Progress is the number of matching plays when the reward was claimed
+	Progress  int       `db:"progress"`
+	Reward    int       `db:"reward"`
+	ClaimedAt time.Time `db:"claimed_at"`
+}
+
+// GetMissionClaims returns the missions of region whose period began at
+// since or later that the user has claimed
+func (r *Repository) GetMissionClaims(ctx context.Context, userID string, region string, since time.Time) ([]*MissionClaim, error) {
+	var cs []*MissionClaim
+	if err := r.db.SelectContext(ctx, &cs, `
+        SELECT region, mission_id, period_start, progress, reward, claimed_at
+        FROM mission_progress
+        WHERE user_id = ? AND region = ? AND period_start >= ?
+        ORDER BY period_start, mission_id
+    `, userID, region, since.UTC()); err != nil {
+		return nil, fmt.Errorf("get mission claims: %w", err)
+	}
+	return cs, nil
+}
+
+// GetUserMissionClaims returns every mission the user has claimed in every
+// region, oldest first
+func (r *Repository) GetUserMissionClaims(ctx context.Context, userID string) ([]*MissionClaim, error) {
+	var cs []*MissionClaim
+	if err := r.db.SelectContext(ctx, &cs, `
+        SELECT region, mission_id, period_start, progress, reward, claimed_at
+        FROM mission_progress
+        WHERE user_id = ?
+        ORDER BY period_start, region, mission_id
+    `, userID); err != nil {
+		return nil, fmt.Errorf("get user mission claims: %w", err)
+	}
+	return cs, nil
+}
+
+type ClaimMissionParams struct {
+	MissionKey
+	// Progress is the number of matching plays, no less than the goal
+	Progress int
+	Reward   int
+	At       time.Time
+}
+
+// ClaimMission records that the user claimed the reward of a complete
+// mission. It returns ErrMissionClaimed when the reward was already claimed.
+func (r *Repository) ClaimMission(ctx context.Context, p ClaimMissionParams) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO mission_progress (user_id, region, mission_id, period_start, progress, reward, claimed_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+    `, p.UserID, p.Region, p.MissionID, p.PeriodStart.UTC(), p.Progress, p.Reward, p.At.UTC())
+	if violatedConstraint(err) == constraintUnique {
+		return ErrMissionClaimed
+	}
+	if err != nil {
+		return fmt.Errorf("claim mission: %w", err)
+	}
+	return nil
+}
+
+// GetMissionPoints returns the sum of the rewards the user has claimed
+func (r *Repository) GetMissionPoints(ctx context.Context, userID string) (int, error) {
+	var points int
+	if err := r.db.GetContext(ctx, &points, `
+        SELECT COALESCE(SUM(reward), 0) FROM mission_progress WHERE user_id = ? AND claimed_at IS NOT NULL
+    `, userID); err != nil {
+		return 0, fmt.Errorf("mission points: %w", err)
+	}
+	return points, nil
+}
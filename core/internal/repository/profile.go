@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Visibility decides who may see the details of a profile
+type Visibility string
+
+const (
+	VisibilityPublic  Visibility = "public"
+	VisibilityFriends Visibility = "friends"
+	VisibilityPrivate Visibility = "private"
+)
+
+// ShowcaseSize is how many charts a profile may show
+const ShowcaseSize = 3
+
+// user_profiles table
+type Profile struct {
+	// Title is the id of an achievement the player has unlocked
+	Title             *string    `db:"title"`
+	FavoriteBeatmapID *string    `db:"favorite_beatmap_id"`
+	Visibility        Visibility `db:"visibility"`
+	// Showcase is the beatmap ids of user_showcase in order
+	Showcase []string `db:"-"`
+}
+
+// GetProfile returns the profile of the user, or the default public one
+// when they have never changed it
+func (r *Repository) GetProfile(ctx context.Context, userID string) (*Profile, error) {
+	p := &Profile{Showcase: []string{}}
+	err := r.db.GetContext(ctx, p, `
+        SELECT title, favorite_beatmap_id, visibility FROM user_profiles WHERE user_id = ?
+    `, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &Profile{Visibility: VisibilityPublic, Showcase: []string{}}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("get profile: %w", err)
+	}
+	if err := r.db.SelectContext(ctx, &p.Showcase, `
+        SELECT beatmap_id FROM user_showcase WHERE user_id = ? ORDER BY slot
+    `, userID); err != nil {
+		return nil, fmt.Errorf("get showcase: %w", err)
+	}
+	return p, nil
+}
+
+// SaveProfile replaces the profile of the user. It returns ErrChartNotFound
+// when the favourite or a showcased chart does not exist.
+func (r *Repository) SaveProfile(ctx context.Context, userID string, p *Profile) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin save profile: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, r.dialect.upsertSQL("user_profiles",
+		[]string{"user_id", "title", "favorite_beatmap_id", "visibility", "updated_at"},
+		[]string{"user_id"},
+		[]string{"title", "favorite_beatmap_id", "visibility", "updated_at"},
+	), userID, p.Title, p.FavoriteBeatmapID, p.Visibility, time.Now().UTC()); err != nil {
+		if violatedConstraint(err) == constraintForeignKey {
+			return ErrChartNotFound
+		}
+		return fmt.Errorf("upsert profile: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_showcase WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("delete showcase: %w", err)
+	}
+	for i, id := range p.Showcase {
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO user_showcase (user_id, slot, beatmap_id) VALUES (?, ?, ?)
+        `, userID, i+1, id); err != nil {
+			if violatedConstraint(err) == constraintForeignKey {
+				return ErrChartNotFound
+			}
+			return fmt.Errorf("insert showcase: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit save profile: %w", err)
+	}
+	return nil
+}
+
+// PlaySummary is how much and how a player plays, counting active scores
+type PlaySummary struct {
+	TotalPlays    int
+	FirstPlayedAt *time.Time
+	LastPlayedAt  *time.Time
+	// PreferredInput is the input of most plays, nil before the first play
+	PreferredInput *InputType
+}
+
+// GetPlaySummary returns the play summary of the user
+func (r *Repository) GetPlaySummary(ctx context.Context, userID string) (*PlaySummary, error) {
+	var inputs []struct {
+		Input InputType `db:"input"`
+		Plays int       `db:"plays"`
+	}
+	if err := r.db.SelectContext(ctx, &inputs, `
+        SELECT s.input, COUNT(*) AS plays
+        FROM scores s WHERE s.user_id = ? AND `+activeScoreCond+`
+        GROUP BY s.input
+        ORDER BY plays DESC, s.input
+    `, userID); err != nil {
+		return nil, fmt.Errorf("plays by input: %w", err)
+	}
+	s := &PlaySummary{}
+	if len(inputs) == 0 {
+		return s, nil
+	}
+	s.PreferredInput = &inputs[0].Input
+	for _, i := range inputs {
+		s.TotalPlays += i.Plays
+	}
+
+	// MIN and MAX would lose the column type on SQLite
+	var first, last time.Time
+	if err := r.db.GetContext(ctx, &first, `
+        SELECT s.created_at FROM scores s WHERE s.user_id = ? AND `+activeScoreCond+`
+        ORDER BY s.created_at, s.id LIMIT 1
+    `, userID); err != nil {
+		return nil, fmt.Errorf("first play: %w", err)
+	}
+	if err := r.db.GetContext(ctx, &last, `
+        SELECT s.created_at FROM scores s WHERE s.user_id = ? AND `+activeScoreCond+`
+        ORDER BY s.created_at DESC, s.id DESC LIMIT 1
+    `, userID); err != nil {
+		return nil, fmt.Errorf("last play: %w", err)
+	}
+	s.FirstPlayedAt, s.LastPlayedAt = &first, &last
+	return s, nil
+}
@@ -1,17 +1,180 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
 	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Repository struct {
-	db *sqlx.DB
+	db      *boundDB
+	dialect dialect
 }
 
 func New(db *sqlx.DB) *Repository {
-	return &Repository{db: db}
+	return &Repository{db: &boundDB{db}, dialect: dialect(db.DriverName())}
+}
+
+// dialect is the SQL flavour of the connected database, named after its driver
+type dialect string
+
+const (
+	dialectMySQL    dialect = "mysql"
+	dialectPostgres dialect = "pgx"
+	dialectSQLite   dialect = "sqlite"
+)
+
+// boundDB rewrites the "?" placeholders used throughout the repository into
+// the bind type of the connected driver ($1, $2, ... on PostgreSQL). Every
+// query gets a child span of ctx and is logged at debug level.
+type boundDB struct {
+	*sqlx.DB
+}
+
+func (d *boundDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, end := startQuery(ctx, d.DriverName(), query)
+	res, err := d.DB.ExecContext(ctx, d.Rebind(query), args...)
+	end(err)
+	return res, err
+}
+
+func (d *boundDB) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, end := startQuery(ctx, d.DriverName(), query)
+	err := d.DB.GetContext(ctx, dest, d.Rebind(query), args...)
+	end(err)
+	return err
+}
+
+func (d *boundDB) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, end := startQuery(ctx, d.DriverName(), query)
+	err := d.DB.SelectContext(ctx, dest, d.Rebind(query), args...)
+	end(err)
+	return err
+}
+
+func (d *boundDB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*boundTx, error) {
+	tx, err := d.DB.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &boundTx{tx}, nil
+}
+
+// boundTx is boundDB for transactions
+type boundTx struct {
+	*sqlx.Tx
+}
+
+func (t *boundTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, end := startQuery(ctx, t.DriverName(), query)
+	res, err := t.Tx.ExecContext(ctx, t.Rebind(query), args...)
+	end(err)
+	return res, err
+}
+
+func (t *boundTx) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, end := startQuery(ctx, t.DriverName(), query)
+	err := t.Tx.GetContext(ctx, dest, t.Rebind(query), args...)
+	end(err)
+	return err
+}
+
+func (t *boundTx) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	ctx, end := startQuery(ctx, t.DriverName(), query)
+	err := t.Tx.SelectContext(ctx, dest, t.Rebind(query), args...)
+	end(err)
+	return err
+}
+
+var tracer = otel.Tracer("github.com/pikachu0310/senirenol-server/core/internal/repository")
+
+// startQuery starts the span of a query. The returned function ends the span
+// and logs the query; arguments are left out of both as they may hold user data.
+func startQuery(ctx context.Context, driver string, query string) (context.Context, func(error)) {
+	start := time.Now()
+	text := strings.Join(strings.Fields(query), " ")
+	op, _, _ := strings.Cut(text, " ")
+	op = strings.ToUpper(op)
+
+	ctx, span := tracer.Start(ctx, op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			dialect(driver).systemName(),
+			semconv.DBOperationName(op),
+			semconv.DBQueryText(text),
+		),
+	)
+	return ctx, func(err error) {
+		// a missing row is an answer, not a failure
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		if !slog.Default().Enabled(ctx, slog.LevelDebug) {
+			return
+		}
+		attrs := []slog.Attr{
+			slog.String("sql", text),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.Any("error", err))
+		}
+		slog.LogAttrs(ctx, slog.LevelDebug, "query", attrs...)
+	}
+}
+
+// systemName is the db.system.name span attribute of the dialect
+func (d dialect) systemName() attribute.KeyValue {
+	switch d {
+	case dialectMySQL:
+		return semconv.DBSystemNameMySQL
+	case dialectPostgres:
+		return semconv.DBSystemNamePostgreSQL
+	case dialectSQLite:
+		return semconv.DBSystemNameSQLite
+	default:
+		return semconv.DBSystemNameKey.String(string(d))
+	}
+}
+
+// upsertSQL builds an INSERT of cols into table that updates the update
+// columns when a row with the same conflict key already exists.
+func (d dialect) upsertSQL(table string, cols []string, conflict []string, update []string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ")
+	q := "INSERT INTO " + table + " (" + strings.Join(cols, ", ") + ") VALUES (" + placeholders + ")"
+
+	sets := make([]string, len(update))
+	switch d {
+	case dialectMySQL:
+		for i, c := range update {
+			sets[i] = c + " = VALUES(" + c + ")"
+		}
+		return q + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	default:
+		for i, c := range update {
+			sets[i] = c + " = excluded." + c
+		}
+		return q + " ON CONFLICT (" + strings.Join(conflict, ", ") + ") DO UPDATE SET " + strings.Join(sets, ", ")
+	}
 }
 
+// activeScoreCond selects scores that count towards bests, rankings and stats.
+// Queries using it must alias the scores table as "s".
+const activeScoreCond = "s.deleted_at IS NULL AND s.invalidated_at IS NULL"
+
 // InputType is the input device enum
 // 0 = keyboard, 1 = button
 type InputType uint8
@@ -31,3 +194,49 @@ const (
 	DiffLycoris
 	DiffParallel
 )
+
+// Valid reports whether d is a known difficulty
+func (d Difficulty) Valid() bool {
+	return d <= DiffParallel
+}
+
+func (d Difficulty) String() string {
+	switch d {
+	case DiffPast:
+		return "past"
+	case DiffPresent:
+		return "present"
+	case DiffFuture:
+		return "future"
+	case DiffLycoris:
+		return "lycoris"
+	case DiffParallel:
+		return "parallel"
+	}
+	return "unknown"
+}
+
+// ParseDifficulty reads the name returned by String, in any case
+func ParseDifficulty(s string) (Difficulty, bool) {
+	for d := DiffPast; d <= DiffParallel; d++ {
+		if strings.EqualFold(s, d.String()) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// Valid reports whether t is a known input device
+func (t InputType) Valid() bool {
+	return t <= InputButton
+}
+
+func (t InputType) String() string {
+	switch t {
+	case InputKeyboard:
+		return "keyboard"
+	case InputButton:
+		return "button"
+	}
+	return "unknown"
+}
@@ -2,9 +2,14 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/pikachu0310/senirenol-server/core/internal/services/scoring"
 )
 
 type InsertScoreParams struct {
@@ -23,24 +28,61 @@ type InsertScoreParams struct {
 }
 
 type ScoreRow struct {
-	ID                  int64     `db:"id"`
-	UserID              string    `db:"user_id"`
-	BeatmapID           string    `db:"beatmap_id"`
-	Score               int       `db:"score"`
-	MaxCombo            int       `db:"max_combo"`
-	PerfectCriticalFast int       `db:"perfect_critical_fast"`
-	PerfectCriticalLate int       `db:"perfect_critical_late"`
-	PerfectFast         int       `db:"perfect_fast"`
-	PerfectLate         int       `db:"perfect_late"`
-	GoodFast            int       `db:"good_fast"`
-	GoodLate            int       `db:"good_late"`
-	Miss                int       `db:"miss"`
-	Input               InputType `db:"input"`
-	CreatedAt           time.Time `db:"created_at"`
+	ID                  int64      `db:"id"`
+	UserID              string     `db:"user_id"`
+	BeatmapID           string     `db:"beatmap_id"`
+	Score               int        `db:"score"`
+	MaxCombo            int        `db:"max_combo"`
+	PerfectCriticalFast int        `db:"perfect_critical_fast"`
+	PerfectCriticalLate int        `db:"perfect_critical_late"`
+	PerfectFast         int        `db:"perfect_fast"`
+	PerfectLate         int        `db:"perfect_late"`
+	GoodFast            int        `db:"good_fast"`
+	GoodLate            int        `db:"good_late"`
+	Miss                int        `db:"miss"`
+	Input               InputType  `db:"input"`
+	CreatedAt           time.Time  `db:"created_at"`
+	DeletedAt           *time.Time `db:"deleted_at"`
+	InvalidatedAt       *time.Time `db:"invalidated_at"`
+	InvalidationReason  *string    `db:"invalidation_reason"`
 }
 
+// Judgements are the judgement counts of the play
+func (s ScoreRow) Judgements() scoring.Judgements {
+	return scoring.Judgements{
+		PerfectCriticalFast: s.PerfectCriticalFast,
+		PerfectCriticalLate: s.PerfectCriticalLate,
+		PerfectFast:         s.PerfectFast,
+		PerfectLate:         s.PerfectLate,
+		GoodFast:            s.GoodFast,
+		GoodLate:            s.GoodLate,
+		Miss:                s.Miss,
+	}
+}
+
+// scoreColumns selects every ScoreRow column from scores aliased as "s"
+const scoreColumns = `s.id, s.user_id, s.beatmap_id, s.score, s.max_combo,
+        s.perfect_critical_fast, s.perfect_critical_late,
+        s.perfect_fast, s.perfect_late,
+        s.good_fast, s.good_late,
+        s.miss, s.input, s.created_at,
+        s.deleted_at, s.invalidated_at, s.invalidation_reason`
+
+// InsertScore stores a play. It returns ErrUserBanned for banned players,
+// and ErrUserNotFound for the placeholders of deleted accounts.
 func (r *Repository) InsertScore(ctx context.Context, p InsertScoreParams) (int64, error) {
-	res, err := r.db.ExecContext(ctx, `
+	var banned bool
+	if err := r.db.GetContext(ctx, &banned, `SELECT banned_at IS NOT NULL FROM users WHERE id = ? AND deleted_at IS NULL`, p.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUserNotFound
+		}
+		return 0, fmt.Errorf("check ban: %w", err)
+	}
+	if banned {
+		return 0, ErrUserBanned
+	}
+
+	query := `
         INSERT INTO scores (
             user_id, beatmap_id, score, max_combo,
             perfect_critical_fast, perfect_critical_late,
@@ -48,14 +90,38 @@ func (r *Repository) InsertScore(ctx context.Context, p InsertScoreParams) (int6
             good_fast, good_late,
             miss, input
         ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-    `, p.UserID, p.BeatmapID, p.Score, p.MaxCombo, p.PerfectCriticalFast, p.PerfectCriticalLate, p.PerfectFast, p.PerfectLate, p.GoodFast, p.GoodLate, p.Miss, p.Input)
+    `
+	args := []any{p.UserID, p.BeatmapID, p.Score, p.MaxCombo, p.PerfectCriticalFast, p.PerfectCriticalLate, p.PerfectFast, p.PerfectLate, p.GoodFast, p.GoodLate, p.Miss, p.Input}
+
+	// PostgreSQL has no LastInsertId
+	if r.dialect == dialectPostgres {
+		var id int64
+		if err := r.db.GetContext(ctx, &id, query+" RETURNING id", args...); err != nil {
+			return 0, r.insertScoreError(ctx, p, err)
+		}
+		return id, nil
+	}
+
+	res, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
-		return 0, fmt.Errorf("insert score: %w", err)
+		return 0, r.insertScoreError(ctx, p, err)
 	}
 	id, _ := res.LastInsertId()
 	return id, nil
 }
 
+// insertScoreError tells which of the user and the chart is missing when the
+// insert violated a foreign key
+func (r *Repository) insertScoreError(ctx context.Context, p InsertScoreParams, err error) error {
+	if violatedConstraint(err) != constraintForeignKey {
+		return fmt.Errorf("insert score: %w", err)
+	}
+	if _, uerr := r.GetUser(ctx, p.UserID); errors.Is(uerr, ErrUserNotFound) {
+		return ErrUserNotFound
+	}
+	return ErrChartNotFound
+}
+
 type RankingEntry struct {
 	UserID string `db:"user_id" json:"user_id"`
 	Name   string `db:"name" json:"player_name"`
@@ -76,9 +142,8 @@ func (r *Repository) GetChartRanking(ctx context.Context, beatmapID string, limi
 		PlayCount   int `db:"play_count"`
 	}
 	if err := r.db.GetContext(ctx, &counts, `
-        SELECT COUNT(DISTINCT user_id) AS player_count, COUNT(*) AS play_count
-        FROM scores WHERE beatmap_id = ?
-    `, beatmapID); err != nil {
+        SELECT COUNT(DISTINCT s.user_id) AS player_count, COUNT(*) AS play_count
+        FROM scores s WHERE s.beatmap_id = ? AND `+activeScoreCond, beatmapID); err != nil {
 		return nil, fmt.Errorf("count ranking: %w", err)
 	}
 
@@ -87,7 +152,7 @@ func (r *Repository) GetChartRanking(ctx context.Context, beatmapID string, limi
         SELECT s.user_id, u.name, MAX(s.score) AS best_score
         FROM scores s
         JOIN users u ON u.id = s.user_id
-        WHERE s.beatmap_id = ?
+        WHERE s.beatmap_id = ? AND ` + activeScoreCond + `
         GROUP BY s.user_id, u.name
         ORDER BY best_score DESC, u.name ASC
         LIMIT ` + strconv.Itoa(limit)
@@ -132,12 +197,197 @@ func (r *Repository) GetUserStats(ctx context.Context, userID string) (*UserStat
 	var s UserStats
 	if err := r.db.GetContext(ctx, &s, `
         SELECT COUNT(*) AS total_plays,
-               COUNT(DISTINCT beatmap_id) AS distinct_charts,
-               MAX(score) AS best_score,
-               AVG(score) AS avg_score
-        FROM scores WHERE user_id = ?
-    `, userID); err != nil {
+               COUNT(DISTINCT s.beatmap_id) AS distinct_charts,
+               MAX(s.score) AS best_score,
+               AVG(s.score) AS avg_score
+        FROM scores s WHERE s.user_id = ? AND `+activeScoreCond, userID); err != nil {
 		return nil, fmt.Errorf("user stats: %w", err)
 	}
 	return &s, nil
 }
+
+// GetPersonalBest returns the user's best active score on the chart, or nil if they have none
+func (r *Repository) GetPersonalBest(ctx context.Context, userID string, beatmapID string) (*int, error) {
+	var best *int
+	if err := r.db.GetContext(ctx, &best, `
+        SELECT MAX(s.score) FROM scores s
+        WHERE s.user_id = ? AND s.beatmap_id = ? AND `+activeScoreCond, userID, beatmapID); err != nil {
+		return nil, fmt.Errorf("personal best: %w", err)
+	}
+	return best, nil
+}
+
+// PlayRow is a score joined with its player and chart
+type PlayRow struct {
+	ScoreRow
+	PlayerName     string  `db:"player_name"`
+	SongName       string  `db:"song_name"`
+	Difficulty     int     `db:"difficulty"`
+	ParallelString *string `db:"parallel_string"`
+}
+
+type GetRecentPlaysParams struct {
+	BeatmapID string
+	SongName  string
+	UserID    string
+	// BeforeID returns plays older than this score ID. 0 starts from the latest play.
+	BeforeID int64
+	Limit    int
+}
+
+// GetRecentPlays returns active plays, newest first
+func (r *Repository) GetRecentPlays(ctx context.Context, p GetRecentPlaysParams) ([]*PlayRow, error) {
+	conds := []string{activeScoreCond}
+	args := []any{}
+	if p.BeatmapID != "" {
+		conds = append(conds, "s.beatmap_id = ?")
+		args = append(args, p.BeatmapID)
+	}
+	if p.SongName != "" {
+		conds = append(conds, "c.song_name = ?")
+		args = append(args, p.SongName)
+	}
+	if p.UserID != "" {
+		conds = append(conds, "s.user_id = ?")
+		args = append(args, p.UserID)
+	}
+	if p.BeforeID > 0 {
+		conds = append(conds, "s.id < ?")
+		args = append(args, p.BeforeID)
+	}
+	args = append(args, p.Limit)
+
+	var rs []*PlayRow
+	if err := r.db.SelectContext(ctx, &rs, `
+        SELECT `+scoreColumns+`,
+               u.name AS player_name, c.song_name, c.difficulty, c.parallel_string
+        FROM scores s
+        JOIN users u ON u.id = s.user_id
+        JOIN charts c ON c.beatmap_id = s.beatmap_id
+        WHERE `+strings.Join(conds, " AND ")+`
+        ORDER BY s.id DESC
+        LIMIT ?
+    `, args...); err != nil {
+		return nil, fmt.Errorf("recent plays: %w", err)
+	}
+	return rs, nil
+}
+
+// UserScoresSort is the ordering of a user's play history
+type UserScoresSort string
+
+const (
+	SortByDate  UserScoresSort = "date"
+	SortByScore UserScoresSort = "score"
+)
+
+type GetUserScoresParams struct {
+	UserID     string
+	BeatmapID  string
+	Difficulty *Difficulty
+	Input      *InputType
+	// From and To bound created_at as [From, To). Zero values are unbounded.
+	From   time.Time
+	To     time.Time
+	Sort   UserScoresSort
+	Asc    bool
+	Limit  int
+	Offset int
+}
+
+// GetUserScores returns a page of the user's active plays and the total number of matching plays
+func (r *Repository) GetUserScores(ctx context.Context, p GetUserScoresParams) ([]*PlayRow, int, error) {
+	conds := []string{"s.user_id = ?", activeScoreCond}
+	args := []any{p.UserID}
+	if p.BeatmapID != "" {
+		conds = append(conds, "s.beatmap_id = ?")
+		args = append(args, p.BeatmapID)
+	}
+	if p.Difficulty != nil {
+		conds = append(conds, "c.difficulty = ?")
+		args = append(args, *p.Difficulty)
+	}
+	if p.Input != nil {
+		conds = append(conds, "s.input = ?")
+		args = append(args, *p.Input)
+	}
+	if !p.From.IsZero() {
+		conds = append(conds, "s.created_at >= ?")
+		args = append(args, p.From.UTC())
+	}
+	if !p.To.IsZero() {
+		conds = append(conds, "s.created_at < ?")
+		args = append(args, p.To.UTC())
+	}
+	where := strings.Join(conds, " AND ")
+
+	var total int
+	if err := r.db.GetContext(ctx, &total, `
+        SELECT COUNT(*) FROM scores s
+        JOIN charts c ON c.beatmap_id = s.beatmap_id
+        WHERE `+where, args...); err != nil {
+		return nil, 0, fmt.Errorf("count user scores: %w", err)
+	}
+
+	dir := "DESC"
+	if p.Asc {
+		dir = "ASC"
+	}
+	order := "s.created_at " + dir + ", s.id " + dir
+	if p.Sort == SortByScore {
+		order = "s.score " + dir + ", s.id " + dir
+	}
+
+	var rs []*PlayRow
+	if err := r.db.SelectContext(ctx, &rs, `
+        SELECT `+scoreColumns+`,
+               u.name AS player_name, c.song_name, c.difficulty, c.parallel_string
+        FROM scores s
+        JOIN users u ON u.id = s.user_id
+        JOIN charts c ON c.beatmap_id = s.beatmap_id
+        WHERE `+where+`
+        ORDER BY `+order+`
+        LIMIT ? OFFSET ?
+    `, append(args, p.Limit, p.Offset)...); err != nil {
+		return nil, 0, fmt.Errorf("user scores: %w", err)
+	}
+	return rs, total, nil
+}
+
+// GetUserBests returns the user's best active play on each chart they have played.
+// Ties are broken by the earliest play.
+func (r *Repository) GetUserBests(ctx context.Context, userID string) ([]*PlayRow, error) {
+	var rs []*PlayRow
+	if err := r.db.SelectContext(ctx, &rs, `
+        SELECT `+scoreColumns+`,
+               u.name AS player_name, c.song_name, c.difficulty, c.parallel_string
+        FROM (
+            SELECT s.*, ROW_NUMBER() OVER (PARTITION BY s.beatmap_id ORDER BY s.score DESC, s.id ASC) AS rn
+            FROM scores s
+            WHERE s.user_id = ? AND `+activeScoreCond+`
+        ) s
+        JOIN users u ON u.id = s.user_id
+        JOIN charts c ON c.beatmap_id = s.beatmap_id
+        WHERE s.rn = 1
+        ORDER BY c.song_name, c.difficulty, s.beatmap_id
+    `, userID); err != nil {
+		return nil, fmt.Errorf("user bests: %w", err)
+	}
+	return rs, nil
+}
+
+// GetScores returns the scores created, deleted or invalidated within tr,
+// including deleted and invalidated ones, in insertion order
+func (r *Repository) GetScores(ctx context.Context, tr TimeRange) ([]*ScoreRow, error) {
+	cond, args := tr.cond("s.created_at", "s.deleted_at", "s.invalidated_at")
+	var rs []*ScoreRow
+	if err := r.db.SelectContext(ctx, &rs, `
+        SELECT `+scoreColumns+`
+        FROM scores s
+        WHERE `+cond+`
+        ORDER BY s.id
+    `, args...); err != nil {
+		return nil, fmt.Errorf("get scores: %w", err)
+	}
+	return rs, nil
+}
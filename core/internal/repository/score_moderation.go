@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ModerationAction is what a moderator does to a set of scores
+type ModerationAction string
+
+const (
+	// ModerationDelete hides scores from every read (soft delete)
+	ModerationDelete ModerationAction = "delete"
+	// ModerationRestore clears both deletion and invalidation
+	ModerationRestore ModerationAction = "restore"
+	// ModerationInvalidate keeps scores in history but excludes them from bests and rankings
+	ModerationInvalidate ModerationAction = "invalidate"
+)
+
+// ModerateScoresParams selects scores either by ScoreID, or by UserID and/or
+// BeatmapID within [From, To).
+type ModerateScoresParams struct {
+	Action    ModerationAction
+	ScoreID   int64
+	UserID    string
+	BeatmapID string
+	From      time.Time
+	To        time.Time
+	Reason    string
+	Actor     string
+}
+
+// ModerateScores applies the action and records it in audit_logs in the same transaction.
+// It returns ErrScoreNotFound when ScoreID is set and no such score exists.
+func (r *Repository) ModerateScores(ctx context.Context, p ModerateScoresParams) (_ int64, err error) {
+	var (
+		set     string
+		setArgs []any
+		args    []any
+	)
+	conds := []string{}
+	switch p.Action {
+	case ModerationDelete:
+		set = "deleted_at = CURRENT_TIMESTAMP"
+		conds = append(conds, "deleted_at IS NULL")
+	case ModerationRestore:
+		set = "deleted_at = NULL, invalidated_at = NULL, invalidation_reason = NULL"
+		conds = append(conds, "(deleted_at IS NOT NULL OR invalidated_at IS NOT NULL)")
+	case ModerationInvalidate:
+		set = "invalidated_at = CURRENT_TIMESTAMP, invalidation_reason = ?"
+		setArgs = append(setArgs, p.Reason)
+		conds = append(conds, "deleted_at IS NULL", "invalidated_at IS NULL")
+	default:
+		return 0, fmt.Errorf("unknown moderation action: %q", p.Action)
+	}
+
+	var target string
+	if p.ScoreID != 0 {
+		conds = append(conds, "id = ?")
+		args = append(args, p.ScoreID)
+		target = "score:" + strconv.FormatInt(p.ScoreID, 10)
+	} else {
+		if p.UserID == "" && p.BeatmapID == "" {
+			return 0, errors.New("moderate scores: user or beatmap is required")
+		}
+		var ts []string
+		if p.UserID != "" {
+			conds = append(conds, "user_id = ?")
+			args = append(args, p.UserID)
+			ts = append(ts, "user:"+p.UserID)
+		}
+		if p.BeatmapID != "" {
+			conds = append(conds, "beatmap_id = ?")
+			args = append(args, p.BeatmapID)
+			ts = append(ts, "chart:"+p.BeatmapID)
+		}
+		conds = append(conds, "created_at >= ?", "created_at < ?")
+		args = append(args, p.From.UTC(), p.To.UTC())
+		target = strings.Join(ts, ",")
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin moderation: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if p.ScoreID != 0 {
+		var id int64
+		if err := tx.GetContext(ctx, &id, `SELECT id FROM scores WHERE id = ?`, p.ScoreID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, ErrScoreNotFound
+			}
+			return 0, fmt.Errorf("find score: %w", err)
+		}
+	}
+
+	// the achievement counters of the players whose scores change are rebuilt from their history
+	if _, err := tx.ExecContext(ctx, `
+        DELETE FROM achievement_progress
+        WHERE user_id IN (SELECT user_id FROM scores WHERE `+strings.Join(conds, " AND ")+`)
+    `, args...); err != nil {
+		return 0, fmt.Errorf("reset achievement progress: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE scores SET `+set+` WHERE `+strings.Join(conds, " AND "), append(setArgs, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("moderate scores: %w", err)
+	}
+	n, _ := res.RowsAffected()
+
+	detail := map[string]any{"affected": n}
+	if p.ScoreID == 0 {
+		detail["from"] = p.From
+		detail["to"] = p.To
+	}
+	if p.Reason != "" {
+		detail["reason"] = p.Reason
+	}
+	if err := insertAuditLog(ctx, tx, p.Actor, "scores."+string(p.Action), target, detail); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit moderation: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RankingSnapshot is the name of the leaderboard snapshots in the snapshots table
+const RankingSnapshot = "rankings"
+
+// RatingEntry is a player's overall rating
+type RatingEntry struct {
+	Place      int     `db:"place"`
+	UserID     string  `db:"user_id"`
+	PlayerName string  `db:"player_name"`
+	Rating     float64 `db:"rating"`
+	Charts     int     `db:"charts"`
+}
+
+// RebuildRankingSnapshots replaces the chart, song and rating snapshots in one
+// transaction. Chart and song snapshots are built from the active scores;
+// ratings are computed by the caller and must already be ordered by place.
+func (r *Repository) RebuildRankingSnapshots(ctx context.Context, ratings []RatingEntry, at time.Time) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin rebuild snapshots: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	for _, t := range []string{"chart_ranking_snapshots", "chart_stats_snapshots", "song_playcount_snapshots", "rating_snapshots"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+t); err != nil {
+			return fmt.Errorf("clear %s: %w", t, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO chart_ranking_snapshots (beatmap_id, place, user_id, player_name, best_score)
+        SELECT b.beatmap_id,
+               ROW_NUMBER() OVER (PARTITION BY b.beatmap_id ORDER BY b.best_score DESC, u.name ASC, b.user_id ASC),
+               b.user_id, u.name, b.best_score
+        FROM (
+            SELECT s.beatmap_id, s.user_id, MAX(s.score) AS best_score
+            FROM scores s
+            WHERE `+activeScoreCond+`
+            GROUP BY s.beatmap_id, s.user_id
+        ) b
+        JOIN users u ON u.id = b.user_id
+    `); err != nil {
+		return fmt.Errorf("snapshot chart rankings: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO chart_stats_snapshots (beatmap_id, player_count, play_count)
+        SELECT c.beatmap_id, COUNT(DISTINCT s.user_id), COUNT(s.id)
+        FROM charts c
+        LEFT JOIN scores s ON s.beatmap_id = c.beatmap_id AND `+activeScoreCond+`
+        GROUP BY c.beatmap_id
+    `); err != nil {
+		return fmt.Errorf("snapshot chart stats: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO song_playcount_snapshots (song_name, place, play_count)
+        SELECT c.song_name, ROW_NUMBER() OVER (ORDER BY COUNT(*) DESC, c.song_name ASC), COUNT(*)
+        FROM scores s
+        JOIN charts c ON c.beatmap_id = s.beatmap_id
+        WHERE `+activeScoreCond+`
+        GROUP BY c.song_name
+    `); err != nil {
+		return fmt.Errorf("snapshot song playcounts: %w", err)
+	}
+
+	for _, e := range ratings {
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO rating_snapshots (user_id, place, player_name, rating, charts) VALUES (?, ?, ?, ?, ?)
+        `, e.UserID, e.Place, e.PlayerName, e.Rating, e.Charts); err != nil {
+			return fmt.Errorf("snapshot rating: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM snapshots WHERE name = ?`, RankingSnapshot); err != nil {
+		return fmt.Errorf("clear snapshot time: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO snapshots (name, taken_at) VALUES (?, ?)`, RankingSnapshot, at.UTC()); err != nil {
+		return fmt.Errorf("record snapshot time: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit rebuild snapshots: %w", err)
+	}
+	return nil
+}
+
+// GetSnapshotTime returns when the named snapshot was taken, or nil if it has never been built
+func (r *Repository) GetSnapshotTime(ctx context.Context, name string) (*time.Time, error) {
+	var ts []time.Time
+	if err := r.db.SelectContext(ctx, &ts, `SELECT taken_at FROM snapshots WHERE name = ?`, name); err != nil {
+		return nil, fmt.Errorf("snapshot time: %w", err)
+	}
+	if len(ts) == 0 {
+		return nil, nil
+	}
+	return &ts[0], nil
+}
+
+// chartStatsSnapshot is a chart_stats_snapshots row
+type chartStatsSnapshot struct {
+	BeatmapID   string `db:"beatmap_id"`
+	PlayerCount int    `db:"player_count"`
+	PlayCount   int    `db:"play_count"`
+}
+
+// GetChartRankingSnapshot returns the snapshotted ranking of the chart, or nil
+// if the chart was not in the last snapshot.
+func (r *Repository) GetChartRankingSnapshot(ctx context.Context, beatmapID string, limit int) (*ChartRanking, error) {
+	var stats []chartStatsSnapshot
+	if err := r.db.SelectContext(ctx, &stats, `
+        SELECT beatmap_id, player_count, play_count FROM chart_stats_snapshots WHERE beatmap_id = ?
+    `, beatmapID); err != nil {
+		return nil, fmt.Errorf("chart stats snapshot: %w", err)
+	}
+	if len(stats) == 0 {
+		return nil, nil
+	}
+
+	var top []RankingEntry
+	if err := r.db.SelectContext(ctx, &top, `
+        SELECT user_id, player_name AS name, best_score
+        FROM chart_ranking_snapshots
+        WHERE beatmap_id = ? AND place <= ?
+        ORDER BY place
+    `, beatmapID, limit); err != nil {
+		return nil, fmt.Errorf("chart ranking snapshot: %w", err)
+	}
+
+	return &ChartRanking{
+		BeatmapID:   beatmapID,
+		PlayerCount: stats[0].PlayerCount,
+		PlayCount:   stats[0].PlayCount,
+		Top:         top,
+	}, nil
+}
+
+// GetAllChartRankingSnapshots is GetAllChartsRankings served from the last snapshot
+func (r *Repository) GetAllChartRankingSnapshots(ctx context.Context, limit int) ([]*ChartRanking, error) {
+	var stats []chartStatsSnapshot
+	if err := r.db.SelectContext(ctx, &stats, `
+        SELECT cs.beatmap_id, cs.player_count, cs.play_count
+        FROM chart_stats_snapshots cs
+        JOIN charts c ON c.beatmap_id = cs.beatmap_id
+        ORDER BY c.song_name, c.difficulty, c.beatmap_id
+    `); err != nil {
+		return nil, fmt.Errorf("chart stats snapshots: %w", err)
+	}
+
+	var rows []struct {
+		BeatmapID string `db:"beatmap_id"`
+		RankingEntry
+	}
+	if err := r.db.SelectContext(ctx, &rows, `
+        SELECT beatmap_id, user_id, player_name AS name, best_score
+        FROM chart_ranking_snapshots
+        WHERE place <= ?
+        ORDER BY beatmap_id, place
+    `, limit); err != nil {
+		return nil, fmt.Errorf("chart ranking snapshots: %w", err)
+	}
+	tops := make(map[string][]RankingEntry)
+	for _, row := range rows {
+		tops[row.BeatmapID] = append(tops[row.BeatmapID], row.RankingEntry)
+	}
+
+	res := make([]*ChartRanking, len(stats))
+	for i, s := range stats {
+		res[i] = &ChartRanking{
+			BeatmapID:   s.BeatmapID,
+			PlayerCount: s.PlayerCount,
+			PlayCount:   s.PlayCount,
+			Top:         tops[s.BeatmapID],
+		}
+	}
+	return res, nil
+}
+
+func (r *Repository) GetSongPlaycountSnapshot(ctx context.Context) ([]*SongPlayCount, error) {
+	var rs []*SongPlayCount
+	if err := r.db.SelectContext(ctx, &rs, `
+        SELECT song_name, play_count FROM song_playcount_snapshots ORDER BY place
+    `); err != nil {
+		return nil, fmt.Errorf("song playcount snapshot: %w", err)
+	}
+	return rs, nil
+}
+
+func (r *Repository) GetRatingSnapshot(ctx context.Context, limit int) ([]*RatingEntry, error) {
+	var rs []*RatingEntry
+	if err := r.db.SelectContext(ctx, &rs, `
+        SELECT place, user_id, player_name, rating, charts
+        FROM rating_snapshots
+        ORDER BY place
+        LIMIT ?
+    `, limit); err != nil {
+		return nil, fmt.Errorf("rating snapshot: %w", err)
+	}
+	return rs, nil
+}
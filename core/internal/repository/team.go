@@ -0,0 +1,414 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TeamRole is what a member may do in their team
+type TeamRole string
+
+const (
+	// TeamOwner manages roles and may delete the team. Every team has exactly one.
+	TeamOwner TeamRole = "owner"
+	// TeamAdmin replaces the invite code and removes members
+	TeamAdmin  TeamRole = "admin"
+	TeamMember TeamRole = "member"
+)
+
+func (r TeamRole) Valid() bool {
+	switch r {
+	case TeamOwner, TeamAdmin, TeamMember:
+		return true
+	}
+	return false
+}
+
+// Outranks reports whether r may manage members with the role o
+func (r TeamRole) Outranks(o TeamRole) bool {
+	return r.rank() > o.rank()
+}
+
+func (r TeamRole) rank() int {
+	switch r {
+	case TeamOwner:
+		return 2
+	case TeamAdmin:
+		return 1
+	}
+	return 0
+}
+
+// InviteCodeLength is the number of characters of a team invite code
+const InviteCodeLength = 8
+
+// inviteCodeLetters leaves out characters that are easily confused
+const inviteCodeLetters = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func newInviteCode() (string, error) {
+	b := make([]byte, InviteCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("invite code: %w", err)
+	}
+	for i := range b {
+		b[i] = inviteCodeLetters[int(b[i])%len(inviteCodeLetters)]
+	}
+	return string(b), nil
+}
+
+type Team struct {
+	ID          string    `db:"id"`
+	Name        string    `db:"name"`
+	InviteCode  string    `db:"invite_code"`
+	CreatedAt   time.Time `db:"created_at"`
+	MemberCount int       `db:"member_count"`
+}
+
+// UserTeam is a team with the role the player has in it
+type UserTeam struct {
+	Team
+	Role     TeamRole  `db:"role"`
+	JoinedAt time.Time `db:"joined_at"`
+}
+
+type TeamMemberRow struct {
+	UserID   string    `db:"user_id"`
+	Name     string    `db:"name"`
+	Role     TeamRole  `db:"role"`
+	JoinedAt time.Time `db:"joined_at"`
+}
+
+const teamColumns = `t.id, t.name, t.invite_code, t.created_at,
+               (SELECT COUNT(*) FROM team_members tm WHERE tm.team_id = t.id) AS member_count`
+
+// CreateTeam creates a team owned by ownerID. It returns ErrDuplicate when
+// the name is taken.
+func (r *Repository) CreateTeam(ctx context.Context, name string, ownerID string) (_ *Team, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin create team: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	code, err := newInviteCode()
+	if err != nil {
+		return nil, err
+	}
+	id := uuid.NewString()
+	if _, err := tx.ExecContext(ctx, "INSERT INTO teams (id, name, invite_code) VALUES (?, ?, ?)", id, name, code); err != nil {
+		if violatedConstraint(err) == constraintUnique {
+			return nil, ErrDuplicate
+		}
+		return nil, fmt.Errorf("insert team: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO team_members (team_id, user_id, role) VALUES (?, ?, ?)", id, ownerID, TeamOwner); err != nil {
+		if violatedConstraint(err) == constraintForeignKey {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("insert team owner: %w", err)
+	}
+
+	t := &Team{}
+	if err := tx.GetContext(ctx, t, "SELECT "+teamColumns+" FROM teams t WHERE t.id = ?", id); err != nil {
+		return nil, fmt.Errorf("select team: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit create team: %w", err)
+	}
+	return t, nil
+}
+
+func (r *Repository) GetTeam(ctx context.Context, teamID string) (*Team, error) {
+	t := &Team{}
+	if err := r.db.GetContext(ctx, t, "SELECT "+teamColumns+" FROM teams t WHERE t.id = ?", teamID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTeamNotFound
+		}
+		return nil, fmt.Errorf("select team: %w", err)
+	}
+	return t, nil
+}
+
+// GetTeams returns every team by name
+func (r *Repository) GetTeams(ctx context.Context) ([]*Team, error) {
+	var ts []*Team
+	if err := r.db.SelectContext(ctx, &ts, "SELECT "+teamColumns+" FROM teams t ORDER BY t.name, t.id"); err != nil {
+		return nil, fmt.Errorf("get teams: %w", err)
+	}
+	return ts, nil
+}
+
+// GetUserTeams returns the teams the player belongs to by name
+func (r *Repository) GetUserTeams(ctx context.Context, userID string) ([]*UserTeam, error) {
+	var ts []*UserTeam
+	if err := r.db.SelectContext(ctx, &ts, `
+        SELECT `+teamColumns+`, m.role, m.joined_at
+        FROM teams t
+        JOIN team_members m ON m.team_id = t.id
+        WHERE m.user_id = ?
+        ORDER BY t.name, t.id
+    `, userID); err != nil {
+		return nil, fmt.Errorf("get user teams: %w", err)
+	}
+	return ts, nil
+}
+
+// GetTeamMembers returns the members of the team by role, then by name
+func (r *Repository) GetTeamMembers(ctx context.Context, teamID string) ([]*TeamMemberRow, error) {
+	var ms []*TeamMemberRow
+	if err := r.db.SelectContext(ctx, &ms, `
+        SELECT m.user_id, u.name, m.role, m.joined_at
+        FROM team_members m
+        JOIN users u ON u.id = m.user_id
+        WHERE m.team_id = ?
+        ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, u.name, m.user_id
+    `, teamID); err != nil {
+		return nil, fmt.Errorf("get team members: %w", err)
+	}
+	return ms, nil
+}
+
+// GetTeamRole returns the role of the player in the team, or
+// ErrTeamMemberNotFound when they are not a member
+func (r *Repository) GetTeamRole(ctx context.Context, teamID string, userID string) (TeamRole, error) {
+	var role TeamRole
+	if err := r.db.GetContext(ctx, &role, "SELECT role FROM team_members WHERE team_id = ? AND user_id = ?", teamID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrTeamMemberNotFound
+		}
+		return "", fmt.Errorf("select team role: %w", err)
+	}
+	return role, nil
+}
+
+// JoinTeam adds the player to the team with the invite code as a member. It
+// returns ErrTeamNotFound for an unknown code and ErrDuplicate when they
+// already belong to the team.
+func (r *Repository) JoinTeam(ctx context.Context, inviteCode string, userID string) (*Team, error) {
+	var teamID string
+	if err := r.db.GetContext(ctx, &teamID, "SELECT id FROM teams WHERE invite_code = ?", inviteCode); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTeamNotFound
+		}
+		return nil, fmt.Errorf("select team by invite code: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, "INSERT INTO team_members (team_id, user_id, role) VALUES (?, ?, ?)", teamID, userID, TeamMember); err != nil {
+		switch violatedConstraint(err) {
+		case constraintUnique:
+			return nil, ErrDuplicate
+		case constraintForeignKey:
+			return nil, ErrTeamNotFound
+		}
+		return nil, fmt.Errorf("join team: %w", err)
+	}
+	return r.GetTeam(ctx, teamID)
+}
+
+// ResetInviteCode replaces the invite code of the team, so the old one no
+// longer works, and returns the new one
+func (r *Repository) ResetInviteCode(ctx context.Context, teamID string) (string, error) {
+	code, err := newInviteCode()
+	if err != nil {
+		return "", err
+	}
+	res, err := r.db.ExecContext(ctx, "UPDATE teams SET invite_code = ? WHERE id = ?", code, teamID)
+	if err != nil {
+		return "", fmt.Errorf("reset invite code: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", ErrTeamNotFound
+	}
+	return code, nil
+}
+
+// SetTeamRole changes the role of a member. Making someone the owner hands
+// the team over: the previous owner becomes an admin.
+func (r *Repository) SetTeamRole(ctx context.Context, teamID string, userID string, role TeamRole) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin set team role: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var current TeamRole
+	if err := tx.GetContext(ctx, &current, "SELECT role FROM team_members WHERE team_id = ? AND user_id = ?", teamID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTeamMemberNotFound
+		}
+		return fmt.Errorf("select team role: %w", err)
+	}
+	if current == TeamOwner && role != TeamOwner {
+		// the owner only steps down by handing the team over
+		return ErrTeamOwner
+	}
+	if role == TeamOwner {
+		if _, err := tx.ExecContext(ctx, "UPDATE team_members SET role = ? WHERE team_id = ? AND role = ?", TeamAdmin, teamID, TeamOwner); err != nil {
+			return fmt.Errorf("demote team owner: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE team_members SET role = ? WHERE team_id = ? AND user_id = ?", role, teamID, userID); err != nil {
+		return fmt.Errorf("set team role: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit set team role: %w", err)
+	}
+	return nil
+}
+
+// RemoveTeamMember removes the player from the team. When the owner leaves,
+// the longest-serving admin, or else member, becomes the owner; the team is
+// deleted with its last member.
+func (r *Repository) RemoveTeamMember(ctx context.Context, teamID string, userID string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin remove team member: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM team_members WHERE team_id = ? AND user_id = ?", teamID, userID)
+	if err != nil {
+		return fmt.Errorf("remove team member: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTeamMemberNotFound
+	}
+	if err := handOverTeam(ctx, tx, teamID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit remove team member: %w", err)
+	}
+	return nil
+}
+
+// handOverTeam gives a team left without an owner to its longest-serving
+// admin or member, or deletes it when nobody is left
+func handOverTeam(ctx context.Context, tx *boundTx, teamID string) error {
+	var owners int
+	if err := tx.GetContext(ctx, &owners, "SELECT COUNT(*) FROM team_members WHERE team_id = ? AND role = ?", teamID, TeamOwner); err != nil {
+		return fmt.Errorf("count team owners: %w", err)
+	}
+	if owners > 0 {
+		return nil
+	}
+	var next string
+	err := tx.GetContext(ctx, &next, `
+        SELECT user_id FROM team_members
+        WHERE team_id = ?
+        ORDER BY CASE role WHEN 'admin' THEN 0 ELSE 1 END, joined_at, user_id
+        LIMIT 1
+    `, teamID)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM teams WHERE id = ?", teamID); err != nil {
+			return fmt.Errorf("delete empty team: %w", err)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("select next team owner: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE team_members SET role = ? WHERE team_id = ? AND user_id = ?", TeamOwner, teamID, next); err != nil {
+		return fmt.Errorf("hand over team: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) DeleteTeam(ctx context.Context, teamID string) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM teams WHERE id = ?", teamID)
+	if err != nil {
+		return fmt.Errorf("delete team: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTeamNotFound
+	}
+	return nil
+}
+
+// TeamTotal sums the personal bests of the current members of a team
+type TeamTotal struct {
+	TeamID string `db:"team_id"`
+	Name   string `db:"name"`
+	// Players is the number of members with a score
+	Players    int `db:"players"`
+	Charts     int `db:"charts"`
+	TotalScore int `db:"total_score"`
+	PlayCount  int `db:"play_count"`
+}
+
+// TeamTotalsParams selects the scores GetTeamTotals adds up
+type TeamTotalsParams struct {
+	// BeatmapID restricts the totals to one chart; empty means every chart
+	BeatmapID string
+	// Range restricts the scores to those played within it
+	Range TimeRange
+	// TeamIDs restricts the totals to these teams; empty means every team
+	TeamIDs []string
+	Limit   int
+}
+
+// GetTeamTotals ranks teams by the sum, over their members and charts, of
+// each member's best score on each chart. Teams without a matching score are
+// left out.
+func (r *Repository) GetTeamTotals(ctx context.Context, p TeamTotalsParams) ([]*TeamTotal, error) {
+	where, args := p.Range.cond("s.created_at")
+	where += " AND " + activeScoreCond
+	if p.BeatmapID != "" {
+		where += " AND s.beatmap_id = ?"
+		args = append(args, p.BeatmapID)
+	}
+	teams := ""
+	if len(p.TeamIDs) > 0 {
+		teams = "WHERE t.id IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(p.TeamIDs)), ", ") + ")"
+		for _, id := range p.TeamIDs {
+			args = append(args, id)
+		}
+	}
+	limit := ""
+	if p.Limit > 0 {
+		limit = "LIMIT " + strconv.Itoa(p.Limit)
+	}
+
+	var ts []*TeamTotal
+	if err := r.db.SelectContext(ctx, &ts, `
+        SELECT t.id AS team_id, t.name,
+               COUNT(DISTINCT b.user_id) AS players,
+               COUNT(DISTINCT b.beatmap_id) AS charts,
+               SUM(b.best) AS total_score,
+               SUM(b.plays) AS play_count
+        FROM teams t
+        JOIN team_members m ON m.team_id = t.id
+        JOIN (
+            SELECT s.user_id, s.beatmap_id, MAX(s.score) AS best, COUNT(*) AS plays
+            FROM scores s
+            WHERE `+where+`
+            GROUP BY s.user_id, s.beatmap_id
+        ) b ON b.user_id = m.user_id
+        `+teams+`
+        GROUP BY t.id, t.name
+        ORDER BY total_score DESC, t.name ASC
+        `+limit, args...); err != nil {
+		return nil, fmt.Errorf("team totals: %w", err)
+	}
+	return ts, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math/rand"
 	"time"
@@ -17,9 +18,30 @@ type (
 		Name      string    `db:"name"`
 		CreatedAt time.Time `db:"created_at"`
 		UpdatedAt time.Time `db:"updated_at"`
+		// BannedAt is set while the player is banned from submitting scores
+		BannedAt  *time.Time `db:"banned_at"`
+		BanReason *string    `db:"ban_reason"`
+		// TokenHash authenticates the player; see auth.Hash. Nil before a token is issued.
+		TokenHash *string `db:"token_hash"`
+		// DeletedAt is set on the anonymous placeholder left by a deleted account
+		DeletedAt *time.Time `db:"deleted_at"`
+		// FriendCode is stored the first time the player asks for it
+		FriendCode *string `db:"friend_code"`
+	}
+
+	// user_names table
+	UserName struct {
+		Name      string    `db:"name"`
+		CreatedAt time.Time `db:"created_at"`
 	}
 )
 
+// DeletedUserName is the name of the placeholder that keeps the scores of a deleted account
+const DeletedUserName = "Deleted player"
+
+// userColumns selects every User column
+const userColumns = "id, name, created_at, updated_at, banned_at, ban_reason, token_hash, deleted_at, friend_code"
+
 func randomDefaultName() string {
 	const letters = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
 	b := make([]byte, 6)
@@ -29,32 +51,178 @@ func randomDefaultName() string {
 	return "Player-" + string(b)
 }
 
-func (r *Repository) CreateUser(ctx context.Context) (uuid.UUID, error) {
+// CreateUser registers a player under a default name. tokenHash, when not
+// empty, is the hash of the token that authenticates them.
+func (r *Repository) CreateUser(ctx context.Context, tokenHash string) (_ uuid.UUID, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("begin create user: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
 	userID := uuid.New()
 	name := randomDefaultName()
-	if _, err := r.db.ExecContext(ctx, "INSERT INTO users (id, name) VALUES (?, ?)", userID.String(), name); err != nil {
+	var hash *string
+	if tokenHash != "" {
+		hash = &tokenHash
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO users (id, name, token_hash) VALUES (?, ?, ?)", userID.String(), name, hash); err != nil {
 		return uuid.Nil, fmt.Errorf("insert user: %w", err)
 	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO user_names (user_id, name) VALUES (?, ?)", userID.String(), name); err != nil {
+		return uuid.Nil, fmt.Errorf("insert user name: %w", err)
+	}
 
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, fmt.Errorf("commit create user: %w", err)
+	}
 	return userID, nil
 }
 
-func (r *Repository) UpdateUserName(ctx context.Context, userID string, name string) error {
-	res, err := r.db.ExecContext(ctx, "UPDATE users SET name = ? WHERE id = ?", name, userID)
+// UpdateUserName renames the player and records the name in user_names
+func (r *Repository) UpdateUserName(ctx context.Context, userID string, name string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin update user: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, "UPDATE users SET name = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", name, userID)
 	if err != nil {
 		return fmt.Errorf("update user: %w", err)
 	}
 	n, _ := res.RowsAffected()
 	if n == 0 {
-		return sql.ErrNoRows
+		return ErrUserNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO user_names (user_id, name) VALUES (?, ?)", userID, name); err != nil {
+		return fmt.Errorf("insert user name: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit update user: %w", err)
 	}
 	return nil
 }
 
 func (r *Repository) GetUser(ctx context.Context, userID string) (*User, error) {
 	user := &User{}
-	if err := r.db.GetContext(ctx, user, "SELECT id, name, created_at, updated_at FROM users WHERE id = ?", userID); err != nil {
+	if err := r.db.GetContext(ctx, user, "SELECT "+userColumns+" FROM users WHERE id = ?", userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
 		return nil, fmt.Errorf("select user: %w", err)
 	}
 	return user, nil
 }
+
+// GetUsers returns the players changed within tr, oldest first
+func (r *Repository) GetUsers(ctx context.Context, tr TimeRange) ([]*User, error) {
+	cond, args := tr.cond("updated_at")
+	var us []*User
+	if err := r.db.SelectContext(ctx, &us, `
+        SELECT `+userColumns+`
+        FROM users
+        WHERE `+cond+`
+        ORDER BY created_at, id
+    `, args...); err != nil {
+		return nil, fmt.Errorf("get users: %w", err)
+	}
+	return us, nil
+}
+
+type BanUserParams struct {
+	UserID string
+	Reason string
+	Actor  string
+}
+
+// BanUser stops the player from submitting scores and invalidates their
+// active scores so that they leave every ranking. It is recorded in
+// audit_logs in the same transaction and returns the number of scores
+// invalidated.
+func (r *Repository) BanUser(ctx context.Context, p BanUserParams) (_ int64, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin ban: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE users SET banned_at = CURRENT_TIMESTAMP, ban_reason = ?, updated_at = CURRENT_TIMESTAMP
+        WHERE id = ?
+    `, p.Reason, p.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("ban user: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, ErrUserNotFound
+	}
+
+	res, err = tx.ExecContext(ctx, `
+        UPDATE scores SET invalidated_at = CURRENT_TIMESTAMP, invalidation_reason = ?
+        WHERE user_id = ? AND deleted_at IS NULL AND invalidated_at IS NULL
+    `, p.Reason, p.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("invalidate scores of banned user: %w", err)
+	}
+	n, _ := res.RowsAffected()
+
+	detail := map[string]any{"invalidated": n}
+	if p.Reason != "" {
+		detail["reason"] = p.Reason
+	}
+	if err := insertAuditLog(ctx, tx, p.Actor, "users.ban", "user:"+p.UserID, detail); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit ban: %w", err)
+	}
+	return n, nil
+}
+
+// UnbanUser lets the player submit scores again. Scores invalidated by the
+// ban stay invalidated until restored by moderation.
+func (r *Repository) UnbanUser(ctx context.Context, userID string, actor string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin unban: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE users SET banned_at = NULL, ban_reason = NULL, updated_at = CURRENT_TIMESTAMP
+        WHERE id = ?
+    `, userID)
+	if err != nil {
+		return fmt.Errorf("unban user: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	if err := insertAuditLog(ctx, tx, actor, "users.unban", "user:"+userID, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit unban: %w", err)
+	}
+	return nil
+}
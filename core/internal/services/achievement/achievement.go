@@ -0,0 +1,212 @@
+// Package achievement unlocks the achievements declared in a YAML file from
+// the plays of each player.
+package achievement
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/scoring"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed definitions.yaml
+var defaultDefinitions []byte
+
+// Per is what a condition counts
+type Per string
+
+const (
+	// PerPlay counts every matching play
+	PerPlay Per = "play"
+	// PerChart counts the distinct charts of the matching plays
+	PerChart Per = "chart"
+)
+
+// Condition selects the plays that count towards an achievement. Filters
+// left empty match every play.
+type Condition struct {
+	// Count is the number of plays, or charts with PerChart, needed. Defaults to 1.
+	Count int `yaml:"count"`
+	// Per defaults to PerPlay
+	Per Per `yaml:"per"`
+	// Difficulty is a difficulty name such as "future"
+	Difficulty string `yaml:"difficulty"`
+	// Input is "keyboard" or "button"
+	Input string `yaml:"input"`
+	// Lamp is the minimum lamp, such as "full_combo"
+	Lamp string `yaml:"lamp"`
+	// Grade is the minimum grade, such as "S"
+	Grade    string `yaml:"grade"`
+	MinScore int    `yaml:"min_score"`
+
+	difficulty *repository.Difficulty
+	input      *repository.InputType
+	lamp       scoring.Lamp
+	grade      scoring.Grade
+}
+
+// Definition is an achievement and the condition that unlocks it
+type Definition struct {
+	// ID is stored with every unlock
+	ID          string    `yaml:"id"`
+	Name        string    `yaml:"name"`
+	Description string    `yaml:"description"`
+	Condition   Condition `yaml:"condition"`
+}
+
+var idPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Load reads and validates a YAML list of definitions. Errors are keyed by
+// the position of the definition, such as "achievements[2]".
+func Load(r io.Reader) ([]Definition, error) {
+	var defs []Definition
+	if err := yaml.NewDecoder(r).Decode(&defs); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	errs := vd.Errors{}
+	seen := make(map[string]bool, len(defs))
+	for i := range defs {
+		d := &defs[i]
+		key := fmt.Sprintf("achievements[%d]", i)
+		if err := vd.ValidateStruct(d,
+			vd.Field(&d.ID, vd.Required, vd.RuneLength(1, 64), vd.Match(idPattern)),
+			vd.Field(&d.Name, vd.Required, vd.RuneLength(1, 64)),
+			vd.Field(&d.Description, vd.RuneLength(0, 255)),
+		); err != nil {
+			errs[key] = err
+			continue
+		}
+		if err := d.Condition.Compile(); err != nil {
+			errs[key] = vd.Errors{"condition": err}
+			continue
+		}
+		if seen[d.ID] {
+			errs[key] = vd.Errors{"id": fmt.Errorf("duplicates %q", d.ID)}
+		}
+		seen[d.ID] = true
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return defs, nil
+}
+
+// Default returns the definitions shipped with the server
+func Default() []Definition {
+	defs, err := Load(bytes.NewReader(defaultDefinitions))
+	if err != nil {
+		panic(fmt.Sprintf("default achievements: %v", err))
+	}
+	return defs
+}
+
+// Compile fills in the defaults of c and resolves its names. Errors are keyed by field.
+func (c *Condition) Compile() error {
+	errs := vd.Errors{}
+	switch {
+	case c.Count < 0:
+		errs["count"] = errors.New("must be no less than 0")
+	case c.Count == 0:
+		c.Count = 1
+	}
+	switch c.Per {
+	case "":
+		c.Per = PerPlay
+	case PerPlay, PerChart:
+	default:
+		errs["per"] = fmt.Errorf("must be %q or %q", PerPlay, PerChart)
+	}
+	if c.Difficulty != "" {
+		if d, ok := repository.ParseDifficulty(c.Difficulty); ok {
+			c.difficulty = &d
+		} else {
+			errs["difficulty"] = fmt.Errorf("unknown difficulty %q", c.Difficulty)
+		}
+	}
+	if c.Input != "" {
+		if t, ok := parseInput(c.Input); ok {
+			c.input = &t
+		} else {
+			errs["input"] = fmt.Errorf("unknown input %q", c.Input)
+		}
+	}
+	if c.Lamp != "" {
+		if c.lamp = find(scoring.Lamps, c.Lamp); c.lamp == "" {
+			errs["lamp"] = fmt.Errorf("unknown lamp %q", c.Lamp)
+		}
+	}
+	if c.Grade != "" {
+		if c.grade = find(scoring.Grades, c.Grade); c.grade == "" {
+			errs["grade"] = fmt.Errorf("unknown grade %q", c.Grade)
+		}
+	}
+	if c.MinScore < 0 {
+		errs["min_score"] = errors.New("must be no less than 0")
+	}
+	return errs.Filter()
+}
+
+func parseInput(s string) (repository.InputType, bool) {
+	for t := repository.InputKeyboard; t.Valid(); t++ {
+		if strings.EqualFold(s, t.String()) {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// find returns the value of vs named s in any case, or "" if there is none
+func find[T ~string](vs []T, s string) T {
+	for _, v := range vs {
+		if strings.EqualFold(s, string(v)) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Matches reports whether p passes every filter of c
+func (c *Condition) Matches(p *repository.PlayRow) bool {
+	if c.difficulty != nil && repository.Difficulty(p.Difficulty) != *c.difficulty {
+		return false
+	}
+	if c.input != nil && p.Input != *c.input {
+		return false
+	}
+	if c.lamp != "" && !scoring.LampOf(p.Score, p.Judgements()).AtLeast(c.lamp) {
+		return false
+	}
+	if c.grade != "" && !scoring.GradeOf(p.Score).AtLeast(c.grade) {
+		return false
+	}
+	return p.Score >= c.MinScore
+}
+
+// progress counts the plays, oldest first, towards c up to Count. met is
+// the play that reached Count, or nil while c is not met.
+func (c *Condition) progress(plays []*repository.PlayRow) (n int, met *repository.PlayRow) {
+	seen := make(map[string]bool)
+	for _, p := range plays {
+		if !c.Matches(p) {
+			continue
+		}
+		if c.Per == PerChart {
+			if seen[p.BeatmapID] {
+				continue
+			}
+			seen[p.BeatmapID] = true
+		}
+		if n++; n == c.Count {
+			return n, p
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,125 @@
+package achievement
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+)
+
+func TestDefault(t *testing.T) {
+	t.Parallel()
+	defs := Default()
+	if len(defs) == 0 {
+		t.Fatal("no default achievements")
+	}
+	for _, d := range defs {
+		if d.Condition.Count < 1 || d.Condition.Per == "" {
+			t.Errorf("%s: defaults not filled in: %+v", d.ID, d.Condition)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Parallel()
+	_, err := Load(strings.NewReader(`
+- id: ok
+  name: OK
+- id: Bad ID
+  name: Bad
+- id: bad_condition
+  name: Bad condition
+  condition:
+    per: song
+    difficulty: hard
+    lamp: perfect
+    grade: SS
+- id: ok
+  name: Again
+`))
+	var errs vd.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got %v, want vd.Errors", err)
+	}
+	if len(errs) != 3 {
+		t.Errorf("got %v, want errors for 3 definitions", errs)
+	}
+	cond, ok := errs["achievements[2]"].(vd.Errors)["condition"].(vd.Errors)
+	if !ok || len(cond) != 4 {
+		t.Errorf("condition errors: %v", errs["achievements[2]"])
+	}
+	if _, ok := errs["achievements[3]"].(vd.Errors)["id"]; !ok {
+		t.Errorf("duplicate id: %v", errs["achievements[3]"])
+	}
+}
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+	defs, err := Load(strings.NewReader(`
+- id: future_fc_2
+  name: Future FC
+  condition: {count: 2, per: chart, difficulty: future, lamp: full_combo}
+- id: button_3
+  name: Buttons
+  condition: {count: 3, input: button}
+- id: lycoris_ap
+  name: Lycoris AP
+  condition: {difficulty: Lycoris, lamp: ALL_PERFECT}
+- id: grade_s
+  name: S
+  condition: {grade: s}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	play := func(i int, beatmapID string, d repository.Difficulty, input repository.InputType, score, good, miss int) *repository.PlayRow {
+		return &repository.PlayRow{
+			ScoreRow: repository.ScoreRow{
+				BeatmapID: beatmapID, Score: score, GoodFast: good, Miss: miss,
+				Input: input, CreatedAt: base.Add(time.Duration(i) * time.Hour),
+			},
+			Difficulty: int(d),
+		}
+	}
+	plays := []*repository.PlayRow{
+		play(0, "a_future", repository.DiffFuture, repository.InputButton, 900000, 1, 0),
+		play(1, "a_future", repository.DiffFuture, repository.InputKeyboard, 960000, 0, 0),
+		play(2, "b_future", repository.DiffFuture, repository.InputButton, 800000, 0, 1),
+		play(3, "a_lycoris", repository.DiffLycoris, repository.InputButton, 990000, 2, 0),
+		play(4, "b_future", repository.DiffFuture, repository.InputKeyboard, 850000, 3, 0),
+	}
+
+	for _, tc := range []struct {
+		id  string
+		n   int
+		met int // index of the play that met the condition, -1 if unmet
+	}{
+		// the same chart counts once
+		{id: "future_fc_2", n: 2, met: 4},
+		{id: "button_3", n: 3, met: 3},
+		// a full combo is not an all perfect
+		{id: "lycoris_ap", n: 0, met: -1},
+		{id: "grade_s", n: 1, met: 1},
+	} {
+		var d Definition
+		for _, x := range defs {
+			if x.ID == tc.id {
+				d = x
+			}
+		}
+		n, met := d.Condition.progress(plays)
+		if n != tc.n {
+			t.Errorf("%s: progress %d, want %d", tc.id, n, tc.n)
+		}
+		switch {
+		case tc.met < 0 && met != nil:
+			t.Errorf("%s: met by %+v", tc.id, met)
+		case tc.met >= 0 && met != plays[tc.met]:
+			t.Errorf("%s: met by %+v, want play %d", tc.id, met, tc.met)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package achievement
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+)
+
+// Status is how far a player is towards an achievement
+type Status struct {
+	Definition
+	// Progress counts towards Condition.Count
+	Progress int
+	// UnlockedAt is nil while the achievement is locked
+	UnlockedAt *time.Time
+}
+
+type Service struct {
+	repo *repository.Repository
+	defs []Definition
+}
+
+func New(repo *repository.Repository, defs []Definition) *Service {
+	return &Service{repo: repo, defs: defs}
+}
+
+// Definitions returns every achievement in the order of the definition file
+func (s *Service) Definitions() []Definition {
+	return s.defs
+}
+
+// Evaluate unlocks the achievements whose conditions the active plays of the
+// user meet, dated by the play that met them, and returns them. Unlocks are
+// kept when the plays are later deleted. It counts the whole history and
+// stores the counters that Record continues from.
+func (s *Service) Evaluate(ctx context.Context, userID string) ([]Status, error) {
+	unlocked, err := s.unlocked(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	pending := s.pending(unlocked)
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	plays, err := s.repo.GetUserPlays(ctx, repository.GetUserPlaysParams{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		news     []Status
+		unlocks  []repository.UserAchievement
+		counters = make(map[string]int, len(pending))
+	)
+	for _, d := range pending {
+		n, met := d.Condition.progress(plays)
+		if met == nil {
+			counters[d.ID] = n
+			continue
+		}
+		at := met.CreatedAt
+		news = append(news, Status{Definition: d, Progress: n, UnlockedAt: &at})
+		unlocks = append(unlocks, repository.UserAchievement{AchievementID: d.ID, UnlockedAt: at})
+	}
+	if err := s.repo.SetAchievementProgress(ctx, userID, counters); err != nil {
+		return nil, err
+	}
+	if len(unlocks) == 0 {
+		return nil, nil
+	}
+	if _, err := s.repo.UnlockAchievements(ctx, userID, unlocks); err != nil {
+		return nil, err
+	}
+	return news, nil
+}
+
+// Record counts a play that was just stored towards the achievements its
+// player has not unlocked, and unlocks and returns those it completes. Only
+// the stored counters and the plays of the same chart are read; the whole
+// history is counted again by Evaluate when a counter is missing, such as
+// after moderation or for a newly defined achievement.
+func (s *Service) Record(ctx context.Context, p *repository.PlayRow) ([]Status, error) {
+	unlocked, err := s.unlocked(ctx, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	pending := s.pending(unlocked)
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	counters, err := s.repo.GetAchievementProgress(ctx, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		matched    []Definition
+		chartPlays []*repository.PlayRow
+	)
+	for _, d := range pending {
+		if _, ok := counters[d.ID]; !ok {
+			return s.Evaluate(ctx, p.UserID)
+		}
+		if !d.Condition.Matches(p) {
+			continue
+		}
+		if d.Condition.Per == PerChart {
+			if chartPlays == nil {
+				if chartPlays, err = s.repo.GetUserPlays(ctx, repository.GetUserPlaysParams{UserID: p.UserID, BeatmapID: p.BeatmapID}); err != nil {
+					return nil, err
+				}
+			}
+			// the chart counted already
+			if slices.ContainsFunc(chartPlays, func(q *repository.PlayRow) bool { return q.ID != p.ID && d.Condition.Matches(q) }) {
+				continue
+			}
+		}
+		matched = append(matched, d)
+	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, len(matched))
+	for i, d := range matched {
+		ids[i] = d.ID
+	}
+	counters, err = s.repo.AddAchievementProgress(ctx, p.UserID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		news    []Status
+		unlocks []repository.UserAchievement
+	)
+	for _, d := range matched {
+		n, ok := counters[d.ID]
+		if !ok {
+			// reset by moderation meanwhile
+			return s.Evaluate(ctx, p.UserID)
+		}
+		if n < d.Condition.Count {
+			continue
+		}
+		at := p.CreatedAt
+		news = append(news, Status{Definition: d, Progress: d.Condition.Count, UnlockedAt: &at})
+		unlocks = append(unlocks, repository.UserAchievement{AchievementID: d.ID, UnlockedAt: at})
+	}
+	if len(unlocks) == 0 {
+		return nil, nil
+	}
+	if _, err := s.repo.UnlockAchievements(ctx, p.UserID, unlocks); err != nil {
+		return nil, err
+	}
+	return news, nil
+}
+
+// Statuses returns the status of every achievement for the user, in the
+// order of the definition file
+func (s *Service) Statuses(ctx context.Context, userID string) ([]Status, error) {
+	unlocked, err := s.unlocked(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	plays, err := s.repo.GetUserPlays(ctx, repository.GetUserPlaysParams{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	ss := make([]Status, len(s.defs))
+	for i, d := range s.defs {
+		ss[i] = Status{Definition: d}
+		if at, ok := unlocked[d.ID]; ok {
+			ss[i].Progress = d.Condition.Count
+			ss[i].UnlockedAt = &at
+			continue
+		}
+		ss[i].Progress, _ = d.Condition.progress(plays)
+	}
+	return ss, nil
+}
+
+// Unlocked returns the achievements the user has unlocked, oldest first.
+// Unlocks of achievements no longer defined are left out.
+func (s *Service) Unlocked(ctx context.Context, userID string) ([]Status, error) {
+	as, err := s.repo.GetUserAchievements(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]Definition, len(s.defs))
+	for _, d := range s.defs {
+		byID[d.ID] = d
+	}
+	ss := make([]Status, 0, len(as))
+	for _, a := range as {
+		d, ok := byID[a.AchievementID]
+		if !ok {
+			continue
+		}
+		at := a.UnlockedAt
+		ss = append(ss, Status{Definition: d, Progress: d.Condition.Count, UnlockedAt: &at})
+	}
+	return ss, nil
+}
+
+// Backfill evaluates every player, such as after adding achievements. It
+// returns the number of players evaluated and of achievements unlocked.
+func (s *Service) Backfill(ctx context.Context) (players int, unlocks int, err error) {
+	us, err := s.repo.GetUsers(ctx, repository.TimeRange{})
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, u := range us {
+		if u.DeletedAt != nil {
+			continue
+		}
+		news, err := s.Evaluate(ctx, u.ID)
+		if err != nil {
+			return players, unlocks, err
+		}
+		players++
+		unlocks += len(news)
+	}
+	return players, unlocks, nil
+}
+
+// pending returns the definitions not in unlocked
+func (s *Service) pending(unlocked map[string]time.Time) []Definition {
+	var ds []Definition
+	for _, d := range s.defs {
+		if _, ok := unlocked[d.ID]; !ok {
+			ds = append(ds, d)
+		}
+	}
+	return ds
+}
+
+// unlocked returns when the user unlocked each achievement
+func (s *Service) unlocked(ctx context.Context, userID string) (map[string]time.Time, error) {
+	as, err := s.repo.GetUserAchievements(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]time.Time, len(as))
+	for _, a := range as {
+		m[a.AchievementID] = a.UnlockedAt
+	}
+	return m, nil
+}
@@ -0,0 +1,161 @@
+// Package analytics aggregates play results into player and chart statistics.
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core/internal/services/scoring"
+)
+
+// Play is a single play result
+type Play struct {
+	ID         int64
+	UserID     string
+	Score      int
+	Judgements scoring.Judgements
+	Input      uint8
+	PlayedAt   time.Time
+}
+
+// JudgementAverages is the mean count of each judgement per play
+type JudgementAverages struct {
+	PerfectCriticalFast float64
+	PerfectCriticalLate float64
+	PerfectFast         float64
+	PerfectLate         float64
+	GoodFast            float64
+	GoodLate            float64
+	Miss                float64
+}
+
+// TrendPoint summarises one play of a trend series
+type TrendPoint struct {
+	ScoreID   int64
+	PlayedAt  time.Time
+	Score     int
+	Fast      int
+	Late      int
+	Miss      int
+	MissRate  float64
+	FastRatio *float64
+}
+
+// PlayerChart is a player's judgement statistics on one chart
+type PlayerChart struct {
+	Plays        int
+	AverageScore float64
+	Average      JudgementAverages
+	Fast         int
+	Late         int
+	// FastRatio is Fast / (Fast + Late), nil without hits
+	FastRatio *float64
+	// MissRate is misses per judged note
+	MissRate float64
+	// MeanTimingErrorMs is the estimated average timing error, negative
+	// meaning early. nil without hits.
+	MeanTimingErrorMs *float64
+	// RecommendedOffsetMs is the judgement offset that would centre the
+	// player's hits (positive: the player hits late, so judge later). nil
+	// until MinHitsForOffset hits have been observed.
+	RecommendedOffsetMs *int
+	// ScoreSlope and MissRateSlope are least-squares slopes per play over the series
+	ScoreSlope    float64
+	MissRateSlope float64
+	Trend         []TrendPoint
+}
+
+// MinHitsForOffset is the number of FAST/LATE hits needed before an offset is recommended
+const MinHitsForOffset = 50
+
+// AnalysePlayerChart aggregates plays, which must be ordered oldest first
+func AnalysePlayerChart(plays []Play) PlayerChart {
+	res := PlayerChart{Plays: len(plays), Trend: make([]TrendPoint, len(plays))}
+	if len(plays) == 0 {
+		return res
+	}
+
+	var (
+		total     scoring.Judgements
+		scoreSum  int
+		scores    = make([]float64, len(plays))
+		missRates = make([]float64, len(plays))
+	)
+	for i, p := range plays {
+		j := p.Judgements
+		total = total.Add(j)
+		scoreSum += p.Score
+		scores[i] = float64(p.Score)
+		missRates[i] = ratio(j.Miss, j.Notes())
+		res.Trend[i] = TrendPoint{
+			ScoreID:   p.ID,
+			PlayedAt:  p.PlayedAt,
+			Score:     p.Score,
+			Fast:      j.Fast(),
+			Late:      j.Late(),
+			Miss:      j.Miss,
+			MissRate:  missRates[i],
+			FastRatio: fastRatio(j),
+		}
+	}
+
+	n := float64(len(plays))
+	res.AverageScore = float64(scoreSum) / n
+	res.Average = JudgementAverages{
+		PerfectCriticalFast: float64(total.PerfectCriticalFast) / n,
+		PerfectCriticalLate: float64(total.PerfectCriticalLate) / n,
+		PerfectFast:         float64(total.PerfectFast) / n,
+		PerfectLate:         float64(total.PerfectLate) / n,
+		GoodFast:            float64(total.GoodFast) / n,
+		GoodLate:            float64(total.GoodLate) / n,
+		Miss:                float64(total.Miss) / n,
+	}
+	res.Fast = total.Fast()
+	res.Late = total.Late()
+	res.FastRatio = fastRatio(total)
+	res.MissRate = ratio(total.Miss, total.Notes())
+	if ms, ok := total.MeanTimingErrorMs(); ok {
+		res.MeanTimingErrorMs = &ms
+		if res.Fast+res.Late >= MinHitsForOffset {
+			offset := int(math.Round(ms))
+			res.RecommendedOffsetMs = &offset
+		}
+	}
+	res.ScoreSlope = slope(scores)
+	res.MissRateSlope = slope(missRates)
+
+	return res
+}
+
+func fastRatio(j scoring.Judgements) *float64 {
+	hits := j.Fast() + j.Late()
+	if hits == 0 {
+		return nil
+	}
+	r := ratio(j.Fast(), hits)
+	return &r
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// slope fits y = a + b*x over x = 0, 1, ... and returns b
+func slope(ys []float64) float64 {
+	n := float64(len(ys))
+	if n < 2 {
+		return 0
+	}
+	var sx, sy, sxy, sxx float64
+	for i, y := range ys {
+		x := float64(i)
+		sx += x
+		sy += y
+		sxy += x * y
+		sxx += x * x
+	}
+	return (n*sxy - sx*sy) / (n*sxx - sx*sx)
+}
@@ -0,0 +1,98 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core/internal/services/scoring"
+)
+
+func TestAnalysePlayerChart(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no plays", func(t *testing.T) {
+		t.Parallel()
+		a := AnalysePlayerChart(nil)
+		if a.Plays != 0 || a.FastRatio != nil || a.RecommendedOffsetMs != nil {
+			t.Fatalf("unexpected analytics for no plays: %+v", a)
+		}
+	})
+
+	t.Run("late player", func(t *testing.T) {
+		t.Parallel()
+		plays := []Play{
+			{ID: 1, Score: 900000, Judgements: scoring.Judgements{PerfectCriticalLate: 40, PerfectLate: 20, GoodLate: 10, Miss: 10}},
+			{ID: 2, Score: 950000, Judgements: scoring.Judgements{PerfectCriticalLate: 60, PerfectLate: 10, GoodFast: 5, Miss: 5}},
+		}
+		a := AnalysePlayerChart(plays)
+		if a.Plays != 2 || a.AverageScore != 925000 {
+			t.Fatalf("unexpected averages: %+v", a)
+		}
+		if a.Fast != 5 || a.Late != 140 {
+			t.Fatalf("fast/late = %d/%d", a.Fast, a.Late)
+		}
+		if a.MissRate != 15.0/160.0 {
+			t.Fatalf("miss rate = %v", a.MissRate)
+		}
+		if a.RecommendedOffsetMs == nil || *a.RecommendedOffsetMs <= 0 {
+			t.Fatalf("expected a positive offset for a late player, got %v", a.RecommendedOffsetMs)
+		}
+		if a.ScoreSlope != 50000 {
+			t.Fatalf("score slope = %v", a.ScoreSlope)
+		}
+		if a.MissRateSlope >= 0 {
+			t.Fatalf("miss rate slope = %v", a.MissRateSlope)
+		}
+	})
+
+	t.Run("too few hits for an offset", func(t *testing.T) {
+		t.Parallel()
+		a := AnalysePlayerChart([]Play{{Score: 1000000, Judgements: scoring.Judgements{PerfectCriticalFast: 3}}})
+		if a.MeanTimingErrorMs == nil || *a.MeanTimingErrorMs >= 0 {
+			t.Fatalf("expected an early timing error, got %v", a.MeanTimingErrorMs)
+		}
+		if a.RecommendedOffsetMs != nil {
+			t.Fatalf("expected no offset, got %d", *a.RecommendedOffsetMs)
+		}
+	})
+}
+
+func TestAnalyseChart(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	fc := scoring.Judgements{PerfectCriticalFast: 100, GoodLate: 2}
+	missed := scoring.Judgements{PerfectCriticalFast: 90, Miss: 12}
+	plays := []Play{
+		{UserID: "a", Score: 650000, Judgements: missed, Input: 0, PlayedAt: now.AddDate(0, 0, -1)},
+		{UserID: "a", Score: 960000, Judgements: fc, Input: 0, PlayedAt: now},
+		{UserID: "b", Score: 820000, Judgements: missed, Input: 1, PlayedAt: now},
+		{UserID: "c", Score: 700000, Judgements: missed, Input: 1, PlayedAt: now.AddDate(0, 0, -30)},
+	}
+	a := AnalyseChart(plays, now, 7)
+
+	if a.Plays != 4 || a.Players != 3 {
+		t.Fatalf("plays/players = %d/%d", a.Plays, a.Players)
+	}
+	if a.ClearRate != 0.75 || a.PlayerClearRate != 1 {
+		t.Fatalf("clear rates = %v/%v", a.ClearRate, a.PlayerClearRate)
+	}
+	if a.Median == nil || *a.Median != 820000 {
+		t.Fatalf("median = %v", a.Median)
+	}
+	if a.Grades[scoring.GradeS] != 1 || a.Grades[scoring.GradeB] != 1 || a.Grades[scoring.GradeC] != 1 {
+		t.Fatalf("grades = %v", a.Grades)
+	}
+	if a.Lamps[scoring.LampFullCombo] != 1 || a.Lamps[scoring.LampClear] != 2 {
+		t.Fatalf("lamps = %v", a.Lamps)
+	}
+	if got := a.ScoreHistogram[960000/HistogramBucketWidth].Count; got != 1 {
+		t.Fatalf("histogram bucket = %d", got)
+	}
+	if a.Inputs[0].Plays != 2 || a.Inputs[1].AverageScore != 760000 {
+		t.Fatalf("inputs = %+v", a.Inputs)
+	}
+	if len(a.PlaysOverTime) != 7 || a.PlaysOverTime[6].Plays != 2 || a.PlaysOverTime[5].Plays != 1 {
+		t.Fatalf("plays over time = %+v", a.PlaysOverTime)
+	}
+}
@@ -0,0 +1,168 @@
+package analytics
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core/internal/services/scoring"
+)
+
+// HistogramBucketWidth is the score range covered by each histogram bucket
+const HistogramBucketWidth = 50000
+
+// Percentiles reported by AnalyseChart
+var Percentiles = []int{10, 25, 50, 75, 90}
+
+// HistogramBucket counts scores in [Min, Max]
+type HistogramBucket struct {
+	Min   int
+	Max   int
+	Count int
+}
+
+// InputSplit summarises plays made with one input device
+type InputSplit struct {
+	Input        uint8
+	Plays        int
+	AverageScore float64
+}
+
+// DailyPlays counts plays on one UTC day
+type DailyPlays struct {
+	Date  time.Time
+	Plays int
+}
+
+// Chart is the score distribution of a chart. Score distributions use each
+// player's best score, so that frequent players don't skew them.
+type Chart struct {
+	Plays   int
+	Players int
+	// ScoreHistogram, Grades, Lamps and Percentiles describe player bests
+	ScoreHistogram []HistogramBucket
+	Grades         map[scoring.Grade]int
+	Lamps          map[scoring.Lamp]int
+	Median         *float64
+	Percentiles    map[int]float64
+	// ClearRate is cleared plays / plays
+	ClearRate float64
+	// PlayerClearRate is players with at least one clear / players
+	PlayerClearRate float64
+	Inputs          []InputSplit
+	// PlaysOverTime covers the days days up to and including now, oldest first
+	PlaysOverTime []DailyPlays
+}
+
+// AnalyseChart aggregates every play of a chart
+func AnalyseChart(plays []Play, now time.Time, days int) Chart {
+	type best struct {
+		score   int
+		lamp    scoring.Lamp
+		cleared bool
+	}
+	bests := map[string]*best{}
+	clears := 0
+	inputPlays := map[uint8]int{}
+	inputScores := map[uint8]int{}
+
+	today := truncateDay(now)
+	first := today.AddDate(0, 0, -(days - 1))
+	daily := make([]DailyPlays, days)
+	for i := range daily {
+		daily[i].Date = first.AddDate(0, 0, i)
+	}
+
+	for _, p := range plays {
+		lamp := scoring.LampOf(p.Score, p.Judgements)
+		if lamp.IsClear() {
+			clears++
+		}
+		b, ok := bests[p.UserID]
+		if !ok {
+			b = &best{score: p.Score, lamp: lamp}
+			bests[p.UserID] = b
+		}
+		b.score = max(b.score, p.Score)
+		if lamp.AtLeast(b.lamp) {
+			b.lamp = lamp
+		}
+		b.cleared = b.cleared || lamp.IsClear()
+
+		inputPlays[p.Input]++
+		inputScores[p.Input] += p.Score
+
+		if d := truncateDay(p.PlayedAt); !d.Before(first) && !d.After(today) {
+			daily[int(d.Sub(first).Hours()/24)].Plays++
+		}
+	}
+
+	res := Chart{
+		Plays:          len(plays),
+		Players:        len(bests),
+		ScoreHistogram: make([]HistogramBucket, scoring.MaxScore/HistogramBucketWidth),
+		Grades:         map[scoring.Grade]int{},
+		Lamps:          map[scoring.Lamp]int{},
+		Percentiles:    map[int]float64{},
+		PlaysOverTime:  daily,
+	}
+	for i := range res.ScoreHistogram {
+		res.ScoreHistogram[i] = HistogramBucket{Min: i * HistogramBucketWidth, Max: (i+1)*HistogramBucketWidth - 1}
+	}
+	res.ScoreHistogram[len(res.ScoreHistogram)-1].Max = scoring.MaxScore
+	for _, g := range scoring.Grades {
+		res.Grades[g] = 0
+	}
+	for _, l := range scoring.Lamps {
+		res.Lamps[l] = 0
+	}
+
+	scores := make([]float64, 0, len(bests))
+	playerClears := 0
+	for _, b := range bests {
+		i := min(max(b.score, 0)/HistogramBucketWidth, len(res.ScoreHistogram)-1)
+		res.ScoreHistogram[i].Count++
+		res.Grades[scoring.GradeOf(b.score)]++
+		res.Lamps[b.lamp]++
+		if b.cleared {
+			playerClears++
+		}
+		scores = append(scores, float64(b.score))
+	}
+	slices.Sort(scores)
+	if len(scores) > 0 {
+		for _, p := range Percentiles {
+			res.Percentiles[p] = percentile(scores, float64(p))
+		}
+		m := res.Percentiles[50]
+		res.Median = &m
+	}
+	res.ClearRate = ratio(clears, len(plays))
+	res.PlayerClearRate = ratio(playerClears, len(bests))
+
+	for _, in := range []uint8{0, 1} {
+		split := InputSplit{Input: in, Plays: inputPlays[in]}
+		if split.Plays > 0 {
+			split.AverageScore = float64(inputScores[in]) / float64(split.Plays)
+		}
+		res.Inputs = append(res.Inputs, split)
+	}
+
+	return res
+}
+
+// percentile interpolates linearly between the closest ranks of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
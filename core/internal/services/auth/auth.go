@@ -0,0 +1,30 @@
+// Package auth issues the bearer tokens that authenticate players.
+// Only the hash of a token is stored, so a leaked database does not leak tokens.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// tokenBytes is the entropy of a token
+const tokenBytes = 32
+
+// NewToken returns a random token and its hash
+func NewToken() (token string, hash string, err error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, Hash(token), nil
+}
+
+// Hash returns the hex encoded SHA-256 of token, as stored in users.token_hash.
+// Tokens are random, so they need no salt or slow hash.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,25 @@
+package auth
+
+import "testing"
+
+func TestNewToken(t *testing.T) {
+	t.Parallel()
+
+	token, hash, err := NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token) != 43 || len(hash) != 64 {
+		t.Errorf("token %q, hash %q", token, hash)
+	}
+	if Hash(token) != hash {
+		t.Errorf("Hash(token) = %s, want %s", Hash(token), hash)
+	}
+	other, _, err := NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == token {
+		t.Error("two tokens are equal")
+	}
+}
@@ -0,0 +1,314 @@
+// Package backup exports users, charts and scores as a versioned logical dump
+// and imports such dumps, to back up a database or move it between environments.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+)
+
+// Kind identifies the files of this package in their manifest
+const Kind = "senirenol-export"
+
+// Version is the version of the dump format written by Export. Import reads
+// dumps up to this version; the format only changes in ways older readers reject.
+const Version = 1
+
+// Manifest describes a dump. It comes first in every format.
+type Manifest struct {
+	Kind    string `json:"kind"`
+	Version int    `json:"version"`
+	// SchemaVersion is the migration version of the exporting database, for reference
+	SchemaVersion int64     `json:"schema_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+	// From and To bound an incremental dump; see Options
+	From   *time.Time     `json:"from,omitempty"`
+	To     *time.Time     `json:"to,omitempty"`
+	Counts map[string]int `json:"counts"`
+}
+
+// ErrUnsupported is returned for a file that is not a dump, or a dump of a newer version
+var ErrUnsupported = errors.New("unsupported dump")
+
+// Dump is the content of an export
+type Dump struct {
+	Manifest Manifest
+	Users    []User
+	Charts   []Chart
+	Scores   []Score
+}
+
+type User struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	BannedAt  *time.Time `json:"banned_at,omitempty"`
+	BanReason *string    `json:"ban_reason,omitempty"`
+	// TokenHash keeps the player signed in after a move between environments
+	TokenHash  *string    `json:"token_hash,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	FriendCode *string    `json:"friend_code,omitempty"`
+}
+
+type Chart struct {
+	BeatmapID      string    `json:"beatmap_id"`
+	SongName       string    `json:"song_name"`
+	Difficulty     int       `json:"difficulty"`
+	ParallelString *string   `json:"parallel_string,omitempty"`
+	NoteCount      *int      `json:"note_count,omitempty"`
+	Constant       *float64  `json:"constant,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Score is a play. ID is the ID in the exporting database; imported scores get new IDs.
+type Score struct {
+	ID                  int64      `json:"id"`
+	UserID              string     `json:"user_id"`
+	BeatmapID           string     `json:"beatmap_id"`
+	Score               int        `json:"score"`
+	MaxCombo            int        `json:"max_combo"`
+	PerfectCriticalFast int        `json:"perfect_critical_fast"`
+	PerfectCriticalLate int        `json:"perfect_critical_late"`
+	PerfectFast         int        `json:"perfect_fast"`
+	PerfectLate         int        `json:"perfect_late"`
+	GoodFast            int        `json:"good_fast"`
+	GoodLate            int        `json:"good_late"`
+	Miss                int        `json:"miss"`
+	Input               uint8      `json:"input"`
+	CreatedAt           time.Time  `json:"created_at"`
+	DeletedAt           *time.Time `json:"deleted_at,omitempty"`
+	InvalidatedAt       *time.Time `json:"invalidated_at,omitempty"`
+	InvalidationReason  *string    `json:"invalidation_reason,omitempty"`
+}
+
+// SchemaVersion returns the migration version of the database
+type SchemaVersion func(ctx context.Context) (int64, error)
+
+type Service struct {
+	repo   *repository.Repository
+	schema SchemaVersion
+	now    func() time.Time
+}
+
+func New(repo *repository.Repository, schema SchemaVersion) *Service {
+	return &Service{repo: repo, schema: schema, now: time.Now}
+}
+
+// Options select what Export writes. A zero bound leaves that side open.
+//
+// An incremental dump, with From or To set, holds the users changed and the
+// scores created, deleted or invalidated within [From, To), and every chart.
+// Its scores may refer to users exported earlier.
+type Options struct {
+	From time.Time
+	To   time.Time
+}
+
+// Export reads every row selected by opts
+func (s *Service) Export(ctx context.Context, opts Options) (*Dump, error) {
+	schema, err := s.schema(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("schema version: %w", err)
+	}
+	tr := repository.TimeRange{From: opts.From, To: opts.To}
+	d := &Dump{Manifest: Manifest{
+		Kind:          Kind,
+		Version:       Version,
+		SchemaVersion: schema,
+		ExportedAt:    s.now().UTC(),
+		From:          utcOrNil(opts.From),
+		To:            utcOrNil(opts.To),
+	}}
+
+	users, err := s.repo.GetUsers(ctx, tr)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		d.Users = append(d.Users, User{
+			ID:         u.ID,
+			Name:       u.Name,
+			CreatedAt:  u.CreatedAt,
+			UpdatedAt:  u.UpdatedAt,
+			BannedAt:   u.BannedAt,
+			BanReason:  u.BanReason,
+			TokenHash:  u.TokenHash,
+			DeletedAt:  u.DeletedAt,
+			FriendCode: u.FriendCode,
+		})
+	}
+
+	charts, err := s.repo.GetCharts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range charts {
+		d.Charts = append(d.Charts, Chart{
+			BeatmapID:      c.BeatmapID,
+			SongName:       c.SongName,
+			Difficulty:     c.Difficulty,
+			ParallelString: c.ParallelString,
+			NoteCount:      c.NoteCount,
+			Constant:       c.Constant,
+			CreatedAt:      c.CreatedAt,
+		})
+	}
+
+	scores, err := s.repo.GetScores(ctx, tr)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range scores {
+		d.Scores = append(d.Scores, Score{
+			ID:                  r.ID,
+			UserID:              r.UserID,
+			BeatmapID:           r.BeatmapID,
+			Score:               r.Score,
+			MaxCombo:            r.MaxCombo,
+			PerfectCriticalFast: r.PerfectCriticalFast,
+			PerfectCriticalLate: r.PerfectCriticalLate,
+			PerfectFast:         r.PerfectFast,
+			PerfectLate:         r.PerfectLate,
+			GoodFast:            r.GoodFast,
+			GoodLate:            r.GoodLate,
+			Miss:                r.Miss,
+			Input:               uint8(r.Input),
+			CreatedAt:           r.CreatedAt,
+			DeletedAt:           r.DeletedAt,
+			InvalidatedAt:       r.InvalidatedAt,
+			InvalidationReason:  r.InvalidationReason,
+		})
+	}
+
+	d.Manifest.Counts = map[string]int{"users": len(d.Users), "charts": len(d.Charts), "scores": len(d.Scores)}
+	return d, nil
+}
+
+// maxReferenceErrors bounds the rows reported by Validate
+const maxReferenceErrors = 20
+
+// Validate checks that every score refers to a user and a chart, either in
+// the dump or already in the database. The errors are keyed by the position
+// of the score, such as "scores[3]", and list at most the first 20 rows.
+func (s *Service) Validate(ctx context.Context, d *Dump) error {
+	users := make(map[string]bool, len(d.Users))
+	for _, u := range d.Users {
+		users[u.ID] = true
+	}
+	charts := make(map[string]bool, len(d.Charts))
+	for _, c := range d.Charts {
+		charts[c.BeatmapID] = true
+	}
+
+	var userIDs, beatmapIDs []string
+	for _, sc := range d.Scores {
+		if _, ok := users[sc.UserID]; !ok {
+			users[sc.UserID] = true
+			userIDs = append(userIDs, sc.UserID)
+		}
+		if _, ok := charts[sc.BeatmapID]; !ok {
+			charts[sc.BeatmapID] = true
+			beatmapIDs = append(beatmapIDs, sc.BeatmapID)
+		}
+	}
+	missingUsers, err := s.repo.MissingUserIDs(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	for _, id := range missingUsers {
+		users[id] = false
+	}
+	missingCharts, err := s.repo.MissingBeatmapIDs(ctx, beatmapIDs)
+	if err != nil {
+		return err
+	}
+	for _, id := range missingCharts {
+		charts[id] = false
+	}
+
+	errs := vd.Errors{}
+	for i, sc := range d.Scores {
+		if len(errs) == maxReferenceErrors {
+			break
+		}
+		row := vd.Errors{}
+		if !users[sc.UserID] {
+			row["user_id"] = fmt.Errorf("refers to unknown user %q", sc.UserID)
+		}
+		if !charts[sc.BeatmapID] {
+			row["beatmap_id"] = fmt.Errorf("refers to unknown chart %q", sc.BeatmapID)
+		}
+		if len(row) > 0 {
+			errs[fmt.Sprintf("scores[%d]", i)] = row
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Import writes a validated dump in one transaction. actor is recorded in audit_logs.
+func (s *Service) Import(ctx context.Context, d *Dump, actor string) (*repository.ImportDataResult, error) {
+	p := repository.ImportDataParams{Actor: actor}
+	for _, u := range d.Users {
+		p.Users = append(p.Users, &repository.User{
+			ID:         u.ID,
+			Name:       u.Name,
+			CreatedAt:  u.CreatedAt,
+			UpdatedAt:  u.UpdatedAt,
+			BannedAt:   u.BannedAt,
+			BanReason:  u.BanReason,
+			TokenHash:  u.TokenHash,
+			DeletedAt:  u.DeletedAt,
+			FriendCode: u.FriendCode,
+		})
+	}
+	for _, c := range d.Charts {
+		p.Charts = append(p.Charts, &repository.Chart{
+			BeatmapID:      c.BeatmapID,
+			SongName:       c.SongName,
+			Difficulty:     c.Difficulty,
+			ParallelString: c.ParallelString,
+			NoteCount:      c.NoteCount,
+			Constant:       c.Constant,
+			CreatedAt:      c.CreatedAt,
+		})
+	}
+	for _, sc := range d.Scores {
+		p.Scores = append(p.Scores, &repository.ScoreRow{
+			ID:                  sc.ID,
+			UserID:              sc.UserID,
+			BeatmapID:           sc.BeatmapID,
+			Score:               sc.Score,
+			MaxCombo:            sc.MaxCombo,
+			PerfectCriticalFast: sc.PerfectCriticalFast,
+			PerfectCriticalLate: sc.PerfectCriticalLate,
+			PerfectFast:         sc.PerfectFast,
+			PerfectLate:         sc.PerfectLate,
+			GoodFast:            sc.GoodFast,
+			GoodLate:            sc.GoodLate,
+			Miss:                sc.Miss,
+			Input:               repository.InputType(sc.Input),
+			CreatedAt:           sc.CreatedAt,
+			DeletedAt:           sc.DeletedAt,
+			InvalidatedAt:       sc.InvalidatedAt,
+			InvalidationReason:  sc.InvalidationReason,
+		})
+	}
+	return s.repo.ImportData(ctx, p)
+}
+
+func utcOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
@@ -0,0 +1,310 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Format is the file format of a dump
+type Format string
+
+const (
+	// FormatJSONL writes one JSON object per line: the manifest, then every
+	// row as {"table": ..., "row": ...}. Rows are written users first, then
+	// charts, then scores, so that a row only refers to rows before it.
+	FormatJSONL Format = "jsonl"
+	// FormatTar writes a gzipped tar of manifest.json and one JSON Lines file
+	// of rows per table, such as users.jsonl.
+	FormatTar Format = "tar"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// ParseFormat parses the name of a format. "tgz" and "tar.gz" mean FormatTar.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "jsonl", "ndjson":
+		return FormatJSONL, nil
+	case "tar", "tgz", "tar.gz":
+		return FormatTar, nil
+	}
+	return "", fmt.Errorf("%w %q: want jsonl or tar", ErrUnknownFormat, s)
+}
+
+// FormatOf tells the format of a file from its extension
+func FormatOf(name string) (Format, error) {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return FormatTar, nil
+	case path.Ext(name) == ".jsonl", path.Ext(name) == ".ndjson":
+		return FormatJSONL, nil
+	}
+	return "", fmt.Errorf("%w: cannot tell the format of %q", ErrUnknownFormat, name)
+}
+
+func (f Format) ContentType() string {
+	if f == FormatTar {
+		return "application/gzip"
+	}
+	return "application/x-ndjson"
+}
+
+// FileName is the conventional name of a dump in the format
+func (f Format) FileName() string {
+	if f == FormatTar {
+		return "senirenol-export.tar.gz"
+	}
+	return "senirenol-export.jsonl"
+}
+
+// tables in the order they are written and read
+var tables = []string{"users", "charts", "scores"}
+
+const manifestTable = "manifest"
+
+// record is a line of a JSON Lines dump
+type record struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+// Encode writes d in the format
+func Encode(w io.Writer, f Format, d *Dump) error {
+	switch f {
+	case FormatJSONL:
+		return encodeJSONL(w, d)
+	case FormatTar:
+		return encodeTar(w, d)
+	}
+	return fmt.Errorf("%w %q", ErrUnknownFormat, f)
+}
+
+// rows iterates over the rows of table
+func (d *Dump) rows(table string) func(yield func(any) bool) {
+	return func(yield func(any) bool) {
+		switch table {
+		case "users":
+			for _, r := range d.Users {
+				if !yield(r) {
+					return
+				}
+			}
+		case "charts":
+			for _, r := range d.Charts {
+				if !yield(r) {
+					return
+				}
+			}
+		case "scores":
+			for _, r := range d.Scores {
+				if !yield(r) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func encodeJSONL(w io.Writer, d *Dump) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	line := func(table string, row any) error {
+		b, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(record{Table: table, Row: b})
+	}
+	if err := line(manifestTable, d.Manifest); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		for row := range d.rows(t) {
+			if err := line(t, row); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func encodeTar(w io.Writer, d *Dump) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	file := func(name string, write func(*json.Encoder) error) error {
+		var buf strings.Builder
+		if err := write(json.NewEncoder(&buf)); err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(buf.Len()),
+			ModTime: d.Manifest.ExportedAt,
+		}); err != nil {
+			return err
+		}
+		_, err := io.WriteString(tw, buf.String())
+		return err
+	}
+
+	if err := file("manifest.json", func(enc *json.Encoder) error {
+		enc.SetIndent("", "  ")
+		return enc.Encode(d.Manifest)
+	}); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if err := file(t+".jsonl", func(enc *json.Encoder) error {
+			for row := range d.rows(t) {
+				if err := enc.Encode(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Decode reads a dump in the format. It fails with ErrUnsupported unless
+// the dump starts with a manifest of a version this package reads.
+func Decode(r io.Reader, f Format) (*Dump, error) {
+	switch f {
+	case FormatJSONL:
+		return decodeJSONL(r)
+	case FormatTar:
+		return decodeTar(r)
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownFormat, f)
+}
+
+func decodeJSONL(r io.Reader) (*Dump, error) {
+	d := &Dump{}
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var rec record
+		if err := dec.Decode(&rec); err == io.EOF {
+			if n == 1 {
+				return nil, fmt.Errorf("%w: empty file", ErrUnsupported)
+			}
+			return d, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		if n == 1 {
+			if rec.Table != manifestTable {
+				return nil, fmt.Errorf("%w: the first record is not a manifest", ErrUnsupported)
+			}
+			if err := d.readManifest(rec.Row); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := d.readRow(rec.Table, rec.Row); err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+	}
+}
+
+func decodeTar(r io.Reader) (*Dump, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	d := &Dump{}
+	for n := 0; ; n++ {
+		h, err := tr.Next()
+		if err == io.EOF {
+			if n == 0 {
+				return nil, fmt.Errorf("%w: empty archive", ErrUnsupported)
+			}
+			return d, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			if h.Name != "manifest.json" {
+				return nil, fmt.Errorf("%w: the first file is not manifest.json", ErrUnsupported)
+			}
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			if err := d.readManifest(b); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		table, ok := strings.CutSuffix(h.Name, ".jsonl")
+		if !ok {
+			continue
+		}
+		dec := json.NewDecoder(tr)
+		for line := 1; ; line++ {
+			var row json.RawMessage
+			if err := dec.Decode(&row); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", h.Name, line, err)
+			}
+			if err := d.readRow(table, row); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", h.Name, line, err)
+			}
+		}
+	}
+}
+
+func (d *Dump) readManifest(b []byte) error {
+	if err := json.Unmarshal(b, &d.Manifest); err != nil {
+		return fmt.Errorf("manifest: %w", err)
+	}
+	m := d.Manifest
+	if m.Kind != Kind {
+		return fmt.Errorf("%w: kind %q", ErrUnsupported, m.Kind)
+	}
+	if m.Version < 1 || m.Version > Version {
+		return fmt.Errorf("%w: version %d, this server reads up to %d", ErrUnsupported, m.Version, Version)
+	}
+	return nil
+}
+
+// readRow appends a row of table. Rows of unknown tables fail, as a
+// newer version would have changed Version.
+func (d *Dump) readRow(table string, b []byte) error {
+	var err error
+	switch table {
+	case "users":
+		var u User
+		err = json.Unmarshal(b, &u)
+		d.Users = append(d.Users, u)
+	case "charts":
+		var c Chart
+		err = json.Unmarshal(b, &c)
+		d.Charts = append(d.Charts, c)
+	case "scores":
+		var s Score
+		err = json.Unmarshal(b, &s)
+		d.Scores = append(d.Scores, s)
+	default:
+		return fmt.Errorf("unknown table %q", table)
+	}
+	return err
+}
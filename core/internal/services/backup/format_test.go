@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+var at = time.Date(2025, 4, 1, 12, 30, 0, 0, time.UTC)
+
+var dump = &Dump{
+	Manifest: Manifest{
+		Kind:          Kind,
+		Version:       Version,
+		SchemaVersion: 6,
+		ExportedAt:    at,
+		From:          ptr(at.Add(-time.Hour)),
+		Counts:        map[string]int{"users": 1, "charts": 1, "scores": 2},
+	},
+	Users:  []User{{ID: "u1", Name: "Player", CreatedAt: at, UpdatedAt: at, BannedAt: ptr(at), BanReason: ptr("cheating")}},
+	Charts: []Chart{{BeatmapID: "a_past", SongName: "A", Difficulty: 0, NoteCount: ptr(321), Constant: ptr(3.5), CreatedAt: at}},
+	Scores: []Score{
+		{ID: 7, UserID: "u1", BeatmapID: "a_past", Score: 990000, MaxCombo: 321, Input: 1, CreatedAt: at},
+		{ID: 9, UserID: "u1", BeatmapID: "a_past", Score: 100, CreatedAt: at, InvalidatedAt: ptr(at), InvalidationReason: ptr("ban")},
+	},
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, f := range []Format{FormatJSONL, FormatTar} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, f, dump); err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		got, err := Decode(&buf, f)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		if !reflect.DeepEqual(got, dump) {
+			t.Errorf("%s: got %+v", f, got)
+		}
+	}
+}
+
+func TestDecodeJSONL(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{"", "empty file"},
+		{`{"table":"users","row":{"id":"u1"}}`, "not a manifest"},
+		{`{"table":"manifest","row":{"kind":"other","version":1}}`, `kind "other"`},
+		{`{"table":"manifest","row":{"kind":"senirenol-export","version":2}}`, "version 2"},
+	} {
+		_, err := Decode(strings.NewReader(tc.in), FormatJSONL)
+		if !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: error %v, want %q", tc.in, err, tc.want)
+		}
+	}
+
+	_, err := Decode(strings.NewReader(`{"table":"manifest","row":{"kind":"senirenol-export","version":1}}
+{"table":"teams","row":{}}
+`), FormatJSONL)
+	if err == nil || err.Error() != `record 2: unknown table "teams"` {
+		t.Errorf("unknown table: %v", err)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]Format{
+		"backup.jsonl":      FormatJSONL,
+		"backup.NDJSON":     FormatJSONL,
+		"backup.tar.gz":     FormatTar,
+		"dir.v2/backup.tgz": FormatTar,
+	} {
+		if got, err := FormatOf(name); err != nil || got != want {
+			t.Errorf("%s: %q, %v", name, got, err)
+		}
+	}
+	if _, err := FormatOf("backup.json"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("backup.json: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+// Package cache keeps the results of hot read queries between the handler and
+// the repository.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Cache stores encoded values under string keys. Memory is the in-process
+// implementation; a cache shared between instances can implement the same
+// interface since values are plain bytes.
+type Cache interface {
+	// Get returns the value of key if it exists and has not expired
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+	// DeletePrefix removes every entry whose key starts with prefix
+	DeletePrefix(ctx context.Context, prefix string)
+	Stats() Stats
+}
+
+// Stats is a snapshot of a cache's counters
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int64
+}
+
+// Fetch returns the cached value of key, or calls load and caches its result
+// as JSON for ttl. Errors from load are returned and never cached.
+func Fetch[T any](ctx context.Context, c Cache, key string, ttl time.Duration, load func(context.Context) (T, error)) (T, error) {
+	if b, ok := c.Get(ctx, key); ok {
+		var v T
+		if err := json.Unmarshal(b, &v); err == nil {
+			return v, nil
+		}
+	}
+
+	v, err := load(ctx)
+	if err != nil {
+		return v, err
+	}
+	if b, err := json.Marshal(v); err == nil {
+		c.Set(ctx, key, b, ttl)
+	}
+	return v, nil
+}
+
+// Nop never stores anything. Every Get is a miss.
+type Nop struct{}
+
+var _ Cache = Nop{}
+
+func (Nop) Get(context.Context, string) ([]byte, bool)         { return nil, false }
+func (Nop) Set(context.Context, string, []byte, time.Duration) {}
+func (Nop) DeletePrefix(context.Context, string)               {}
+func (Nop) Stats() Stats                                       { return Stats{} }
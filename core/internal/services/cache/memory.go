@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process Cache bounded by the total size of its values.
+// When full, the least recently used entries are evicted first. Expired
+// entries are dropped when read or evicted.
+type Memory struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	ll       *list.List
+	items    map[string]*list.Element
+	now      func() time.Time
+
+	hits, misses, evictions uint64
+}
+
+type entry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+var _ Cache = (*Memory)(nil)
+
+func NewMemory(maxBytes int64) *Memory {
+	return &Memory{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.items[key]
+	if !ok {
+		m.misses++
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !m.now().Before(e.expiresAt) {
+		m.remove(el)
+		m.misses++
+		return nil, false
+	}
+	m.ll.MoveToFront(el)
+	m.hits++
+	return e.value, true
+}
+
+// Set stores value for ttl. Values larger than the whole cache are not stored.
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) {
+	size := int64(len(value))
+	if ttl <= 0 || size > m.maxBytes {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.items[key]; ok {
+		m.remove(el)
+	}
+	for m.bytes+size > m.maxBytes {
+		m.remove(m.ll.Back())
+		m.evictions++
+	}
+	m.items[key] = m.ll.PushFront(&entry{key: key, value: value, expiresAt: m.now().Add(ttl)})
+	m.bytes += size
+}
+
+func (m *Memory) DeletePrefix(_ context.Context, prefix string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, el := range m.items {
+		if strings.HasPrefix(key, prefix) {
+			m.remove(el)
+		}
+	}
+}
+
+func (m *Memory) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return Stats{
+		Hits:      m.hits,
+		Misses:    m.misses,
+		Evictions: m.evictions,
+		Entries:   len(m.items),
+		Bytes:     m.bytes,
+	}
+}
+
+func (m *Memory) remove(el *list.Element) {
+	e := m.ll.Remove(el).(*entry)
+	delete(m.items, e.key)
+	m.bytes -= int64(len(e.value))
+}
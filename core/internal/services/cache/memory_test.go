@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewMemory(10)
+	m.now = func() time.Time { return now }
+
+	m.Set(ctx, "ranking:a", []byte("aaaa"), time.Minute)
+	m.Set(ctx, "ranking:b", []byte("bbbb"), time.Minute)
+	if v, ok := m.Get(ctx, "ranking:a"); !ok || string(v) != "aaaa" {
+		t.Fatalf("get a = %q, %v", v, ok)
+	}
+
+	// b is least recently used and makes room for c
+	m.Set(ctx, "songs", []byte("cccc"), time.Minute)
+	if _, ok := m.Get(ctx, "ranking:b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if _, ok := m.Get(ctx, "songs"); !ok {
+		t.Error("songs should be cached")
+	}
+
+	// larger than the whole cache
+	m.Set(ctx, "huge", make([]byte, 11), time.Minute)
+	if _, ok := m.Get(ctx, "huge"); ok {
+		t.Error("huge should not be cached")
+	}
+
+	m.DeletePrefix(ctx, "ranking:")
+	if _, ok := m.Get(ctx, "ranking:a"); ok {
+		t.Error("a should have been deleted")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := m.Get(ctx, "songs"); ok {
+		t.Error("songs should have expired")
+	}
+
+	want := Stats{Hits: 2, Misses: 4, Evictions: 1, Entries: 0, Bytes: 0}
+	if got := m.Stats(); got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	type ranking struct {
+		Top []string
+	}
+	m := NewMemory(1 << 10)
+	loads := 0
+	load := func(context.Context) (*ranking, error) {
+		loads++
+		return &ranking{Top: []string{"alice", "bob"}}, nil
+	}
+
+	for range 3 {
+		r, err := Fetch(ctx, m, "ranking", time.Minute, load)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Top) != 2 || r.Top[0] != "alice" {
+			t.Fatalf("ranking = %+v", r)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("loads = %d, want 1", loads)
+	}
+
+	errLoad := errors.New("load failed")
+	if _, err := Fetch(ctx, m, "broken", time.Minute, func(context.Context) (int, error) { return 0, errLoad }); !errors.Is(err, errLoad) {
+		t.Errorf("err = %v, want %v", err, errLoad)
+	}
+	if _, ok := m.Get(ctx, "broken"); ok {
+		t.Error("errors must not be cached")
+	}
+}
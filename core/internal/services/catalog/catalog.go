@@ -0,0 +1,260 @@
+// Package catalog imports and exports the chart catalog as CSV, JSON or YAML
+// files, so that the chart team can maintain it in a spreadsheet.
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"gopkg.in/yaml.v3"
+)
+
+// Entry is a chart in a catalog file
+type Entry struct {
+	BeatmapID      string     `json:"beatmap_id" yaml:"beatmap_id"`
+	SongName       string     `json:"song_name" yaml:"song_name"`
+	Difficulty     Difficulty `json:"difficulty" yaml:"difficulty"`
+	ParallelString *string    `json:"parallel_string,omitempty" yaml:"parallel_string,omitempty"`
+	NoteCount      *int       `json:"note_count,omitempty" yaml:"note_count,omitempty"`
+	// Constant is the declared chart constant
+	Constant *float64 `json:"constant,omitempty" yaml:"constant,omitempty"`
+}
+
+// Difficulty is written as its number (0-4) or its name ("future").
+// It is always encoded as the number, like in the API.
+type Difficulty repository.Difficulty
+
+func parseDifficulty(s string) (Difficulty, error) {
+	if d, ok := repository.ParseDifficulty(s); ok {
+		return Difficulty(d), nil
+	}
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("unknown difficulty %q", s)
+	}
+	return Difficulty(n), nil
+}
+
+func (d *Difficulty) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		s = string(b)
+	}
+	v, err := parseDifficulty(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func (d *Difficulty) UnmarshalYAML(n *yaml.Node) error {
+	v, err := parseDifficulty(n.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", n.Line, err)
+	}
+	*d = v
+	return nil
+}
+
+// Validate checks every entry, and that no beatmap is listed twice.
+// Errors are keyed by the position of the entry, such as "charts[2]".
+func Validate(es []Entry) error {
+	errs := vd.Errors{}
+	seen := make(map[string]bool, len(es))
+	for i := range es {
+		e := &es[i]
+		key := fmt.Sprintf("charts[%d]", i)
+		if err := vd.ValidateStruct(e,
+			vd.Field(&e.BeatmapID, vd.Required, vd.RuneLength(1, 128)),
+			vd.Field(&e.SongName, vd.Required, vd.RuneLength(1, 255)),
+			vd.Field(&e.Difficulty, vd.Max(Difficulty(repository.DiffParallel))),
+			vd.Field(&e.ParallelString, vd.RuneLength(0, 16)),
+			vd.Field(&e.NoteCount, vd.Min(0)),
+			vd.Field(&e.Constant, vd.Min(0.0)),
+		); err != nil {
+			errs[key] = err
+			continue
+		}
+		if seen[e.BeatmapID] {
+			errs[key] = vd.Errors{"beatmap_id": fmt.Errorf("duplicates %q", e.BeatmapID)}
+		}
+		seen[e.BeatmapID] = true
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Op is what an import does to a chart
+type Op string
+
+const (
+	OpCreate    Op = "create"
+	OpUpdate    Op = "update"
+	OpUnchanged Op = "unchanged"
+)
+
+// FieldChange is a column whose value an import sets. Old is nil for a new
+// chart, and both are nil for a missing optional value.
+type FieldChange struct {
+	Field string
+	Old   any
+	New   any
+}
+
+// Change is what an import does to one chart
+type Change struct {
+	Op        Op
+	BeatmapID string
+	Fields    []FieldChange
+}
+
+// Result is the outcome of an import. Changes lists the created and updated
+// charts in the order of the file.
+type Result struct {
+	DryRun    bool
+	Created   int
+	Updated   int
+	Unchanged int
+	Changes   []Change
+}
+
+type Service struct {
+	repo *repository.Repository
+}
+
+func New(repo *repository.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+type ImportOptions struct {
+	// DryRun only computes the changes
+	DryRun bool
+	// Actor is recorded in audit_logs
+	Actor string
+}
+
+// Import creates and updates the charts of a validated catalog in one
+// transaction. Charts missing from the catalog are kept, as deleting a chart
+// would delete its scores.
+func (s *Service) Import(ctx context.Context, es []Entry, opts ImportOptions) (*Result, error) {
+	current, err := s.repo.GetCharts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*repository.Chart, len(current))
+	for _, c := range current {
+		byID[c.BeatmapID] = c
+	}
+
+	res := &Result{DryRun: opts.DryRun}
+	var writes []*repository.Chart
+	for _, e := range es {
+		next := e.chart()
+		ch := diff(byID[e.BeatmapID], next)
+		switch ch.Op {
+		case OpCreate:
+			res.Created++
+		case OpUpdate:
+			res.Updated++
+		default:
+			res.Unchanged++
+			continue
+		}
+		res.Changes = append(res.Changes, ch)
+		writes = append(writes, next)
+	}
+
+	if opts.DryRun || len(writes) == 0 {
+		return res, nil
+	}
+	if err := s.repo.ImportCharts(ctx, repository.ImportChartsParams{
+		Charts:  writes,
+		Actor:   opts.Actor,
+		Created: res.Created,
+		Updated: res.Updated,
+	}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Export returns every chart as catalog entries, ordered like GetCharts
+func (s *Service) Export(ctx context.Context) ([]Entry, error) {
+	cs, err := s.repo.GetCharts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	es := make([]Entry, len(cs))
+	for i, c := range cs {
+		es[i] = Entry{
+			BeatmapID:      c.BeatmapID,
+			SongName:       c.SongName,
+			Difficulty:     Difficulty(c.Difficulty),
+			ParallelString: c.ParallelString,
+			NoteCount:      c.NoteCount,
+			Constant:       c.Constant,
+		}
+	}
+	return es, nil
+}
+
+func (e Entry) chart() *repository.Chart {
+	c := &repository.Chart{
+		BeatmapID:      e.BeatmapID,
+		SongName:       e.SongName,
+		Difficulty:     int(e.Difficulty),
+		ParallelString: e.ParallelString,
+		NoteCount:      e.NoteCount,
+		Constant:       e.Constant,
+	}
+	// an empty cell means no parallel string
+	if c.ParallelString != nil && *c.ParallelString == "" {
+		c.ParallelString = nil
+	}
+	return c
+}
+
+// diff compares the stored chart, nil when it does not exist, with the imported one
+func diff(old, next *repository.Chart) Change {
+	ch := Change{Op: OpUpdate, BeatmapID: next.BeatmapID}
+	if old == nil {
+		ch.Op = OpCreate
+		old = &repository.Chart{}
+	}
+	add := func(field string, o, n any) {
+		if ch.Op == OpCreate {
+			if n != nil {
+				ch.Fields = append(ch.Fields, FieldChange{Field: field, New: n})
+			}
+			return
+		}
+		if o != n {
+			ch.Fields = append(ch.Fields, FieldChange{Field: field, Old: o, New: n})
+		}
+	}
+	add("song_name", old.SongName, next.SongName)
+	add("difficulty", old.Difficulty, next.Difficulty)
+	add("parallel_string", deref(old.ParallelString), deref(next.ParallelString))
+	add("note_count", deref(old.NoteCount), deref(next.NoteCount))
+	add("constant", deref(old.Constant), deref(next.Constant))
+
+	if ch.Op == OpUpdate && len(ch.Fields) == 0 {
+		ch.Op = OpUnchanged
+	}
+	return ch
+}
+
+// deref returns the value of p, or an untyped nil so that missing values compare equal
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
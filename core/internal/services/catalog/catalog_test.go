@@ -0,0 +1,151 @@
+package catalog
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+var entries = []Entry{
+	{BeatmapID: "a_past", SongName: "A", Difficulty: 0, NoteCount: ptr(321), Constant: ptr(3.5)},
+	{BeatmapID: "a_parallel", SongName: "A, again", Difficulty: 4, ParallelString: ptr("Ⅱ")},
+}
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		format Format
+		in     string
+	}{
+		{FormatCSV, "\ufeffsong_name,beatmap_id,difficulty,parallel_string,note_count,constant,memo\n" +
+			"A,a_past,past,,321,3.5,ignored\n" +
+			"\"A, again\",a_parallel,4,Ⅱ,,,\n"},
+		{FormatJSON, `[
+			{"beatmap_id":"a_past","song_name":"A","difficulty":"Past","note_count":321,"constant":3.5},
+			{"beatmap_id":"a_parallel","song_name":"A, again","difficulty":4,"parallel_string":"Ⅱ"}
+		]`},
+		{FormatYAML, `
+- beatmap_id: a_past
+  song_name: A
+  difficulty: past
+  note_count: 321
+  constant: 3.5
+- beatmap_id: a_parallel
+  song_name: A, again
+  difficulty: 4
+  parallel_string: Ⅱ
+`},
+	} {
+		got, err := Decode(strings.NewReader(tc.in), tc.format)
+		if err != nil {
+			t.Errorf("%s: %v", tc.format, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entries) {
+			t.Errorf("%s: got %+v", tc.format, got)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		format Format
+		in     string
+		want   string
+	}{
+		{FormatCSV, "beatmap_id,song_name\n", `missing column "difficulty"`},
+		{FormatCSV, "beatmap_id,song_name,difficulty\na,A,hard\n", `line 2: difficulty: unknown difficulty "hard"`},
+		{FormatCSV, "beatmap_id,song_name,difficulty,note_count\na,A,0,many\n", "line 2: note_count"},
+		{FormatJSON, `[{"difficulty":"hard"}]`, `unknown difficulty "hard"`},
+		{FormatYAML, "- difficulty: hard\n", `line 1: unknown difficulty "hard"`},
+	} {
+		_, err := Decode(strings.NewReader(tc.in), tc.format)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s %q: error %v, want %q", tc.format, tc.in, err, tc.want)
+		}
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, f := range []Format{FormatCSV, FormatJSON, FormatYAML} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, f, entries); err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		got, err := Decode(&buf, f)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		if !reflect.DeepEqual(got, entries) {
+			t.Errorf("%s: got %+v", f, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	if err := Validate(entries); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Validate([]Entry{
+		{BeatmapID: "a", SongName: "A"},
+		{BeatmapID: "b", Difficulty: 5, NoteCount: ptr(-1)},
+		{BeatmapID: "a", SongName: "A"},
+	})
+	var es vd.Errors
+	if !errors.As(err, &es) {
+		t.Fatalf("error %v is not vd.Errors", err)
+	}
+	if _, ok := es["charts[0]"]; ok {
+		t.Errorf("first entry should be valid: %v", es)
+	}
+	if got := es["charts[1]"].Error(); got != "difficulty: must be no greater than 4; note_count: must be no less than 0; song_name: cannot be blank." {
+		t.Errorf("charts[1]: %s", got)
+	}
+	if got := es["charts[2]"].Error(); got != `beatmap_id: duplicates "a".` {
+		t.Errorf("charts[2]: %s", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	stored := &repository.Chart{BeatmapID: "a", SongName: "A", Difficulty: 1, NoteCount: ptr(100)}
+
+	ch := diff(stored, &repository.Chart{BeatmapID: "a", SongName: "A", Difficulty: 1, NoteCount: ptr(100)})
+	if ch.Op != OpUnchanged {
+		t.Errorf("same chart: %+v", ch)
+	}
+
+	ch = diff(stored, &repository.Chart{BeatmapID: "a", SongName: "A", Difficulty: 1, Constant: ptr(7.2)})
+	want := []FieldChange{
+		{Field: "note_count", Old: 100, New: nil},
+		{Field: "constant", Old: nil, New: 7.2},
+	}
+	if ch.Op != OpUpdate || !reflect.DeepEqual(ch.Fields, want) {
+		t.Errorf("updated chart: %+v", ch)
+	}
+
+	ch = diff(nil, &repository.Chart{BeatmapID: "b", SongName: "B", Difficulty: 2})
+	want = []FieldChange{
+		{Field: "song_name", New: "B"},
+		{Field: "difficulty", New: 2},
+	}
+	if ch.Op != OpCreate || !reflect.DeepEqual(ch.Fields, want) {
+		t.Errorf("new chart: %+v", ch)
+	}
+}
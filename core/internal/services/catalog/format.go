@@ -0,0 +1,198 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a file format of the catalog
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// ErrUnknownFormat is returned for a format other than csv, json and yaml
+var ErrUnknownFormat = errors.New("unknown catalog format")
+
+// ParseFormat reads a format name or file extension, such as "yml"
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(s, ".")) {
+	case "csv":
+		return FormatCSV, nil
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, s)
+}
+
+// FormatOf tells the format of a file from its extension
+func FormatOf(path string) (Format, error) {
+	return ParseFormat(filepath.Ext(path))
+}
+
+// ContentType is the MIME type of files in f
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatYAML:
+		return "application/yaml; charset=utf-8"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+// csvHeader is the header row written by Encode. Decode matches columns by
+// name, in any order, and ignores columns it does not know.
+var csvHeader = []string{"beatmap_id", "song_name", "difficulty", "parallel_string", "note_count", "constant"}
+
+// Decode reads every entry of a catalog file. JSON and YAML files hold an
+// array of entries; CSV files have a header row naming the columns.
+func Decode(r io.Reader, f Format) ([]Entry, error) {
+	var es []Entry
+	switch f {
+	case FormatCSV:
+		return decodeCSV(r)
+	case FormatJSON:
+		if err := json.NewDecoder(r).Decode(&es); err != nil {
+			return nil, err
+		}
+	case FormatYAML:
+		if err := yaml.NewDecoder(r).Decode(&es); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+	}
+	return es, nil
+}
+
+func decodeCSV(r io.Reader) ([]Entry, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		// spreadsheets may save a byte order mark
+		col[strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))] = i
+	}
+	for _, name := range []string{"beatmap_id", "song_name", "difficulty"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var es []Entry
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return es, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		cell := func(name string) string {
+			if i, ok := col[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		e := Entry{
+			BeatmapID: cell("beatmap_id"),
+			SongName:  cell("song_name"),
+		}
+		if e.Difficulty, err = parseDifficulty(cell("difficulty")); err != nil {
+			return nil, fmt.Errorf("line %d: difficulty: %w", line, err)
+		}
+		if v := cell("parallel_string"); v != "" {
+			e.ParallelString = &v
+		}
+		if v := cell("note_count"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: note_count: %w", line, err)
+			}
+			e.NoteCount = &n
+		}
+		if v := cell("constant"); v != "" {
+			c, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: constant: %w", line, err)
+			}
+			e.Constant = &c
+		}
+		es = append(es, e)
+	}
+}
+
+// Encode writes es in the format f
+func Encode(w io.Writer, f Format, es []Entry) error {
+	switch f {
+	case FormatCSV:
+		return encodeCSV(w, es)
+	case FormatJSON:
+		if es == nil {
+			es = []Entry{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(es)
+	case FormatYAML:
+		if es == nil {
+			es = []Entry{}
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(es); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+	}
+}
+
+func encodeCSV(w io.Writer, es []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range es {
+		rec := []string{e.BeatmapID, e.SongName, strconv.Itoa(int(e.Difficulty)), "", "", ""}
+		if e.ParallelString != nil {
+			rec[3] = *e.ParallelString
+		}
+		if e.NoteCount != nil {
+			rec[4] = strconv.Itoa(*e.NoteCount)
+		}
+		if e.Constant != nil {
+			rec[5] = strconv.FormatFloat(*e.Constant, 'f', -1, 64)
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,57 @@
+package difficulty
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+)
+
+// Estimator refits chart difficulties from the scores in the repository
+type Estimator struct {
+	repo *repository.Repository
+}
+
+func NewEstimator(repo *repository.Repository) *Estimator {
+	return &Estimator{repo: repo}
+}
+
+// Recompute refits every chart and replaces the stored estimates.
+// It returns the number of charts estimated.
+func (e *Estimator) Recompute(ctx context.Context) (int, error) {
+	bests, err := e.repo.GetPlayerChartBests(ctx)
+	if err != nil {
+		return 0, err
+	}
+	charts, err := e.repo.GetCharts(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	obs := make([]Observation, len(bests))
+	for i, b := range bests {
+		obs[i] = Observation{UserID: b.UserID, BeatmapID: b.BeatmapID, Score: b.Score}
+	}
+	declared := make(map[string]int, len(charts))
+	for _, c := range charts {
+		declared[c.BeatmapID] = c.Difficulty
+	}
+
+	now := time.Now()
+	fitted := Fit(obs, declared)
+	es := make([]repository.DifficultyEstimate, len(fitted))
+	for i, f := range fitted {
+		es[i] = repository.DifficultyEstimate{
+			BeatmapID:         f.BeatmapID,
+			EstimatedConstant: f.Constant,
+			IRTDifficulty:     f.IRTDifficulty,
+			SamplePlayers:     f.Players,
+			UpdatedAt:         now,
+		}
+	}
+	if err := e.repo.ReplaceDifficultyEstimates(ctx, es); err != nil {
+		return 0, fmt.Errorf("store estimates: %w", err)
+	}
+	return len(es), nil
+}
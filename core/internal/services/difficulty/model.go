@@ -0,0 +1,164 @@
+// Package difficulty estimates chart difficulty constants from player performance.
+//
+// Each player's best score on a chart is mapped to logit space and modelled
+// as ability minus difficulty (a Rasch-style model with a continuous
+// outcome). Abilities and difficulties are fitted by alternating least
+// squares, then difficulties are calibrated onto the constant scale by
+// regressing them against the nominal level of each chart's declared
+// difficulty.
+package difficulty
+
+import (
+	"math"
+
+	"github.com/pikachu0310/senirenol-server/core/internal/services/scoring"
+)
+
+const (
+	// MinChartsPerPlayer is the number of charts a player must have played to inform the model
+	MinChartsPerPlayer = 3
+	// MinPlayersPerChart is the number of eligible players a chart needs to be estimated
+	MinPlayersPerChart = 3
+
+	iterations = 100
+	// shrinkage pulls sparsely played charts and players towards the mean
+	shrinkage = 1.0
+	// scoreEpsilon keeps perfect and zero scores finite in logit space
+	scoreEpsilon = 0.001
+
+	minConstant = 1.0
+	maxConstant = 15.0
+)
+
+// NominalLevels is the constant each declared difficulty is expected to have
+// (0=Past,1=Present,2=Future,3=Lycoris,4=Parallel)
+var NominalLevels = map[int]float64{
+	0: 3,
+	1: 6,
+	2: 9,
+	3: 10.5,
+	4: 11,
+}
+
+// Observation is a player's best score on a chart
+type Observation struct {
+	UserID    string
+	BeatmapID string
+	Score     int
+}
+
+// Estimate is the fitted difficulty of one chart
+type Estimate struct {
+	BeatmapID string
+	// Constant is the difficulty on the declared constant scale, rounded to 0.1
+	Constant float64
+	// IRTDifficulty is the raw fitted difficulty in logit units, mean 0
+	IRTDifficulty float64
+	Players       int
+}
+
+// Fit estimates every chart with enough eligible players. declared maps
+// beatmap IDs to their declared difficulty.
+func Fit(obs []Observation, declared map[string]int) []Estimate {
+	perPlayer := map[string]int{}
+	for _, o := range obs {
+		perPlayer[o.UserID]++
+	}
+	var eligible []Observation
+	perChart := map[string]int{}
+	for _, o := range obs {
+		if perPlayer[o.UserID] >= MinChartsPerPlayer {
+			eligible = append(eligible, o)
+			perChart[o.BeatmapID]++
+		}
+	}
+
+	theta := map[string]float64{}
+	b := map[string]float64{}
+	y := make([]float64, len(eligible))
+	for i, o := range eligible {
+		y[i] = logit(float64(o.Score) / scoring.MaxScore)
+		theta[o.UserID] = 0
+		b[o.BeatmapID] = 0
+	}
+
+	for range iterations {
+		// players: theta = mean(y + b)
+		sum := map[string]float64{}
+		for i, o := range eligible {
+			sum[o.UserID] += y[i] + b[o.BeatmapID]
+		}
+		for p := range theta {
+			theta[p] = sum[p] / (float64(perPlayer[p]) + shrinkage)
+		}
+		// charts: b = mean(theta - y)
+		sum = map[string]float64{}
+		for i, o := range eligible {
+			sum[o.BeatmapID] += theta[o.UserID] - y[i]
+		}
+		var mean float64
+		for c := range b {
+			b[c] = sum[c] / (float64(perChart[c]) + shrinkage)
+			mean += b[c]
+		}
+		// anchor the scale at mean difficulty 0
+		mean /= float64(len(b))
+		for c := range b {
+			b[c] -= mean
+		}
+	}
+
+	var res []Estimate
+	for c, n := range perChart {
+		if n < MinPlayersPerChart {
+			continue
+		}
+		res = append(res, Estimate{BeatmapID: c, IRTDifficulty: b[c], Players: n})
+	}
+	alpha, beta := calibrate(res, declared)
+	for i := range res {
+		c := alpha + beta*res[i].IRTDifficulty
+		res[i].Constant = math.Round(min(max(c, minConstant), maxConstant)*10) / 10
+	}
+	return res
+}
+
+// calibrate fits constant = alpha + beta*difficulty against the nominal
+// levels. With too little spread it falls back to a unit slope around the
+// mean nominal level.
+func calibrate(es []Estimate, declared map[string]int) (alpha, beta float64) {
+	var xs, ys []float64
+	for _, e := range es {
+		level, ok := NominalLevels[declared[e.BeatmapID]]
+		if !ok {
+			continue
+		}
+		xs = append(xs, e.IRTDifficulty)
+		ys = append(ys, level)
+	}
+	if len(xs) == 0 {
+		return NominalLevels[2], 1
+	}
+	n := float64(len(xs))
+	var sx, sy, sxx, sxy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+	den := n*sxx - sx*sx
+	if den < 1e-9 {
+		return sy/n - sx/n, 1
+	}
+	beta = (n*sxy - sx*sy) / den
+	if beta <= 0 {
+		return sy/n - sx/n, 1
+	}
+	return (sy - beta*sx) / n, beta
+}
+
+func logit(p float64) float64 {
+	p = min(max(p, scoreEpsilon), 1-scoreEpsilon)
+	return math.Log(p / (1 - p))
+}
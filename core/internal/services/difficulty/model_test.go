@@ -0,0 +1,77 @@
+package difficulty
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	t.Parallel()
+
+	// true difficulties in logit units, declared as Past..Parallel
+	charts := []struct {
+		id       string
+		b        float64
+		declared int
+	}{
+		{"past", -2, 0},
+		{"present", -1, 1},
+		{"future", 0.5, 2},
+		{"lycoris", 1.5, 3},
+		{"parallel", 2, 4},
+	}
+	var obs []Observation
+	for p := range 8 {
+		theta := float64(p)/2 + 1
+		for _, c := range charts {
+			s := 1 / (1 + math.Exp(-(theta - c.b)))
+			obs = append(obs, Observation{UserID: fmt.Sprint(p), BeatmapID: c.id, Score: int(s * 1000000)})
+		}
+	}
+	// a casual player with too few charts must not affect the fit
+	obs = append(obs, Observation{UserID: "casual", BeatmapID: "past", Score: 0})
+
+	declared := map[string]int{}
+	for _, c := range charts {
+		declared[c.id] = c.declared
+	}
+	es := Fit(obs, declared)
+	if len(es) != len(charts) {
+		t.Fatalf("estimated %d charts, want %d", len(es), len(charts))
+	}
+
+	got := map[string]Estimate{}
+	for _, e := range es {
+		got[e.BeatmapID] = e
+		if e.Players != 8 {
+			t.Errorf("%s: players = %d, want 8", e.BeatmapID, e.Players)
+		}
+	}
+	var constants []float64
+	for _, c := range charts {
+		constants = append(constants, got[c.id].Constant)
+	}
+	if !slices.IsSorted(constants) {
+		t.Errorf("constants are not ordered by true difficulty: %v", constants)
+	}
+	for i, c := range constants {
+		if c < minConstant || c > maxConstant {
+			t.Errorf("constant %d out of range: %v", i, c)
+		}
+	}
+}
+
+func TestFitNotEnoughPlayers(t *testing.T) {
+	t.Parallel()
+
+	obs := []Observation{
+		{UserID: "a", BeatmapID: "x", Score: 900000},
+		{UserID: "a", BeatmapID: "y", Score: 800000},
+		{UserID: "a", BeatmapID: "z", Score: 700000},
+	}
+	if es := Fit(obs, map[string]int{}); len(es) != 0 {
+		t.Fatalf("expected no estimates, got %+v", es)
+	}
+}
@@ -0,0 +1,58 @@
+// Package friendcode derives the short codes players exchange to become friends.
+//
+// A code is 10 characters of Crockford's base32 taken from a hash of the user
+// ID, shown as "XXXXX-XXXXX". It cannot be turned back into the ID, so
+// knowing a code does not let anyone act as the player.
+package friendcode
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"strings"
+)
+
+// Length is the number of characters of a code, without the hyphen
+const Length = 10
+
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var encoding = base32.NewEncoding(alphabet).WithPadding(base32.NoPadding)
+
+// Of returns the code of the user, as stored in users.friend_code
+func Of(userID string) string {
+	sum := sha256.Sum256([]byte("friend-code:" + userID))
+	return encoding.EncodeToString(sum[:])[:Length]
+}
+
+// Format inserts the hyphen shown to players
+func Format(code string) string {
+	if len(code) != Length {
+		return code
+	}
+	return code[:Length/2] + "-" + code[Length/2:]
+}
+
+// Parse normalises a code typed by a player: case, hyphens and spaces are
+// ignored, and the letters Crockford's base32 leaves out are read as the
+// digits they look like. It reports false for anything that is not a code.
+func Parse(s string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(s) {
+		switch r {
+		case '-', ' ':
+			continue
+		case 'O':
+			r = '0'
+		case 'I', 'L':
+			r = '1'
+		}
+		if !strings.ContainsRune(alphabet, r) {
+			return "", false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() != Length {
+		return "", false
+	}
+	return b.String(), true
+}
@@ -0,0 +1,41 @@
+package friendcode
+
+import "testing"
+
+func TestOf(t *testing.T) {
+	t.Parallel()
+
+	const id = "0b6e7a0c-4f3e-4d5a-9a51-7d2f1c3b8e90"
+	code := Of(id)
+	if len(code) != Length || Of(id) != code {
+		t.Fatalf("Of(%s) = %q", id, code)
+	}
+	if Of("4c1f2e3d-0000-4000-8000-000000000000") == code {
+		t.Error("two users have the same code")
+	}
+	if got, ok := Parse(code); !ok || got != code {
+		t.Errorf("Parse(%q) = %q, %v", code, got, ok)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"ABCDE-FGH12":   "ABCDEFGH12",
+		" abcde fgh12 ": "ABCDEFGH12",
+		"0OIL1-23456":   "0011123456",
+	} {
+		if got, ok := Parse(in); !ok || got != want {
+			t.Errorf("Parse(%q) = %q, %v, want %q", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "ABCDE", "ABCDE-FGH123", "ABCDE-FGHU1"} {
+		if got, ok := Parse(in); ok {
+			t.Errorf("Parse(%q) = %q, want failure", in, got)
+		}
+	}
+	if got := Format("ABCDEFGH12"); got != "ABCDE-FGH12" {
+		t.Errorf("Format: %q", got)
+	}
+}
@@ -0,0 +1,151 @@
+// Package health decides whether the server should receive traffic.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core/internal/services/worker"
+)
+
+// pingTimeout bounds the database check so that a hung connection fails the probe instead of blocking it
+const pingTimeout = 2 * time.Second
+
+// Status is the outcome of a check, ordered from best to worst
+type Status string
+
+const (
+	StatusOK Status = "ok"
+	// StatusDegraded still serves traffic, but something needs attention
+	StatusDegraded    Status = "degraded"
+	StatusUnavailable Status = "unavailable"
+)
+
+func (s Status) worse(t Status) bool {
+	rank := map[Status]int{StatusOK: 0, StatusDegraded: 1, StatusUnavailable: 2}
+	return rank[s] > rank[t]
+}
+
+type Check struct {
+	Name   string
+	Status Status
+	Detail string
+}
+
+// Report is the overall status, the worst of its checks
+type Report struct {
+	Status Status
+	Checks []Check
+}
+
+func (r *Report) add(c Check) {
+	r.Checks = append(r.Checks, c)
+	if c.Status.worse(r.Status) {
+		r.Status = c.Status
+	}
+}
+
+// Pinger is the database connection pool
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// MigrationVersions returns the schema version of the database and the latest known one
+type MigrationVersions func(ctx context.Context) (current int64, latest int64, err error)
+
+type Checker struct {
+	db         Pinger
+	migrations MigrationVersions
+	jobs       *worker.Scheduler
+	now        func() time.Time
+
+	drainOnce sync.Once
+	draining  chan struct{}
+}
+
+func New(db Pinger, migrations MigrationVersions, jobs *worker.Scheduler) *Checker {
+	return &Checker{
+		db:         db,
+		migrations: migrations,
+		jobs:       jobs,
+		now:        time.Now,
+		draining:   make(chan struct{}),
+	}
+}
+
+// Drain marks the server as shutting down. Readiness fails from then on so
+// that load balancers stop sending requests while in-flight ones finish.
+func (c *Checker) Drain() {
+	c.drainOnce.Do(func() { close(c.draining) })
+}
+
+// Draining is closed by Drain. Long-lived responses such as event streams
+// end when it is closed so that they do not hold up the shutdown.
+func (c *Checker) Draining() <-chan struct{} {
+	return c.draining
+}
+
+// Ready checks the database, its schema version and the background jobs.
+// The server is unavailable when the database is unreachable or not migrated,
+// and degraded while a job is overdue.
+func (c *Checker) Ready(ctx context.Context) Report {
+	r := Report{Status: StatusOK}
+
+	select {
+	case <-c.draining:
+		r.add(Check{Name: "shutdown", Status: StatusUnavailable, Detail: "draining"})
+		return r
+	default:
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if err := c.db.PingContext(pctx); err != nil {
+		slog.WarnContext(ctx, "readiness: database ping", "error", err)
+		r.add(Check{Name: "database", Status: StatusUnavailable, Detail: "ping failed"})
+		return r
+	}
+	r.add(Check{Name: "database", Status: StatusOK})
+
+	r.add(c.checkMigrations(ctx))
+
+	now := c.now()
+	for _, s := range c.jobs.Statuses() {
+		ch := Check{Name: "job:" + s.Name, Status: StatusOK}
+		if s.Overdue(now) {
+			ch.Status = StatusDegraded
+			ch.Detail = "no successful run in " + (2 * s.Interval).String()
+			if s.LastError != "" {
+				// the probe is unauthenticated, so the error itself only goes to the log
+				slog.WarnContext(ctx, "readiness: job overdue", "job", s.Name, "error", s.LastError)
+				ch.Detail += ", last run failed"
+			}
+		}
+		r.add(ch)
+	}
+	return r
+}
+
+func (c *Checker) checkMigrations(ctx context.Context) Check {
+	ch := Check{Name: "migrations", Status: StatusOK}
+	current, latest, err := c.migrations(ctx)
+	switch {
+	case err != nil:
+		slog.WarnContext(ctx, "readiness: migration version", "error", err)
+		ch.Status = StatusUnavailable
+		ch.Detail = "version unknown"
+	case current < latest:
+		ch.Status = StatusUnavailable
+		ch.Detail = fmt.Sprintf("version %d, want %d", current, latest)
+	case current > latest:
+		// migrated by a newer release during a rolling deploy; the old schema may still work
+		ch.Status = StatusDegraded
+		ch.Detail = fmt.Sprintf("version %d is newer than %d", current, latest)
+	default:
+		ch.Detail = fmt.Sprintf("version %d", current)
+	}
+	return ch
+}
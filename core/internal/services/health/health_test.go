@@ -0,0 +1,93 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core/internal/services/worker"
+)
+
+type pinger struct{ err error }
+
+func (p pinger) PingContext(context.Context) error { return p.err }
+
+func versions(current, latest int64) MigrationVersions {
+	return func(context.Context) (int64, int64, error) { return current, latest, nil }
+}
+
+func TestReady(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	idle := worker.NewScheduler()
+
+	for _, tc := range []struct {
+		name string
+		c    *Checker
+		want Status
+	}{
+		{"ok", New(pinger{}, versions(4, 4), idle), StatusOK},
+		{"database down", New(pinger{errors.New("refused")}, versions(4, 4), idle), StatusUnavailable},
+		{"migration pending", New(pinger{}, versions(3, 4), idle), StatusUnavailable},
+		{"newer schema", New(pinger{}, versions(5, 4), idle), StatusDegraded},
+	} {
+		if got := tc.c.Ready(ctx).Status; got != tc.want {
+			t.Errorf("%s: status = %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestReadyOverdueJob(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	jobs := worker.NewScheduler()
+	failed := make(chan struct{})
+	if err := jobs.Register(worker.Job{Name: "snapshots", Interval: time.Minute, Run: func(context.Context) error {
+		defer func() {
+			select {
+			case failed <- struct{}{}:
+			default:
+			}
+		}()
+		return errors.New("boom")
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	jobs.Start(ctx)
+	<-failed
+	jobs.Stop()
+
+	c := New(pinger{}, versions(4, 4), jobs)
+	if r := c.Ready(ctx); r.Status != StatusOK {
+		t.Errorf("a failure within two intervals should not degrade: %+v", r)
+	}
+
+	c.now = func() time.Time { return time.Now().Add(3 * time.Minute) }
+	r := c.Ready(ctx)
+	if r.Status != StatusDegraded {
+		t.Fatalf("status = %s, want degraded", r.Status)
+	}
+	last := r.Checks[len(r.Checks)-1]
+	if last.Name != "job:snapshots" || last.Detail != "no successful run in 2m0s, last run failed" {
+		t.Errorf("job check = %+v", last)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	t.Parallel()
+
+	c := New(pinger{}, versions(4, 4), worker.NewScheduler())
+	c.Drain()
+	c.Drain()
+	select {
+	case <-c.Draining():
+	default:
+		t.Fatal("Draining should be closed")
+	}
+	if got := c.Ready(context.Background()).Status; got != StatusUnavailable {
+		t.Errorf("status = %s, want unavailable", got)
+	}
+}
@@ -0,0 +1,271 @@
+// Package leaderboard serves rankings to the handlers. Reads come from the
+// materialised snapshot tables when they are enabled and built, otherwise
+// from live queries, and are cached in between.
+package leaderboard
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/cache"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/difficulty"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/scoring"
+)
+
+// SnapshotJob is the worker job name of Rebuild
+const SnapshotJob = "ranking-snapshots"
+
+// Cache keys. Rankings are keyed by chart and limit so that one prefix
+// invalidates every page size.
+const (
+	rankingCachePrefix      = "ranking:"
+	allRankingsCachePrefix  = rankingCachePrefix + "all:"
+	chartRankingCachePrefix = rankingCachePrefix + "chart:"
+	ratingsCachePrefix      = rankingCachePrefix + "ratings:"
+	songPlaycountCacheKey   = "songs:playcount"
+)
+
+type Options struct {
+	// CacheTTL is how long results stay cached
+	CacheTTL time.Duration
+	// Snapshots serves reads from the snapshot tables once they have been built
+	Snapshots bool
+}
+
+type Service struct {
+	repo  *repository.Repository
+	cache cache.Cache
+	opts  Options
+	now   func() time.Time
+}
+
+func New(repo *repository.Repository, c cache.Cache, opts Options) *Service {
+	return &Service{repo: repo, cache: c, opts: opts, now: time.Now}
+}
+
+// Result is a ranking and when its snapshot was taken. SnapshotAt is nil for live results.
+type Result[T any] struct {
+	Items      T
+	SnapshotAt *time.Time
+}
+
+func (s *Service) ChartRanking(ctx context.Context, beatmapID string, limit int) (Result[*repository.ChartRanking], error) {
+	key := chartRankingCachePrefix + beatmapID + ":" + strconv.Itoa(limit)
+	return cache.Fetch(ctx, s.cache, key, s.opts.CacheTTL, func(ctx context.Context) (Result[*repository.ChartRanking], error) {
+		at, err := s.SnapshotTime(ctx)
+		if err != nil {
+			return Result[*repository.ChartRanking]{}, err
+		}
+		if at != nil {
+			r, err := s.repo.GetChartRankingSnapshot(ctx, beatmapID, limit)
+			if err != nil {
+				return Result[*repository.ChartRanking]{}, err
+			}
+			// charts added after the snapshot are served live until the next rebuild
+			if r != nil {
+				return Result[*repository.ChartRanking]{Items: r, SnapshotAt: at}, nil
+			}
+		}
+		r, err := s.repo.GetChartRanking(ctx, beatmapID, limit)
+		return Result[*repository.ChartRanking]{Items: r}, err
+	})
+}
+
+func (s *Service) AllChartRankings(ctx context.Context, limit int) (Result[[]*repository.ChartRanking], error) {
+	return cache.Fetch(ctx, s.cache, allRankingsCachePrefix+strconv.Itoa(limit), s.opts.CacheTTL, func(ctx context.Context) (Result[[]*repository.ChartRanking], error) {
+		at, err := s.SnapshotTime(ctx)
+		if err != nil {
+			return Result[[]*repository.ChartRanking]{}, err
+		}
+		if at != nil {
+			rs, err := s.repo.GetAllChartRankingSnapshots(ctx, limit)
+			return Result[[]*repository.ChartRanking]{Items: rs, SnapshotAt: at}, err
+		}
+		rs, err := s.repo.GetAllChartsRankings(ctx, limit)
+		return Result[[]*repository.ChartRanking]{Items: rs}, err
+	})
+}
+
+func (s *Service) SongPlaycounts(ctx context.Context) (Result[[]*repository.SongPlayCount], error) {
+	return cache.Fetch(ctx, s.cache, songPlaycountCacheKey, s.opts.CacheTTL, func(ctx context.Context) (Result[[]*repository.SongPlayCount], error) {
+		at, err := s.SnapshotTime(ctx)
+		if err != nil {
+			return Result[[]*repository.SongPlayCount]{}, err
+		}
+		if at != nil {
+			rs, err := s.repo.GetSongPlaycountSnapshot(ctx)
+			return Result[[]*repository.SongPlayCount]{Items: rs, SnapshotAt: at}, err
+		}
+		rs, err := s.repo.GetSongPlaycountRanking(ctx)
+		return Result[[]*repository.SongPlayCount]{Items: rs}, err
+	})
+}
+
+// Ratings returns the top limit players by overall rating
+func (s *Service) Ratings(ctx context.Context, limit int) (Result[[]*repository.RatingEntry], error) {
+	return cache.Fetch(ctx, s.cache, ratingsCachePrefix+strconv.Itoa(limit), s.opts.CacheTTL, func(ctx context.Context) (Result[[]*repository.RatingEntry], error) {
+		at, err := s.SnapshotTime(ctx)
+		if err != nil {
+			return Result[[]*repository.RatingEntry]{}, err
+		}
+		if at != nil {
+			rs, err := s.repo.GetRatingSnapshot(ctx, limit)
+			return Result[[]*repository.RatingEntry]{Items: rs, SnapshotAt: at}, err
+		}
+		es, err := s.computeRatings(ctx)
+		if err != nil {
+			return Result[[]*repository.RatingEntry]{}, err
+		}
+		rs := make([]*repository.RatingEntry, 0, min(limit, len(es)))
+		for i := range es[:min(limit, len(es))] {
+			rs = append(rs, &es[i])
+		}
+		return Result[[]*repository.RatingEntry]{Items: rs}, nil
+	})
+}
+
+// SnapshotTime returns when the snapshots served to readers were taken, or nil
+// when reads are live because snapshots are disabled or not built yet.
+func (s *Service) SnapshotTime(ctx context.Context) (*time.Time, error) {
+	if !s.opts.Snapshots {
+		return nil, nil
+	}
+	return s.repo.GetSnapshotTime(ctx, repository.RankingSnapshot)
+}
+
+// Rebuild rebuilds every ranking snapshot and drops cached results
+func (s *Service) Rebuild(ctx context.Context) error {
+	at := s.now()
+	ratings, err := s.computeRatings(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.RebuildRankingSnapshots(ctx, ratings, at); err != nil {
+		return err
+	}
+	s.InvalidateAll(ctx)
+	return nil
+}
+
+// InvalidateChart drops cached results that a new score or chart change on beatmapID affects
+func (s *Service) InvalidateChart(ctx context.Context, beatmapID string) {
+	s.cache.DeletePrefix(ctx, chartRankingCachePrefix+beatmapID+":")
+	s.cache.DeletePrefix(ctx, allRankingsCachePrefix)
+	s.cache.DeletePrefix(ctx, ratingsCachePrefix)
+	s.cache.DeletePrefix(ctx, songPlaycountCacheKey)
+}
+
+// InvalidateAll drops every cached result, e.g. after moderation across charts
+// or a player rename that shows up in every ranking
+func (s *Service) InvalidateAll(ctx context.Context) {
+	s.cache.DeletePrefix(ctx, rankingCachePrefix)
+	s.cache.DeletePrefix(ctx, songPlaycountCacheKey)
+}
+
+func (s *Service) CacheStats() cache.Stats {
+	return s.cache.Stats()
+}
+
+// computeRatings rates every player from their best play on each chart, using
+// the estimated constant of the chart or the nominal level of its declared difficulty.
+func (s *Service) computeRatings(ctx context.Context) ([]repository.RatingEntry, error) {
+	bests, err := s.repo.GetPlayerChartBests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	constants, err := s.chartConstants(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type player struct {
+		name    string
+		ratings []float64
+	}
+	players := map[string]*player{}
+	for _, b := range bests {
+		c, ok := constants[b.BeatmapID]
+		if !ok {
+			// deleted between the two queries
+			continue
+		}
+		p, ok := players[b.UserID]
+		if !ok {
+			p = &player{name: b.PlayerName}
+			players[b.UserID] = p
+		}
+		p.ratings = append(p.ratings, scoring.PlayRating(c, b.Score))
+	}
+
+	es := make([]repository.RatingEntry, 0, len(players))
+	for id, p := range players {
+		es = append(es, repository.RatingEntry{
+			UserID:     id,
+			PlayerName: p.name,
+			Rating:     scoring.Rating(p.ratings),
+			Charts:     len(p.ratings),
+		})
+	}
+	slices.SortFunc(es, func(a, b repository.RatingEntry) int {
+		return cmp.Or(cmp.Compare(b.Rating, a.Rating), cmp.Compare(a.PlayerName, b.PlayerName), cmp.Compare(a.UserID, b.UserID))
+	})
+	for i := range es {
+		es[i].Place = i + 1
+	}
+	return es, nil
+}
+
+// RatedPlay is the best play of a player on a chart and the rating it earns
+type RatedPlay struct {
+	*repository.PlayRow
+	Rating float64
+}
+
+// PlayerRating returns the best play of the player on each chart, highest
+// rated first, and the rating of the player. It is computed live, unlike Ratings.
+func (s *Service) PlayerRating(ctx context.Context, userID string) ([]RatedPlay, float64, error) {
+	bests, err := s.repo.GetUserBests(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	constants, err := s.chartConstants(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ps := make([]RatedPlay, 0, len(bests))
+	ratings := make([]float64, 0, len(bests))
+	for _, b := range bests {
+		c, ok := constants[b.BeatmapID]
+		if !ok {
+			continue
+		}
+		r := scoring.PlayRating(c, b.Score)
+		ps = append(ps, RatedPlay{PlayRow: b, Rating: r})
+		ratings = append(ratings, r)
+	}
+	slices.SortStableFunc(ps, func(a, b RatedPlay) int { return cmp.Compare(b.Rating, a.Rating) })
+	return ps, scoring.Rating(ratings), nil
+}
+
+// chartConstants returns the estimated constant of every chart, or the
+// nominal level of its declared difficulty before it has an estimate
+func (s *Service) chartConstants(ctx context.Context) (map[string]float64, error) {
+	charts, err := s.repo.GetChartDifficulties(ctx)
+	if err != nil {
+		return nil, err
+	}
+	constants := make(map[string]float64, len(charts))
+	for _, c := range charts {
+		if c.EstimatedConstant != nil {
+			constants[c.BeatmapID] = *c.EstimatedConstant
+		} else {
+			constants[c.BeatmapID] = difficulty.NominalLevels[c.Difficulty]
+		}
+	}
+	return constants, nil
+}
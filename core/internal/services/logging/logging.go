@@ -0,0 +1,78 @@
+// Package logging builds the structured logger of the server and carries the
+// request ID through contexts so that every log line of a request, down to the
+// repository, can be correlated.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Formats selectable with LOG_FORMAT
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New builds a logger writing to w at the named level ("debug", "info", "warn"
+// or "error") in the named format. Records logged with a context carrying a
+// request ID get a request_id attribute.
+func New(w io.Writer, level string, format string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("log level: %w", err)
+	}
+	opts := &slog.HandlerOptions{Level: l}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case FormatJSON, "":
+		h = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unsupported log format: %s", format)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" outside of a request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds the request ID and trace ID of the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	l, err := New(&buf, "warn", FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	l.InfoContext(ctx, "dropped")
+	l.With("job", "x").WarnContext(ctx, "kept")
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("want exactly one JSON record, got %q: %v", buf.String(), err)
+	}
+	if rec["msg"] != "kept" || rec["request_id"] != "req-1" || rec["job"] != "x" {
+		t.Errorf("record = %v", rec)
+	}
+
+	if _, err := New(&buf, "verbose", FormatJSON); err == nil {
+		t.Error("unknown level should fail")
+	}
+	if _, err := New(&buf, "info", "xml"); err == nil {
+		t.Error("unknown format should fail")
+	}
+}
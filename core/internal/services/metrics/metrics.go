@@ -0,0 +1,129 @@
+// Package metrics collects HTTP, database and domain metrics and exposes them
+// in the Prometheus exposition format.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/pikachu0310/senirenol-server/core/internal/services/cache"
+)
+
+const namespace = "senirenol"
+
+// Metrics owns its own registry so that several servers can run in one process
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requestDuration      *prometheus.HistogramVec
+	validationRejections *prometheus.CounterVec
+	scoresSubmitted      *prometheus.CounterVec
+	usersRegistered      prometheus.Counter
+}
+
+// New registers process, Go runtime and connection pool metrics for db.
+// dbName labels the pool metrics.
+func New(db *sql.DB, dbName string) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests by route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		validationRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "validation_rejections_total",
+			Help:      "Requests rejected as invalid, by route.",
+		}, []string{"route"}),
+		scoresSubmitted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "scores_submitted_total",
+			Help:      "Scores submitted, by chart, difficulty and input device.",
+		}, []string{"beatmap_id", "difficulty", "input"}),
+		usersRegistered: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "users_registered_total",
+			Help:      "Users registered.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, dbName),
+		m.requestDuration,
+		m.validationRejections,
+		m.scoresSubmitted,
+		m.usersRegistered,
+	)
+	return m
+}
+
+// RegisterCache exposes the counters of a cache under name
+func (m *Metrics) RegisterCache(name string, stats func() cache.Stats) {
+	labels := prometheus.Labels{"cache": name}
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cache_hits_total", Help: "Cache hits.", ConstLabels: labels,
+		}, func() float64 { return float64(stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cache_misses_total", Help: "Cache misses.", ConstLabels: labels,
+		}, func() float64 { return float64(stats().Misses) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace, Name: "cache_evictions_total", Help: "Cache entries evicted to make room.", ConstLabels: labels,
+		}, func() float64 { return float64(stats().Evictions) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "cache_entries", Help: "Cached entries.", ConstLabels: labels,
+		}, func() float64 { return float64(stats().Entries) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "cache_bytes", Help: "Total size of cached values.", ConstLabels: labels,
+		}, func() float64 { return float64(stats().Bytes) }),
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware observes the latency of every routed request. Requests answered
+// with 400 or 422 are also counted as validation rejections. statusOf tells
+// the status the error handler will answer a failed request with.
+func (m *Metrics) Middleware(statusOf func(echo.Context, error) int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			// unmatched requests share one label instead of one per path
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			status := statusOf(c, err)
+			m.requestDuration.
+				WithLabelValues(c.Request().Method, route, strconv.Itoa(status)).
+				Observe(time.Since(start).Seconds())
+			if status == http.StatusBadRequest || status == http.StatusUnprocessableEntity {
+				m.validationRejections.WithLabelValues(route).Inc()
+			}
+			return err
+		}
+	}
+}
+
+func (m *Metrics) ScoreSubmitted(beatmapID string, difficulty string, input string) {
+	m.scoresSubmitted.WithLabelValues(beatmapID, difficulty, input).Inc()
+}
+
+func (m *Metrics) UserRegistered() {
+	m.usersRegistered.Inc()
+}
@@ -0,0 +1,207 @@
+// Package mission draws daily and weekly missions from templates declared in
+// a YAML file and tracks the progress of players on them.
+package mission
+
+import (
+	"bytes"
+	"cmp"
+	_ "embed"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/pikachu0310/senirenol-server/core/internal/services/achievement"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed missions.yaml
+var defaultRotation []byte
+
+// Period is how long a mission lasts
+type Period string
+
+const (
+	// Daily missions last from midnight to midnight
+	Daily Period = "daily"
+	// Weekly missions last from Monday midnight to the next
+	Weekly Period = "weekly"
+)
+
+// Periods lists every period in the order missions are shown
+var Periods = []Period{Daily, Weekly}
+
+// window returns the day or week containing now in loc
+func (p Period) window(now time.Time, loc *time.Location) (start, end time.Time) {
+	t := now.In(loc)
+	start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	if p == Weekly {
+		start = start.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 7)
+	}
+	return start, start.AddDate(0, 0, 1)
+}
+
+// Template is a mission that may be drawn
+type Template struct {
+	// ID is stored with the progress of every player
+	ID          string `yaml:"id"`
+	Period      Period `yaml:"period"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Reward is the number of mission points a claim gives
+	Reward int `yaml:"reward"`
+	// Condition.Count is the number of plays needed
+	Condition achievement.Condition `yaml:"condition"`
+}
+
+// Rotation is the templates and how many of them are drawn for each period
+type Rotation struct {
+	Daily     int        `yaml:"daily"`
+	Weekly    int        `yaml:"weekly"`
+	Templates []Template `yaml:"templates"`
+}
+
+var idPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Load reads and validates a YAML rotation. Template errors are keyed by
+// their position, such as "templates[2]".
+func Load(r io.Reader) (*Rotation, error) {
+	var rot Rotation
+	if err := yaml.NewDecoder(r).Decode(&rot); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	errs := vd.Errors{}
+	if rot.Daily < 0 {
+		errs["daily"] = errors.New("must be no less than 0")
+	}
+	if rot.Weekly < 0 {
+		errs["weekly"] = errors.New("must be no less than 0")
+	}
+	seen := make(map[string]bool, len(rot.Templates))
+	for i := range rot.Templates {
+		tp := &rot.Templates[i]
+		key := fmt.Sprintf("templates[%d]", i)
+		if err := vd.ValidateStruct(tp,
+			vd.Field(&tp.ID, vd.Required, vd.RuneLength(1, 64), vd.Match(idPattern)),
+			vd.Field(&tp.Period, vd.Required, vd.In(Daily, Weekly)),
+			vd.Field(&tp.Name, vd.Required, vd.RuneLength(1, 64)),
+			vd.Field(&tp.Description, vd.RuneLength(0, 255)),
+			vd.Field(&tp.Reward, vd.Min(0)),
+		); err != nil {
+			errs[key] = err
+			continue
+		}
+		if err := tp.Condition.Compile(); err != nil {
+			errs[key] = vd.Errors{"condition": err}
+			continue
+		}
+		if tp.Condition.Per != achievement.PerPlay {
+			errs[key] = vd.Errors{"condition": vd.Errors{"per": errors.New("missions count plays")}}
+			continue
+		}
+		if seen[tp.ID] {
+			errs[key] = vd.Errors{"id": fmt.Errorf("duplicates %q", tp.ID)}
+		}
+		seen[tp.ID] = true
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &rot, nil
+}
+
+// Default returns the rotation shipped with the server
+func Default() *Rotation {
+	rot, err := Load(bytes.NewReader(defaultRotation))
+	if err != nil {
+		panic(fmt.Sprintf("default missions: %v", err))
+	}
+	return rot
+}
+
+// Region is a group of players who share the day boundaries of a time zone
+type Region struct {
+	Name     string
+	Location *time.Location
+}
+
+// ParseRegions reads a comma separated list of name=timezone, such as
+// "jp=Asia/Tokyo,global=UTC". The first region is the default one.
+func ParseRegions(s string) ([]Region, error) {
+	var rs []Region
+	for _, f := range strings.Split(s, ",") {
+		name, tz, ok := strings.Cut(strings.TrimSpace(f), "=")
+		if !ok || !idPattern.MatchString(name) || len(name) > 32 {
+			return nil, fmt.Errorf("region %q: want name=timezone with a lower case name", f)
+		}
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("region %q: %w", name, err)
+		}
+		if slices.ContainsFunc(rs, func(r Region) bool { return r.Name == name }) {
+			return nil, fmt.Errorf("region %q is listed twice", name)
+		}
+		rs = append(rs, Region{Name: name, Location: loc})
+	}
+	return rs, nil
+}
+
+// Mission is a template drawn for a region and period
+type Mission struct {
+	Template
+	Region string
+	Start  time.Time
+	End    time.Time
+}
+
+// Missions returns the missions of the region at now, daily ones first and
+// each in the order of the templates
+func (rot *Rotation) Missions(r Region, now time.Time) []Mission {
+	var ms []Mission
+	for _, p := range Periods {
+		start, end := p.window(now, r.Location)
+		for _, tp := range rot.draw(r.Name, p, start) {
+			ms = append(ms, Mission{Template: tp, Region: r.Name, Start: start, End: end})
+		}
+	}
+	return ms
+}
+
+// draw picks the templates of the period that starts at start. Each template
+// gets a key hashed from the region, the local date and its id, and those
+// with the smallest keys are drawn, so adding a template only changes the
+// draws it wins.
+func (rot *Rotation) draw(region string, p Period, start time.Time) []Template {
+	n := rot.Daily
+	if p == Weekly {
+		n = rot.Weekly
+	}
+	type entry struct {
+		index int
+		key   uint64
+	}
+	var pool []entry
+	for i, tp := range rot.Templates {
+		if tp.Period != p {
+			continue
+		}
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%s/%s/%s/%s", region, p, start.Format(time.DateOnly), tp.ID)
+		pool = append(pool, entry{index: i, key: h.Sum64()})
+	}
+	slices.SortFunc(pool, func(a, b entry) int { return cmp.Compare(a.key, b.key) })
+	pool = pool[:min(n, len(pool))]
+	slices.SortFunc(pool, func(a, b entry) int { return cmp.Compare(a.index, b.index) })
+
+	tps := make([]Template, len(pool))
+	for i, e := range pool {
+		tps[i] = rot.Templates[e.index]
+	}
+	return tps
+}
@@ -0,0 +1,243 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	vd "github.com/go-ozzo/ozzo-validation"
+	"github.com/pikachu0310/senirenol-server/core/database"
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+)
+
+func mustRegions(t *testing.T, s string) []Region {
+	t.Helper()
+	rs, err := ParseRegions(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs
+}
+
+func TestWindow(t *testing.T) {
+	t.Parallel()
+	tokyo := mustRegions(t, "jp=Asia/Tokyo")[0].Location
+	// Sunday 2026-10-18 23:30 in Tokyo
+	now := time.Date(2026, 10, 18, 14, 30, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		period     Period
+		loc        *time.Location
+		start, end time.Time
+	}{
+		{Daily, tokyo, time.Date(2026, 10, 17, 15, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)},
+		{Daily, time.UTC, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{Weekly, tokyo, time.Date(2026, 10, 11, 15, 0, 0, 0, time.UTC), time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)},
+		{Weekly, time.UTC, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+	} {
+		start, end := tc.period.window(now, tc.loc)
+		if !start.Equal(tc.start) || !end.Equal(tc.end) {
+			t.Errorf("%s in %s: [%s, %s), want [%s, %s)", tc.period, tc.loc, start, end, tc.start, tc.end)
+		}
+	}
+}
+
+func TestDraw(t *testing.T) {
+	t.Parallel()
+	rot := Default()
+	jp := mustRegions(t, "jp=Asia/Tokyo")[0]
+	day := time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC)
+	ids := func(ms []Mission) []string {
+		out := make([]string, len(ms))
+		for i, m := range ms {
+			out[i] = m.ID
+		}
+		return out
+	}
+
+	ms := rot.Missions(jp, day)
+	if len(ms) != rot.Daily+rot.Weekly {
+		t.Fatalf("got %d missions, want %d", len(ms), rot.Daily+rot.Weekly)
+	}
+	for i, m := range ms {
+		want := Daily
+		if i >= rot.Daily {
+			want = Weekly
+		}
+		if m.Period != want {
+			t.Errorf("mission %d is %s, want %s", i, m.Period, want)
+		}
+	}
+	// the draw only depends on the local day
+	if later := rot.Missions(jp, day.Add(10*time.Hour)); !slices.Equal(ids(later), ids(ms)) {
+		t.Errorf("later the same day: %v, want %v", ids(later), ids(ms))
+	}
+	// weekly missions stay for the week while daily ones change
+	changed := false
+	for d := 1; d < 7; d++ {
+		next := rot.Missions(jp, day.AddDate(0, 0, d))
+		if !slices.Equal(ids(next)[rot.Daily:], ids(ms)[rot.Daily:]) {
+			t.Errorf("weekly missions changed on day %d", d)
+		}
+		changed = changed || !slices.Equal(ids(next)[:rot.Daily], ids(ms)[:rot.Daily])
+	}
+	if !changed {
+		t.Error("daily missions never changed in a week")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Parallel()
+	_, err := Load(strings.NewReader(`
+daily: -1
+templates:
+  - {id: ok, period: daily, name: OK}
+  - {id: monthly, period: monthly, name: Monthly}
+  - {id: charts, period: weekly, name: Charts, condition: {count: 3, per: chart}}
+  - {id: ok, period: weekly, name: Again}
+`))
+	var errs vd.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got %v, want vd.Errors", err)
+	}
+	for _, key := range []string{"daily", "templates[1]", "templates[2]", "templates[3]"} {
+		if errs[key] == nil {
+			t.Errorf("no error for %s: %v", key, errs)
+		}
+	}
+	if len(errs) != 4 {
+		t.Errorf("got %v", errs)
+	}
+
+	for _, s := range []string{"jp", "JP=Asia/Tokyo", "jp=Nowhere/City", "jp=UTC,jp=UTC"} {
+		if _, err := ParseRegions(s); err == nil {
+			t.Errorf("ParseRegions(%q) succeeded", s)
+		}
+	}
+}
+
+func TestService(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db, err := database.SetupSQLite(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	repo := repository.New(db)
+	uid, err := repo.CreateUser(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := uid.String()
+
+	rot, err := Load(strings.NewReader(`
+daily: 1
+weekly: 1
+templates:
+  - {id: present_2, period: daily, name: Present, reward: 10, condition: {count: 2, difficulty: present}}
+  - {id: grade_s_3, period: weekly, name: S, reward: 50, condition: {count: 3, grade: S}}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions := mustRegions(t, "jp=Asia/Tokyo,global=UTC")
+	s := New(repo, rot, regions)
+	// Monday 2026-10-19 23:00 in Tokyo, 14:00 in UTC
+	now := time.Date(2026, 10, 19, 14, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	for _, d := range []repository.Difficulty{repository.DiffPresent, repository.DiffFuture} {
+		if err := repo.UpsertChart(ctx, repository.UpsertChartParams{BeatmapID: "song_" + d.String(), SongName: "Song", Difficulty: int(d)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// play stores a score played now
+	play := func(d repository.Difficulty, score int) int64 {
+		t.Helper()
+		id, err := repo.InsertScore(ctx, repository.InsertScoreParams{UserID: userID, BeatmapID: "song_" + d.String(), Score: score})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, "UPDATE scores SET created_at = ? WHERE id = ?", now, id); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	progress := func(r Region) []int {
+		t.Helper()
+		ss, err := s.Statuses(ctx, userID, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]int, len(ss))
+		for i, st := range ss {
+			out[i] = st.Progress
+		}
+		return out
+	}
+	jp, global := regions[0], regions[1]
+
+	play(repository.DiffPresent, 960000)
+	play(repository.DiffFuture, 950000)
+	if got := progress(jp); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("progress: %v", got)
+	}
+	if _, err := s.Claim(ctx, userID, jp, "present_2"); !errors.Is(err, repository.ErrMissionIncomplete) {
+		t.Errorf("claim of an incomplete mission: %v", err)
+	}
+	if _, err := s.Claim(ctx, userID, jp, "nope"); !errors.Is(err, repository.ErrMissionNotFound) {
+		t.Errorf("claim of an unknown mission: %v", err)
+	}
+
+	cheated := play(repository.DiffPresent, 990000)
+	if got := progress(jp); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("progress: %v", got)
+	}
+	// invalidated scores stop counting
+	if _, err := repo.ModerateScores(ctx, repository.ModerateScoresParams{Action: repository.ModerationInvalidate, ScoreID: cheated, Actor: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := progress(jp); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("progress after invalidation: %v", got)
+	}
+	if _, err := s.Claim(ctx, userID, jp, "present_2"); !errors.Is(err, repository.ErrMissionIncomplete) {
+		t.Errorf("claim with an invalidated score: %v", err)
+	}
+
+	play(repository.DiffPresent, 800000)
+	play(repository.DiffPresent, 990000)
+	// progress stops at the goal
+	if got := progress(jp); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("progress: %v", got)
+	}
+	if m, err := s.Claim(ctx, userID, jp, "present_2"); err != nil || m.Reward != 10 {
+		t.Fatalf("claim: %v, %v", m, err)
+	}
+	if _, err := s.Claim(ctx, userID, jp, "present_2"); !errors.Is(err, repository.ErrMissionClaimed) {
+		t.Errorf("second claim: %v", err)
+	}
+
+	// a new day in Tokyo, but still the same day in UTC
+	now = now.Add(2 * time.Hour)
+	if got := progress(jp); !slices.Equal(got, []int{0, 3}) {
+		t.Errorf("progress on the next day in jp: %v", got)
+	}
+	if got := progress(global); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("progress in global: %v", got)
+	}
+	if _, err := s.Claim(ctx, userID, jp, "present_2"); !errors.Is(err, repository.ErrMissionIncomplete) {
+		t.Errorf("claim of today's mission: %v", err)
+	}
+	// the weekly mission carries over
+	if _, err := s.Claim(ctx, userID, jp, "grade_s_3"); err != nil {
+		t.Errorf("claim of the weekly mission: %v", err)
+	}
+	if points, err := s.Points(ctx, userID); err != nil || points != 60 {
+		t.Errorf("points: %d, %v", points, err)
+	}
+}
@@ -0,0 +1,131 @@
+package mission
+
+import (
+	"context"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core/internal/repository"
+)
+
+// Status is how far a player is on a mission
+type Status struct {
+	Mission
+	// Progress counts the matching plays, up to Condition.Count
+	Progress int
+	// ClaimedAt is nil until the reward is claimed
+	ClaimedAt *time.Time
+}
+
+// Complete reports whether the reward may be claimed
+func (s Status) Complete() bool {
+	return s.Progress >= s.Condition.Count
+}
+
+type Service struct {
+	repo     *repository.Repository
+	rotation *Rotation
+	regions  []Region
+	now      func() time.Time
+}
+
+// New returns the missions of rot for the regions, the first of which is the default
+func New(repo *repository.Repository, rot *Rotation, regions []Region) *Service {
+	return &Service{repo: repo, rotation: rot, regions: regions, now: time.Now}
+}
+
+// Region returns the region named name, or the default region for ""
+func (s *Service) Region(name string) (Region, bool) {
+	for _, r := range s.regions {
+		if name == "" || r.Name == name {
+			return r, true
+		}
+	}
+	return Region{}, false
+}
+
+// Missions returns the missions of the region now
+func (s *Service) Missions(r Region) []Mission {
+	return s.rotation.Missions(r, s.now())
+}
+
+// Statuses returns the progress of the user on the missions of the region now
+func (s *Service) Statuses(ctx context.Context, userID string, r Region) ([]Status, error) {
+	ms := s.Missions(r)
+	if len(ms) == 0 {
+		return nil, nil
+	}
+	since := ms[0].Start
+	for _, m := range ms {
+		if m.Start.Before(since) {
+			since = m.Start
+		}
+	}
+	plays, err := s.repo.GetUserPlays(ctx, repository.GetUserPlaysParams{UserID: userID, Played: repository.TimeRange{From: since}})
+	if err != nil {
+		return nil, err
+	}
+	cs, err := s.repo.GetMissionClaims(ctx, userID, r.Name, since)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := make([]Status, len(ms))
+	for i, m := range ms {
+		ss[i] = Status{Mission: m, Progress: min(m.progress(plays), m.Condition.Count)}
+		for _, c := range cs {
+			if c.MissionID == m.ID && c.PeriodStart.Equal(m.Start) {
+				ss[i].ClaimedAt = &c.ClaimedAt
+			}
+		}
+	}
+	return ss, nil
+}
+
+// Claim gives the user the reward of a complete mission of the region. Only
+// missions of the current day or week can be claimed.
+func (s *Service) Claim(ctx context.Context, userID string, r Region, missionID string) (*Mission, error) {
+	now := s.now()
+	for _, m := range s.rotation.Missions(r, now) {
+		if m.ID != missionID {
+			continue
+		}
+		plays, err := s.repo.GetUserPlays(ctx, repository.GetUserPlaysParams{UserID: userID, Played: repository.TimeRange{From: m.Start, To: m.End}})
+		if err != nil {
+			return nil, err
+		}
+		n := m.progress(plays)
+		if n < m.Condition.Count {
+			return nil, repository.ErrMissionIncomplete
+		}
+		if err := s.repo.ClaimMission(ctx, repository.ClaimMissionParams{
+			MissionKey: m.key(userID),
+			Progress:   n,
+			Reward:     m.Reward,
+			At:         now,
+		}); err != nil {
+			return nil, err
+		}
+		return &m, nil
+	}
+	return nil, repository.ErrMissionNotFound
+}
+
+// Points returns the sum of the rewards the user has claimed in every region
+func (s *Service) Points(ctx context.Context, userID string) (int, error) {
+	return s.repo.GetMissionPoints(ctx, userID)
+}
+
+// progress counts the plays made during the period of m that match its condition
+func (m Mission) progress(plays []*repository.PlayRow) int {
+	n := 0
+	for _, p := range plays {
+		if !p.CreatedAt.Before(m.Start) && p.CreatedAt.Before(m.End) && m.Condition.Matches(p) {
+			n++
+		}
+	}
+	return n
+}
+
+func (m Mission) key(userID string) repository.MissionKey {
+	return repository.MissionKey{UserID: userID, Region: m.Region, MissionID: m.ID, PeriodStart: m.Start}
+}
@@ -0,0 +1,89 @@
+// Package realtime fans leaderboard events out to streaming clients.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// GlobalTopic receives every event regardless of chart
+const GlobalTopic = "global"
+
+// ChartTopic is the topic for events about a single chart
+func ChartTopic(beatmapID string) string {
+	return "chart:" + beatmapID
+}
+
+// Event is a message delivered to subscribers. Data is pre-encoded JSON so
+// that brokers spanning several instances can forward it as-is.
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+	At   time.Time       `json:"at"`
+}
+
+// Broker publishes events to topics and lets clients subscribe to them.
+// Hub is the in-process implementation; a broker backed by a shared message
+// bus can implement the same interface to fan out across instances.
+type Broker interface {
+	Publish(ctx context.Context, topic string, ev Event) error
+	// Subscribe returns a channel of events for topic and a function that
+	// cancels the subscription and closes the channel.
+	Subscribe(topic string) (<-chan Event, func())
+}
+
+// Hub is an in-process Broker. Slow subscribers whose buffer is full miss
+// events instead of blocking publishers.
+type Hub struct {
+	mu      sync.RWMutex
+	subs    map[string]map[chan Event]struct{}
+	bufSize int
+}
+
+var _ Broker = (*Hub)(nil)
+
+func NewHub(bufSize int) *Hub {
+	return &Hub{
+		subs:    make(map[string]map[chan Event]struct{}),
+		bufSize: bufSize,
+	}
+}
+
+func (h *Hub) Publish(_ context.Context, topic string, ev Event) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subs[topic] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+	return nil
+}
+
+func (h *Hub) Subscribe(topic string) (<-chan Event, func()) {
+	ch := make(chan Event, h.bufSize)
+
+	h.mu.Lock()
+	if h.subs[topic] == nil {
+		h.subs[topic] = make(map[chan Event]struct{})
+	}
+	h.subs[topic][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs[topic], ch)
+			if len(h.subs[topic]) == 0 {
+				delete(h.subs, topic)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
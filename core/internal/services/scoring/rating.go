@@ -0,0 +1,39 @@
+package scoring
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// RatingBestCount is how many of a player's best play ratings make up their rating
+const RatingBestCount = 10
+
+// PlayRating is what a score earns on a chart with the given constant.
+// It is constant+2 at MaxScore, constant+1 at 980000 and constant at 950000
+// (grade S), then falls by 1 per 30000 below that without going negative.
+func PlayRating(constant float64, score int) float64 {
+	switch {
+	case score >= MaxScore:
+		return constant + 2
+	case score >= 980000:
+		return constant + 1 + float64(score-980000)/20000
+	default:
+		return math.Max(0, constant+float64(score-950000)/30000)
+	}
+}
+
+// Rating is the mean of the best RatingBestCount play ratings, one per chart.
+// Players with fewer charts have the missing ones counted as 0.
+func Rating(playRatings []float64) float64 {
+	rs := slices.Clone(playRatings)
+	slices.SortFunc(rs, func(a, b float64) int { return cmp.Compare(b, a) })
+	if len(rs) > RatingBestCount {
+		rs = rs[:RatingBestCount]
+	}
+	var sum float64
+	for _, r := range rs {
+		sum += r
+	}
+	return sum / RatingBestCount
+}
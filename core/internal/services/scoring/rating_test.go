@@ -0,0 +1,43 @@
+package scoring
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPlayRating(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		score int
+		want  float64
+	}{
+		{MaxScore, 11},
+		{990000, 10.5},
+		{980000, 10},
+		{950000, 9},
+		{920000, 8},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := PlayRating(9, tt.score); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("PlayRating(9, %d) = %v, want %v", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestRating(t *testing.T) {
+	t.Parallel()
+
+	rs := []float64{1, 12, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	// best 10 are 3..12
+	if got, want := Rating(rs), 7.5; got != want {
+		t.Errorf("Rating = %v, want %v", got, want)
+	}
+	if got, want := Rating([]float64{10}), 1.0; got != want {
+		t.Errorf("Rating of one chart = %v, want %v", got, want)
+	}
+	if rs[0] != 1 {
+		t.Error("Rating must not reorder its argument")
+	}
+}
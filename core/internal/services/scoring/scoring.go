@@ -0,0 +1,173 @@
+// Package scoring derives grades and clear lamps from play results.
+//
+// Scores range from 0 to MaxScore. A play is cleared when it reaches
+// ClearBorder, i.e. grade C or better.
+package scoring
+
+const (
+	MaxScore    = 1000000
+	ClearBorder = 700000
+)
+
+// Grade is the letter rank of a score
+type Grade string
+
+const (
+	GradeSPlus Grade = "S+"
+	GradeS     Grade = "S"
+	GradeA     Grade = "A"
+	GradeB     Grade = "B"
+	GradeC     Grade = "C"
+	GradeD     Grade = "D"
+)
+
+// Grades lists every grade from best to worst
+var Grades = []Grade{GradeSPlus, GradeS, GradeA, GradeB, GradeC, GradeD}
+
+var gradeBorders = []struct {
+	border int
+	grade  Grade
+}{
+	{990000, GradeSPlus},
+	{950000, GradeS},
+	{900000, GradeA},
+	{800000, GradeB},
+	{ClearBorder, GradeC},
+}
+
+func GradeOf(score int) Grade {
+	for _, b := range gradeBorders {
+		if score >= b.border {
+			return b.grade
+		}
+	}
+	return GradeD
+}
+
+// AtLeast reports whether g is as good as or better than other
+func (g Grade) AtLeast(other Grade) bool {
+	return gradeIndex(g) <= gradeIndex(other)
+}
+
+func gradeIndex(g Grade) int {
+	for i, x := range Grades {
+		if x == g {
+			return i
+		}
+	}
+	return len(Grades)
+}
+
+// Lamp is the clear status of a play
+type Lamp string
+
+const (
+	// LampAllPerfect: no GOOD and no MISS
+	LampAllPerfect Lamp = "ALL_PERFECT"
+	// LampFullCombo: no MISS
+	LampFullCombo Lamp = "FULL_COMBO"
+	// LampClear: reached ClearBorder
+	LampClear Lamp = "CLEAR"
+	// LampFailed: below ClearBorder with at least one MISS
+	LampFailed Lamp = "FAILED"
+)
+
+// Lamps lists every lamp from best to worst
+var Lamps = []Lamp{LampAllPerfect, LampFullCombo, LampClear, LampFailed}
+
+// Judgements are the judgement counts of a play
+type Judgements struct {
+	PerfectCriticalFast int
+	PerfectCriticalLate int
+	PerfectFast         int
+	PerfectLate         int
+	GoodFast            int
+	GoodLate            int
+	Miss                int
+}
+
+func LampOf(score int, j Judgements) Lamp {
+	switch {
+	case j.Miss == 0 && j.GoodFast+j.GoodLate == 0:
+		return LampAllPerfect
+	case j.Miss == 0:
+		return LampFullCombo
+	case score >= ClearBorder:
+		return LampClear
+	default:
+		return LampFailed
+	}
+}
+
+// AtLeast reports whether l is as good as or better than other
+func (l Lamp) AtLeast(other Lamp) bool {
+	return lampIndex(l) <= lampIndex(other)
+}
+
+func lampIndex(l Lamp) int {
+	for i, x := range Lamps {
+		if x == l {
+			return i
+		}
+	}
+	return len(Lamps)
+}
+
+// IsClear reports whether the lamp counts as a clear
+func (l Lamp) IsClear() bool {
+	return l.AtLeast(LampClear)
+}
+
+// Timing windows (half-width, in milliseconds) of each judgement
+const (
+	PerfectCriticalWindowMs = 25.0
+	PerfectWindowMs         = 50.0
+	GoodWindowMs            = 100.0
+)
+
+// Notes returns the number of judged notes
+func (j Judgements) Notes() int {
+	return j.PerfectCriticalFast + j.PerfectCriticalLate + j.PerfectFast + j.PerfectLate + j.GoodFast + j.GoodLate + j.Miss
+}
+
+// Fast returns the number of hits judged early
+func (j Judgements) Fast() int {
+	return j.PerfectCriticalFast + j.PerfectFast + j.GoodFast
+}
+
+// Late returns the number of hits judged late
+func (j Judgements) Late() int {
+	return j.PerfectCriticalLate + j.PerfectLate + j.GoodLate
+}
+
+// Add returns the element-wise sum of j and o
+func (j Judgements) Add(o Judgements) Judgements {
+	return Judgements{
+		PerfectCriticalFast: j.PerfectCriticalFast + o.PerfectCriticalFast,
+		PerfectCriticalLate: j.PerfectCriticalLate + o.PerfectCriticalLate,
+		PerfectFast:         j.PerfectFast + o.PerfectFast,
+		PerfectLate:         j.PerfectLate + o.PerfectLate,
+		GoodFast:            j.GoodFast + o.GoodFast,
+		GoodLate:            j.GoodLate + o.GoodLate,
+		Miss:                j.Miss + o.Miss,
+	}
+}
+
+// MeanTimingErrorMs estimates the average hit timing error, assuming each hit
+// lands in the middle of its judgement window. Negative means early. ok is
+// false when there are no hits.
+func (j Judgements) MeanTimingErrorMs() (ms float64, ok bool) {
+	hits := j.Fast() + j.Late()
+	if hits == 0 {
+		return 0, false
+	}
+	mid := func(inner, outer float64) float64 { return (inner + outer) / 2 }
+	critical := mid(0, PerfectCriticalWindowMs)
+	perfect := mid(PerfectCriticalWindowMs, PerfectWindowMs)
+	good := mid(PerfectWindowMs, GoodWindowMs)
+
+	sum := critical*float64(j.PerfectCriticalLate-j.PerfectCriticalFast) +
+		perfect*float64(j.PerfectLate-j.PerfectFast) +
+		good*float64(j.GoodLate-j.GoodFast)
+	return sum / float64(hits), true
+}
@@ -0,0 +1,82 @@
+// Package tracing installs the OpenTelemetry tracer provider that the HTTP
+// middleware, the repository and the background jobs record spans with.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+)
+
+// Exporters selectable with TRACE_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+type Options struct {
+	// Exporter is one of the Exporter* constants
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://localhost:4318.
+	// Empty falls back to the OTEL_EXPORTER_OTLP_* environment variables.
+	Endpoint string
+	// SampleRatio is the fraction of new traces recorded. Traces continued
+	// from a caller follow the caller's sampling decision.
+	SampleRatio float64
+	ServiceName string
+	// Writer receives the stdout exporter's output. Defaults to os.Stdout.
+	Writer io.Writer
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The returned function flushes pending spans and must be called
+// before exit. With ExporterNone spans are not recorded at all.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exp sdktrace.SpanExporter
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var o []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			o = append(o, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exp, err = otlptracehttp.New(ctx, o...)
+	case ExporterStdout:
+		w := opts.Writer
+		if w == nil {
+			w = os.Stdout
+		}
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter: %s", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s trace exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
@@ -0,0 +1,34 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+// Setup installs global state, so the cases run in order
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := Setup(ctx, Options{Exporter: "zipkin"}); err == nil {
+		t.Error("unknown exporter should fail")
+	}
+
+	var buf bytes.Buffer
+	shutdown, err := Setup(ctx, Options{Exporter: ExporterStdout, SampleRatio: 1, ServiceName: "senirenol-test", Writer: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := otel.Tracer("test").Start(ctx, "exported-span")
+	span.End()
+	if err := shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `"Name":"exported-span"`) || !strings.Contains(out, "senirenol-test") {
+		t.Errorf("span not exported: %s", out)
+	}
+}
@@ -0,0 +1,201 @@
+// Package worker runs periodic background jobs inside the server process.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var tracer = otel.Tracer("github.com/pikachu0310/senirenol-server/core/internal/services/worker")
+
+// Job is a task run every Interval. Runs of the same job never overlap.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Status is the run history of a registered job
+type Status struct {
+	Name     string
+	Interval time.Duration
+	Running  bool
+	Runs     int
+	Failures int
+	// Since is when the scheduler started running the job, zero before Start
+	Since time.Time
+	// LastStarted and LastFinished are zero until the job has run
+	LastStarted  time.Time
+	LastFinished time.Time
+	// LastSuccess is when a run last finished without error
+	LastSuccess time.Time
+	LastError   string
+}
+
+// Overdue reports whether the job has gone more than two intervals without a
+// successful run since it was started, e.g. because it keeps failing or a run
+// takes far longer than its interval.
+func (s Status) Overdue(now time.Time) bool {
+	if s.Since.IsZero() {
+		return false
+	}
+	last := s.Since
+	if s.LastSuccess.After(last) {
+		last = s.LastSuccess
+	}
+	return now.Sub(last) > 2*s.Interval
+}
+
+// ErrUnknownJob is returned by RunNow for names that were never registered
+var ErrUnknownJob = errors.New("unknown job")
+
+// Scheduler is the registry of jobs and runs each of them on its own ticker
+// between Start and Stop.
+type Scheduler struct {
+	mu     sync.Mutex
+	jobs   []*entry
+	byName map[string]*entry
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	now    func() time.Time
+}
+
+type entry struct {
+	job    Job
+	run    sync.Mutex // serialises scheduled and manual runs
+	status Status
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		byName: make(map[string]*entry),
+		now:    time.Now,
+	}
+}
+
+// Register adds a job. It must be called before Start.
+func (s *Scheduler) Register(j Job) error {
+	if j.Name == "" || j.Run == nil {
+		return errors.New("register job: name and run are required")
+	}
+	if j.Interval <= 0 {
+		return fmt.Errorf("register job %s: interval must be positive", j.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byName[j.Name]; ok {
+		return fmt.Errorf("register job %s: already registered", j.Name)
+	}
+	e := &entry{job: j, status: Status{Name: j.Name, Interval: j.Interval}}
+	s.jobs = append(s.jobs, e)
+	s.byName[j.Name] = e
+	return nil
+}
+
+// Start runs every job immediately and then on its interval until Stop is
+// called or ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancel = cancel
+	now := s.now()
+	for _, e := range s.jobs {
+		e.status.Since = now
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(ctx, e)
+		}()
+	}
+}
+
+// Stop cancels running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+// RunNow runs the named job synchronously, waiting for a scheduled run in progress to finish first
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	s.mu.Lock()
+	e, ok := s.byName[name]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+	return s.runOnce(ctx, e)
+}
+
+// Statuses returns the status of every job in registration order
+func (s *Scheduler) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Status, len(s.jobs))
+	for i, e := range s.jobs {
+		out[i] = e.status
+	}
+	return out
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	ticker := time.NewTicker(e.job.Interval)
+	defer ticker.Stop()
+	for {
+		if err := s.runOnce(ctx, e); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "job failed", "job", e.job.Name, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, e *entry) error {
+	e.run.Lock()
+	defer e.run.Unlock()
+
+	s.mu.Lock()
+	e.status.Running = true
+	e.status.LastStarted = s.now()
+	s.mu.Unlock()
+
+	// RunNow from a request traces the job inside the request
+	ctx, span := tracer.Start(ctx, "job "+e.job.Name)
+	err := e.job.Run(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.status.Running = false
+	e.status.Runs++
+	e.status.LastFinished = s.now()
+	if err != nil {
+		e.status.Failures++
+		e.status.LastError = err.Error()
+	} else {
+		e.status.LastSuccess = e.status.LastFinished
+		e.status.LastError = ""
+	}
+	return err
+}
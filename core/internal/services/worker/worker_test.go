@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	t.Parallel()
+
+	s := NewScheduler()
+	var runs atomic.Int32
+	started := make(chan struct{}, 1)
+	if err := s.Register(Job{Name: "count", Interval: time.Hour, Run: func(ctx context.Context) error {
+		runs.Add(1)
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	failing := errors.New("boom")
+	if err := s.Register(Job{Name: "fail", Interval: time.Hour, Run: func(ctx context.Context) error { return failing }}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Register(Job{Name: "count", Interval: time.Hour, Run: func(context.Context) error { return nil }}); err == nil {
+		t.Error("duplicate job name should be rejected")
+	}
+
+	s.Start(context.Background())
+	<-started
+	if err := s.RunNow(context.Background(), "count"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RunNow(context.Background(), "fail"); !errors.Is(err, failing) {
+		t.Errorf("RunNow(fail) = %v, want %v", err, failing)
+	}
+	if err := s.RunNow(context.Background(), "missing"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("RunNow(missing) = %v, want %v", err, ErrUnknownJob)
+	}
+	s.Stop()
+
+	if got := runs.Load(); got != 2 {
+		t.Errorf("runs = %d, want 2", got)
+	}
+	st := s.Statuses()
+	if len(st) != 2 || st[0].Name != "count" || st[1].Name != "fail" {
+		t.Fatalf("statuses = %+v", st)
+	}
+	if st[0].Runs != 2 || st[0].LastSuccess.IsZero() || st[0].LastError != "" {
+		t.Errorf("count status = %+v", st[0])
+	}
+	if st[1].Runs != 2 || st[1].Failures != 2 || !st[1].LastSuccess.IsZero() || st[1].LastError != "boom" {
+		t.Errorf("fail status = %+v", st[1])
+	}
+}
+
+func TestStatusOverdue(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name string
+		s    Status
+		at   time.Duration
+		want bool
+	}{
+		{"not started", Status{Interval: time.Minute}, time.Hour, false},
+		{"first run pending", Status{Interval: time.Minute, Since: start}, 90 * time.Second, false},
+		{"never succeeded", Status{Interval: time.Minute, Since: start}, 3 * time.Minute, true},
+		{"recent success", Status{Interval: time.Minute, Since: start, LastSuccess: start.Add(time.Hour)}, time.Hour + time.Minute, false},
+		{"stale success", Status{Interval: time.Minute, Since: start, LastSuccess: start.Add(time.Hour)}, time.Hour + 5*time.Minute, true},
+	} {
+		if got := tc.s.Overdue(start.Add(tc.at)); got != tc.want {
+			t.Errorf("%s: Overdue = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -11,8 +11,18 @@ import (
 )
 
 func SetupRoutes(h *handler.Handler, e *echo.Echo) {
+	e.HTTPErrorHandler = h.HandleError
+	e.Use(handler.Trace, handler.RequestID(), handler.LogRequests(), h.ObserveRequests)
+
 	e.StaticFS("/", frontend.UI)
 
+	// Prometheus metrics
+	e.GET("/metrics", h.ServeMetrics)
+
+	// probes
+	e.GET("/livez", h.GetLiveness)
+	e.GET("/readyz", h.GetReadiness)
+
 	// Swagger UI
 	docs.SwaggerInfo.Host = "senirenol.trap.games"
 	docs.SwaggerInfo.BasePath = "/api/v1"
@@ -32,8 +42,31 @@ func SetupRoutes(h *handler.Handler, e *echo.Echo) {
 	{
 		userAPI.POST("", h.RegisterUser)
 		userAPI.POST("/update", h.UpdateUserName)
+		userAPI.GET("/me/export", h.ExportMyData, h.RequireUser)
+		userAPI.DELETE("/me", h.DeleteMe, h.RequireUser)
+		userAPI.GET("/me/friend-code", h.GetMyFriendCode, h.RequireUser)
+		userAPI.GET("/me/friends", h.GetMyFriends, h.RequireUser)
+		userAPI.DELETE("/me/friends/:userID", h.RemoveFriend, h.RequireUser)
+		userAPI.GET("/me/friends/requests", h.GetMyFriendRequests, h.RequireUser)
+		userAPI.POST("/me/friends/requests", h.SendFriendRequest, h.RequireUser)
+		userAPI.POST("/me/friends/requests/:userID/accept", h.AcceptFriendRequest, h.RequireUser)
+		userAPI.DELETE("/me/friends/requests/:userID", h.DeleteFriendRequest, h.RequireUser)
+		userAPI.GET("/me/rivals", h.GetMyRivals, h.RequireUser)
+		userAPI.GET("/me/teams", h.GetMyTeams, h.RequireUser)
+		userAPI.PUT("/me/rivals/:userID", h.AddRival, h.RequireUser)
+		userAPI.GET("/me/profile", h.GetMyProfile, h.RequireUser)
+		userAPI.PATCH("/me/profile", h.UpdateMyProfile, h.RequireUser)
+		userAPI.GET("/me/missions", h.GetMyMissions, h.RequireUser)
+		userAPI.POST("/me/missions/:missionID/claim", h.ClaimMission, h.RequireUser)
+		userAPI.DELETE("/me/rivals/:userID", h.RemoveRival, h.RequireUser)
 		userAPI.GET("/:userID", h.GetUser)
+		userAPI.GET("/:userID/profile", h.GetUserProfile)
 		userAPI.GET("/:userID/stats", h.GetUserStats)
+		userAPI.GET("/:userID/achievements", h.GetUserAchievements)
+		userAPI.GET("/:userID/scores", h.GetUserScores)
+		userAPI.GET("/:userID/bests", h.GetUserBests)
+		userAPI.GET("/:userID/versus/:otherID", h.GetVersus)
+		userAPI.GET("/:userID/charts/:beatmapID/analytics", h.GetUserChartAnalytics)
 	}
 
 	// chart API
@@ -41,11 +74,62 @@ func SetupRoutes(h *handler.Handler, e *echo.Echo) {
 	{
 		chartAPI.POST("", h.UpsertChart)
 		chartAPI.GET("/ranking", h.GetChartRanking)
+		chartAPI.GET("/ranking/events", h.StreamRankingEvents)
+		chartAPI.GET("/difficulty", h.GetChartDifficulties)
+		chartAPI.GET("/:beatmapID/analytics", h.GetChartAnalytics)
+	}
+
+	// team API
+	teamAPI := v1API.Group("/teams")
+	{
+		teamAPI.GET("", h.GetTeams)
+		teamAPI.POST("", h.CreateTeam, h.RequireUser)
+		teamAPI.POST("/join", h.JoinTeam, h.RequireUser)
+		teamAPI.GET("/ranking", h.GetTeamRanking)
+		teamAPI.GET("/ranking/weekly", h.GetWeeklyTeamRanking)
+		teamAPI.GET("/:teamID", h.GetTeam)
+		teamAPI.DELETE("/:teamID", h.DeleteTeam, h.RequireUser)
+		teamAPI.GET("/:teamID/invite-code", h.GetTeamInviteCode, h.RequireUser)
+		teamAPI.POST("/:teamID/invite-code", h.ResetTeamInviteCode, h.RequireUser)
+		teamAPI.PUT("/:teamID/members/:userID/role", h.SetTeamMemberRole, h.RequireUser)
+		teamAPI.DELETE("/:teamID/members/:userID", h.RemoveTeamMember, h.RequireUser)
+		teamAPI.GET("/:teamID/versus/:otherID", h.GetTeamVersus)
 	}
 
 	// song API
 	v1API.GET("/songs/playcount", h.GetSongPlaycountRanking)
 
+	// achievement API
+	v1API.GET("/achievements", h.GetAchievements)
+
+	// mission API
+	v1API.GET("/missions", h.GetMissions)
+
+	// rating API
+	v1API.GET("/ratings/ranking", h.GetRatingRanking)
+
 	// score API
-	v1API.POST("/scores", h.SubmitScore)
+	scoreAPI := v1API.Group("/scores")
+	{
+		scoreAPI.POST("", h.SubmitScore)
+		scoreAPI.GET("/recent", h.GetRecentPlays)
+	}
+
+	// admin API
+	adminAPI := v1API.Group("/admin", h.RequireAdmin)
+	{
+		adminAPI.GET("/audit-logs", h.GetAuditLogs)
+		adminAPI.GET("/cache/stats", h.GetCacheStats)
+		adminAPI.GET("/export", h.ExportData)
+		adminAPI.POST("/import", h.ImportData)
+		adminAPI.GET("/jobs", h.GetJobs)
+		adminAPI.POST("/rankings/rebuild", h.RebuildRankings)
+		adminAPI.GET("/charts/catalog", h.ExportChartCatalog)
+		adminAPI.POST("/charts/catalog", h.ImportChartCatalog)
+		adminAPI.POST("/charts/difficulty/recompute", h.RecomputeChartDifficulties)
+		adminAPI.POST("/scores/moderate", h.ModerateScores)
+		adminAPI.DELETE("/scores/:scoreID", h.DeleteScore)
+		adminAPI.POST("/scores/:scoreID/restore", h.RestoreScore)
+		adminAPI.POST("/scores/:scoreID/invalidate", h.InvalidateScore)
+	}
 }
@@ -24,134 +24,141 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/charts": {
-            "post": {
-                "consumes": [
-                    "application/json"
-                ],
+        "/achievements": {
+            "get": {
+                "description": "定義されているすべての実績を返します",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "charts"
-                ],
-                "summary": "譜面登録/更新",
-                "parameters": [
-                    {
-                        "description": "譜面情報",
-                        "name": "chart",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.UpsertChartRequest"
-                        }
-                    }
+                    "achievements"
                 ],
+                "summary": "実績一覧",
                 "responses": {
                     "200": {
-                        "description": "登録結果",
-                        "schema": {
-                            "$ref": "#/definitions/handler.UpsertChartResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                        "description": "実績一覧",
                         "schema": {
-                            "$ref": "#/definitions/handler.ErrorResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handler.AchievementResponse"
+                            }
                         }
                     }
                 }
             }
         },
-        "/charts/ranking": {
+        "/admin/audit-logs": {
             "get": {
-                "description": "beatmap_idを指定したランキング、未指定時は全譜面のランキング",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "管理操作の履歴を新しい順に返します",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "charts"
+                    "admin"
                 ],
-                "summary": "譜面ランキング",
+                "summary": "監査ログ取得",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "譜面ID",
-                        "name": "beatmap_id",
-                        "in": "query"
-                    },
                     {
                         "type": "integer",
-                        "description": "ランキング上限",
+                        "description": "取得件数 (既定50, 最大100)",
                         "name": "limit",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "ランキング配列",
+                        "description": "監査ログ",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/handler.ChartRankingResponse"
+                                "$ref": "#/definitions/handler.AuditLogResponse"
                             }
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/ping": {
+        "/admin/cache/stats": {
             "get": {
-                "description": "サーバーの死活確認用エンドポイント",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "ランキング等のキャッシュのヒット/ミス数を返します",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "ping"
+                    "admin"
                 ],
-                "summary": "Ping API",
+                "summary": "キャッシュ統計",
                 "responses": {
                     "200": {
-                        "description": "pong",
+                        "description": "キャッシュ統計",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handler.CacheStatsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/scores": {
-            "post": {
-                "description": "プレイ結果を登録します",
-                "consumes": [
-                    "application/json"
+        "/admin/charts/catalog": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "すべての譜面をインポートと同じ形式で返します",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/yaml"
                 ],
                 "tags": [
-                    "scores"
+                    "admin"
                 ],
-                "summary": "スコア登録",
+                "summary": "譜面カタログの出力",
                 "parameters": [
                     {
-                        "description": "スコア情報",
-                        "name": "score",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.SubmitScoreRequest"
-                        }
+                        "type": "string",
+                        "description": "形式 (csv, json, yaml。既定json)",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "登録されたスコアのID",
+                        "description": "譜面カタログ",
                         "schema": {
-                            "$ref": "#/definitions/handler.SubmitScoreResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handler.ChartCatalogEntry"
+                            }
                         }
                     },
                     "400": {
@@ -159,85 +166,166 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
                     }
                 }
-            }
-        },
-        "/songs/playcount": {
-            "get": {
+            },
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "CSV/JSON/YAMLの譜面カタログをまとめて登録・更新します。形式はformatかContent-Typeで指定します。\nすべての譜面を1つのトランザクションで反映し、dry_run=trueのときは反映せずに差分だけを返します。カタログにない譜面は削除しません。\nCSVは1行目に列名 (beatmap_id, song_name, difficulty, parallel_string, note_count, constant) を書きます",
+                "consumes": [
+                    "application/json",
+                    "text/csv",
+                    "application/yaml"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "charts"
+                    "admin"
                 ],
-                "summary": "楽曲プレイ回数ランキング",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                "summary": "譜面カタログの一括登録",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "形式 (csv, json, yaml)。未指定時はContent-Typeから判定",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "trueなら反映せずに差分を返す",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "description": "譜面カタログ",
+                        "name": "catalog",
+                        "in": "body",
+                        "required": true,
                         "schema": {
                             "type": "array",
                             "items": {
-                                "type": "object",
-                                "additionalProperties": true
+                                "$ref": "#/definitions/handler.ChartCatalogEntry"
                             }
                         }
                     }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "作成・更新した譜面",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ChartImportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "読み込めないファイル",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "未対応の形式",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "入力値の検証エラー (detailsに行ごとの理由)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "/users": {
+        "/admin/charts/difficulty/recompute": {
             "post": {
-                "description": "初回起動時に匿名ユーザーを生成し、user_idを返します",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "定期ジョブを待たずに全譜面の難易度を再推定します",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "ユーザー登録",
+                "summary": "譜面定数の再推定",
                 "responses": {
                     "200": {
-                        "description": "登録されたユーザーのID",
+                        "description": "推定した譜面数",
                         "schema": {
-                            "$ref": "#/definitions/handler.RegisterUserResponse"
+                            "$ref": "#/definitions/handler.RecomputeDifficultyResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/users/update": {
-            "post": {
-                "description": "user_idに対してuser_nameを更新します",
-                "consumes": [
-                    "application/json"
+        "/admin/export": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
+                "description": "ユーザー・譜面・スコアをバージョン付きのダンプとして返します。1行目 (tarではmanifest.json) がバージョンと件数を持つマニフェストです。\nfrom/toを指定すると、その期間に更新されたユーザーと、作成・削除・無効化されたスコアだけを書き出します (差分エクスポート)。譜面は常にすべて書き出します。",
                 "produces": [
-                    "application/json"
+                    "application/x-ndjson",
+                    "application/gzip"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "ユーザー名更新",
+                "summary": "データの書き出し",
                 "parameters": [
                     {
-                        "description": "更新内容",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handler.updateUserNameRequest"
-                        }
+                        "type": "string",
+                        "description": "形式 (jsonl, tar。既定jsonl)。tarはgzip圧縮したtarです",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "この時刻以降 (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "この時刻より前 (RFC3339)",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "更新結果",
+                        "description": "ダンプ",
                         "schema": {
-                            "$ref": "#/definitions/handler.UpdateUserNameResponse"
+                            "type": "file"
                         }
                     },
                     "400": {
@@ -245,42 +333,79 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/users/{userID}": {
-            "get": {
-                "description": "指定したIDのユーザー情報を取得します（名前のみ）",
+        "/admin/import": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "ExportDataのダンプを1つのトランザクションで読み込みます。形式はformatかContent-Typeで指定します。\nユーザーと譜面はIDごとに上書きします。スコアには新しいIDを振り、同じプレイヤー・譜面・スコア・時刻 (秒) のスコアが既にあれば読み飛ばします。\nダンプにもデータベースにもないユーザーや譜面を参照するスコアがあれば、何も書き込まずに422を返します",
                 "consumes": [
-                    "application/json"
+                    "application/x-ndjson",
+                    "application/gzip"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "ユーザー情報取得",
+                "summary": "データの読み込み",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "uuid",
-                        "description": "User ID",
-                        "name": "userID",
-                        "in": "path",
-                        "required": true
+                        "description": "形式 (jsonl, tar)。未指定時はContent-Typeから判定",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "description": "ダンプ",
+                        "name": "dump",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "ユーザー情報",
+                        "description": "書き込んだ件数",
                         "schema": {
-                            "$ref": "#/definitions/handler.GetUserResponse"
+                            "$ref": "#/definitions/handler.DataImportResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "読み込めないダンプ、または未対応のバージョン",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "未対応の形式",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "存在しないユーザー・譜面への参照 (detailsに行ごとの理由)",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
@@ -288,35 +413,2665 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{userID}/stats": {
+        "/admin/jobs": {
             "get": {
-                "description": "プレイ回数など統計情報を返します",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "登録されている定期ジョブの実行状況を返します",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
-                ],
-                "summary": "ユーザー統計",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "User ID",
-                        "name": "userID",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "バックグラウンドジョブの状態",
                 "responses": {
                     "200": {
-                        "description": "統計情報",
+                        "description": "ジョブ一覧",
                         "schema": {
-                            "$ref": "#/definitions/handler.UserStatsResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handler.JobStatusResponse"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/rankings/rebuild": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "定期ジョブを待たずに譜面・楽曲・レーティングのランキングスナップショットを再構築します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "ランキングスナップショットの再構築",
+                "responses": {
+                    "200": {
+                        "description": "集計時刻",
+                        "schema": {
+                            "$ref": "#/definitions/handler.RebuildRankingsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "スナップショットが無効",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/scores/moderate": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "ユーザーまたは譜面の、期間[from, to)内の全スコアを削除・復元・無効化します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "スコア一括モデレーション",
+                "parameters": [
+                    {
+                        "description": "対象と操作 (action: delete/restore/invalidate)",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ModerateScoresRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "影響件数",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ModerateScoresResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "入力値の検証エラー (detailsに項目ごとの理由)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/scores/{scoreID}": {
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "指定したスコアを論理削除します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "スコア削除",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Score ID",
+                        "name": "scoreID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "影響件数",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ModerateScoresResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/scores/{scoreID}/invalidate": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "履歴には残したまま、自己ベスト・ランキング・統計の対象から外します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "スコア無効化",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Score ID",
+                        "name": "scoreID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "無効化理由",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handler.InvalidateScoreRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "影響件数",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ModerateScoresResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "入力値の検証エラー (detailsに項目ごとの理由)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/scores/{scoreID}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "論理削除・無効化されたスコアを元に戻します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "スコア復元",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Score ID",
+                        "name": "scoreID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "影響件数",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ModerateScoresResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/charts": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "charts"
+                ],
+                "summary": "譜面登録/更新",
+                "parameters": [
+                    {
+                        "description": "譜面情報",
+                        "name": "chart",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.UpsertChartRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "登録結果",
+                        "schema": {
+                            "$ref": "#/definitions/handler.UpsertChartResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "入力値の検証エラー (detailsに項目ごとの理由)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/charts/difficulty": {
+            "get": {
+                "description": "申告された難易度と、プレイヤーの成績から推定した譜面定数を並べて返します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "charts"
+                ],
+                "summary": "譜面難易度一覧",
+                "responses": {
+                    "200": {
+                        "description": "譜面難易度",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handler.ChartDifficultyResponse"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/charts/ranking": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "beatmap_idを指定したランキング、未指定時は全譜面のランキング。\nスナップショットから返す場合はsnapshot_atに集計時刻が入ります\nscope=friendsでは認証したプレイヤーとそのフレンドだけのランキングを返します (キャッシュ・スナップショットは使いません)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "charts"
+                ],
+                "summary": "譜面ランキング",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "譜面ID",
+                        "name": "beatmap_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ランキング上限",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "範囲 (all, friends。既定all)。friendsはユーザートークンが必要です",
+                        "name": "scope",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ランキング配列",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handler.ChartRankingResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "scope=friendsでユーザートークンがない",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/charts/ranking/events": {
+            "get": {
+                "description": "Server-Sent Eventsで自己ベスト更新(personal_best)とトップN変動(ranking_updated)を配信します。beatmap_id未指定時は全譜面のイベントを配信します",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "charts"
+                ],
+                "summary": "ランキング更新のストリーム",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "譜面ID",
+                        "name": "beatmap_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/charts/{beatmapID}/analytics": {
+            "get": {
+                "description": "各プレイヤーの自己ベストによるスコア分布・ランプ/グレード分布・パーセンタイルと、全プレイのクリア率・入力デバイス比・日別プレイ数を返します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "charts"
+                ],
+                "summary": "譜面の分析",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "譜面ID",
+                        "name": "beatmapID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "日別プレイ数の日数 (既定30, 最大365)",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "譜面の分析",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ChartAnalyticsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/missions": {
+            "get": {
+                "description": "地域のタイムゾーンで今日と今週に出ているミッションを、デイリー、ウィークリーの順に返します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "missions"
+                ],
+                "summary": "今日と今週のミッション",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "地域 (省略時は既定の地域)",
+                        "name": "region",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ミッション一覧",
+                        "schema": {
+                            "$ref": "#/definitions/handler.MissionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ping": {
+            "get": {
+                "description": "サーバーの死活確認用エンドポイント",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "ping"
+                ],
+                "summary": "Ping API",
+                "responses": {
+                    "200": {
+                        "description": "pong",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/ratings/ranking": {
+            "get": {
+                "description": "各譜面のベストスコアと譜面定数から算出した総合レーティングの順位を返します。\nスナップショットから返す場合はsnapshot_atに集計時刻が入ります",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ratings"
+                ],
+                "summary": "レーティングランキング",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "取得件数 (既定20, 最大100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "レーティングランキング",
+                        "schema": {
+                            "$ref": "#/definitions/handler.RatingRankingResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scores": {
+            "post": {
+                "description": "プレイ結果を登録します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scores"
+                ],
+                "summary": "スコア登録",
+                "parameters": [
+                    {
+                        "description": "スコア情報",
+                        "name": "score",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.SubmitScoreRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "登録されたスコアのIDと、このプレイで解除された実績",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SubmitScoreResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "BANされたユーザー (USER_BANNED)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "ユーザーまたは譜面が存在しない (USER_NOT_FOUND / CHART_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "入力値の検証エラー (detailsに項目ごとの理由)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scores/recent": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "最新のプレイを新しい順に返します。next_before_idをbefore_idに指定すると続きを取得できます\nuser_idを指定した場合、プロフィールの公開範囲がfriendsのプレイヤーはフレンドと本人、privateのプレイヤーは本人だけが取得できます。本人やフレンドとして見るにはトークンを付けてください",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scores"
+                ],
+                "summary": "最近のプレイ",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "譜面ID",
+                        "name": "beatmap_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "楽曲名",
+                        "name": "song_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "このスコアIDより古いプレイを返す",
+                        "name": "before_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "取得件数 (既定20, 最大100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "プレイ一覧",
+                        "schema": {
+                            "$ref": "#/definitions/handler.RecentPlaysResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "トークンが無効",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "公開範囲により見られない (PROFILE_PRIVATE)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/songs/playcount": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "charts"
+                ],
+                "summary": "楽曲プレイ回数ランキング",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "object",
+                                "additionalProperties": true
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/teams": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "チーム一覧",
+                "responses": {
+                    "200": {
+                        "description": "チーム (名前順)",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handler.TeamResponse"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "認証したプレイヤーをオーナーとしてチームを作成し、招待コードを返します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "チーム作成",
+                "parameters": [
+                    {
+                        "description": "チーム名",
+                        "name": "team",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateTeamRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "作成したチームと招待コード",
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateTeamResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "同じ名前のチームがある",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "入力値の検証エラー (detailsに項目ごとの理由)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/teams/join": {
+            "post": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "招待コードのチームにメンバーとして参加します。招待コードの大文字・小文字とハイフンは区別しません。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "チームへの参加",
+                "parameters": [
+                    {
+                        "description": "招待コード",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.JoinTeamRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "参加したチーム",
+                        "schema": {
+                            "$ref": "#/definitions/handler.TeamResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "招待コードのチームが存在しない (TEAM_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "参加済み",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "入力値の検証エラー (detailsに項目ごとの理由)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/teams/ranking": {
+            "get": {
+                "description": "現在のメンバーの自己ベストを合計した順位を返します。beatmap_idを指定するとその譜面、未指定時は全譜面の合計です。\nスコアのないチームは含みません",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "チームランキング",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "譜面ID",
+                        "name": "beatmap_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "取得件数 (既定20, 最大100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "チームランキング",
+                        "schema": {
+                            "$ref": "#/definitions/handler.TeamRankingResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/teams/ranking/weekly": {
+            "get": {
+                "description": "週 (月曜0時UTCから1週間) の間のプレイだけで、現在のメンバーの譜面ごとのベストを合計した順位を返します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "週間チームランキング",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ISO週 (例: 2026-W42)。既定は今週",
+                        "name": "week",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "取得件数 (既定20, 最大100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "週間チームランキング",
+                        "schema": {
+                            "$ref": "#/definitions/handler.TeamRankingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/teams/{teamID}": {
+            "get": {
+                "description": "チームとメンバー (オーナー・管理者・メンバーの順) を返します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "チーム情報",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "チーム",
+                        "schema": {
+                            "$ref": "#/definitions/handler.TeamResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "チームが存在しない (TEAM_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "オーナーだけが削除できます。スコアは削除されません。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "チーム削除",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "オーナーではない",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "チームが存在しない (TEAM_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/teams/{teamID}/invite-code": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "オーナーと管理者だけが取得できます",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "招待コード",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "招待コード",
+                        "schema": {
+                            "$ref": "#/definitions/handler.InviteCodeResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "オーナー・管理者ではない",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "チームが存在しない (TEAM_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "新しい招待コードを発行し、古いコードを使えなくします。オーナーと管理者だけが実行できます。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "招待コードの再発行",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "新しい招待コード",
+                        "schema": {
+                            "$ref": "#/definitions/handler.InviteCodeResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "オーナー・管理者ではない",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "チームが存在しない (TEAM_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/teams/{teamID}/members/{userID}": {
+            "delete": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "自分を指定すると脱退します。オーナーは管理者とメンバーを、管理者はメンバーを除名できます。\nオーナーが脱退すると最も古い管理者 (いなければメンバー) がオーナーになり、最後のメンバーが脱退するとチームは削除されます。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "チームからの脱退・除名",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "メンバーのID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "除名する権限がない",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "チームまたはメンバーが存在しない (TEAM_NOT_FOUND, TEAM_MEMBER_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/teams/{teamID}/members/{userID}/role": {
+            "put": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "オーナーだけが変更できます。他のメンバーをオーナーにすると、自分は管理者になります。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "メンバーの役割変更",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "メンバーのID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "役割",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.SetTeamRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "オーナーではない",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "チームまたはメンバーが存在しない (TEAM_NOT_FOUND, TEAM_MEMBER_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "オーナーが引き継がずに降りようとした",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "入力値の検証エラー (detailsに項目ごとの理由)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/teams/{teamID}/versus/{otherID}": {
+            "get": {
+                "description": "2つのチームの週間合計 (週間チームランキングと同じ集計) を比べます",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "チーム対抗戦",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "対戦相手のTeam ID",
+                        "name": "otherID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO週 (例: 2026-W42)。既定は今週",
+                        "name": "week",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "対抗戦の結果",
+                        "schema": {
+                            "$ref": "#/definitions/handler.TeamVersusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "チームが存在しない (TEAM_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "post": {
+                "description": "初回起動時に匿名ユーザーを生成し、user_idとトークンを返します。\nトークンは/users/meのAPIの認証に使います。再発行できないので、クライアントで保存してください。",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "ユーザー登録",
+                "responses": {
+                    "200": {
+                        "description": "登録されたユーザーのIDとトークン",
+                        "schema": {
+                            "$ref": "#/definitions/handler.RegisterUserResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me": {
+            "delete": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "認証したプレイヤーのアカウント・トークン・名前の履歴を削除します。\nスコアは誰のものか分からない匿名ユーザー (\"Deleted player\") に移すので、プレイ回数やランキングの集計は変わりません。削除は監査ログに記録します。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "アカウント削除",
+                "responses": {
+                    "200": {
+                        "description": "削除結果",
+                        "schema": {
+                            "$ref": "#/definitions/handler.DeleteUserResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/export": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "認証したプレイヤーのプロフィール・名前の履歴・すべてのスコア (削除・無効化されたものを含む)・実績・フレンド・フレンド申請・ライバル・所属チーム・報酬を受け取ったミッションと獲得ポイントをJSONで返します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "個人データの書き出し",
+                "responses": {
+                    "200": {
+                        "description": "個人データ",
+                        "schema": {
+                            "$ref": "#/definitions/handler.PersonalDataResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/friend-code": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "認証したプレイヤーのフレンドコードを返します。フレンドコードはユーザーIDから決まり、変わりません。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "friends"
+                ],
+                "summary": "フレンドコード",
+                "responses": {
+                    "200": {
+                        "description": "フレンドコード",
+                        "schema": {
+                            "$ref": "#/definitions/handler.FriendCodeResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/friends": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "friends"
+                ],
+                "summary": "フレンド一覧",
+                "responses": {
+                    "200": {
+                        "description": "フレンド (名前順)",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handler.ContactResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/friends/requests": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "受け取った申請と送った申請を新しい順に返します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "friends"
+                ],
+                "summary": "フレンド申請一覧",
+                "responses": {
+                    "200": {
+                        "description": "フレンド申請",
+                        "schema": {
+                            "$ref": "#/definitions/handler.FriendRequestsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "フレンドコードのプレイヤーにフレンド申請を送ります。相手から申請が届いていれば、その場でフレンドになります。\nフレンドコードの大文字・小文字とハイフンは区別しません",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "friends"
+                ],
+                "summary": "フレンド申請",
+                "parameters": [
+                    {
+                        "description": "相手のフレンドコード",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.SendFriendRequestRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "requestedまたはaccepted",
+                        "schema": {
+                            "$ref": "#/definitions/handler.SendFriendRequestResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "フレンドコードのプレイヤーが存在しない (USER_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "既にフレンド、または申請済み",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "フレンドコードが不正、または自分のフレンドコード",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/friends/requests/{userID}": {
+            "delete": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "受け取った申請を断るか、送った申請を取り消します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "friends"
+                ],
+                "summary": "フレンド申請の拒否・取り消し",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "相手のプレイヤーのID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "申請が存在しない (FRIEND_REQUEST_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/friends/requests/{userID}/accept": {
+            "post": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "friends"
+                ],
+                "summary": "フレンド申請の承認",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "申請したプレイヤーのID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "申請が存在しない (FRIEND_REQUEST_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/friends/{userID}": {
+            "delete": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "双方のフレンド一覧から削除します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "friends"
+                ],
+                "summary": "フレンド解除",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "フレンドのID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "フレンドではない (FRIEND_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/missions": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "今日と今週のミッションの進捗と受け取り状況、これまでに獲得したミッションポイントを返します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "missions"
+                ],
+                "summary": "自分のミッション",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "地域 (省略時は既定の地域)",
+                        "name": "region",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ミッションの進捗",
+                        "schema": {
+                            "$ref": "#/definitions/handler.MyMissionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/missions/{missionID}/claim": {
+            "post": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "達成したミッションの報酬を受け取ります。受け取れるのは今日と今週のミッションだけです",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "missions"
+                ],
+                "summary": "ミッション報酬の受け取り",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ミッションID",
+                        "name": "missionID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "地域 (省略時は既定の地域)",
+                        "name": "region",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "受け取った報酬と合計ミッションポイント",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ClaimMissionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "今日と今週のミッションにない (MISSION_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "未達成 (MISSION_INCOMPLETE) または受け取り済み (CONFLICT)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/profile": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "公開範囲にかかわらず、認証したプレイヤーのプロフィールをすべて返します",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "自分のプロフィール",
+                "responses": {
+                    "200": {
+                        "description": "プロフィール",
+                        "schema": {
+                            "$ref": "#/definitions/handler.UserProfileResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "指定した項目だけを更新し、更新後のプロフィールを返します。\n称号は解除済みの実績、ショーケースはプレイしたことのある譜面(3つまで、重複不可)だけを指定できます。titleとfavorite_beatmap_idは空文字列で解除します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "プロフィール更新",
+                "parameters": [
+                    {
+                        "description": "更新する項目",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新後のプロフィール",
+                        "schema": {
+                            "$ref": "#/definitions/handler.UserProfileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "入力値の検証エラー (detailsに項目ごとの理由)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/rivals": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "friends"
+                ],
+                "summary": "ライバル一覧",
+                "responses": {
+                    "200": {
+                        "description": "ライバル (名前順)",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handler.ContactResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/rivals/{userID}": {
+            "put": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "相手の承認なしに登録できます。登録できるのは20人までです。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "friends"
+                ],
+                "summary": "ライバル登録",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ライバルにするプレイヤーのID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "プレイヤーが存在しない (USER_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "登録済み、または上限 (RIVAL_LIMIT)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "自分自身",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "friends"
+                ],
+                "summary": "ライバル解除",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "ライバルのID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "ライバルではない (RIVAL_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/teams": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "teams"
+                ],
+                "summary": "所属チーム一覧",
+                "responses": {
+                    "200": {
+                        "description": "所属チームと役割",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handler.MyTeamResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/update": {
+            "post": {
+                "description": "user_idに対してuser_nameを更新します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "ユーザー名更新",
+                "parameters": [
+                    {
+                        "description": "更新内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.updateUserNameRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "更新結果",
+                        "schema": {
+                            "$ref": "#/definitions/handler.UpdateUserNameResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "ユーザーが存在しない (USER_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "入力値の検証エラー (detailsに項目ごとの理由)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}": {
+            "get": {
+                "description": "指定したIDのユーザーの名前・称号と解除した実績を返します。プロフィールの詳細は/users/{userID}/profileを使ってください",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "ユーザー情報取得",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ユーザー情報",
+                        "schema": {
+                            "$ref": "#/definitions/handler.GetUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "ユーザーが存在しない (USER_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/achievements": {
+            "get": {
+                "description": "すべての実績について、指定したユーザーの進捗と解除日時を返します。未解除の実績のunlocked_atはnullです",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "ユーザーの実績",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "実績の進捗",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handler.UserAchievementResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "ユーザーが存在しない (USER_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/bests": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "プレイした各譜面の自己ベストを1件ずつ返します\nプロフィールの公開範囲がfriendsのプレイヤーはフレンドと本人、privateのプレイヤーは本人だけが取得できます。本人やフレンドとして見るにはトークンを付けてください",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "ユーザーの自己ベスト一覧",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "譜面ごとの自己ベスト",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handler.PlayResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "トークンが無効",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "公開範囲により見られない (PROFILE_PRIVATE)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/charts/{beatmapID}/analytics": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "直近N回のプレイから判定の平均分布、FAST/LATE比、MISS率、推移と推奨オフセットを返します\nプロフィールの公開範囲がfriendsのプレイヤーはフレンドと本人、privateのプレイヤーは本人だけが取得できます。本人やフレンドとして見るにはトークンを付けてください",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "ユーザーの譜面別判定分析",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "譜面ID",
+                        "name": "beatmapID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "対象とする直近のプレイ数 (既定20, 最大100)",
+                        "name": "last",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "判定分析",
+                        "schema": {
+                            "$ref": "#/definitions/handler.UserChartAnalyticsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "トークンが無効",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "公開範囲により見られない (PROFILE_PRIVATE)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/profile": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "指定したユーザーのプロフィールを返します。名前と称号は常に公開されます。\nそれ以外(details)は公開範囲がfriendsならフレンドと本人、privateなら本人にだけ返し、それ以外にはnullを返します。本人やフレンドとして見るにはトークンを付けてください",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "プロフィール",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "プロフィール",
+                        "schema": {
+                            "$ref": "#/definitions/handler.UserProfileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "トークンが無効",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "ユーザーが存在しない (USER_NOT_FOUND)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/scores": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "指定したユーザーのプレイ履歴を譜面・難易度・入力デバイス・期間で絞り込んで返します\nプロフィールの公開範囲がfriendsのプレイヤーはフレンドと本人、privateのプレイヤーは本人だけが取得できます。本人やフレンドとして見るにはトークンを付けてください",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "ユーザーのプレイ履歴",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "譜面ID",
+                        "name": "beatmap_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "難易度 (0=Past,1=Present,2=Future,3=Lycoris,4=Parallel)",
+                        "name": "difficulty",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "入力デバイス (0=keyboard,1=button)",
+                        "name": "input",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "この日時以降 (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "この日時より前 (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "並び順の基準 (date/score, 既定date)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "昇順/降順 (asc/desc, 既定desc)",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "取得件数 (既定20, 最大100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "オフセット",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "プレイ履歴",
+                        "schema": {
+                            "$ref": "#/definitions/handler.UserScoresResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "トークンが無効",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "公開範囲により見られない (PROFILE_PRIVATE)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/stats": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "プレイ回数など統計情報を返します\nプロフィールの公開範囲がfriendsのプレイヤーはフレンドと本人、privateのプレイヤーは本人だけが取得できます。本人やフレンドとして見るにはトークンを付けてください",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "ユーザー統計",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "統計情報",
+                        "schema": {
+                            "$ref": "#/definitions/handler.UserStatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "トークンが無効",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "公開範囲により見られない (PROFILE_PRIVATE)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/versus/{otherID}": {
+            "get": {
+                "security": [
+                    {
+                        "UserToken": []
+                    }
+                ],
+                "description": "2人のプレイヤーの自己ベストが異なる譜面を並べ、勝ち・負け・引き分けの数を返します。\n片方しかプレイしていない譜面も含み、プレイしていない側のスコアはnullです。差の大きい順に並べます。\n片方だけがスコア0でプレイした譜面は差が0のため、勝ち・負けのどちらにも数えません。\nプロフィールの公開範囲がfriendsのプレイヤーはフレンドと本人、privateのプレイヤーは本人だけが取得できます。本人やフレンドとして見るにはトークンを付けてください",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "自己ベストの比較",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "比較相手のUser ID",
+                        "name": "otherID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "比較結果",
+                        "schema": {
+                            "$ref": "#/definitions/handler.VersusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "トークンが無効",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "公開範囲により見られない (PROFILE_PRIVATE)",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "ユーザーが存在しない (USER_NOT_FOUND)",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
@@ -326,113 +3081,1401 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "handler.AchievementResponse": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Full Combo 10 Future charts"
+                },
+                "goal": {
+                    "description": "Goal is the number of plays, or of charts, that unlocks the achievement",
+                    "type": "integer",
+                    "example": 10
+                },
+                "id": {
+                    "type": "string",
+                    "example": "future_full_combo_10"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Future Proof"
+                }
+            }
+        },
+        "handler.AuditLogResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.CacheStatsResponse": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "entries": {
+                    "type": "integer"
+                },
+                "evictions": {
+                    "type": "integer"
+                },
+                "hit_ratio": {
+                    "type": "number"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.ChartAnalyticsResponse": {
+            "type": "object",
+            "properties": {
+                "average_score": {
+                    "type": "number"
+                },
+                "beatmap_id": {
+                    "type": "string"
+                },
+                "best_score": {
+                    "type": "integer"
+                },
+                "clear_rate": {
+                    "description": "ClearRate is over plays, PlayerClearRate over players",
+                    "type": "number"
+                },
+                "difficulty": {
+                    "type": "integer"
+                },
+                "estimated_constant": {
+                    "description": "EstimatedConstant is null until the chart has enough players to be estimated",
+                    "type": "number"
+                },
+                "grade_distribution": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "input_split": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.InputSplitResponse"
+                    }
+                },
+                "lamp_distribution": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "median_score": {
+                    "type": "number"
+                },
+                "parallel_string": {
+                    "type": "string"
+                },
+                "percentiles": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                },
+                "play_count": {
+                    "type": "integer"
+                },
+                "player_clear_rate": {
+                    "type": "number"
+                },
+                "player_count": {
+                    "type": "integer"
+                },
+                "plays_over_time": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.DailyPlaysResponse"
+                    }
+                },
+                "score_histogram": {
+                    "description": "ScoreHistogram, GradeDistribution, LampDistribution, MedianScore and\nPercentiles are computed from each player's best",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.HistogramBucketResponse"
+                    }
+                },
+                "song_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ChartCatalogEntry": {
+            "type": "object",
+            "properties": {
+                "beatmap_id": {
+                    "type": "string",
+                    "example": "songA_future"
+                },
+                "constant": {
+                    "type": "number",
+                    "example": 9.8
+                },
+                "difficulty": {
+                    "description": "0-4, or past/present/future/lycoris/parallel on import",
+                    "type": "integer",
+                    "example": 2
+                },
+                "note_count": {
+                    "type": "integer",
+                    "example": 812
+                },
+                "parallel_string": {
+                    "type": "string"
+                },
+                "song_name": {
+                    "type": "string",
+                    "example": "Song A"
+                }
+            }
+        },
+        "handler.ChartChangeResponse": {
+            "type": "object",
+            "properties": {
+                "beatmap_id": {
+                    "type": "string",
+                    "example": "songA_future"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.ChartFieldChangeResponse"
+                    }
+                },
+                "op": {
+                    "description": "create or update",
+                    "type": "string",
+                    "example": "update"
+                }
+            }
+        },
+        "handler.ChartDifficultyResponse": {
+            "type": "object",
+            "properties": {
+                "beatmap_id": {
+                    "type": "string"
+                },
+                "declared_difficulty": {
+                    "type": "integer"
+                },
+                "estimated_at": {
+                    "type": "string"
+                },
+                "estimated_constant": {
+                    "description": "EstimatedConstant, SamplePlayers and EstimatedAt are null until the chart has been estimated",
+                    "type": "number"
+                },
+                "parallel_string": {
+                    "type": "string"
+                },
+                "sample_players": {
+                    "type": "integer"
+                },
+                "song_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ChartFieldChangeResponse": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "constant"
+                },
+                "new": {},
+                "old": {}
+            }
+        },
+        "handler.ChartImportResponse": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "description": "created and updated charts",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.ChartChangeResponse"
+                    }
+                },
+                "created": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "unchanged": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
         "handler.ChartRankingResponse": {
             "type": "object",
             "properties": {
-                "beatmap_id": {
-                    "type": "string"
+                "beatmap_id": {
+                    "type": "string"
+                },
+                "play_count": {
+                    "type": "integer"
+                },
+                "player_count": {
+                    "type": "integer"
+                },
+                "snapshot_at": {
+                    "description": "SnapshotAt is set when served from a ranking snapshot",
+                    "type": "string"
+                },
+                "top": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.RankingEntryResponse"
+                    }
+                }
+            }
+        },
+        "handler.ClaimMissionResponse": {
+            "type": "object",
+            "properties": {
+                "points": {
+                    "type": "integer",
+                    "example": 130
+                },
+                "reward": {
+                    "type": "integer",
+                    "example": 10
+                }
+            }
+        },
+        "handler.ContactResponse": {
+            "type": "object",
+            "properties": {
+                "player_name": {
+                    "type": "string"
+                },
+                "since": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.CreateTeamRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "Senirenol Lab"
+                }
+            }
+        },
+        "handler.CreateTeamResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "invite_code": {
+                    "type": "string",
+                    "example": "K7QF2M9X"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.DailyPlaysResponse": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "plays": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.DataImportResponse": {
+            "type": "object",
+            "properties": {
+                "charts": {
+                    "type": "integer"
+                },
+                "moderated_scores": {
+                    "description": "scores already stored whose deletion or invalidation was updated from the dump",
+                    "type": "integer"
+                },
+                "scores": {
+                    "type": "integer"
+                },
+                "skipped_scores": {
+                    "description": "scores already stored, which were not imported again",
+                    "type": "integer"
+                },
+                "users": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.DeleteUserResponse": {
+            "type": "object",
+            "properties": {
+                "anonymised_scores": {
+                    "description": "scores moved to the anonymous placeholder",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "deleted"
+                }
+            }
+        },
+        "handler.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "USER_NOT_FOUND"
+                },
+                "details": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.FieldErrorResponse"
+                    }
+                },
+                "message": {
+                    "type": "string",
+                    "example": "user not found"
+                }
+            }
+        },
+        "handler.FieldErrorResponse": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "user_id"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "cannot be blank"
+                }
+            }
+        },
+        "handler.FriendCodeResponse": {
+            "type": "object",
+            "properties": {
+                "friend_code": {
+                    "type": "string",
+                    "example": "7K3QF-M9XA2"
+                }
+            }
+        },
+        "handler.FriendRequestsResponse": {
+            "type": "object",
+            "properties": {
+                "incoming": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.ContactResponse"
+                    }
+                },
+                "outgoing": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.ContactResponse"
+                    }
+                }
+            }
+        },
+        "handler.GetUserResponse": {
+            "type": "object",
+            "properties": {
+                "achievements": {
+                    "description": "Achievements are the unlocked achievements, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.UserAchievementResponse"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "title": {
+                    "description": "Title is the achievement the player chose to show, if any",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/handler.AchievementResponse"
+                        }
+                    ]
+                }
+            }
+        },
+        "handler.HistogramBucketResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "max": {
+                    "type": "integer"
+                },
+                "min": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.InputSplitResponse": {
+            "type": "object",
+            "properties": {
+                "average_score": {
+                    "type": "number"
+                },
+                "input": {
+                    "type": "integer"
+                },
+                "plays": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.InvalidateScoreRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.InviteCodeResponse": {
+            "type": "object",
+            "properties": {
+                "invite_code": {
+                    "type": "string",
+                    "example": "K7QF2M9X"
+                }
+            }
+        },
+        "handler.JobStatusResponse": {
+            "type": "object",
+            "properties": {
+                "failures": {
+                    "type": "integer"
+                },
+                "interval_seconds": {
+                    "type": "number"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_finished_at": {
+                    "type": "string"
+                },
+                "last_started_at": {
+                    "type": "string"
+                },
+                "last_success_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "runs": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.JoinTeamRequest": {
+            "type": "object",
+            "properties": {
+                "invite_code": {
+                    "type": "string",
+                    "example": "K7QF2M9X"
+                }
+            }
+        },
+        "handler.JudgementAveragesResponse": {
+            "type": "object",
+            "properties": {
+                "good_fast": {
+                    "type": "number"
+                },
+                "good_late": {
+                    "type": "number"
+                },
+                "miss": {
+                    "type": "number"
+                },
+                "perfect_critical_fast": {
+                    "type": "number"
+                },
+                "perfect_critical_late": {
+                    "type": "number"
+                },
+                "perfect_fast": {
+                    "type": "number"
+                },
+                "perfect_late": {
+                    "type": "number"
+                }
+            }
+        },
+        "handler.MissionResponse": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Play 3 Present charts today"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "goal": {
+                    "description": "Goal is the number of plays that completes the mission",
+                    "type": "integer",
+                    "example": 3
+                },
+                "id": {
+                    "type": "string",
+                    "example": "daily_present_3"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Present Practice"
+                },
+                "period": {
+                    "type": "string",
+                    "enum": [
+                        "daily",
+                        "weekly"
+                    ],
+                    "example": "daily"
+                },
+                "reward": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "starts_at": {
+                    "description": "StartsAt and EndsAt bound the day or week of the mission",
+                    "type": "string"
+                }
+            }
+        },
+        "handler.MissionsResponse": {
+            "type": "object",
+            "properties": {
+                "missions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.MissionResponse"
+                    }
+                },
+                "region": {
+                    "type": "string",
+                    "example": "jp"
+                }
+            }
+        },
+        "handler.ModerateScoresRequest": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "beatmap_id": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ModerateScoresResponse": {
+            "type": "object",
+            "properties": {
+                "affected": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.MyMissionsResponse": {
+            "type": "object",
+            "properties": {
+                "missions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.UserMissionResponse"
+                    }
+                },
+                "points": {
+                    "description": "Points is the sum of every reward claimed so far",
+                    "type": "integer",
+                    "example": 120
+                },
+                "region": {
+                    "type": "string",
+                    "example": "jp"
+                }
+            }
+        },
+        "handler.MyTeamResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "joined_at": {
+                    "type": "string"
+                },
+                "member_count": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "owner"
+                }
+            }
+        },
+        "handler.PersonalAchievementResponse": {
+            "type": "object",
+            "properties": {
+                "achievement_id": {
+                    "type": "string"
+                },
+                "unlocked_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.PersonalDataResponse": {
+            "type": "object",
+            "properties": {
+                "achievements": {
+                    "description": "Achievements are every stored unlock, including those of achievements no longer defined",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.PersonalAchievementResponse"
+                    }
+                },
+                "exported_at": {
+                    "type": "string"
+                },
+                "friend_requests": {
+                    "$ref": "#/definitions/handler.FriendRequestsResponse"
+                },
+                "friends": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.ContactResponse"
+                    }
+                },
+                "mission_points": {
+                    "type": "integer"
+                },
+                "missions": {
+                    "description": "Missions are the missions the player claimed. The progress of the\nothers is counted from the scores.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.PersonalMissionResponse"
+                    }
+                },
+                "name_history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.UserNameResponse"
+                    }
+                },
+                "profile": {
+                    "$ref": "#/definitions/handler.PersonalProfileResponse"
+                },
+                "rivals": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.ContactResponse"
+                    }
+                },
+                "scores": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.PersonalScoreResponse"
+                    }
+                },
+                "teams": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.MyTeamResponse"
+                    }
+                }
+            }
+        },
+        "handler.PersonalMissionResponse": {
+            "type": "object",
+            "properties": {
+                "claimed_at": {
+                    "type": "string"
+                },
+                "mission_id": {
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "progress": {
+                    "type": "integer"
+                },
+                "region": {
+                    "type": "string",
+                    "example": "jp"
+                },
+                "reward": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.PersonalProfileResponse": {
+            "type": "object",
+            "properties": {
+                "ban_reason": {
+                    "type": "string"
+                },
+                "banned_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "favorite_beatmap_id": {
+                    "type": "string"
+                },
+                "friend_code": {
+                    "description": "FriendCode is null until the player first asks for it",
+                    "type": "string",
+                    "example": "7K3QF-M9XA2"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "showcase": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "description": "Title, FavoriteBeatmapID, Showcase and Visibility are the profile settings",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "visibility": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.PersonalScoreResponse": {
+            "type": "object",
+            "properties": {
+                "beatmap_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "good_fast": {
+                    "type": "integer"
+                },
+                "good_late": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "input": {
+                    "type": "integer"
+                },
+                "invalidated_at": {
+                    "type": "string"
+                },
+                "invalidation_reason": {
+                    "type": "string"
+                },
+                "max_combo": {
+                    "type": "integer"
+                },
+                "miss": {
+                    "type": "integer"
+                },
+                "perfect_critical_fast": {
+                    "type": "integer"
+                },
+                "perfect_critical_late": {
+                    "type": "integer"
+                },
+                "perfect_fast": {
+                    "type": "integer"
+                },
+                "perfect_late": {
+                    "type": "integer"
+                },
+                "score": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.PlayResponse": {
+            "type": "object",
+            "properties": {
+                "beatmap_id": {
+                    "type": "string"
+                },
+                "difficulty": {
+                    "type": "integer"
+                },
+                "good_fast": {
+                    "type": "integer"
+                },
+                "good_late": {
+                    "type": "integer"
+                },
+                "grade": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "input": {
+                    "type": "integer"
+                },
+                "lamp": {
+                    "type": "string"
+                },
+                "max_combo": {
+                    "type": "integer"
+                },
+                "miss": {
+                    "type": "integer"
+                },
+                "parallel_string": {
+                    "type": "string"
+                },
+                "perfect_critical_fast": {
+                    "type": "integer"
+                },
+                "perfect_critical_late": {
+                    "type": "integer"
+                },
+                "perfect_fast": {
+                    "type": "integer"
+                },
+                "perfect_late": {
+                    "type": "integer"
+                },
+                "played_at": {
+                    "type": "string"
+                },
+                "player_name": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "integer"
+                },
+                "song_name": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ProfileChartResponse": {
+            "type": "object",
+            "properties": {
+                "beatmap_id": {
+                    "type": "string"
+                },
+                "difficulty": {
+                    "type": "integer"
+                },
+                "parallel_string": {
+                    "type": "string"
+                },
+                "song_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ProfileDetailsResponse": {
+            "type": "object",
+            "properties": {
+                "favorite_chart": {
+                    "$ref": "#/definitions/handler.ProfileChartResponse"
+                },
+                "first_played_at": {
+                    "type": "string"
+                },
+                "last_played_at": {
+                    "type": "string"
+                },
+                "preferred_input": {
+                    "description": "PreferredInput is the input of most plays (0=keyboard, 1=button), null before the first play",
+                    "type": "integer",
+                    "example": 1
+                },
+                "rating": {
+                    "type": "number",
+                    "example": 12.35
+                },
+                "showcase": {
+                    "description": "Showcase is the personal best on each showcased chart, or the best\nrated plays when the player has not chosen any",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.PlayResponse"
+                    }
+                },
+                "total_plays": {
+                    "description": "TotalPlays, FirstPlayedAt and LastPlayedAt count active scores only",
+                    "type": "integer",
+                    "example": 128
+                }
+            }
+        },
+        "handler.RankingEntryResponse": {
+            "type": "object",
+            "properties": {
+                "player_name": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.RatingEntryResponse": {
+            "type": "object",
+            "properties": {
+                "charts": {
+                    "type": "integer"
+                },
+                "player_name": {
+                    "type": "string"
+                },
+                "rank": {
+                    "type": "integer"
+                },
+                "rating": {
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.RatingRankingResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.RatingEntryResponse"
+                    }
+                },
+                "snapshot_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.RebuildRankingsResponse": {
+            "type": "object",
+            "properties": {
+                "snapshot_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.RecentPlaysResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.PlayResponse"
+                    }
+                },
+                "next_before_id": {
+                    "description": "NextBeforeID is the before_id for the next page, null on the last page",
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.RecomputeDifficultyResponse": {
+            "type": "object",
+            "properties": {
+                "estimated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.RegisterUserResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "token": {
+                    "description": "Token authenticates the player as \"Authorization: Bearer \u003ctoken\u003e\". It is only returned here.",
+                    "type": "string"
+                }
+            }
+        },
+        "handler.SendFriendRequestRequest": {
+            "type": "object",
+            "properties": {
+                "friend_code": {
+                    "type": "string",
+                    "example": "7K3QF-M9XA2"
+                }
+            }
+        },
+        "handler.SendFriendRequestResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "description": "Status is \"accepted\" when the other player had already asked, else \"requested\"",
+                    "type": "string",
+                    "example": "requested"
+                }
+            }
+        },
+        "handler.SetTeamRoleRequest": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "description": "Role is owner, admin or member. Making someone the owner hands the team over.",
+                    "type": "string",
+                    "example": "admin"
+                }
+            }
+        },
+        "handler.SubmitScoreRequest": {
+            "type": "object",
+            "properties": {
+                "beatmap_id": {
+                    "type": "string"
+                },
+                "good_fast": {
+                    "type": "integer"
+                },
+                "good_late": {
+                    "type": "integer"
+                },
+                "input": {
+                    "type": "integer"
+                },
+                "max_combo": {
+                    "type": "integer"
+                },
+                "miss": {
+                    "type": "integer"
+                },
+                "perfect_critical_fast": {
+                    "type": "integer"
+                },
+                "perfect_critical_late": {
+                    "type": "integer"
+                },
+                "perfect_fast": {
+                    "type": "integer"
+                },
+                "perfect_late": {
+                    "type": "integer"
+                },
+                "score": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.SubmitScoreResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "unlocked_achievements": {
+                    "description": "UnlockedAchievements are the achievements this play unlocked",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.UserAchievementResponse"
+                    }
+                }
+            }
+        },
+        "handler.TeamMemberResponse": {
+            "type": "object",
+            "properties": {
+                "joined_at": {
+                    "type": "string"
+                },
+                "player_name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "member"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.TeamRankingEntryResponse": {
+            "type": "object",
+            "properties": {
+                "charts": {
+                    "type": "integer"
                 },
                 "play_count": {
                     "type": "integer"
                 },
-                "player_count": {
+                "players": {
+                    "description": "Players is the number of members with a score",
                     "type": "integer"
                 },
-                "top": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/handler.RankingEntryResponse"
-                    }
+                "rank": {
+                    "type": "integer"
+                },
+                "team_id": {
+                    "type": "string"
+                },
+                "team_name": {
+                    "type": "string"
+                },
+                "total_score": {
+                    "type": "integer"
                 }
             }
         },
-        "handler.ErrorResponse": {
+        "handler.TeamRankingResponse": {
             "type": "object",
             "properties": {
-                "message": {
+                "beatmap_id": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.TeamRankingEntryResponse"
+                    }
+                },
+                "to": {
                     "type": "string"
+                },
+                "week": {
+                    "type": "string",
+                    "example": "2026-W42"
                 }
             }
         },
-        "handler.GetUserResponse": {
+        "handler.TeamResponse": {
             "type": "object",
             "properties": {
+                "created_at": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
+                "member_count": {
+                    "type": "integer"
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.TeamMemberResponse"
+                    }
+                },
                 "name": {
                     "type": "string"
                 }
             }
         },
-        "handler.RankingEntryResponse": {
+        "handler.TeamTotalResponse": {
             "type": "object",
             "properties": {
-                "player_name": {
-                    "type": "string"
+                "charts": {
+                    "type": "integer"
                 },
-                "score": {
+                "play_count": {
                     "type": "integer"
                 },
-                "user_id": {
+                "players": {
+                    "description": "Players is the number of members with a score",
+                    "type": "integer"
+                },
+                "team_id": {
+                    "type": "string"
+                },
+                "team_name": {
                     "type": "string"
+                },
+                "total_score": {
+                    "type": "integer"
                 }
             }
         },
-        "handler.RegisterUserResponse": {
+        "handler.TeamVersusResponse": {
             "type": "object",
             "properties": {
-                "id": {
+                "from": {
+                    "type": "string"
+                },
+                "other": {
+                    "$ref": "#/definitions/handler.TeamTotalResponse"
+                },
+                "team": {
+                    "$ref": "#/definitions/handler.TeamTotalResponse"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "week": {
+                    "type": "string",
+                    "example": "2026-W42"
+                },
+                "winner_id": {
+                    "description": "WinnerID is the team with the higher total, or null for a draw",
                     "type": "string"
                 }
             }
         },
-        "handler.SubmitScoreRequest": {
+        "handler.TrendPointResponse": {
             "type": "object",
             "properties": {
-                "beatmap_id": {
-                    "type": "string"
-                },
-                "good_fast": {
+                "fast": {
                     "type": "integer"
                 },
-                "good_late": {
-                    "type": "integer"
-                },
-                "input": {
-                    "type": "integer"
+                "fast_ratio": {
+                    "type": "number"
                 },
-                "max_combo": {
+                "late": {
                     "type": "integer"
                 },
                 "miss": {
                     "type": "integer"
                 },
-                "perfect_critical_fast": {
-                    "type": "integer"
-                },
-                "perfect_critical_late": {
-                    "type": "integer"
-                },
-                "perfect_fast": {
-                    "type": "integer"
+                "miss_rate": {
+                    "type": "number"
                 },
-                "perfect_late": {
-                    "type": "integer"
+                "played_at": {
+                    "type": "string"
                 },
                 "score": {
                     "type": "integer"
                 },
-                "user_id": {
-                    "type": "string"
+                "score_id": {
+                    "type": "integer"
                 }
             }
         },
-        "handler.SubmitScoreResponse": {
+        "handler.UpdateProfileRequest": {
             "type": "object",
             "properties": {
-                "id": {
-                    "type": "integer"
+                "favorite_beatmap_id": {
+                    "description": "FavoriteBeatmapID is a registered chart. An empty string removes it.",
+                    "type": "string",
+                    "example": "songA_future"
+                },
+                "showcase": {
+                    "description": "Showcase lists up to 3 charts the player has played. An empty list\nshows their best rated plays instead.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "songA_future",
+                        "songB_lycoris"
+                    ]
+                },
+                "title": {
+                    "description": "Title is the id of an unlocked achievement. An empty string removes the title.",
+                    "type": "string",
+                    "example": "lycoris_clear"
+                },
+                "visibility": {
+                    "type": "string",
+                    "enum": [
+                        "public",
+                        "friends",
+                        "private"
+                    ],
+                    "example": "friends"
                 }
             }
         },
@@ -469,6 +4512,197 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.UserAchievementResponse": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Full Combo 10 Future charts"
+                },
+                "goal": {
+                    "description": "Goal is the number of plays, or of charts, that unlocks the achievement",
+                    "type": "integer",
+                    "example": 10
+                },
+                "id": {
+                    "type": "string",
+                    "example": "future_full_combo_10"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Future Proof"
+                },
+                "progress": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "unlocked_at": {
+                    "description": "UnlockedAt is null while the achievement is locked",
+                    "type": "string"
+                }
+            }
+        },
+        "handler.UserChartAnalyticsResponse": {
+            "type": "object",
+            "properties": {
+                "average_judgements": {
+                    "$ref": "#/definitions/handler.JudgementAveragesResponse"
+                },
+                "average_score": {
+                    "type": "number"
+                },
+                "beatmap_id": {
+                    "type": "string"
+                },
+                "fast_count": {
+                    "type": "integer"
+                },
+                "fast_ratio": {
+                    "description": "FastRatio is fast / (fast + late), null without hits",
+                    "type": "number"
+                },
+                "late_count": {
+                    "type": "integer"
+                },
+                "mean_timing_error_ms": {
+                    "description": "MeanTimingErrorMs is negative when hitting early",
+                    "type": "number"
+                },
+                "miss_rate": {
+                    "type": "number"
+                },
+                "miss_rate_trend_per_play": {
+                    "type": "number"
+                },
+                "recommended_offset_ms": {
+                    "description": "RecommendedOffsetMs is the judgement offset adjustment, null until enough hits are recorded",
+                    "type": "integer"
+                },
+                "sample_size": {
+                    "type": "integer"
+                },
+                "score_trend_per_play": {
+                    "type": "number"
+                },
+                "total_plays": {
+                    "type": "integer"
+                },
+                "trend": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.TrendPointResponse"
+                    }
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.UserMissionResponse": {
+            "type": "object",
+            "properties": {
+                "claimed_at": {
+                    "description": "ClaimedAt is null until the reward is claimed",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Play 3 Present charts today"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "goal": {
+                    "description": "Goal is the number of plays that completes the mission",
+                    "type": "integer",
+                    "example": 3
+                },
+                "id": {
+                    "type": "string",
+                    "example": "daily_present_3"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Present Practice"
+                },
+                "period": {
+                    "type": "string",
+                    "enum": [
+                        "daily",
+                        "weekly"
+                    ],
+                    "example": "daily"
+                },
+                "progress": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "reward": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "starts_at": {
+                    "description": "StartsAt and EndsAt bound the day or week of the mission",
+                    "type": "string"
+                }
+            }
+        },
+        "handler.UserNameResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.UserProfileResponse": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "$ref": "#/definitions/handler.ProfileDetailsResponse"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "title": {
+                    "$ref": "#/definitions/handler.AchievementResponse"
+                },
+                "visibility": {
+                    "type": "string",
+                    "enum": [
+                        "public",
+                        "friends",
+                        "private"
+                    ]
+                }
+            }
+        },
+        "handler.UserScoresResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.PlayResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
         "handler.UserStatsResponse": {
             "type": "object",
             "properties": {
@@ -486,6 +4720,66 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.VersusChartResponse": {
+            "type": "object",
+            "properties": {
+                "beatmap_id": {
+                    "type": "string"
+                },
+                "diff": {
+                    "description": "Diff is score minus other_score, counting a missing score as 0",
+                    "type": "integer"
+                },
+                "difficulty": {
+                    "type": "integer"
+                },
+                "other_score": {
+                    "type": "integer"
+                },
+                "parallel_string": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "integer"
+                },
+                "song_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.VersusResponse": {
+            "type": "object",
+            "properties": {
+                "charts": {
+                    "description": "Charts are the charts where the bests differ",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.VersusChartResponse"
+                    }
+                },
+                "draws": {
+                    "type": "integer"
+                },
+                "losses": {
+                    "type": "integer"
+                },
+                "other_id": {
+                    "type": "string"
+                },
+                "other_name": {
+                    "type": "string"
+                },
+                "player_name": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "wins": {
+                    "type": "integer"
+                }
+            }
+        },
         "handler.updateUserNameRequest": {
             "type": "object",
             "properties": {
@@ -497,6 +4791,20 @@ const docTemplate = `{
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "\"Bearer \u003cADMIN_TOKEN\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        },
+        "UserToken": {
+            "description": "\"Bearer \u003ctoken\u003e\" (the token returned by POST /users)",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -0,0 +1,124 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"gotest.tools/v3/assert"
+)
+
+func TestAccountExportAndDeletion(t *testing.T) {
+	gina := registerPlayer(t, "Gina")
+	rec := doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songG_past","song_name":"Song G","difficulty":0}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	submitScore(t, gina.id, "songG_past", 970000, scoreOptions{input: 1})
+
+	// Hal is a friend and a rival, Ivy asked Gina and Gina asked Jo
+	hal, ivy, jo := registerPlayer(t, "Hal"), registerPlayer(t, "Ivy"), registerPlayer(t, "Jo")
+	friendCode := func(t *testing.T, p player) string {
+		t.Helper()
+		rec := doUserRequest(t, p, "GET", "/api/v1/users/me/friend-code", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		return unmarshalResponse(t, rec)["friend_code"].(string)
+	}
+	ginaCode := friendCode(t, gina)
+	for _, req := range []struct {
+		from player
+		code string
+	}{{hal, ginaCode}, {ivy, ginaCode}, {gina, friendCode(t, jo)}} {
+		rec = doUserRequest(t, req.from, "POST", "/api/v1/users/me/friends/requests", fmt.Sprintf(`{"friend_code":"%s"}`, req.code))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+	rec = doUserRequest(t, gina, "POST", "/api/v1/users/me/friends/requests/"+hal.id+"/accept", "")
+	assert.Equal(t, rec.Result().Status, `204 No Content`)
+	rec = doUserRequest(t, gina, "PUT", "/api/v1/users/me/rivals/"+hal.id, "")
+	assert.Equal(t, rec.Result().Status, `204 No Content`)
+	// Gina owns a team and is a member of Hal's
+	rec = doUserRequest(t, gina, "POST", "/api/v1/teams", `{"name":"Gina Team"}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	rec = doUserRequest(t, hal, "POST", "/api/v1/teams", `{"name":"Hal Team"}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	rec = doUserRequest(t, gina, "POST", "/api/v1/teams/join", fmt.Sprintf(`{"invite_code":"%s"}`, unmarshalResponse(t, rec)["invite_code"]))
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+
+	ranking := func(t *testing.T) map[string]any {
+		t.Helper()
+		rec := doRequest(t, "GET", "/api/v1/charts/ranking?beatmap_id=songG_past", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var arr []map[string]any
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &arr))
+		return arr[0]
+	}
+
+	t.Run("requires the user token", func(t *testing.T) {
+		assert.Equal(t, doUserRequest(t, player{}, "GET", "/api/v1/users/me/export", "").Result().Status, `401 Unauthorized`)
+		assert.Equal(t, doUserRequest(t, player{token: "wrong"}, "GET", "/api/v1/users/me/export", "").Result().Status, `401 Unauthorized`)
+		assert.Equal(t, doAdminRequest(t, "DELETE", "/api/v1/users/me", "").Result().Status, `401 Unauthorized`)
+	})
+
+	t.Run("export", func(t *testing.T) {
+		rec := doUserRequest(t, gina, "GET", "/api/v1/users/me/export", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Assert(t, strings.HasPrefix(rec.Header().Get(echo.HeaderContentDisposition), "attachment"))
+		res := unmarshalResponse(t, rec)
+		assert.Equal(t, res["profile"].(map[string]any)["id"], gina.id)
+		assert.Equal(t, res["profile"].(map[string]any)["name"], "Gina")
+		assert.Equal(t, res["profile"].(map[string]any)["friend_code"], ginaCode)
+
+		names := res["name_history"].([]any)
+		assert.Equal(t, len(names), 2)
+		assert.Assert(t, strings.HasPrefix(names[0].(map[string]any)["name"].(string), "Player-"))
+		assert.Equal(t, names[1].(map[string]any)["name"], "Gina")
+
+		scores := res["scores"].([]any)
+		assert.Equal(t, len(scores), 1)
+		assert.Equal(t, scores[0].(map[string]any)["score"], float64(970000))
+
+		contacts := func(v any) []string {
+			var out []string
+			for _, c := range v.([]any) {
+				out = append(out, c.(map[string]any)["user_id"].(string))
+			}
+			return out
+		}
+		assert.DeepEqual(t, contacts(res["friends"]), []string{hal.id})
+		requests := res["friend_requests"].(map[string]any)
+		assert.DeepEqual(t, contacts(requests["incoming"]), []string{ivy.id})
+		assert.DeepEqual(t, contacts(requests["outgoing"]), []string{jo.id})
+		assert.DeepEqual(t, contacts(res["rivals"]), []string{hal.id})
+
+		teams := res["teams"].([]any)
+		assert.Equal(t, len(teams), 2)
+		for i, want := range []struct{ name, role string }{{"Gina Team", "owner"}, {"Hal Team", "member"}} {
+			team := teams[i].(map[string]any)
+			assert.Equal(t, team["name"], want.name)
+			assert.Equal(t, team["role"], want.role)
+			assert.Assert(t, team["joined_at"] != nil)
+		}
+	})
+
+	t.Run("deletion anonymises the scores", func(t *testing.T) {
+		before := ranking(t)
+
+		rec := doUserRequest(t, gina, "DELETE", "/api/v1/users/me", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, unmarshalResponse(t, rec)["anonymised_scores"], float64(1))
+
+		after := ranking(t)
+		assert.Equal(t, after["play_count"], before["play_count"])
+		assert.Equal(t, after["player_count"], before["player_count"])
+		top := after["top"].([]any)[0].(map[string]any)
+		assert.Equal(t, top["player_name"], "Deleted player")
+		assert.Assert(t, top["user_id"] != gina.id)
+
+		assert.Equal(t, doRequest(t, "GET", "/api/v1/users/"+gina.id, "").Result().Status, `404 Not Found`)
+		assert.Equal(t, doUserRequest(t, gina, "GET", "/api/v1/users/me/export", "").Result().Status, `401 Unauthorized`)
+
+		rec = doAdminRequest(t, "GET", "/api/v1/admin/audit-logs?limit=1", "")
+		assert.Assert(t, strings.Contains(rec.Body.String(), `"users.delete"`), rec.Body.String())
+		assert.Assert(t, strings.Contains(rec.Body.String(), "user:"+gina.id))
+	})
+}
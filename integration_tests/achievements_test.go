@@ -0,0 +1,146 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestAchievements(t *testing.T) {
+	p := registerPlayer(t, "")
+
+	for d, name := range []string{"past", "present", "future", "lycoris"} {
+		rec := doRequest(t, "POST", "/api/v1/charts", fmt.Sprintf(`{"beatmap_id":"songL_%s","song_name":"Song L","difficulty":%d}`, name, d))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+
+	// submit returns the ids of the achievements the play unlocked
+	submit := func(t *testing.T, beatmapID string, score, good, miss, input int) []string {
+		t.Helper()
+		rec := submitScore(t, p.id, beatmapID, score, scoreOptions{good: good, miss: miss, input: input})
+		var res struct {
+			UnlockedAchievements []struct {
+				ID         string  `json:"id"`
+				UnlockedAt *string `json:"unlocked_at"`
+			} `json:"unlocked_achievements"`
+		}
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Assert(t, res.UnlockedAchievements != nil)
+		ids := make([]string, len(res.UnlockedAchievements))
+		for i, a := range res.UnlockedAchievements {
+			assert.Assert(t, a.UnlockedAt != nil)
+			ids[i] = a.ID
+		}
+		return ids
+	}
+
+	t.Run("definitions", func(t *testing.T) {
+		rec := doRequest(t, "GET", "/api/v1/achievements", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var defs []map[string]any
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &defs))
+		byID := map[string]map[string]any{}
+		for _, d := range defs {
+			byID[d["id"].(string)] = d
+		}
+		assert.Equal(t, byID["future_full_combo_10"]["goal"], float64(10))
+		assert.Equal(t, byID["button_plays_100"]["goal"], float64(100))
+		assert.Equal(t, byID["lycoris_all_perfect"]["goal"], float64(1))
+	})
+
+	t.Run("unlock on submit", func(t *testing.T) {
+		// a clear with a miss
+		assert.DeepEqual(t, submit(t, "songL_lycoris", 900000, 0, 1, 0), []string{"first_play", "first_clear", "lycoris_clear"})
+		// a full combo is not enough
+		assert.DeepEqual(t, submit(t, "songL_lycoris", 950000, 2, 0, 0), []string{})
+		assert.DeepEqual(t, submit(t, "songL_lycoris", 1000000, 0, 0, 1), []string{"lycoris_all_perfect"})
+		// unlocks are kept
+		assert.DeepEqual(t, submit(t, "songL_lycoris", 1000000, 0, 0, 1), []string{})
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		rec := doRequest(t, "GET", "/api/v1/users/"+p.id, "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		unlocked := unmarshalResponse(t, rec)["achievements"].([]any)
+		ids := make([]string, len(unlocked))
+		for i, a := range unlocked {
+			ids[i] = a.(map[string]any)["id"].(string)
+		}
+		slices.Sort(ids)
+		assert.DeepEqual(t, ids, []string{"first_clear", "first_play", "lycoris_all_perfect", "lycoris_clear"})
+
+		rec = doRequest(t, "GET", "/api/v1/users/"+p.id+"/achievements", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var ss []map[string]any
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &ss))
+		byID := map[string]map[string]any{}
+		for _, s := range ss {
+			byID[s["id"].(string)] = s
+		}
+		assert.Equal(t, byID["plays_100"]["progress"], float64(4))
+		assert.Equal(t, byID["plays_100"]["unlocked_at"], nil)
+		assert.Equal(t, byID["button_plays_100"]["progress"], float64(2))
+		assert.Equal(t, byID["future_full_combo_10"]["progress"], float64(0))
+		assert.Equal(t, byID["lycoris_all_perfect"]["progress"], float64(1))
+		assert.Assert(t, byID["lycoris_all_perfect"]["unlocked_at"] != nil)
+
+		rec = doRequest(t, "GET", "/api/v1/users/00000000-0000-0000-0000-000000000000/achievements", "")
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		rec = doRequest(t, "GET", "/api/v1/users/nope/achievements", "")
+		assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+	})
+
+	t.Run("personal data", func(t *testing.T) {
+		rec := doUserRequest(t, p, "GET", "/api/v1/users/me/export", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, len(unmarshalResponse(t, rec)["achievements"].([]any)), 4)
+	})
+
+	t.Run("counters follow moderation", func(t *testing.T) {
+		q := registerPlayer(t, "")
+		for i := range 10 {
+			rec := doRequest(t, "POST", "/api/v1/charts", fmt.Sprintf(`{"beatmap_id":"songF%d_future","song_name":"Song F%d","difficulty":2}`, i, i))
+			assert.Equal(t, rec.Result().Status, `200 OK`)
+		}
+		// fullCombo returns the score ID and whether the play unlocked future_full_combo_10
+		fullCombo := func(t *testing.T, chart int) (int64, bool) {
+			t.Helper()
+			rec := submitScore(t, q.id, fmt.Sprintf("songF%d_future", chart), 950000, scoreOptions{good: 1})
+			var res struct {
+				ID                   int64 `json:"id"`
+				UnlockedAchievements []struct {
+					ID string `json:"id"`
+				} `json:"unlocked_achievements"`
+			}
+			assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+			for _, a := range res.UnlockedAchievements {
+				if a.ID == "future_full_combo_10" {
+					return res.ID, true
+				}
+			}
+			return res.ID, false
+		}
+
+		first, _ := fullCombo(t, 0)
+		for i := 1; i < 9; i++ {
+			_, unlocked := fullCombo(t, i)
+			assert.Assert(t, !unlocked)
+		}
+		// a chart counts once
+		again, unlocked := fullCombo(t, 0)
+		assert.Assert(t, !unlocked)
+
+		// with the plays of the first chart deleted, the tenth chart makes nine
+		for _, id := range []int64{first, again} {
+			rec := doAdminRequest(t, "DELETE", fmt.Sprintf("/api/v1/admin/scores/%d", id), "")
+			assert.Equal(t, rec.Result().Status, `200 OK`)
+		}
+		_, unlocked = fullCombo(t, 9)
+		assert.Assert(t, !unlocked)
+		_, unlocked = fullCombo(t, 0)
+		assert.Assert(t, unlocked)
+	})
+}
@@ -0,0 +1,226 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core"
+	"github.com/pikachu0310/senirenol-server/core/database"
+
+	"github.com/labstack/echo/v4"
+	"gotest.tools/v3/assert"
+)
+
+func TestDataExportImport(t *testing.T) {
+	rec := doRequest(t, "POST", "/api/v1/users", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	uid := unmarshalResponse(t, rec)["id"].(string)
+
+	rec = doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songB_past","song_name":"Song B","difficulty":0}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	var scoreIDs []int64
+	for _, score := range []int{800000, 950000} {
+		body := fmt.Sprintf(`{"user_id":"%s","beatmap_id":"songB_past","score":%d,"max_combo":100,"perfect_critical_fast":10,"perfect_critical_late":10,"perfect_fast":0,"perfect_late":0,"good_fast":0,"good_late":0,"miss":0,"input":0}`, uid, score)
+		rec := doRequest(t, "POST", "/api/v1/scores", body)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		scoreIDs = append(scoreIDs, int64(unmarshalResponse(t, rec)["id"].(float64)))
+	}
+
+	// records splits a JSON Lines dump into its manifest and the rows of each table
+	records := func(t *testing.T, dump string) (map[string]any, map[string][]map[string]any) {
+		t.Helper()
+		lines := strings.Split(strings.TrimSpace(dump), "\n")
+		var manifest map[string]any
+		rows := map[string][]map[string]any{}
+		for i, l := range lines {
+			var rec struct {
+				Table string         `json:"table"`
+				Row   map[string]any `json:"row"`
+			}
+			assert.NilError(t, json.Unmarshal([]byte(l), &rec))
+			if i == 0 {
+				assert.Equal(t, rec.Table, "manifest")
+				manifest = rec.Row
+				continue
+			}
+			rows[rec.Table] = append(rows[rec.Table], rec.Row)
+		}
+		return manifest, rows
+	}
+	importDump := func(t *testing.T, contentType, dump string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/v1/admin/import", strings.NewReader(dump))
+		req.Header.Set(echo.HeaderContentType, contentType)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+adminToken)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var full string
+	t.Run("export", func(t *testing.T) {
+		rec := doAdminRequest(t, "GET", "/api/v1/admin/export", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, rec.Header().Get(echo.HeaderContentType), "application/x-ndjson")
+		full = rec.Body.String()
+
+		manifest, rows := records(t, full)
+		assert.Equal(t, manifest["kind"], "senirenol-export")
+		assert.Equal(t, manifest["version"], float64(1))
+		assert.Equal(t, manifest["counts"].(map[string]any)["scores"], float64(len(rows["scores"])))
+
+		var mine []float64
+		for _, s := range rows["scores"] {
+			if s["user_id"] == uid {
+				mine = append(mine, s["score"].(float64))
+			}
+		}
+		assert.DeepEqual(t, mine, []float64{800000, 950000})
+	})
+
+	t.Run("incremental export", func(t *testing.T) {
+		rec := doAdminRequest(t, "GET", "/api/v1/admin/export?from=2999-01-01T00:00:00Z", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		manifest, rows := records(t, rec.Body.String())
+		assert.Equal(t, manifest["from"], "2999-01-01T00:00:00Z")
+		assert.Equal(t, len(rows["users"]), 0)
+		assert.Equal(t, len(rows["scores"]), 0)
+		assert.Assert(t, len(rows["charts"]) > 0)
+	})
+
+	t.Run("import skips stored scores", func(t *testing.T) {
+		rec := importDump(t, "application/x-ndjson", full)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res := unmarshalResponse(t, rec)
+		assert.Equal(t, res["scores"], float64(0))
+		assert.Assert(t, res["skipped_scores"].(float64) >= 2)
+	})
+
+	t.Run("tar round trip", func(t *testing.T) {
+		rec := doAdminRequest(t, "GET", "/api/v1/admin/export?format=tar", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, rec.Header().Get(echo.HeaderContentType), "application/gzip")
+
+		rec = importDump(t, "application/gzip", rec.Body.String())
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, unmarshalResponse(t, rec)["scores"], float64(0))
+	})
+
+	t.Run("unknown references reject the whole dump", func(t *testing.T) {
+		dump := `{"table":"manifest","row":{"kind":"senirenol-export","version":1}}
+{"table":"scores","row":{"id":1,"user_id":"` + uid + `","beatmap_id":"songB_future","score":1,"created_at":"2025-01-01T00:00:00Z"}}
+{"table":"scores","row":{"id":2,"user_id":"00000000-0000-0000-0000-000000000000","beatmap_id":"songB_past","score":1,"created_at":"2025-01-01T00:00:00Z"}}
+`
+		rec := importDump(t, "application/x-ndjson", dump)
+		assert.Equal(t, rec.Result().Status, `422 Unprocessable Entity`)
+		assert.DeepEqual(t, unmarshalResponse(t, rec)["details"], []any{
+			map[string]any{"field": "scores[0].beatmap_id", "message": `refers to unknown chart "songB_future"`},
+			map[string]any{"field": "scores[1].user_id", "message": `refers to unknown user "00000000-0000-0000-0000-000000000000"`},
+		})
+	})
+
+	t.Run("newer versions are rejected", func(t *testing.T) {
+		rec := importDump(t, "application/x-ndjson", `{"table":"manifest","row":{"kind":"senirenol-export","version":2}}`)
+		assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		rec := importDump(t, "text/csv", full)
+		assert.Equal(t, rec.Result().Status, `415 Unsupported Media Type`)
+	})
+
+	t.Run("identical plays stay apart", func(t *testing.T) {
+		// 同じ時刻・同じスコアのプレイが2件あり、片方だけ無効化されているダンプ
+		score := func(id int, extra string) string {
+			return fmt.Sprintf(`{"table":"scores","row":{"id":%d,"user_id":"%s","beatmap_id":"songB_past","score":123456,"max_combo":100,"created_at":"2024-05-01T00:00:00Z"%s}}`, id, uid, extra)
+		}
+		dump := `{"table":"manifest","row":{"kind":"senirenol-export","version":1}}
+` + score(1, "") + "\n" + score(2, `,"invalidated_at":"2024-05-02T00:00:00Z","invalidation_reason":"dup"`) + "\n"
+		reasons := func(t *testing.T) []any {
+			t.Helper()
+			rec := doAdminRequest(t, "GET", "/api/v1/admin/export", "")
+			assert.Equal(t, rec.Result().Status, `200 OK`)
+			_, rows := records(t, rec.Body.String())
+			var out []any
+			for _, s := range rows["scores"] {
+				if s["user_id"] == uid && s["score"] == float64(123456) {
+					out = append(out, s["invalidation_reason"])
+				}
+			}
+			return out
+		}
+
+		rec := importDump(t, "application/x-ndjson", dump)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res := unmarshalResponse(t, rec)
+		assert.Equal(t, res["scores"], float64(2))
+		assert.Equal(t, res["moderated_scores"], float64(0))
+		assert.DeepEqual(t, reasons(t), []any{nil, "dup"})
+
+		// 読み込み直しても、それぞれが保存済みの1件と対応する
+		rec = importDump(t, "application/x-ndjson", dump)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res = unmarshalResponse(t, rec)
+		assert.Equal(t, res["scores"], float64(0))
+		assert.Equal(t, res["skipped_scores"], float64(2))
+		assert.Equal(t, res["moderated_scores"], float64(0))
+		assert.DeepEqual(t, reasons(t), []any{nil, "dup"})
+
+		// 両方が無効化されたダンプでは、無効化されていない方だけが更新される
+		rec = importDump(t, "application/x-ndjson", strings.Replace(dump, score(1, ""), score(1, `,"invalidated_at":"2024-05-02T00:00:00Z","invalidation_reason":"dup"`), 1))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res = unmarshalResponse(t, rec)
+		assert.Equal(t, res["skipped_scores"], float64(1))
+		assert.Equal(t, res["moderated_scores"], float64(1))
+		assert.DeepEqual(t, reasons(t), []any{"dup", "dup"})
+	})
+
+	t.Run("moderation carries over", func(t *testing.T) {
+		// 別のデータベースのサーバーを移行先にする
+		targetDB, err := database.SetupSQLite(filepath.Join(t.TempDir(), "target.db"))
+		assert.NilError(t, err)
+		t.Cleanup(func() { _ = targetDB.Close() })
+		s, err := core.InjectDeps(targetDB, config)
+		assert.NilError(t, err)
+		target := echo.New()
+		core.SetupRoutes(s.Handler, target)
+		do := func(t *testing.T, method, path, body string) *httptest.ResponseRecorder {
+			t.Helper()
+			req := httptest.NewRequest(method, path, strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, "application/x-ndjson")
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+adminToken)
+			rec := httptest.NewRecorder()
+			target.ServeHTTP(rec, req)
+			assert.Equal(t, rec.Result().Status, `200 OK`)
+			return rec
+		}
+
+		from := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+		rec := doAdminRequest(t, "GET", "/api/v1/admin/export", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res := unmarshalResponse(t, do(t, "POST", "/api/v1/admin/import", rec.Body.String()))
+		assert.Assert(t, res["scores"].(float64) >= 2)
+
+		rec = doAdminRequest(t, "POST", fmt.Sprintf("/api/v1/admin/scores/%d/invalidate", scoreIDs[1]), `{"reason":"cheated"}`)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		rec = doAdminRequest(t, "GET", "/api/v1/admin/export?from="+from, "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res = unmarshalResponse(t, do(t, "POST", "/api/v1/admin/import", rec.Body.String()))
+		assert.Equal(t, res["scores"], float64(0))
+		assert.Equal(t, res["moderated_scores"], float64(1))
+
+		_, rows := records(t, do(t, "GET", "/api/v1/admin/export", "").Body.String())
+		var reasons []any
+		for _, s := range rows["scores"] {
+			if s["user_id"] == uid && s["score"] != float64(123456) {
+				reasons = append(reasons, s["invalidation_reason"])
+			}
+		}
+		assert.DeepEqual(t, reasons, []any{nil, "cheated"})
+	})
+}
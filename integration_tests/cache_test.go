@@ -0,0 +1,56 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRankingCache(t *testing.T) {
+	stats := func() map[string]any {
+		rec := doAdminRequest(t, "GET", "/api/v1/admin/cache/stats", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		return unmarshalResponse(t, rec)
+	}
+	ranking := func() []map[string]any {
+		rec := doRequest(t, "GET", "/api/v1/charts/ranking?beatmap_id=songC_future", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		arr := []map[string]any{}
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &arr))
+		assert.Equal(t, len(arr), 1)
+		top, _ := arr[0]["top"].([]any)
+		out := make([]map[string]any, len(top))
+		for i, e := range top {
+			out[i] = e.(map[string]any)
+		}
+		return out
+	}
+
+	rec := doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songC_future","song_name":"Song C","difficulty":2}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	rec = doRequest(t, "POST", "/api/v1/users", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	uid := unmarshalResponse(t, rec)["id"].(string)
+
+	// 2回目はキャッシュから返る
+	before := stats()
+	assert.Equal(t, len(ranking()), 0)
+	assert.Equal(t, len(ranking()), 0)
+	after := stats()
+	assert.Equal(t, after["hits"].(float64)-before["hits"].(float64), float64(1))
+	assert.Equal(t, after["misses"].(float64)-before["misses"].(float64), float64(1))
+
+	// スコア登録でキャッシュが破棄される
+	body := `{"user_id":"` + uid + `","beatmap_id":"songC_future","score":920000,"max_combo":100,"perfect_critical_fast":10,"perfect_critical_late":10,"perfect_fast":0,"perfect_late":0,"good_fast":0,"good_late":0,"miss":0,"input":0}`
+	rec = doRequest(t, "POST", "/api/v1/scores", body)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	top := ranking()
+	assert.Equal(t, len(top), 1)
+	assert.Equal(t, top[0]["score"], float64(920000))
+
+	// 名前の変更もランキングに反映される
+	rec = doRequest(t, "POST", "/api/v1/users/update", `{"user_id":"`+uid+`","user_name":"cached-player"}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	assert.Equal(t, ranking()[0]["player_name"], "cached-player")
+}
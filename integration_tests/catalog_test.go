@@ -0,0 +1,82 @@
+package integrationtests
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestChartCatalog(t *testing.T) {
+	csv := "beatmap_id,song_name,difficulty,parallel_string,note_count,constant\n" +
+		"songK_past,Song K,past,,300,2.5\n" +
+		"songK_parallel,Song K,4,Ⅱ,,\n"
+
+	exported := func(t *testing.T, format string) string {
+		t.Helper()
+		rec := doAdminRequest(t, "GET", "/api/v1/admin/charts/catalog?format="+format, "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		return rec.Body.String()
+	}
+
+	t.Run("dry run writes nothing", func(t *testing.T) {
+		rec := doAdminRequest(t, "POST", "/api/v1/admin/charts/catalog?format=csv&dry_run=true", csv)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res := unmarshalResponse(t, rec)
+		assert.Equal(t, res["dry_run"], true)
+		assert.Equal(t, res["created"], float64(2))
+		assert.Equal(t, len(res["changes"].([]any)), 2)
+		assert.Assert(t, !strings.Contains(exported(t, "csv"), "songK_past"))
+	})
+
+	t.Run("import", func(t *testing.T) {
+		rec := doAdminRequest(t, "POST", "/api/v1/admin/charts/catalog?format=csv", csv)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res := unmarshalResponse(t, rec)
+		assert.Equal(t, res["dry_run"], false)
+		assert.Equal(t, res["created"], float64(2))
+
+		out := exported(t, "csv")
+		assert.Assert(t, strings.Contains(out, "songK_past,Song K,0,,300,2.5\n"), out)
+		assert.Assert(t, strings.Contains(out, "songK_parallel,Song K,4,Ⅱ,,\n"), out)
+	})
+
+	t.Run("update shows the diff", func(t *testing.T) {
+		yaml := "- beatmap_id: songK_past\n  song_name: Song K\n  difficulty: past\n  note_count: 300\n  constant: 2.7\n" +
+			"- beatmap_id: songK_parallel\n  song_name: Song K\n  difficulty: parallel\n  parallel_string: Ⅱ\n"
+		rec := doAdminRequest(t, "POST", "/api/v1/admin/charts/catalog?format=yaml", yaml)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res := unmarshalResponse(t, rec)
+		assert.Equal(t, res["updated"], float64(1))
+		assert.Equal(t, res["unchanged"], float64(1))
+		assert.DeepEqual(t, res["changes"], []any{map[string]any{
+			"op":         "update",
+			"beatmap_id": "songK_past",
+			"fields":     []any{map[string]any{"field": "constant", "old": 2.5, "new": 2.7}},
+		}})
+		assert.Assert(t, strings.Contains(exported(t, "yaml"), "constant: 2.7"))
+	})
+
+	t.Run("invalid rows reject the whole file", func(t *testing.T) {
+		rec := doAdminRequest(t, "POST", "/api/v1/admin/charts/catalog", `[
+			{"beatmap_id":"songK_future","song_name":"Song K","difficulty":2},
+			{"beatmap_id":"songK_future","song_name":"","difficulty":2}
+		]`)
+		assert.Equal(t, rec.Result().Status, `422 Unprocessable Entity`)
+		e := unmarshalResponse(t, rec)
+		assert.Equal(t, e["code"], "VALIDATION_FAILED")
+		assert.DeepEqual(t, e["details"], []any{map[string]any{"field": "charts[1].song_name", "message": "cannot be blank"}})
+		assert.Assert(t, !strings.Contains(exported(t, "json"), "songK_future"))
+	})
+
+	t.Run("malformed file", func(t *testing.T) {
+		rec := doAdminRequest(t, "POST", "/api/v1/admin/charts/catalog?format=csv", "beatmap_id,song_name\n")
+		assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+		assert.Equal(t, unmarshalResponse(t, rec)["code"], "INVALID_REQUEST")
+	})
+
+	t.Run("requires the admin token", func(t *testing.T) {
+		rec := doRequest(t, "GET", "/api/v1/admin/charts/catalog", "")
+		assert.Equal(t, rec.Result().Status, `401 Unauthorized`)
+	})
+}
@@ -2,6 +2,7 @@ package integrationtests
 
 import (
 	"encoding/json"
+	"strconv"
 	"testing"
 
 	"gotest.tools/v3/assert"
@@ -31,3 +32,78 @@ func TestCharts(t *testing.T) {
 	rec = doRequest(t, "GET", "/api/v1/songs/playcount", "")
 	assert.Equal(t, rec.Result().Status, `200 OK`)
 }
+
+func TestChartAnalytics(t *testing.T) {
+	rec := doRequest(t, "POST", "/api/v1/users", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	uid := unmarshalResponse(t, rec)["id"].(string)
+
+	rec = doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songZ_lycoris","song_name":"Song Z","difficulty":3}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+
+	for _, sc := range []int{640000, 910000} {
+		body := `{"user_id":"` + uid + `","beatmap_id":"songZ_lycoris","score":` + strconv.Itoa(sc) + `,"max_combo":100,"perfect_critical_fast":10,"perfect_critical_late":10,"perfect_fast":0,"perfect_late":0,"good_fast":0,"good_late":0,"miss":5,"input":1}`
+		rec = doRequest(t, "POST", "/api/v1/scores", body)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+
+	rec = doRequest(t, "GET", "/api/v1/charts/songZ_lycoris/analytics?days=7", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	var a struct {
+		PlayCount         int                `json:"play_count"`
+		PlayerCount       int                `json:"player_count"`
+		ClearRate         float64            `json:"clear_rate"`
+		GradeDistribution map[string]int     `json:"grade_distribution"`
+		Percentiles       map[string]float64 `json:"percentiles"`
+		PlaysOverTime     []map[string]any   `json:"plays_over_time"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &a))
+	assert.Equal(t, a.PlayCount, 2)
+	assert.Equal(t, a.PlayerCount, 1)
+	assert.Equal(t, a.ClearRate, 0.5)
+	assert.Equal(t, a.GradeDistribution["A"], 1)
+	assert.Equal(t, a.Percentiles["p50"], 910000.0)
+	assert.Equal(t, len(a.PlaysOverTime), 7)
+
+	rec = doRequest(t, "GET", "/api/v1/charts/no_such_chart/analytics", "")
+	assert.Equal(t, rec.Result().Status, `404 Not Found`)
+}
+
+func TestChartDifficultyEstimation(t *testing.T) {
+	charts := []string{"songD_past", "songD_present", "songD_future"}
+	for i, id := range charts {
+		rec := doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"`+id+`","song_name":"Song D","difficulty":`+strconv.Itoa(i)+`}`)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+	for p := range 3 {
+		rec := doRequest(t, "POST", "/api/v1/users", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		uid := unmarshalResponse(t, rec)["id"].(string)
+		for i, id := range charts {
+			sc := 990000 - i*60000 - p*10000
+			body := `{"user_id":"` + uid + `","beatmap_id":"` + id + `","score":` + strconv.Itoa(sc) + `,"max_combo":100,"perfect_critical_fast":10,"perfect_critical_late":10,"perfect_fast":0,"perfect_late":0,"good_fast":0,"good_late":0,"miss":0,"input":0}`
+			rec = doRequest(t, "POST", "/api/v1/scores", body)
+			assert.Equal(t, rec.Result().Status, `200 OK`)
+		}
+	}
+
+	rec := doAdminRequest(t, "POST", "/api/v1/admin/charts/difficulty/recompute", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	assert.Assert(t, unmarshalResponse(t, rec)["estimated"].(float64) >= 3)
+
+	rec = doRequest(t, "GET", "/api/v1/charts/difficulty", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	var ds []struct {
+		BeatmapID         string   `json:"beatmap_id"`
+		EstimatedConstant *float64 `json:"estimated_constant"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &ds))
+	got := map[string]float64{}
+	for _, d := range ds {
+		if d.EstimatedConstant != nil {
+			got[d.BeatmapID] = *d.EstimatedConstant
+		}
+	}
+	assert.Assert(t, got["songD_past"] < got["songD_present"])
+	assert.Assert(t, got["songD_present"] < got["songD_future"])
+}
@@ -0,0 +1,96 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"gotest.tools/v3/assert"
+)
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details []struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	} `json:"details"`
+}
+
+func TestErrorResponse(t *testing.T) {
+	rec := doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songE_past","song_name":"Song E","difficulty":0}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	rec = doRequest(t, "POST", "/api/v1/users", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	uid := unmarshalResponse(t, rec)["id"].(string)
+
+	decode := func(t *testing.T, body []byte) errorBody {
+		t.Helper()
+		var e errorBody
+		assert.NilError(t, json.Unmarshal(body, &e))
+		return e
+	}
+
+	t.Run("validation failure lists every field", func(t *testing.T) {
+		t.Parallel()
+		rec := doRequest(t, "POST", "/api/v1/scores", `{"score":900000}`)
+		assert.Equal(t, rec.Result().Status, `422 Unprocessable Entity`)
+		e := decode(t, rec.Body.Bytes())
+		assert.Equal(t, e.Code, "VALIDATION_FAILED")
+		assert.Equal(t, len(e.Details), 2)
+		assert.Equal(t, e.Details[0].Field, "beatmap_id")
+		assert.Equal(t, e.Details[0].Message, "cannot be blank")
+		assert.Equal(t, e.Details[1].Field, "user_id")
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		t.Parallel()
+		rec := doRequest(t, "POST", "/api/v1/scores", `{"score":`)
+		assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+		assert.Equal(t, decode(t, rec.Body.Bytes()).Code, "INVALID_REQUEST")
+	})
+
+	t.Run("malformed parameter", func(t *testing.T) {
+		t.Parallel()
+		rec := doRequest(t, "GET", "/api/v1/users/nope", "")
+		assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+		e := decode(t, rec.Body.Bytes())
+		assert.Equal(t, e.Code, "INVALID_REQUEST")
+		assert.Equal(t, e.Details[0].Field, "userID")
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		t.Parallel()
+		missing := uuid.NewString()
+		rec := doRequest(t, "GET", "/api/v1/users/"+missing, "")
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		assert.DeepEqual(t, unmarshalResponse(t, rec), map[string]any{"code": "USER_NOT_FOUND", "message": "user not found"})
+
+		rec = doRequest(t, "POST", "/api/v1/users/update", fmt.Sprintf(`{"user_id":"%s","user_name":"Eve"}`, missing))
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		assert.Equal(t, decode(t, rec.Body.Bytes()).Code, "USER_NOT_FOUND")
+
+		rec = doRequest(t, "POST", "/api/v1/scores", fmt.Sprintf(`{"user_id":"%s","beatmap_id":"songE_past","score":900000}`, missing))
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		assert.Equal(t, decode(t, rec.Body.Bytes()).Code, "USER_NOT_FOUND")
+	})
+
+	t.Run("unknown chart", func(t *testing.T) {
+		t.Parallel()
+		rec := doRequest(t, "POST", "/api/v1/scores", fmt.Sprintf(`{"user_id":"%s","beatmap_id":"songE_nothing","score":900000}`, uid))
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		assert.Equal(t, decode(t, rec.Body.Bytes()).Code, "CHART_NOT_FOUND")
+
+		rec = doRequest(t, "GET", "/api/v1/charts/songE_nothing/analytics", "")
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		assert.Equal(t, decode(t, rec.Body.Bytes()).Code, "CHART_NOT_FOUND")
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		t.Parallel()
+		rec := doRequest(t, "GET", "/api/v1/no-such-route", "")
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		assert.DeepEqual(t, unmarshalResponse(t, rec), map[string]any{"code": "NOT_FOUND", "message": "Not Found"})
+	})
+}
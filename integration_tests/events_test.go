@@ -0,0 +1,48 @@
+package integrationtests
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRankingEvents(t *testing.T) {
+	rec := doRequest(t, "POST", "/api/v1/users", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	uid := unmarshalResponse(t, rec)["id"].(string)
+
+	rec = doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songEv_future","song_name":"Song Ev","difficulty":2}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/v1/charts/ranking/events?beatmap_id=songEv_future", nil)
+	assert.NilError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	assert.NilError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, res.Header.Get("Content-Type"), "text/event-stream")
+
+	body := fmt.Sprintf(`{"user_id":"%s","beatmap_id":"songEv_future","score":950000,"max_combo":100,"perfect_critical_fast":10,"perfect_critical_late":10,"perfect_fast":0,"perfect_late":0,"good_fast":0,"good_late":0,"miss":0,"input":0}`, uid)
+	rec = doRequest(t, "POST", "/api/v1/scores", body)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+
+	var events []string
+	sc := bufio.NewScanner(res.Body)
+	for len(events) < 2 && sc.Scan() {
+		if ev, ok := strings.CutPrefix(sc.Text(), "event: "); ok {
+			events = append(events, ev)
+		}
+	}
+	assert.DeepEqual(t, events, []string{"personal_best", "ranking_updated"})
+}
@@ -0,0 +1,187 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestFriendsAndRivals(t *testing.T) {
+	names := func(t *testing.T, rec *httptest.ResponseRecorder) []string {
+		t.Helper()
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var arr []map[string]any
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &arr))
+		out := make([]string, len(arr))
+		for i, c := range arr {
+			out[i] = c["player_name"].(string)
+		}
+		return out
+	}
+
+	fay, finn, fred := registerPlayer(t, "Fay"), registerPlayer(t, "Finn"), registerPlayer(t, "Fred")
+	for _, d := range []string{"past", "present", "future"} {
+		rec := doRequest(t, "POST", "/api/v1/charts", fmt.Sprintf(`{"beatmap_id":"songF_%s","song_name":"Song F","difficulty":0}`, d))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+	submitScore(t, fay.id, "songF_past", 950000)
+	submitScore(t, fay.id, "songF_present", 900000)
+	submitScore(t, finn.id, "songF_past", 990000)
+	submitScore(t, finn.id, "songF_present", 900000)
+	submitScore(t, finn.id, "songF_future", 800000)
+	submitScore(t, fred.id, "songF_past", 1000000)
+
+	code := func(t *testing.T, p player) string {
+		t.Helper()
+		rec := doUserRequest(t, p, "GET", "/api/v1/users/me/friend-code", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		return unmarshalResponse(t, rec)["friend_code"].(string)
+	}
+
+	t.Run("friend code", func(t *testing.T) {
+		c := code(t, fay)
+		assert.Equal(t, len(c), 11)
+		assert.Equal(t, c[5], byte('-'))
+		assert.Equal(t, code(t, fay), c)
+		assert.Assert(t, code(t, finn) != c)
+		assert.Equal(t, doUserRequest(t, player{}, "GET", "/api/v1/users/me/friend-code", "").Result().Status, `401 Unauthorized`)
+	})
+
+	t.Run("requests", func(t *testing.T) {
+		rec := doUserRequest(t, fay, "POST", "/api/v1/users/me/friends/requests", `{"friend_code":"00000-00000"}`)
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		rec = doUserRequest(t, fay, "POST", "/api/v1/users/me/friends/requests", `{"friend_code":"not a code"}`)
+		assert.Equal(t, rec.Result().Status, `422 Unprocessable Entity`)
+		rec = doUserRequest(t, fay, "POST", "/api/v1/users/me/friends/requests", fmt.Sprintf(`{"friend_code":"%s"}`, code(t, fay)))
+		assert.Equal(t, rec.Result().Status, `422 Unprocessable Entity`)
+
+		// case and hyphens do not matter
+		finnCode := strings.ToLower(strings.ReplaceAll(code(t, finn), "-", ""))
+		rec = doUserRequest(t, fay, "POST", "/api/v1/users/me/friends/requests", fmt.Sprintf(`{"friend_code":"%s"}`, finnCode))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, unmarshalResponse(t, rec)["status"], "requested")
+		rec = doUserRequest(t, fay, "POST", "/api/v1/users/me/friends/requests", fmt.Sprintf(`{"friend_code":"%s"}`, finnCode))
+		assert.Equal(t, rec.Result().Status, `409 Conflict`)
+
+		rec = doUserRequest(t, finn, "GET", "/api/v1/users/me/friends/requests", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res := unmarshalResponse(t, rec)
+		assert.Equal(t, len(res["incoming"].([]any)), 1)
+		assert.Equal(t, res["incoming"].([]any)[0].(map[string]any)["user_id"], fay.id)
+		assert.Equal(t, len(res["outgoing"].([]any)), 0)
+
+		rec = doUserRequest(t, finn, "POST", "/api/v1/users/me/friends/requests/"+fred.id+"/accept", "")
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		assert.Equal(t, unmarshalResponse(t, rec)["code"], "FRIEND_REQUEST_NOT_FOUND")
+		rec = doUserRequest(t, finn, "POST", "/api/v1/users/me/friends/requests/"+fay.id+"/accept", "")
+		assert.Equal(t, rec.Result().Status, `204 No Content`)
+
+		assert.DeepEqual(t, names(t, doUserRequest(t, fay, "GET", "/api/v1/users/me/friends", "")), []string{"Finn"})
+		assert.DeepEqual(t, names(t, doUserRequest(t, finn, "GET", "/api/v1/users/me/friends", "")), []string{"Fay"})
+
+		// a request back to someone who already asked is accepted at once
+		rec = doUserRequest(t, fred, "POST", "/api/v1/users/me/friends/requests", fmt.Sprintf(`{"friend_code":"%s"}`, code(t, finn)))
+		assert.Equal(t, unmarshalResponse(t, rec)["status"], "requested")
+		rec = doUserRequest(t, finn, "POST", "/api/v1/users/me/friends/requests", fmt.Sprintf(`{"friend_code":"%s"}`, code(t, fred)))
+		assert.Equal(t, unmarshalResponse(t, rec)["status"], "accepted")
+		assert.DeepEqual(t, names(t, doUserRequest(t, finn, "GET", "/api/v1/users/me/friends", "")), []string{"Fay", "Fred"})
+	})
+
+	t.Run("friends ranking", func(t *testing.T) {
+		rec := doRequest(t, "GET", "/api/v1/charts/ranking?beatmap_id=songF_past&scope=friends", "")
+		assert.Equal(t, rec.Result().Status, `401 Unauthorized`)
+		rec = doRequest(t, "GET", "/api/v1/charts/ranking?scope=nobody", "")
+		assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+
+		rank := func(t *testing.T, p player) map[string]any {
+			t.Helper()
+			rec := doUserRequest(t, p, "GET", "/api/v1/charts/ranking?beatmap_id=songF_past&scope=friends", "")
+			assert.Equal(t, rec.Result().Status, `200 OK`)
+			var arr []map[string]any
+			assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &arr))
+			assert.Equal(t, len(arr), 1)
+			return arr[0]
+		}
+		r := rank(t, fay)
+		assert.Equal(t, r["player_count"], float64(2))
+		top := r["top"].([]any)
+		assert.Equal(t, top[0].(map[string]any)["player_name"], "Finn")
+		assert.Equal(t, top[1].(map[string]any)["player_name"], "Fay")
+
+		assert.Equal(t, rank(t, finn)["player_count"], float64(3))
+
+		rec = doUserRequest(t, fay, "GET", "/api/v1/charts/ranking?scope=friends", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var all []map[string]any
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &all))
+		assert.Assert(t, len(all) >= 3)
+	})
+
+	t.Run("remove friend", func(t *testing.T) {
+		rec := doUserRequest(t, fay, "DELETE", "/api/v1/users/me/friends/"+finn.id, "")
+		assert.Equal(t, rec.Result().Status, `204 No Content`)
+		assert.DeepEqual(t, names(t, doUserRequest(t, finn, "GET", "/api/v1/users/me/friends", "")), []string{"Fred"})
+		rec = doUserRequest(t, fay, "DELETE", "/api/v1/users/me/friends/"+finn.id, "")
+		assert.Equal(t, unmarshalResponse(t, rec)["code"], "FRIEND_NOT_FOUND")
+	})
+
+	t.Run("rivals", func(t *testing.T) {
+		assert.Equal(t, doUserRequest(t, fay, "PUT", "/api/v1/users/me/rivals/"+fay.id, "").Result().Status, `422 Unprocessable Entity`)
+		assert.Equal(t, doUserRequest(t, fay, "PUT", "/api/v1/users/me/rivals/00000000-0000-0000-0000-000000000000", "").Result().Status, `404 Not Found`)
+		assert.Equal(t, doUserRequest(t, fay, "PUT", "/api/v1/users/me/rivals/"+fred.id, "").Result().Status, `204 No Content`)
+		assert.Equal(t, doUserRequest(t, fay, "PUT", "/api/v1/users/me/rivals/"+fred.id, "").Result().Status, `409 Conflict`)
+		assert.Equal(t, doUserRequest(t, fay, "PUT", "/api/v1/users/me/rivals/"+finn.id, "").Result().Status, `204 No Content`)
+		assert.DeepEqual(t, names(t, doUserRequest(t, fay, "GET", "/api/v1/users/me/rivals", "")), []string{"Finn", "Fred"})
+		// following is one-way
+		assert.DeepEqual(t, names(t, doUserRequest(t, fred, "GET", "/api/v1/users/me/rivals", "")), []string{})
+
+		assert.Equal(t, doUserRequest(t, fay, "DELETE", "/api/v1/users/me/rivals/"+finn.id, "").Result().Status, `204 No Content`)
+		rec := doUserRequest(t, fay, "DELETE", "/api/v1/users/me/rivals/"+finn.id, "")
+		assert.Equal(t, unmarshalResponse(t, rec)["code"], "RIVAL_NOT_FOUND")
+	})
+
+	t.Run("versus", func(t *testing.T) {
+		rec := doRequest(t, "GET", "/api/v1/users/"+fay.id+"/versus/"+finn.id, "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res := unmarshalResponse(t, rec)
+		assert.Equal(t, res["player_name"], "Fay")
+		assert.Equal(t, res["other_name"], "Finn")
+		assert.Equal(t, res["wins"], float64(0))
+		assert.Equal(t, res["losses"], float64(2))
+		assert.Equal(t, res["draws"], float64(1))
+
+		charts := res["charts"].([]any)
+		assert.Equal(t, len(charts), 2)
+		// biggest difference first; songF_future was only played by Finn
+		first := charts[0].(map[string]any)
+		assert.Equal(t, first["beatmap_id"], "songF_future")
+		assert.Equal(t, first["score"], nil)
+		assert.Equal(t, first["other_score"], float64(800000))
+		assert.Equal(t, first["diff"], float64(-800000))
+		second := charts[1].(map[string]any)
+		assert.Equal(t, second["beatmap_id"], "songF_past")
+		assert.Equal(t, second["diff"], float64(-40000))
+
+		// a best of 0 against no play is neither a win nor a loss
+		fern := registerPlayer(t, "Fern")
+		rec = doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songF_lycoris","song_name":"Song F","difficulty":3}`)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		submitScore(t, fern.id, "songF_lycoris", 0)
+		rec = doRequest(t, "GET", "/api/v1/users/"+fay.id+"/versus/"+fern.id, "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res = unmarshalResponse(t, rec)
+		assert.Equal(t, res["wins"], float64(2))
+		assert.Equal(t, res["losses"], float64(0))
+		assert.Equal(t, res["draws"], float64(0))
+		assert.Equal(t, len(res["charts"].([]any)), 3)
+
+		rec = doRequest(t, "GET", "/api/v1/users/"+fay.id+"/versus/00000000-0000-0000-0000-000000000000", "")
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		rec = doRequest(t, "GET", "/api/v1/users/"+fay.id+"/versus/nope", "")
+		assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+	})
+}
@@ -0,0 +1,28 @@
+package integrationtests
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestHealth(t *testing.T) {
+	t.Run("liveness", func(t *testing.T) {
+		t.Parallel()
+		rec := doRequest(t, "GET", "/livez", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.DeepEqual(t, unmarshalResponse(t, rec), map[string]any{"status": "ok"})
+	})
+
+	t.Run("readiness", func(t *testing.T) {
+		t.Parallel()
+		rec := doRequest(t, "GET", "/readyz", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res := unmarshalResponse(t, rec)
+		assert.Equal(t, res["status"], "ok")
+		checks := res["checks"].([]any)
+		assert.Equal(t, checks[0].(map[string]any)["name"], "database")
+		assert.Equal(t, checks[1].(map[string]any)["name"], "migrations")
+		assert.Equal(t, checks[1].(map[string]any)["status"], "ok")
+	})
+}
@@ -2,6 +2,8 @@ package integrationtests
 
 import (
 	"encoding/json"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"regexp"
 	"strings"
@@ -26,13 +28,77 @@ func escapeSnapshot(t *testing.T, s string) string {
 	return s
 }
 
-func doRequest(t *testing.T, method, path string, bodystr string) *httptest.ResponseRecorder {
+// player is a registered user together with the token they authenticate with.
+type player struct{ id, token string }
+
+// scoreOptions sets the judgement counts and the input device of a submitted score.
+type scoreOptions struct{ good, miss, input int }
+
+// serveRequest sends a JSON request to h, authenticated with token unless it is empty.
+func serveRequest(t *testing.T, h http.Handler, method, path, token, bodystr string) *httptest.ResponseRecorder {
 	t.Helper()
 
 	req := httptest.NewRequest(method, path, strings.NewReader(bodystr))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
 	rec := httptest.NewRecorder()
-	e.ServeHTTP(rec, req)
+	h.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func doRequest(t *testing.T, method, path string, bodystr string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serveRequest(t, e, method, path, "", bodystr)
+}
+
+func doAdminRequest(t *testing.T, method, path string, bodystr string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serveRequest(t, e, method, path, adminToken, bodystr)
+}
+
+// doUserRequest sends a request as p; the zero player sends it anonymously.
+func doUserRequest(t *testing.T, p player, method, path string, bodystr string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return serveRequest(t, e, method, path, p.token, bodystr)
+}
+
+// registerPlayer registers a new user and renames them to name unless it is empty.
+func registerPlayer(t *testing.T, name string) player {
+	t.Helper()
+
+	rec := doRequest(t, "POST", "/api/v1/users", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	res := unmarshalResponse(t, rec)
+	p := player{id: res["id"].(string), token: res["token"].(string)}
+	if name != "" {
+		rec = doRequest(t, "POST", "/api/v1/users/update", fmt.Sprintf(`{"user_id":"%s","user_name":"%s"}`, p.id, name))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+
+	return p
+}
+
+// scoreBody is the body of a score submission with 20 critical perfects and the judgements in o.
+func scoreBody(userID, beatmapID string, score int, o scoreOptions) string {
+	return fmt.Sprintf(`{"user_id":"%s","beatmap_id":"%s","score":%d,"max_combo":100,"perfect_critical_fast":10,"perfect_critical_late":10,"perfect_fast":0,"perfect_late":0,"good_fast":%d,"good_late":0,"miss":%d,"input":%d}`, userID, beatmapID, score, o.good, o.miss, o.input)
+}
+
+// submitScore submits a score and asserts that it was accepted.
+func submitScore(t *testing.T, userID, beatmapID string, score int, opts ...scoreOptions) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var o scoreOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	rec := doRequest(t, "POST", "/api/v1/scores", scoreBody(userID, beatmapID, score, o))
+	assert.Equal(t, rec.Result().Status, `200 OK`, rec.Body.String())
 
 	return rec
 }
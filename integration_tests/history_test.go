@@ -0,0 +1,132 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestUserHistory(t *testing.T) {
+	rec := doRequest(t, "POST", "/api/v1/users", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	uid := unmarshalResponse(t, rec)["id"].(string)
+
+	rec = doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songH_present","song_name":"Song H","difficulty":1}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	rec = doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songH_future","song_name":"Song H","difficulty":2}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+
+	plays := []struct {
+		beatmapID string
+		score     int
+		input     int
+	}{
+		{"songH_present", 910000, 0},
+		{"songH_present", 950000, 1},
+		{"songH_future", 880000, 0},
+		{"songH_future", 870000, 1},
+	}
+	for _, p := range plays {
+		body := fmt.Sprintf(`{"user_id":"%s","beatmap_id":"%s","score":%d,"max_combo":100,"perfect_critical_fast":10,"perfect_critical_late":10,"perfect_fast":0,"perfect_late":0,"good_fast":1,"good_late":1,"miss":1,"input":%d}`, uid, p.beatmapID, p.score, p.input)
+		rec = doRequest(t, "POST", "/api/v1/scores", body)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+
+	type history struct {
+		Items []struct {
+			BeatmapID string `json:"beatmap_id"`
+			Score     int    `json:"score"`
+		} `json:"items"`
+		Total int `json:"total"`
+	}
+
+	t.Run("latest first", func(t *testing.T) {
+		rec := doRequest(t, "GET", "/api/v1/users/"+uid+"/scores?limit=3", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var h history
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &h))
+		assert.Equal(t, h.Total, 4)
+		assert.Equal(t, len(h.Items), 3)
+		assert.Equal(t, h.Items[0].Score, 870000)
+	})
+
+	t.Run("filter and sort by score", func(t *testing.T) {
+		rec := doRequest(t, "GET", "/api/v1/users/"+uid+"/scores?difficulty=2&sort=score&order=asc", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var h history
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &h))
+		assert.Equal(t, h.Total, 2)
+		assert.Equal(t, h.Items[0].Score, 870000)
+		assert.Equal(t, h.Items[1].Score, 880000)
+
+		rec = doRequest(t, "GET", "/api/v1/users/"+uid+"/scores?input=1", "")
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &h))
+		assert.Equal(t, h.Total, 2)
+	})
+
+	t.Run("invalid filters", func(t *testing.T) {
+		rec := doRequest(t, "GET", "/api/v1/users/"+uid+"/scores?difficulty=9", "")
+		assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+		rec = doRequest(t, "GET", "/api/v1/users/"+uid+"/scores?from=yesterday", "")
+		assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+	})
+
+	t.Run("bests", func(t *testing.T) {
+		rec := doRequest(t, "GET", "/api/v1/users/"+uid+"/bests", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var bests []struct {
+			BeatmapID string `json:"beatmap_id"`
+			Score     int    `json:"score"`
+		}
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &bests))
+		assert.Equal(t, len(bests), 2)
+		got := map[string]int{}
+		for _, b := range bests {
+			got[b.BeatmapID] = b.Score
+		}
+		assert.DeepEqual(t, got, map[string]int{"songH_present": 950000, "songH_future": 880000})
+	})
+}
+
+func TestUserChartAnalytics(t *testing.T) {
+	rec := doRequest(t, "POST", "/api/v1/users", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	uid := unmarshalResponse(t, rec)["id"].(string)
+
+	rec = doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songA_future","song_name":"Song A","difficulty":2}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+
+	for i := 0; i < 3; i++ {
+		body := fmt.Sprintf(`{"user_id":"%s","beatmap_id":"songA_future","score":%d,"max_combo":100,"perfect_critical_fast":10,"perfect_critical_late":30,"perfect_fast":5,"perfect_late":15,"good_fast":1,"good_late":4,"miss":%d,"input":0}`, uid, 900000+i*10000, 3-i)
+		rec = doRequest(t, "POST", "/api/v1/scores", body)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+
+	rec = doRequest(t, "GET", "/api/v1/users/"+uid+"/charts/songA_future/analytics?last=2", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	var a struct {
+		TotalPlays          int     `json:"total_plays"`
+		SampleSize          int     `json:"sample_size"`
+		FastCount           int     `json:"fast_count"`
+		LateCount           int     `json:"late_count"`
+		RecommendedOffsetMs *int    `json:"recommended_offset_ms"`
+		ScoreTrend          float64 `json:"score_trend_per_play"`
+		Trend               []struct {
+			Score int `json:"score"`
+		} `json:"trend"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &a))
+	assert.Equal(t, a.TotalPlays, 3)
+	assert.Equal(t, a.SampleSize, 2)
+	assert.Equal(t, a.FastCount, 32)
+	assert.Equal(t, a.LateCount, 98)
+	assert.Assert(t, a.RecommendedOffsetMs != nil && *a.RecommendedOffsetMs > 0)
+	assert.Equal(t, a.ScoreTrend, 10000.0)
+	assert.Equal(t, len(a.Trend), 2)
+	assert.Equal(t, a.Trend[0].Score, 910000)
+
+	rec = doRequest(t, "GET", "/api/v1/users/"+uid+"/charts/no_such_chart/analytics", "")
+	assert.Equal(t, rec.Result().Status, `404 Not Found`)
+}
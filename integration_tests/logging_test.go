@@ -0,0 +1,27 @@
+package integrationtests
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"gotest.tools/v3/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	t.Run("generated", func(t *testing.T) {
+		t.Parallel()
+		rec := doRequest(t, "GET", "/api/v1/ping", "")
+		assert.Equal(t, rec.Code, 200)
+		assert.Assert(t, rec.Header().Get(echo.HeaderXRequestID) != "")
+	})
+
+	t.Run("propagated from the client", func(t *testing.T) {
+		t.Parallel()
+		req := httptest.NewRequest("GET", "/api/v1/ping", nil)
+		req.Header.Set(echo.HeaderXRequestID, "client-request-1")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), "client-request-1")
+	})
+}
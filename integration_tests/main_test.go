@@ -1,7 +1,11 @@
 package integrationtests
 
 import (
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/pikachu0310/senirenol-server/core"
 	"github.com/pikachu0310/senirenol-server/core/database"
@@ -12,20 +16,84 @@ import (
 	"github.com/ory/dockertest/v3"
 )
 
-var e *echo.Echo
+var (
+	e *echo.Echo
+	// db and config are shared with tests that build their own server
+	db     *sqlx.DB
+	config core.Config
+)
+
+const (
+	adminToken   = "test-admin-token"
+	metricsToken = "test-metrics-token"
+)
 
+// TestMain runs the suite against SQLite by default. Set TEST_DB_DRIVER=mysql
+// or TEST_DB_DRIVER=postgres to run it against a container instead (requires Docker).
 func TestMain(m *testing.M) {
-	config := core.Config{
+	config = core.Config{
 		DBUser: "root",
 		DBPass: "pass",
 		DBHost: "localhost",
 		DBPort: 3306,
 		DBName: "app",
+
+		CacheTTL:      time.Minute,
+		CacheMaxBytes: 1 << 20,
+
+		MissionRegions: "jp=Asia/Tokyo",
+
+		AdminToken:   adminToken,
+		MetricsToken: metricsToken,
 	}
 
 	e = echo.New()
 	e.Logger.SetLevel(log.INFO)
 
+	var cleanup func()
+	switch driver := os.Getenv("TEST_DB_DRIVER"); driver {
+	case "", core.DBDriverSQLite:
+		db, cleanup = setupSQLite()
+	case core.DBDriverMySQL:
+		db, cleanup = setupMySQL(config)
+	case core.DBDriverPostgres:
+		db, cleanup = setupPostgres(config)
+	default:
+		e.Logger.Fatalf("unsupported TEST_DB_DRIVER: %s", driver)
+	}
+
+	s, err := core.InjectDeps(db, config)
+	if err != nil {
+		e.Logger.Fatalf("inject deps: %v", err)
+	}
+
+	core.SetupRoutes(s.Handler, e)
+
+	e.Logger.Info("start integration test")
+	code := m.Run()
+
+	cleanup()
+	os.Exit(code)
+}
+
+func setupSQLite() (*sqlx.DB, func()) {
+	dir, err := os.MkdirTemp("", "senirenol-test-")
+	if err != nil {
+		e.Logger.Fatalf("create temp dir: %v", err)
+	}
+
+	db, err := database.SetupSQLite(filepath.Join(dir, "app.db"))
+	if err != nil {
+		e.Logger.Fatalf("setup sqlite: %v", err)
+	}
+
+	return db, func() {
+		_ = db.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func setupMySQL(config core.Config) (*sqlx.DB, func()) {
 	pool, err := dockertest.NewPool("")
 	if err != nil {
 		e.Logger.Fatalf("connect to docker: %v", err)
@@ -63,14 +131,61 @@ func TestMain(m *testing.M) {
 		e.Logger.Fatalf("connect to database container: %v", err)
 	}
 
-	s := core.InjectDeps(db)
+	return db, func() {
+		_ = db.Close()
+		if err := pool.Purge(resource); err != nil {
+			e.Logger.Fatalf("purge docker: %v", err)
+		}
+	}
+}
 
-	core.SetupRoutes(s.Handler, e)
+func setupPostgres(config core.Config) (*sqlx.DB, func()) {
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		e.Logger.Fatalf("connect to docker: %v", err)
+	}
 
-	e.Logger.Info("start integration test")
-	m.Run()
+	if err := pool.Client.Ping(); err != nil {
+		e.Logger.Fatalf("ping docker: %v", err)
+	}
+
+	config.DBUser = "postgres"
+	config.DBSSLMode = "disable"
+
+	resource, err := pool.Run("postgres", "17", []string{
+		"POSTGRES_PASSWORD=" + config.DBPass,
+		"POSTGRES_DB=" + config.DBName,
+	})
+	if err != nil {
+		e.Logger.Fatalf("run docker: %v", err)
+	}
 
-	if err := pool.Purge(resource); err != nil {
-		e.Logger.Fatalf("purge docker: %v", err)
+	port, err := strconv.Atoi(resource.GetPort("5432/tcp"))
+	if err != nil {
+		e.Logger.Fatalf("parse port: %v", err)
+	}
+	config.DBPort = port
+
+	e.Logger.Info("wait for database container")
+
+	var db *sqlx.DB
+	if err := pool.Retry(func() error {
+		_db, err := database.SetupPostgres(config.PostgresDSN())
+		if err != nil {
+			return err
+		}
+
+		db = _db
+
+		return nil
+	}); err != nil {
+		e.Logger.Fatalf("connect to database container: %v", err)
+	}
+
+	return db, func() {
+		_ = db.Close()
+		if err := pool.Purge(resource); err != nil {
+			e.Logger.Fatalf("purge docker: %v", err)
+		}
 	}
 }
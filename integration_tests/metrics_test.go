@@ -0,0 +1,50 @@
+package integrationtests
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"gotest.tools/v3/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	scrape := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, scrape("").Result().Status, `401 Unauthorized`)
+	assert.Equal(t, scrape("wrong").Result().Status, `401 Unauthorized`)
+
+	rec := doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songP_lycoris","song_name":"Song P","difficulty":3}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	rec = doRequest(t, "POST", "/api/v1/users", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	uid := unmarshalResponse(t, rec)["id"].(string)
+	body := `{"user_id":"` + uid + `","beatmap_id":"songP_lycoris","score":900000,"max_combo":100,"perfect_critical_fast":10,"perfect_critical_late":10,"perfect_fast":0,"perfect_late":0,"good_fast":0,"good_late":0,"miss":0,"input":1}`
+	rec = doRequest(t, "POST", "/api/v1/scores", body)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	rec = doRequest(t, "POST", "/api/v1/scores", `{}`)
+	assert.Equal(t, rec.Result().Status, `422 Unprocessable Entity`)
+
+	rec = scrape(metricsToken)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	out := rec.Body.String()
+	for _, want := range []string{
+		`senirenol_scores_submitted_total{beatmap_id="songP_lycoris",difficulty="lycoris",input="button"} 1`,
+		`senirenol_users_registered_total `,
+		`senirenol_validation_rejections_total{route="/api/v1/scores"} `,
+		`senirenol_http_request_duration_seconds_bucket{method="POST",route="/api/v1/scores",status="200",le="+Inf"} `,
+		`senirenol_cache_hits_total{cache="leaderboard"} `,
+		`go_sql_open_connections{db_name=`,
+	} {
+		assert.Assert(t, strings.Contains(out, want), "missing %q", want)
+	}
+}
@@ -0,0 +1,165 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pikachu0310/senirenol-server/core"
+
+	"github.com/labstack/echo/v4"
+	"gotest.tools/v3/assert"
+)
+
+func TestMissions(t *testing.T) {
+	// 抽選の結果が決まるよう、各期間にテンプレートが1つだけのサーバーを別に立てる
+	file := filepath.Join(t.TempDir(), "missions.yaml")
+	assert.NilError(t, os.WriteFile(file, []byte(`
+daily: 1
+weekly: 1
+templates:
+  - {id: present_2, period: daily, name: Present, reward: 10, condition: {count: 2, difficulty: present}}
+  - {id: play_3, period: weekly, name: Play, reward: 30, condition: {count: 3}}
+`), 0o644))
+	cfg := config
+	cfg.MissionsFile = file
+	cfg.MissionRegions = "jp=Asia/Tokyo,global=UTC"
+	s, err := core.InjectDeps(db, cfg)
+	assert.NilError(t, err)
+	se := echo.New()
+	core.SetupRoutes(s.Handler, se)
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		return serveRequest(t, se, method, path, token, body)
+	}
+
+	p := registerPlayer(t, "")
+	for d, name := range []string{"past", "present"} {
+		rec := doRequest(t, "POST", "/api/v1/charts", fmt.Sprintf(`{"beatmap_id":"songN_%s","song_name":"Song N","difficulty":%d}`, name, d))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+	// 進捗はスコアから数えるので、どのサーバーに送信してもよい
+	submit := func(t *testing.T, beatmapID string) {
+		t.Helper()
+		submitScore(t, p.id, beatmapID, 950000)
+	}
+
+	type mission struct {
+		ID        string  `json:"id"`
+		Period    string  `json:"period"`
+		Goal      int     `json:"goal"`
+		Reward    int     `json:"reward"`
+		StartsAt  string  `json:"starts_at"`
+		EndsAt    string  `json:"ends_at"`
+		Progress  int     `json:"progress"`
+		ClaimedAt *string `json:"claimed_at"`
+	}
+	type missions struct {
+		Region   string    `json:"region"`
+		Points   int       `json:"points"`
+		Missions []mission `json:"missions"`
+	}
+	mine := func(t *testing.T, region string) missions {
+		t.Helper()
+		rec := do("GET", "/api/v1/users/me/missions?region="+region, p.token, "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var res missions
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res
+	}
+	progress := func(ms missions) []int {
+		out := make([]int, len(ms.Missions))
+		for i, m := range ms.Missions {
+			out[i] = m.Progress
+		}
+		return out
+	}
+
+	t.Run("list", func(t *testing.T) {
+		rec := do("GET", "/api/v1/missions", "", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var res missions
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, res.Region, "jp")
+		assert.Equal(t, len(res.Missions), 2)
+		assert.Equal(t, res.Missions[0].ID, "present_2")
+		assert.Equal(t, res.Missions[0].Period, "daily")
+		assert.Equal(t, res.Missions[0].Goal, 2)
+		assert.Equal(t, res.Missions[1].ID, "play_3")
+		assert.Equal(t, res.Missions[1].Reward, 30)
+		assert.Assert(t, res.Missions[0].StartsAt < res.Missions[0].EndsAt)
+
+		rec = do("GET", "/api/v1/missions?region=mars", "", "")
+		assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+	})
+
+	t.Run("progress and claim", func(t *testing.T) {
+		rec := do("GET", "/api/v1/users/me/missions", "", "")
+		assert.Equal(t, rec.Result().Status, `401 Unauthorized`)
+
+		submit(t, "songN_present")
+		submit(t, "songN_past")
+		assert.DeepEqual(t, progress(mine(t, "")), []int{1, 2})
+		assert.DeepEqual(t, progress(mine(t, "global")), []int{1, 2})
+
+		rec = do("POST", "/api/v1/users/me/missions/present_2/claim", p.token, "")
+		assert.Equal(t, rec.Result().Status, `409 Conflict`)
+		assert.Equal(t, unmarshalResponse(t, rec)["code"], "MISSION_INCOMPLETE")
+		rec = do("POST", "/api/v1/users/me/missions/nope/claim", p.token, "")
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		assert.Equal(t, unmarshalResponse(t, rec)["code"], "MISSION_NOT_FOUND")
+
+		submit(t, "songN_present")
+		submit(t, "songN_present")
+		// progress stops at the goal
+		assert.DeepEqual(t, progress(mine(t, "")), []int{2, 3})
+
+		rec = do("POST", "/api/v1/users/me/missions/present_2/claim", p.token, "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res := unmarshalResponse(t, rec)
+		assert.Equal(t, res["reward"], float64(10))
+		assert.Equal(t, res["points"], float64(10))
+		rec = do("POST", "/api/v1/users/me/missions/present_2/claim", p.token, "")
+		assert.Equal(t, rec.Result().Status, `409 Conflict`)
+		assert.Equal(t, unmarshalResponse(t, rec)["code"], "CONFLICT")
+
+		// each region is claimed separately
+		rec = do("POST", "/api/v1/users/me/missions/play_3/claim?region=global", p.token, "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, unmarshalResponse(t, rec)["points"], float64(40))
+
+		ms := mine(t, "")
+		assert.Equal(t, ms.Points, 40)
+		assert.Assert(t, ms.Missions[0].ClaimedAt != nil)
+		assert.Assert(t, ms.Missions[1].ClaimedAt == nil)
+	})
+
+	t.Run("personal data", func(t *testing.T) {
+		rec := doUserRequest(t, p, "GET", "/api/v1/users/me/export", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var res struct {
+			Missions []struct {
+				Region    string  `json:"region"`
+				MissionID string  `json:"mission_id"`
+				Progress  int     `json:"progress"`
+				Reward    int     `json:"reward"`
+				ClaimedAt *string `json:"claimed_at"`
+			} `json:"missions"`
+			MissionPoints int `json:"mission_points"`
+		}
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, res.MissionPoints, 40)
+		got := map[string][2]int{}
+		for _, m := range res.Missions {
+			assert.Equal(t, m.ClaimedAt != nil, m.Reward > 0)
+			got[m.Region+"/"+m.MissionID] = [2]int{m.Progress, m.Reward}
+		}
+		// only claims are stored; the rest of the progress is the scores
+		assert.DeepEqual(t, got, map[string][2]int{
+			"jp/present_2":  {3, 10},
+			"global/play_3": {4, 30},
+		})
+	})
+}
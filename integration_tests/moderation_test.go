@@ -0,0 +1,92 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestScoreModeration(t *testing.T) {
+	rec := doRequest(t, "POST", "/api/v1/users", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	uid := unmarshalResponse(t, rec)["id"].(string)
+
+	rec = doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songMod_present","song_name":"Song Mod","difficulty":1}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+
+	submit := func(score int) int64 {
+		body := fmt.Sprintf(`{"user_id":"%s","beatmap_id":"songMod_present","score":%d,"max_combo":100,"perfect_critical_fast":10,"perfect_critical_late":10,"perfect_fast":0,"perfect_late":0,"good_fast":0,"good_late":0,"miss":0,"input":0}`, uid, score)
+		rec := doRequest(t, "POST", "/api/v1/scores", body)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		return int64(unmarshalResponse(t, rec)["id"].(float64))
+	}
+	bestScore := func() int {
+		rec := doRequest(t, "GET", "/api/v1/charts/ranking?beatmap_id=songMod_present", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var arr []map[string]any
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &arr))
+		top, _ := arr[0]["top"].([]any)
+		if len(top) == 0 {
+			return 0
+		}
+		return int(top[0].(map[string]any)["score"].(float64))
+	}
+
+	low := submit(900000)
+	high := submit(999000)
+	assert.Equal(t, bestScore(), 999000)
+
+	t.Run("admin token is required", func(t *testing.T) {
+		rec := doRequest(t, "DELETE", fmt.Sprintf("/api/v1/admin/scores/%d", high), "")
+		assert.Equal(t, rec.Result().Status, `401 Unauthorized`)
+	})
+
+	t.Run("unknown score", func(t *testing.T) {
+		rec := doAdminRequest(t, "DELETE", "/api/v1/admin/scores/999999999", "")
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+	})
+
+	t.Run("invalidate and restore", func(t *testing.T) {
+		rec := doAdminRequest(t, "POST", fmt.Sprintf("/api/v1/admin/scores/%d/invalidate", high), `{"reason":"cheated"}`)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, int(unmarshalResponse(t, rec)["affected"].(float64)), 1)
+		assert.Equal(t, bestScore(), 900000)
+
+		rec = doAdminRequest(t, "POST", fmt.Sprintf("/api/v1/admin/scores/%d/restore", high), "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, bestScore(), 999000)
+	})
+
+	t.Run("delete by time range", func(t *testing.T) {
+		body := fmt.Sprintf(`{"action":"delete","user_id":"%s","beatmap_id":"songMod_present","from":"%s","to":"%s"}`,
+			uid,
+			time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+			time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		)
+		rec := doAdminRequest(t, "POST", "/api/v1/admin/scores/moderate", body)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, int(unmarshalResponse(t, rec)["affected"].(float64)), 2)
+		assert.Equal(t, bestScore(), 0)
+
+		rec = doAdminRequest(t, "POST", fmt.Sprintf("/api/v1/admin/scores/%d/restore", low), "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, bestScore(), 900000)
+	})
+
+	t.Run("audit logs", func(t *testing.T) {
+		rec := doAdminRequest(t, "GET", "/api/v1/admin/audit-logs?limit=1", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var logs []map[string]any
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &logs))
+		assert.Equal(t, len(logs), 1)
+		assert.Equal(t, logs[0]["action"].(string), "scores.restore")
+
+		for _, limit := range []string{"abc", "-1", "0"} {
+			rec = doAdminRequest(t, "GET", "/api/v1/admin/audit-logs?limit="+limit, "")
+			assert.Equal(t, rec.Result().Status, `400 Bad Request`, limit)
+		}
+	})
+}
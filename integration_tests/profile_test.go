@@ -0,0 +1,208 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestProfile(t *testing.T) {
+	type profile struct {
+		ID    string `json:"id"`
+		Title *struct {
+			ID string `json:"id"`
+		} `json:"title"`
+		Visibility string `json:"visibility"`
+		Details    *struct {
+			FavoriteChart *struct {
+				BeatmapID string `json:"beatmap_id"`
+				SongName  string `json:"song_name"`
+			} `json:"favorite_chart"`
+			Showcase []struct {
+				BeatmapID string `json:"beatmap_id"`
+				Score     int    `json:"score"`
+			} `json:"showcase"`
+			Rating         float64 `json:"rating"`
+			TotalPlays     int     `json:"total_plays"`
+			FirstPlayedAt  *string `json:"first_played_at"`
+			LastPlayedAt   *string `json:"last_played_at"`
+			PreferredInput *uint8  `json:"preferred_input"`
+		} `json:"details"`
+	}
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder) profile {
+		t.Helper()
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var p profile
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+		return p
+	}
+	showcase := func(p profile) []string {
+		out := make([]string, len(p.Details.Showcase))
+		for i, s := range p.Details.Showcase {
+			out[i] = s.BeatmapID
+		}
+		return out
+	}
+
+	owner, viewer := registerPlayer(t, ""), registerPlayer(t, "")
+	for d, name := range []string{"past", "present", "future", "lycoris"} {
+		rec := doRequest(t, "POST", "/api/v1/charts", fmt.Sprintf(`{"beatmap_id":"songQ_%s","song_name":"Song Q","difficulty":%d}`, name, d))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+
+	t.Run("new player", func(t *testing.T) {
+		p := decode(t, doUserRequest(t, player{}, "GET", "/api/v1/users/"+owner.id+"/profile", ""))
+		assert.Equal(t, p.Visibility, "public")
+		assert.Assert(t, p.Title == nil)
+		assert.Equal(t, p.Details.TotalPlays, 0)
+		assert.Assert(t, p.Details.FirstPlayedAt == nil)
+		assert.Assert(t, p.Details.PreferredInput == nil)
+		assert.DeepEqual(t, showcase(p), []string{})
+	})
+
+	for _, play := range []struct {
+		beatmapID    string
+		score, input int
+	}{
+		{"songQ_past", 990000, 1},
+		{"songQ_present", 950000, 1},
+		{"songQ_future", 900000, 0},
+		{"songQ_lycoris", 800000, 1},
+		{"songQ_future", 970000, 1},
+	} {
+		submitScore(t, owner.id, play.beatmapID, play.score, scoreOptions{input: play.input})
+	}
+
+	t.Run("stats", func(t *testing.T) {
+		p := decode(t, doUserRequest(t, player{}, "GET", "/api/v1/users/"+owner.id+"/profile", ""))
+		assert.Equal(t, p.Details.TotalPlays, 5)
+		assert.Assert(t, p.Details.FirstPlayedAt != nil && p.Details.LastPlayedAt != nil)
+		assert.Equal(t, *p.Details.PreferredInput, uint8(1))
+		assert.Assert(t, p.Details.Rating > 0)
+		// the best rated plays by default
+		assert.Equal(t, len(p.Details.Showcase), 3)
+		assert.Equal(t, p.Details.Showcase[0].BeatmapID, "songQ_future")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		rec := doUserRequest(t, owner, "PATCH", "/api/v1/users/me/profile", `{"title":"first_play","favorite_beatmap_id":"songQ_future","showcase":["songQ_future","songQ_past"],"visibility":"friends"}`)
+		p := decode(t, rec)
+		assert.Equal(t, p.Title.ID, "first_play")
+		assert.Equal(t, p.Visibility, "friends")
+		assert.Equal(t, p.Details.FavoriteChart.SongName, "Song Q")
+		assert.DeepEqual(t, showcase(p), []string{"songQ_future", "songQ_past"})
+		assert.Equal(t, p.Details.Showcase[0].Score, 970000)
+
+		// fields left out are kept
+		p = decode(t, doUserRequest(t, owner, "PATCH", "/api/v1/users/me/profile", `{"favorite_beatmap_id":""}`))
+		assert.Equal(t, p.Title.ID, "first_play")
+		assert.Assert(t, p.Details.FavoriteChart == nil)
+		assert.DeepEqual(t, showcase(p), []string{"songQ_future", "songQ_past"})
+
+		rec = doRequest(t, "GET", "/api/v1/users/"+owner.id, "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, unmarshalResponse(t, rec)["title"].(map[string]any)["id"], "first_play")
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		rec := doUserRequest(t, owner, "PATCH", "/api/v1/users/me/profile", `{"showcase":["a","b","c","d"],"visibility":"secret"}`)
+		assert.Equal(t, rec.Result().Status, `422 Unprocessable Entity`)
+		assert.DeepEqual(t, unmarshalResponse(t, rec)["details"], []any{
+			map[string]any{"field": "showcase", "message": "the length must be no more than 3"},
+			map[string]any{"field": "visibility", "message": "must be a valid value"},
+		})
+
+		rec = doUserRequest(t, owner, "PATCH", "/api/v1/users/me/profile", `{"title":"plays_1000","favorite_beatmap_id":"nope","showcase":["songQ_past","songQ_past"]}`)
+		assert.Equal(t, rec.Result().Status, `422 Unprocessable Entity`)
+		assert.DeepEqual(t, unmarshalResponse(t, rec)["details"], []any{
+			map[string]any{"field": "favorite_beatmap_id", "message": "must be a registered chart"},
+			map[string]any{"field": "showcase", "message": "must not list a chart twice"},
+			map[string]any{"field": "title", "message": "must be an unlocked achievement"},
+		})
+		// the player has not played charts of others
+		rec = doUserRequest(t, viewer, "PATCH", "/api/v1/users/me/profile", `{"showcase":["songQ_past"]}`)
+		assert.Equal(t, rec.Result().Status, `422 Unprocessable Entity`)
+
+		rec = doUserRequest(t, player{}, "PATCH", "/api/v1/users/me/profile", `{}`)
+		assert.Equal(t, rec.Result().Status, `401 Unauthorized`)
+	})
+
+	t.Run("privacy", func(t *testing.T) {
+		path := "/api/v1/users/" + owner.id + "/profile"
+		// name and title stay public
+		p := decode(t, doUserRequest(t, player{}, "GET", path, ""))
+		assert.Equal(t, p.Title.ID, "first_play")
+		assert.Assert(t, p.Details == nil)
+		assert.Assert(t, decode(t, doUserRequest(t, viewer, "GET", path, "")).Details == nil)
+		assert.Assert(t, decode(t, doUserRequest(t, owner, "GET", path, "")).Details != nil)
+
+		// become friends
+		rec := doUserRequest(t, owner, "GET", "/api/v1/users/me/friend-code", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		code := unmarshalResponse(t, rec)["friend_code"].(string)
+		rec = doUserRequest(t, viewer, "POST", "/api/v1/users/me/friends/requests", fmt.Sprintf(`{"friend_code":"%s"}`, code))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		rec = doUserRequest(t, owner, "POST", "/api/v1/users/me/friends/requests/"+viewer.id+"/accept", "")
+		assert.Equal(t, rec.Result().Status, `204 No Content`)
+		assert.Assert(t, decode(t, doUserRequest(t, viewer, "GET", path, "")).Details != nil)
+
+		decode(t, doUserRequest(t, owner, "PATCH", "/api/v1/users/me/profile", `{"visibility":"private"}`))
+		assert.Assert(t, decode(t, doUserRequest(t, viewer, "GET", path, "")).Details == nil)
+		assert.Assert(t, decode(t, doUserRequest(t, owner, "GET", "/api/v1/users/me/profile", "")).Details != nil)
+
+		rec = doUserRequest(t, player{token: "nope"}, "GET", path, "")
+		assert.Equal(t, rec.Result().Status, `401 Unauthorized`)
+		rec = doUserRequest(t, player{}, "GET", "/api/v1/users/00000000-0000-0000-0000-000000000000/profile", "")
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+	})
+
+	t.Run("personal data", func(t *testing.T) {
+		rec := doUserRequest(t, owner, "GET", "/api/v1/users/me/export", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		p := unmarshalResponse(t, rec)["profile"].(map[string]any)
+		assert.Equal(t, p["visibility"], "private")
+		assert.DeepEqual(t, p["showcase"], []any{"songQ_future", "songQ_past"})
+	})
+
+	t.Run("play data follows the visibility", func(t *testing.T) {
+		paths := []string{
+			"/api/v1/users/" + owner.id + "/stats",
+			"/api/v1/users/" + owner.id + "/scores",
+			"/api/v1/users/" + owner.id + "/bests",
+			"/api/v1/users/" + owner.id + "/charts/songQ_past/analytics",
+			"/api/v1/users/" + owner.id + "/versus/" + viewer.id,
+			"/api/v1/users/" + viewer.id + "/versus/" + owner.id,
+			"/api/v1/scores/recent?user_id=" + owner.id,
+		}
+		status := func(p player, path string) string {
+			rec := doUserRequest(t, p, "GET", path, "")
+			if rec.Code == http.StatusForbidden {
+				assert.Equal(t, unmarshalResponse(t, rec)["code"], "PROFILE_PRIVATE")
+			}
+			return rec.Result().Status
+		}
+
+		// private: only the owner
+		for _, path := range paths {
+			assert.Equal(t, status(player{}, path), `403 Forbidden`, path)
+			assert.Equal(t, status(viewer, path), `403 Forbidden`, path)
+			assert.Equal(t, status(owner, path), `200 OK`, path)
+		}
+		assert.Equal(t, status(player{token: "nope"}, paths[0]), `401 Unauthorized`)
+
+		decode(t, doUserRequest(t, owner, "PATCH", "/api/v1/users/me/profile", `{"visibility":"friends"}`))
+		for _, path := range paths {
+			assert.Equal(t, status(player{}, path), `403 Forbidden`, path)
+			assert.Equal(t, status(viewer, path), `200 OK`, path)
+		}
+
+		decode(t, doUserRequest(t, owner, "PATCH", "/api/v1/users/me/profile", `{"visibility":"public"}`))
+		for _, path := range paths {
+			assert.Equal(t, status(player{}, path), `200 OK`, path)
+		}
+	})
+}
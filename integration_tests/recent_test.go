@@ -0,0 +1,64 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRecentPlays(t *testing.T) {
+	rec := doRequest(t, "POST", "/api/v1/users", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	uid := unmarshalResponse(t, rec)["id"].(string)
+	rec = doRequest(t, "POST", "/api/v1/users/update", fmt.Sprintf(`{"user_id":"%s","user_name":"Carol"}`, uid))
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+
+	rec = doRequest(t, "POST", "/api/v1/charts", `{"beatmap_id":"songR_past","song_name":"Song R","difficulty":0}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+
+	for i, miss := range []int{0, 3, 0} {
+		body := fmt.Sprintf(`{"user_id":"%s","beatmap_id":"songR_past","score":%d,"max_combo":100,"perfect_critical_fast":50,"perfect_critical_late":40,"perfect_fast":5,"perfect_late":5,"good_fast":%d,"good_late":0,"miss":%d,"input":1}`, uid, 960000+i, i, miss)
+		rec = doRequest(t, "POST", "/api/v1/scores", body)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+
+	type page struct {
+		Items []struct {
+			PlayerName string `json:"player_name"`
+			SongName   string `json:"song_name"`
+			Score      int    `json:"score"`
+			Grade      string `json:"grade"`
+			Lamp       string `json:"lamp"`
+		} `json:"items"`
+		NextBeforeID *int64 `json:"next_before_id"`
+	}
+
+	rec = doRequest(t, "GET", "/api/v1/scores/recent?user_id="+uid+"&limit=2", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	var p1 page
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &p1))
+	assert.Equal(t, len(p1.Items), 2)
+	assert.Equal(t, p1.Items[0].Score, 960002)
+	assert.Equal(t, p1.Items[0].PlayerName, "Carol")
+	assert.Equal(t, p1.Items[0].SongName, "Song R")
+	assert.Equal(t, p1.Items[0].Grade, "S")
+	assert.Equal(t, p1.Items[0].Lamp, "FULL_COMBO")
+	assert.Equal(t, p1.Items[1].Lamp, "CLEAR")
+	assert.Assert(t, p1.NextBeforeID != nil)
+
+	rec = doRequest(t, "GET", fmt.Sprintf("/api/v1/scores/recent?user_id=%s&limit=2&before_id=%d", uid, *p1.NextBeforeID), "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	var p2 page
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &p2))
+	assert.Equal(t, len(p2.Items), 1)
+	assert.Equal(t, p2.Items[0].Lamp, "ALL_PERFECT")
+	assert.Assert(t, p2.NextBeforeID == nil)
+
+	rec = doRequest(t, "GET", "/api/v1/scores/recent?song_name=Song%20R", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+
+	rec = doRequest(t, "GET", "/api/v1/scores/recent?user_id=nope", "")
+	assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+}
@@ -0,0 +1,117 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pikachu0310/senirenol-server/core"
+
+	"github.com/labstack/echo/v4"
+	"gotest.tools/v3/assert"
+)
+
+func TestRankingSnapshots(t *testing.T) {
+	// スナップショットを有効にしたサーバーを別に立てる
+	cfg := config
+	cfg.RankingSnapshotInterval = time.Hour
+	s, err := core.InjectDeps(db, cfg)
+	assert.NilError(t, err)
+	se := echo.New()
+	core.SetupRoutes(s.Handler, se)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+adminToken)
+		rec := httptest.NewRecorder()
+		se.ServeHTTP(rec, req)
+		return rec
+	}
+	type ranking struct {
+		Top []struct {
+			Score int `json:"score"`
+		} `json:"top"`
+		SnapshotAt *time.Time `json:"snapshot_at"`
+	}
+	chartRanking := func() ranking {
+		rec := do("GET", "/api/v1/charts/ranking?beatmap_id=songS_future", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var rs []ranking
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rs))
+		assert.Equal(t, len(rs), 1)
+		return rs[0]
+	}
+	submit := func(uid string, score string) {
+		body := `{"user_id":"` + uid + `","beatmap_id":"songS_future","score":` + score + `,"max_combo":100,"perfect_critical_fast":10,"perfect_critical_late":10,"perfect_fast":0,"perfect_late":0,"good_fast":0,"good_late":0,"miss":0,"input":0}`
+		rec := do("POST", "/api/v1/scores", body)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+
+	rec := do("POST", "/api/v1/charts", `{"beatmap_id":"songS_future","song_name":"Song S","difficulty":2}`)
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	rec = do("POST", "/api/v1/users", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	uid := unmarshalResponse(t, rec)["id"].(string)
+	submit(uid, "920000")
+
+	rec = do("POST", "/api/v1/admin/rankings/rebuild", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	var rebuilt struct {
+		SnapshotAt *time.Time `json:"snapshot_at"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &rebuilt))
+	assert.Assert(t, rebuilt.SnapshotAt != nil)
+
+	r := chartRanking()
+	assert.Assert(t, r.SnapshotAt != nil)
+	assert.Equal(t, len(r.Top), 1)
+	assert.Equal(t, r.Top[0].Score, 920000)
+
+	// 次の再構築まではスナップショットの内容を返す
+	submit(uid, "990000")
+	assert.Equal(t, chartRanking().Top[0].Score, 920000)
+
+	rec = do("GET", "/api/v1/ratings/ranking?limit=100", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	var ratings struct {
+		Items []struct {
+			UserID string  `json:"user_id"`
+			Rating float64 `json:"rating"`
+			Charts int     `json:"charts"`
+		} `json:"items"`
+		SnapshotAt *time.Time `json:"snapshot_at"`
+	}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &ratings))
+	assert.Assert(t, ratings.SnapshotAt != nil)
+	found := false
+	for _, it := range ratings.Items {
+		if it.UserID == uid {
+			found = true
+			// Future (定数9) で920000 -> 8.0、ベスト10譜面の平均
+			assert.Equal(t, it.Rating, 0.8)
+			assert.Equal(t, it.Charts, 1)
+		}
+	}
+	assert.Assert(t, found)
+
+	rec = do("POST", "/api/v1/admin/rankings/rebuild", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	assert.Equal(t, chartRanking().Top[0].Score, 990000)
+
+	rec = do("GET", "/api/v1/admin/jobs", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	var jobs []map[string]any
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &jobs))
+	assert.Equal(t, len(jobs), 1)
+	assert.Equal(t, jobs[0]["name"], "ranking-snapshots")
+	assert.Equal(t, jobs[0]["runs"], float64(2))
+
+	// スナップショットが無効なサーバーでは常に集計する
+	rec = doRequest(t, "GET", "/api/v1/ratings/ranking", "")
+	assert.Equal(t, rec.Result().Status, `200 OK`)
+	assert.Assert(t, !strings.Contains(rec.Body.String(), "snapshot_at"))
+	rec = doAdminRequest(t, "POST", "/api/v1/admin/rankings/rebuild", "")
+	assert.Equal(t, rec.Result().Status, `409 Conflict`)
+}
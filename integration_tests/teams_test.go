@@ -0,0 +1,195 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTeams(t *testing.T) {
+	ranking := func(t *testing.T, path string) []map[string]any {
+		t.Helper()
+		rec := doRequest(t, "GET", path, "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var res struct {
+			Items []map[string]any `json:"items"`
+		}
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		return res.Items
+	}
+	// only the teams of this test, as other tests may create teams too
+	ours := func(items []map[string]any, ids ...string) []map[string]any {
+		var out []map[string]any
+		for _, it := range items {
+			for _, id := range ids {
+				if it["team_id"] == id {
+					out = append(out, it)
+				}
+			}
+		}
+		return out
+	}
+
+	tara, theo, tess, tom := registerPlayer(t, "Tara"), registerPlayer(t, "Theo"), registerPlayer(t, "Tess"), registerPlayer(t, "Tom")
+	for _, d := range []string{"past", "present"} {
+		rec := doRequest(t, "POST", "/api/v1/charts", fmt.Sprintf(`{"beatmap_id":"songT_%s","song_name":"Song T","difficulty":0}`, d))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+	}
+	submitScore(t, tara.id, "songT_past", 900000)
+	submitScore(t, tara.id, "songT_past", 950000)
+	submitScore(t, theo.id, "songT_past", 800000)
+	submitScore(t, theo.id, "songT_present", 700000)
+	submitScore(t, tess.id, "songT_past", 990000)
+	submitScore(t, tom.id, "songT_present", 100000)
+
+	var lab, club, invite string
+
+	t.Run("create and join", func(t *testing.T) {
+		rec := doUserRequest(t, tara, "POST", "/api/v1/teams", `{"name":"  "}`)
+		assert.Equal(t, rec.Result().Status, `422 Unprocessable Entity`)
+
+		rec = doUserRequest(t, tara, "POST", "/api/v1/teams", `{"name":"Team T Lab"}`)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res := unmarshalResponse(t, rec)
+		lab, invite = res["id"].(string), res["invite_code"].(string)
+		assert.Equal(t, len(invite), 8)
+		rec = doUserRequest(t, theo, "POST", "/api/v1/teams", `{"name":"Team T Lab"}`)
+		assert.Equal(t, rec.Result().Status, `409 Conflict`)
+
+		rec = doUserRequest(t, tess, "POST", "/api/v1/teams", `{"name":"Team T Club"}`)
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res = unmarshalResponse(t, rec)
+		club = res["id"].(string)
+		rec = doUserRequest(t, tom, "POST", "/api/v1/teams/join", fmt.Sprintf(`{"invite_code":"%s"}`, res["invite_code"]))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+
+		rec = doUserRequest(t, theo, "POST", "/api/v1/teams/join", `{"invite_code":"NOPE2345"}`)
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		assert.Equal(t, unmarshalResponse(t, rec)["code"], "TEAM_NOT_FOUND")
+		rec = doUserRequest(t, theo, "POST", "/api/v1/teams/join", fmt.Sprintf(`{"invite_code":"%s-%s"}`, strings.ToLower(invite[:4]), strings.ToLower(invite[4:])))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		assert.Equal(t, unmarshalResponse(t, rec)["member_count"], float64(2))
+		rec = doUserRequest(t, theo, "POST", "/api/v1/teams/join", fmt.Sprintf(`{"invite_code":"%s"}`, invite))
+		assert.Equal(t, rec.Result().Status, `409 Conflict`)
+
+		rec = doUserRequest(t, theo, "GET", "/api/v1/users/me/teams", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		var mine []map[string]any
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &mine))
+		assert.Equal(t, len(mine), 1)
+		assert.Equal(t, mine[0]["role"], "member")
+
+		rec = doRequest(t, "GET", "/api/v1/teams/"+lab, "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		members := unmarshalResponse(t, rec)["members"].([]any)
+		assert.Equal(t, members[0].(map[string]any)["player_name"], "Tara")
+		assert.Equal(t, members[0].(map[string]any)["role"], "owner")
+		assert.Equal(t, members[1].(map[string]any)["player_name"], "Theo")
+	})
+
+	t.Run("roles", func(t *testing.T) {
+		assert.Equal(t, doUserRequest(t, theo, "GET", "/api/v1/teams/"+lab+"/invite-code", "").Result().Status, `403 Forbidden`)
+		assert.Equal(t, doUserRequest(t, tom, "GET", "/api/v1/teams/"+lab+"/invite-code", "").Result().Status, `403 Forbidden`)
+		rec := doUserRequest(t, theo, "PUT", "/api/v1/teams/"+lab+"/members/"+tara.id+"/role", `{"role":"member"}`)
+		assert.Equal(t, rec.Result().Status, `403 Forbidden`)
+		rec = doUserRequest(t, tara, "PUT", "/api/v1/teams/"+lab+"/members/"+theo.id+"/role", `{"role":"boss"}`)
+		assert.Equal(t, rec.Result().Status, `422 Unprocessable Entity`)
+		rec = doUserRequest(t, tara, "PUT", "/api/v1/teams/"+lab+"/members/"+tara.id+"/role", `{"role":"member"}`)
+		assert.Equal(t, rec.Result().Status, `409 Conflict`)
+
+		rec = doUserRequest(t, tara, "PUT", "/api/v1/teams/"+lab+"/members/"+theo.id+"/role", `{"role":"admin"}`)
+		assert.Equal(t, rec.Result().Status, `204 No Content`)
+		rec = doUserRequest(t, theo, "POST", "/api/v1/teams/"+lab+"/invite-code", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		newInvite := unmarshalResponse(t, rec)["invite_code"].(string)
+		assert.Assert(t, newInvite != invite)
+		rec = doUserRequest(t, tara, "GET", "/api/v1/teams/"+lab+"/invite-code", "")
+		assert.Equal(t, unmarshalResponse(t, rec)["invite_code"], newInvite)
+		// the old code no longer works
+		rec = doUserRequest(t, tom, "POST", "/api/v1/teams/join", fmt.Sprintf(`{"invite_code":"%s"}`, invite))
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		invite = newInvite
+
+		// admins cannot remove the owner
+		rec = doUserRequest(t, theo, "DELETE", "/api/v1/teams/"+lab+"/members/"+tara.id, "")
+		assert.Equal(t, rec.Result().Status, `403 Forbidden`)
+	})
+
+	t.Run("rankings", func(t *testing.T) {
+		items := ours(ranking(t, "/api/v1/teams/ranking?beatmap_id=songT_past&limit=100"), lab, club)
+		assert.Equal(t, len(items), 2)
+		// Lab: 950000 + 800000; Club: 990000 (Tom has no score on the chart)
+		assert.Equal(t, items[0]["team_name"], "Team T Lab")
+		assert.Equal(t, items[0]["total_score"], float64(1750000))
+		assert.Equal(t, items[0]["players"], float64(2))
+		assert.Equal(t, items[0]["play_count"], float64(3))
+		assert.Equal(t, items[1]["team_name"], "Team T Club")
+		assert.Equal(t, items[1]["total_score"], float64(990000))
+		assert.Equal(t, items[1]["players"], float64(1))
+
+		items = ours(ranking(t, "/api/v1/teams/ranking?limit=100"), lab, club)
+		assert.Equal(t, items[0]["total_score"], float64(2450000))
+		assert.Equal(t, items[0]["charts"], float64(2))
+		assert.Equal(t, items[1]["total_score"], float64(1090000))
+
+		items = ours(ranking(t, "/api/v1/teams/ranking/weekly?limit=100"), lab, club)
+		assert.Equal(t, len(items), 2)
+		assert.Equal(t, items[0]["total_score"], float64(2450000))
+		assert.Equal(t, len(ours(ranking(t, "/api/v1/teams/ranking/weekly?week=2020-W10&limit=100"), lab, club)), 0)
+
+		rec := doRequest(t, "GET", "/api/v1/teams/ranking/weekly?week=2025-W53", "")
+		assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+		rec = doRequest(t, "GET", "/api/v1/teams/ranking/weekly?week=last", "")
+		assert.Equal(t, rec.Result().Status, `400 Bad Request`)
+	})
+
+	t.Run("versus", func(t *testing.T) {
+		rec := doRequest(t, "GET", "/api/v1/teams/"+club+"/versus/"+lab, "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res := unmarshalResponse(t, rec)
+		assert.Equal(t, res["team"].(map[string]any)["total_score"], float64(1090000))
+		assert.Equal(t, res["other"].(map[string]any)["total_score"], float64(2450000))
+		assert.Equal(t, res["winner_id"], lab)
+
+		rec = doRequest(t, "GET", "/api/v1/teams/"+club+"/versus/"+lab+"?week=2020-W10", "")
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		res = unmarshalResponse(t, rec)
+		assert.Equal(t, res["week"], "2020-W10")
+		assert.Equal(t, res["from"], "2020-03-02T00:00:00Z")
+		assert.Equal(t, res["other"].(map[string]any)["team_name"], "Team T Lab")
+		assert.Equal(t, res["other"].(map[string]any)["total_score"], float64(0))
+		assert.Equal(t, res["winner_id"], nil)
+
+		rec = doRequest(t, "GET", "/api/v1/teams/"+club+"/versus/00000000-0000-0000-0000-000000000000", "")
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+	})
+
+	t.Run("leaving hands the team over", func(t *testing.T) {
+		assert.Equal(t, doUserRequest(t, tom, "DELETE", "/api/v1/teams/"+lab, "").Result().Status, `403 Forbidden`)
+		assert.Equal(t, doUserRequest(t, theo, "DELETE", "/api/v1/teams/"+lab, "").Result().Status, `403 Forbidden`)
+
+		rec := doUserRequest(t, tom, "POST", "/api/v1/teams/join", fmt.Sprintf(`{"invite_code":"%s"}`, invite))
+		assert.Equal(t, rec.Result().Status, `200 OK`)
+		// admins remove members
+		assert.Equal(t, doUserRequest(t, theo, "DELETE", "/api/v1/teams/"+lab+"/members/"+tom.id, "").Result().Status, `204 No Content`)
+		rec = doUserRequest(t, theo, "DELETE", "/api/v1/teams/"+lab+"/members/"+tom.id, "")
+		assert.Equal(t, rec.Result().Status, `404 Not Found`)
+		assert.Equal(t, unmarshalResponse(t, rec)["code"], "TEAM_MEMBER_NOT_FOUND")
+
+		assert.Equal(t, doUserRequest(t, tara, "DELETE", "/api/v1/teams/"+lab+"/members/"+tara.id, "").Result().Status, `204 No Content`)
+		rec = doUserRequest(t, theo, "GET", "/api/v1/users/me/teams", "")
+		var mine []map[string]any
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &mine))
+		assert.Equal(t, mine[0]["role"], "owner")
+
+		// an account deletion hands over too, and the last member takes the team with them
+		assert.Equal(t, doUserRequest(t, theo, "DELETE", "/api/v1/users/me", "").Result().Status, `200 OK`)
+		assert.Equal(t, doRequest(t, "GET", "/api/v1/teams/"+lab, "").Result().Status, `404 Not Found`)
+
+		assert.Equal(t, doUserRequest(t, tess, "DELETE", "/api/v1/teams/"+club, "").Result().Status, `204 No Content`)
+		assert.Equal(t, doRequest(t, "GET", "/api/v1/teams/"+club, "").Result().Status, `404 Not Found`)
+	})
+}
@@ -1,11 +1,19 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/pikachu0310/senirenol-server/core"
-	"github.com/pikachu0310/senirenol-server/core/database"
 
+	"github.com/alecthomas/kong"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/ras0q/goalie"
@@ -27,9 +35,28 @@ import (
 // @BasePath /api/v1
 // @schemes https
 
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description "Bearer <ADMIN_TOKEN>"
+
+// @securityDefinitions.apikey UserToken
+// @in header
+// @name Authorization
+// @description "Bearer <token>" (the token returned by POST /users)
+
+// cli is the command line. Config is read from flags or the environment by every command.
+type cli struct {
+	core.Config
+
+	Serve serveCmd `cmd:"" default:"1" help:"Serve the API (default)."`
+	core.Commands
+}
+
 func main() {
 	if err := run(); err != nil {
-		log.Fatalf("runtime error: %+v", err)
+		slog.Error("runtime error", "error", err)
+		os.Exit(1)
 	}
 }
 
@@ -37,27 +64,79 @@ func run() (err error) {
 	g := goalie.New()
 	defer g.Collect(&err)
 
-	var config core.Config
-	config.Parse()
+	var c cli
+	kctx := kong.Parse(&c, kong.Description("Senirenol Bloom score server."))
+	config := &c.Config
+
+	logger, err := config.NewLogger()
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+
+	// flushed last so that spans of the shutdown are exported too
+	shutdownTracing, err := config.SetupTracing(context.Background())
+	if err != nil {
+		return err
+	}
+	defer g.Guard(func() error { return shutdownTracing(context.Background()) })
+
+	// ends the server, and cancels the other commands
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	kctx.BindTo(ctx, (*context.Context)(nil))
+	kctx.BindTo(kctx.Stdout, (*io.Writer)(nil))
+	return kctx.Run(config)
+}
+
+type serveCmd struct{}
+
+func (serveCmd) Run(ctx context.Context, config *core.Config) (err error) {
+	g := goalie.New()
+	defer g.Collect(&err)
 
 	e := echo.New()
 
-	// middlewares
+	// middlewares; request IDs, access logs and error responses are set up with the routes
 	e.Use(middleware.Recover())
-	e.Use(middleware.Logger())
 
 	// connect to and migrate database
-	db, err := database.Setup(config.MySQLConfig())
+	db, err := config.SetupDatabase()
 	if err != nil {
 		return err
 	}
 	defer g.Guard(db.Close)
 
-	s := core.InjectDeps(db)
+	s, err := core.InjectDeps(db, *config)
+	if err != nil {
+		return err
+	}
+
+	// background jobs; stopped before the database is closed
+	s.Scheduler.Start(context.Background())
+	defer s.Scheduler.Stop()
 
 	core.SetupRoutes(s.Handler, e)
 
-	if err := e.Start(config.AppAddr); err != nil {
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- e.Start(config.AppAddr) }()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// fail readiness and end event streams, then wait for in-flight requests
+	slog.Info("shutting down", "timeout", config.ShutdownTimeout)
+	s.Health.Drain()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
 